@@ -0,0 +1,142 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"net/url"
+	"slices"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+// Run bundle entry names. Each task is stored as BundleTasksDir + <escaped task ID>.json.
+const (
+	BundleRunEntry     = "run.json"     // status response with dag, policy and order expanded
+	BundleRequestEntry = "request.json" // StartRunRequest that reproduces the run
+	BundleDAGEntry     = "dag.json"
+	BundlePolicyEntry  = "policy.json"
+	BundleUsageEntry   = "usage.json"
+	BundleTasksDir     = "tasks/"
+)
+
+// TaskBundleDTO is a task's entry in a run bundle.
+type TaskBundleDTO struct {
+	ID       string            `json:"id"`
+	State    string            `json:"state"`
+	Model    string            `json:"model"`
+	Deps     []string          `json:"deps,omitempty"`
+	Prompt   string            `json:"prompt"`
+	Inputs   map[string]string `json:"inputs,omitempty"` // including routed dependency outputs
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Output   string            `json:"output,omitempty"`
+	Outputs  map[string]string `json:"outputs,omitempty"`
+	Error    *ErrorDTO         `json:"error,omitempty"`
+	Usage    *UsageDTO         `json:"usage,omitempty"`
+}
+
+// BundleTaskEntry returns the bundle entry name for a task.
+// The ID is path-escaped so it is always a single file name.
+func BundleTaskEntry(id string) string {
+	return BundleTasksDir + url.PathEscape(id) + ".json"
+}
+
+// WriteBundle writes a finished run as a ZIP archive to w.
+// Entries are written in a fixed order (tasks sorted by ID).
+func WriteBundle(w io.Writer, b *RunBundle) error {
+	snap := b.Snapshot
+	run := SnapshotToResponse(snap)
+	run.ApplyExpand(snap, ExpandOptions{DAG: true, Policy: true, Order: true})
+
+	ids := make([]contracts.TaskID, 0, len(b.Specs))
+	for id := range b.Specs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	req := StartRunRequest{
+		ID:         string(snap.ID),
+		Policy:     *PolicyToDTO(snap.Policy),
+		Tasks:      make([]TaskDTO, 0, len(ids)),
+		ConfigHash: snap.ConfigHash,
+		Labels:     snap.Labels,
+		Memory:     b.Memory,
+	}
+	for _, id := range ids {
+		req.Tasks = append(req.Tasks, TaskToDTO(b.Specs[id]))
+	}
+
+	zw := zip.NewWriter(w)
+	entries := []struct {
+		name  string
+		value any
+	}{
+		{BundleRunEntry, run},
+		{BundleRequestEntry, req},
+		{BundleDAGEntry, run.DAG},
+		{BundlePolicyEntry, run.Policy},
+		{BundleUsageEntry, run.Usage},
+	}
+	for _, e := range entries {
+		if err := writeBundleEntry(zw, e.name, e.value); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range ids {
+		task, ok := b.Tasks[id]
+		if !ok {
+			continue
+		}
+		if err := writeBundleEntry(zw, BundleTaskEntry(string(id)), taskToBundleDTO(task)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeBundleEntry writes value as an indented JSON file entry.
+func writeBundleEntry(zw *zip.Writer, name string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// taskToBundleDTO converts a task's final state to its bundle entry.
+func taskToBundleDTO(task contracts.Task) TaskBundleDTO {
+	dto := TaskBundleDTO{
+		ID:    string(task.ID),
+		State: task.State.String(),
+		Model: string(task.Model),
+		Deps:  taskIDStrings(task.Deps),
+	}
+	if task.Inputs != nil {
+		dto.Prompt = task.Inputs.Prompt
+		dto.Inputs = task.Inputs.Inputs
+		dto.Metadata = redactMetadata(task.Inputs.Metadata)
+	}
+	if task.Outputs != nil {
+		dto.Output = task.Outputs.Output
+		dto.Outputs = task.Outputs.Outputs
+		dto.Usage = &UsageDTO{
+			Tokens:       int64(task.Outputs.Usage.Tokens),
+			InputTokens:  int64(task.Outputs.Usage.InputTokens),
+			OutputTokens: int64(task.Outputs.Usage.OutputTokens),
+			Cost: &CostDTO{
+				Amount:   task.Outputs.Usage.Cost.Amount,
+				Currency: string(task.Outputs.Usage.Cost.Currency),
+			},
+		}
+	}
+	if task.Error != nil {
+		dto.Error = &ErrorDTO{Code: task.Error.Code, Message: task.Error.Message}
+	}
+	return dto
+}
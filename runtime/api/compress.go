@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressMinBytes is the default minimum body size for gzip compression.
+// Smaller bodies are sent uncompressed since gzip overhead outweighs the savings.
+const DefaultCompressMinBytes = 1024
+
+// gzipHandler compresses responses of at least minBytes for clients that send
+// Accept-Encoding: gzip. The body is buffered so the size is known before the
+// headers are written. A negative minBytes disables compression.
+func gzipHandler(next http.HandlerFunc, minBytes int) http.HandlerFunc {
+	if minBytes < 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next(w, r)
+			return
+		}
+
+		bw := &bufferedResponseWriter{ResponseWriter: w}
+		next(bw, r)
+		if bw.status == 0 {
+			bw.status = http.StatusOK
+		}
+
+		if bw.buf.Len() < minBytes || w.Header().Get("Content-Encoding") != "" {
+			w.WriteHeader(bw.status)
+			w.Write(bw.buf.Bytes())
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Del("Content-Length")
+		w.WriteHeader(bw.status)
+		gz := gzip.NewWriter(w)
+		gz.Write(bw.buf.Bytes())
+		gz.Close()
+	}
+}
+
+// bufferedResponseWriter captures the status code and body of a response.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+func (b *bufferedResponseWriter) WriteHeader(code int) {
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *bufferedResponseWriter) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.buf.Write(p)
+}
+
+// acceptsGzip reports whether an Accept-Encoding header value allows gzip.
+// Codings with q=0 are treated as refused.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -13,6 +13,27 @@ var (
 	// ErrRunExists is returned when trying to create a run with an existing ID.
 	ErrRunExists = errors.New("run already exists")
 
+	// ErrRunNotTerminal is returned when an operation needs a finished run.
+	ErrRunNotTerminal = errors.New("run is not in a terminal state")
+
+	// ErrTaskTerminal is returned when cancelling a task that already finished.
+	ErrTaskTerminal = errors.New("task is already in a terminal state")
+
+	// ErrLabelLimitExceeded is returned when a run label is at its active-run limit.
+	ErrLabelLimitExceeded = errors.New("label active run limit exceeded")
+
+	// ErrTooManyScheduled is returned when a scheduled run is submitted while
+	// ServerOptions.MaxScheduledRuns runs are waiting for their start.
+	ErrTooManyScheduled = errors.New("too many scheduled runs")
+
+	// ErrUnknownMetadataKey is returned under strict metadata for a task
+	// metadata key that is not accepted (see ServerOptions.StrictMetadata).
+	ErrUnknownMetadataKey = errors.New("unknown task metadata key")
+
+	// ErrRestartInterrupted is the error of a run that was still active when
+	// the sidecar stopped; it is failed when restored from persistence.
+	ErrRestartInterrupted = errors.New("run interrupted by sidecar restart")
+
 	// ErrNotImplemented is returned for endpoints not yet implemented.
 	ErrNotImplemented = errors.New("not implemented in V1")
 )
@@ -22,21 +43,33 @@ type ErrorCode string
 
 // Error codes for API responses.
 const (
-	CodeInvalidInput   ErrorCode = "invalid_input"
-	CodeDAGCycle       ErrorCode = "dag_cycle"
-	CodeDAGInvalid     ErrorCode = "dag_invalid"
-	CodeDepNotFound    ErrorCode = "dep_not_found"
-	CodeRunNotFound    ErrorCode = "run_not_found"
-	CodeRunExists      ErrorCode = "run_exists"
-	CodeRunCompleted   ErrorCode = "run_completed"
-	CodeRunAborted     ErrorCode = "run_aborted"
-	CodeBudgetExceeded ErrorCode = "budget_exceeded"
-	CodeTaskFailed     ErrorCode = "task_failed"
-	CodeDeadlock       ErrorCode = "deadlock"
-	CodeCancelled      ErrorCode = "cancelled"
-	CodeTimeout        ErrorCode = "timeout"
-	CodeNotImplemented ErrorCode = "not_implemented"
-	CodeInternalError  ErrorCode = "internal_error"
+	CodeInvalidInput        ErrorCode = "invalid_input"
+	CodeDAGCycle            ErrorCode = "dag_cycle"
+	CodeDAGInvalid          ErrorCode = "dag_invalid"
+	CodeDepNotFound         ErrorCode = "dep_not_found"
+	CodeRunNotFound         ErrorCode = "run_not_found"
+	CodeRunExists           ErrorCode = "run_exists"
+	CodeRunCompleted        ErrorCode = "run_completed"
+	CodeRunAborted          ErrorCode = "run_aborted"
+	CodeRunNotTerminal      ErrorCode = "run_not_terminal"
+	CodeTaskNotFound        ErrorCode = "task_not_found"
+	CodeTaskTerminal        ErrorCode = "task_terminal"
+	CodeBudgetExceeded      ErrorCode = "budget_exceeded"
+	CodeModelBudgetExceeded ErrorCode = "model_budget_exceeded"
+	CodeBudgetPoolExhausted ErrorCode = "budget_pool_exhausted"
+	CodeTaskFailed          ErrorCode = "task_failed"
+	CodeDeadlock            ErrorCode = "deadlock"
+	CodeBatchLimitExceeded  ErrorCode = "batch_limit_exceeded"
+	CodeFailureThreshold    ErrorCode = "failure_threshold_exceeded"
+	CodeLabelLimitExceeded  ErrorCode = "label_limit_exceeded"
+	CodeTooManyScheduled    ErrorCode = "too_many_scheduled"
+	CodeUnknownMetadataKey  ErrorCode = "unknown_metadata_key"
+	CodeCancelled           ErrorCode = "cancelled"
+	CodeTimeout             ErrorCode = "timeout"
+	CodeContextTimeout      ErrorCode = "context_timeout"
+	CodeRestartInterrupted  ErrorCode = "restart_interrupted"
+	CodeNotImplemented      ErrorCode = "not_implemented"
+	CodeInternalError       ErrorCode = "internal_error"
 )
 
 // HTTPError represents an error with an associated HTTP status code.
@@ -60,11 +93,21 @@ func MapError(err error) *HTTPError {
 		return nil
 	}
 
+	// Already mapped (e.g. an error restored from persistence)
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+
 	// Check for specific error types
 	switch {
-	case errors.Is(err, contracts.ErrInvalidInput):
+	case errors.Is(err, contracts.ErrInvalidInput),
+		errors.Is(err, contracts.ErrCurrencyMismatch):
 		return &HTTPError{http.StatusBadRequest, CodeInvalidInput, err}
 
+	case errors.Is(err, ErrUnknownMetadataKey):
+		return &HTTPError{http.StatusBadRequest, CodeUnknownMetadataKey, err}
+
 	case errors.Is(err, contracts.ErrDAGCycle):
 		return &HTTPError{http.StatusUnprocessableEntity, CodeDAGCycle, err}
 
@@ -86,24 +129,57 @@ func MapError(err error) *HTTPError {
 	case errors.Is(err, contracts.ErrRunAborted):
 		return &HTTPError{http.StatusConflict, CodeRunAborted, err}
 
+	case errors.Is(err, ErrRunNotTerminal):
+		return &HTTPError{http.StatusConflict, CodeRunNotTerminal, err}
+
+	case errors.Is(err, contracts.ErrTaskNotFound):
+		return &HTTPError{http.StatusNotFound, CodeTaskNotFound, err}
+
+	case errors.Is(err, ErrTaskTerminal):
+		return &HTTPError{http.StatusConflict, CodeTaskTerminal, err}
+
+	case errors.Is(err, contracts.ErrModelBudgetExceeded):
+		return &HTTPError{http.StatusUnprocessableEntity, CodeModelBudgetExceeded, err}
+
+	case errors.Is(err, contracts.ErrBudgetPoolExhausted):
+		return &HTTPError{http.StatusUnprocessableEntity, CodeBudgetPoolExhausted, err}
+
 	case errors.Is(err, contracts.ErrBudgetExceeded):
 		return &HTTPError{http.StatusUnprocessableEntity, CodeBudgetExceeded, err}
 
+	case errors.Is(err, ErrLabelLimitExceeded):
+		return &HTTPError{http.StatusTooManyRequests, CodeLabelLimitExceeded, err}
+
+	case errors.Is(err, ErrTooManyScheduled):
+		return &HTTPError{http.StatusTooManyRequests, CodeTooManyScheduled, err}
+
 	case errors.Is(err, contracts.ErrTaskFailed):
 		return &HTTPError{http.StatusInternalServerError, CodeTaskFailed, err}
 
 	case errors.Is(err, contracts.ErrDeadlock):
 		return &HTTPError{http.StatusInternalServerError, CodeDeadlock, err}
 
+	case errors.Is(err, contracts.ErrBatchLimitExceeded):
+		return &HTTPError{http.StatusInternalServerError, CodeBatchLimitExceeded, err}
+
+	case errors.Is(err, contracts.ErrFailureThresholdExceeded):
+		return &HTTPError{http.StatusUnprocessableEntity, CodeFailureThreshold, err}
+
 	case errors.Is(err, context.Canceled),
 		errors.Is(err, contracts.ErrTaskCancelled):
 		// 499: nginx convention for "client closed request"
 		return &HTTPError{499, CodeCancelled, err}
 
+	case errors.Is(err, contracts.ErrContextTimeout):
+		return &HTTPError{http.StatusGatewayTimeout, CodeContextTimeout, err}
+
 	case errors.Is(err, context.DeadlineExceeded),
 		errors.Is(err, contracts.ErrTaskTimeout):
 		return &HTTPError{http.StatusGatewayTimeout, CodeTimeout, err}
 
+	case errors.Is(err, ErrRestartInterrupted):
+		return &HTTPError{http.StatusServiceUnavailable, CodeRestartInterrupted, err}
+
 	case errors.Is(err, ErrNotImplemented):
 		return &HTTPError{http.StatusNotImplemented, CodeNotImplemented, err}
 
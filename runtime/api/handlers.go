@@ -1,14 +1,23 @@
 package api
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/anthropics/claude-workflow/runtime/contracts"
@@ -27,20 +36,95 @@ const runRetention = time.Hour
 // Imported from orchestration package for consistency.
 type TaskExecutorFunc = orchestration.TaskExecutorFunc
 
+// StreamingTaskExecutorFunc is a TaskExecutorFunc variant that emits partial output.
+// Imported from orchestration package for consistency.
+type StreamingTaskExecutorFunc = orchestration.StreamingTaskExecutorFunc
+
+// defaultCurrency is used when ServerOptions.DefaultCurrency is empty.
+const defaultCurrency = contracts.Currency("USD")
+
 // Handlers contains the HTTP handler methods for the API.
 type Handlers struct {
-	store    *RunStore
-	executor TaskExecutorFunc
-	auditDir string // directory for run audit JSON files (empty = disabled)
+	store           Store
+	executor        TaskExecutorFunc
+	auditDir        string             // directory for run audit JSON files (empty = disabled)
+	defaultCurrency contracts.Currency // applied to budgets without a currency
+
+	// streamingExecutor overrides executor when set.
+	streamingExecutor StreamingTaskExecutorFunc
+
+	// executors routes tasks by metadata; executor is the fallback.
+	executors map[string]TaskExecutorFunc
+
+	budgetPool    contracts.BudgetPool // shared across runs (nil = disabled)
+	noBudget      bool                 // dev-only: skip budget enforcement for all runs
+	defaultBudget contracts.Cost       // applied by StartRun to runs without a budget (zero = none)
+
+	policyDefaults  *PolicyDefaults   // merged under submitted policies (nil = none)
+	metadataKeys    map[string]bool   // accepted task metadata keys (nil = any key)
+	providerHeaders map[string]string // attached to every task's context (nil = none)
+
+	// labelLimits caps active runs per "key=value" label (nil = none) and
+	// maxScheduled the runs waiting for their start (0 = no cap).
+	// limitMu serializes the limit checks with run creation.
+	labelLimits  map[string]int
+	maxScheduled int
+	limitMu      sync.Mutex
+
+	// clock decides when scheduled runs start and times each run.
+	clock orchestration.Clock
+
+	// controls holds the channels to the orchestrators of active runs.
+	controlsMu sync.Mutex
+	controls   map[contracts.RunID]*runControls
+}
+
+// runControls delivers API requests to a running orchestrator.
+type runControls struct {
+	canceller *orchestration.TaskCanceller
+	budget    *orchestration.BudgetOverride
 }
 
 // NewHandlers creates a new Handlers instance.
 // auditDir specifies the directory for run audit JSON files (empty = disabled).
-func NewHandlers(store *RunStore, executor TaskExecutorFunc, auditDir string) *Handlers {
+func NewHandlers(store Store, executor TaskExecutorFunc, auditDir string) *Handlers {
+	return NewHandlersWithOptions(store, executor, ServerOptions{AuditDir: auditDir})
+}
+
+// NewHandlersWithOptions creates a new Handlers instance with custom options.
+func NewHandlersWithOptions(store Store, executor TaskExecutorFunc, opts ServerOptions) *Handlers {
+	currency := opts.DefaultCurrency
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	var metadataKeys map[string]bool
+	if opts.StrictMetadata {
+		metadataKeys = make(map[string]bool)
+		for _, key := range append(KnownMetadataKeys(), opts.MetadataKeys...) {
+			metadataKeys[key] = true
+		}
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = orchestration.SystemClock
+	}
 	return &Handlers{
-		store:    store,
-		executor: executor,
-		auditDir: auditDir,
+		store:             store,
+		executor:          executor,
+		auditDir:          opts.AuditDir,
+		defaultCurrency:   currency,
+		streamingExecutor: opts.StreamingExecutor,
+		executors:         opts.Executors,
+		budgetPool:        opts.BudgetPool,
+		noBudget:          opts.NoBudget,
+		defaultBudget:     contracts.Cost{Amount: opts.DefaultBudget, Currency: currency},
+		policyDefaults:    opts.PolicyDefaults,
+		metadataKeys:      metadataKeys,
+		providerHeaders:   canonicalHeaders(opts.ProviderHeaders),
+		labelLimits:       opts.LabelLimits,
+		maxScheduled:      opts.MaxScheduledRuns,
+		clock:             clock,
+		controls:          make(map[contracts.RunID]*runControls),
 	}
 }
 
@@ -64,8 +148,21 @@ func (h *Handlers) HandleStartRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// House defaults fill what the request leaves unset; caps clamp the rest
+	h.policyDefaults.Apply(&req.Policy)
+
 	// Validate required fields
-	if err := validateStartRunRequest(&req); err != nil {
+	if err := validateStartRunRequest(&req, h.noBudget); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if err := validateMetadataKeys(req.Tasks, h.metadataKeys); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Normalize currencies so downstream comparisons are unambiguous
+	if err := normalizeCurrencies(&req.Policy, h.defaultCurrency); err != nil {
 		WriteError(w, err)
 		return
 	}
@@ -108,7 +205,44 @@ func (h *Handlers) HandleStartRun(w http.ResponseWriter, r *http.Request) {
 		Policy: policy,
 		DAG:    dag,
 		Tasks:  taskMap,
-		Memory: make(map[string]string),
+		Memory: seedMemory(req.Memory),
+
+		ConfigHash: req.ConfigHash,
+		StartAfter: contracts.Timestamp(req.StartAfter),
+		Labels:     req.Labels,
+	}
+
+	if err := h.startLimitedRun(run); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Return 202 Accepted (use snapshot for consistency, though race unlikely here)
+	snap, _ := h.store.GetSnapshot(run.ID)
+	resp := SnapshotToResponse(snap)
+	resp.Warnings = startRunWarnings(&req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, resp)
+}
+
+// StartRun stores run and executes it in the background.
+// A run with StartAfter in the future is stored as RunScheduled and waits.
+// It is also the entry point for runs created in-process (e.g. clones or
+// resumed runs), which skip request validation: a run without a positive
+// budget gets the server's default budget, if one is configured, instead of
+// failing every task with ErrBudgetNotSet.
+func (h *Handlers) StartRun(run *contracts.Run) error {
+	if h.noBudget {
+		run.Policy.BudgetDisabled = true
+	}
+	if run.TraceID == "" {
+		run.TraceID = contracts.NewTraceID()
+	}
+	h.applyDefaultBudget(run)
+	if h.startsLater(run) {
+		run.State = contracts.RunScheduled
 	}
 
 	// Create cancellable context for the run
@@ -117,26 +251,187 @@ func (h *Handlers) HandleStartRun(w http.ResponseWriter, r *http.Request) {
 	// Store the run
 	if err := h.store.Create(run, cancel); err != nil {
 		cancel() // clean up context
-		WriteError(w, err)
-		return
+		return err
 	}
 
 	// Best-effort cleanup of old completed runs
 	h.store.PruneCompleted(runRetention)
 
+	h.controlsMu.Lock()
+	h.controls[run.ID] = &runControls{
+		canceller: orchestration.NewTaskCanceller(),
+		budget:    orchestration.NewBudgetOverride(),
+	}
+	h.controlsMu.Unlock()
+
 	// Start orchestrator in background
 	go h.runOrchestrator(ctx, run)
+	return nil
+}
 
-	// Return 202 Accepted (use snapshot for consistency, though race unlikely here)
-	snap, _ := h.store.GetSnapshot(run.ID)
-	resp := SnapshotToResponse(snap)
+// startsLater reports whether run has a start_after time in the future.
+func (h *Handlers) startsLater(run *contracts.Run) bool {
+	return h.clock.Now().UnixMilli() < int64(run.StartAfter)
+}
+
+// startLimitedRun starts run unless one of its labels is at its active-run
+// limit, or it is scheduled and maxScheduled runs already wait for their
+// start. The counts are checked and the run created under limitMu, so
+// concurrent submissions cannot both take the last slot.
+func (h *Handlers) startLimitedRun(run *contracts.Run) error {
+	if len(h.labelLimits) == 0 && h.maxScheduled <= 0 {
+		return h.StartRun(run)
+	}
+	h.limitMu.Lock()
+	defer h.limitMu.Unlock()
+
+	if h.maxScheduled > 0 && h.startsLater(run) {
+		if scheduled := h.store.ScheduledCount(); scheduled >= h.maxScheduled {
+			return fmt.Errorf("%d runs are scheduled (limit %d): %w", scheduled, h.maxScheduled, ErrTooManyScheduled)
+		}
+	}
+	for _, label := range RunLabels(run.Labels) {
+		limit, ok := h.labelLimits[label]
+		if !ok {
+			continue
+		}
+		if active := h.store.ActiveLabelCount(label); active >= limit {
+			return fmt.Errorf("label %s has %d active runs (limit %d): %w", label, active, limit, ErrLabelLimitExceeded)
+		}
+	}
+	return h.StartRun(run)
+}
+
+// applyDefaultBudget sets the server's default budget on a run whose budget
+// is zero. Runs with budgets disabled, or without a configured default, are
+// left unchanged.
+func (h *Handlers) applyDefaultBudget(run *contracts.Run) {
+	if run.Policy.BudgetDisabled || run.Policy.BudgetLimit.Amount > 0 || h.defaultBudget.Amount <= 0 {
+		return
+	}
+	run.Policy.BudgetLimit = h.defaultBudget
+	log.Printf("[AUDIT] event=budget_defaulted run_id=%s trace_id=%s budget=%.4f currency=%s",
+		run.ID, run.TraceID, h.defaultBudget.Amount, h.defaultBudget.Currency)
+}
+
+// Run listing page sizes for GET /api/v1/runs.
+const (
+	defaultListLimit = 50
+	maxListLimit     = 1000
+)
+
+// listStates are the values accepted by GET /api/v1/runs?state=.
+var listStates = []string{"pending", "scheduled", "running", "aborting", "completed", "failed", "aborted"}
+
+// HandleListRuns handles GET /api/v1/runs.
+// Returns run summaries sorted by created_at descending (ties by ID).
+// Optional ?state= filters by API state; ?limit= (default 50, max 1000) and
+// ?offset= paginate. X-Total-Count holds the number of matching runs.
+func (h *Handlers) HandleListRuns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	state := query.Get("state")
+	if state != "" && !slices.Contains(listStates, state) {
+		WriteError(w, fmt.Errorf("unknown state %q: %w", state, contracts.ErrInvalidInput))
+		return
+	}
+	limit, err := parseListParam(query.Get("limit"), "limit", defaultListLimit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if limit < 1 || limit > maxListLimit {
+		WriteError(w, fmt.Errorf("limit must be between 1 and %d: %w", maxListLimit, contracts.ErrInvalidInput))
+		return
+	}
+	offset, err := parseListParam(query.Get("offset"), "offset", 0)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// List reads shadow state, never the orchestrator's live Run
+	runs := h.store.List()
+	if state != "" {
+		runs = slices.DeleteFunc(runs, func(s RunSummary) bool { return s.APIState != state })
+	}
+	slices.SortFunc(runs, func(a, b RunSummary) int {
+		if a.CreatedAt != b.CreatedAt {
+			return cmp.Compare(b.CreatedAt, a.CreatedAt)
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	total := len(runs)
+	start := min(offset, total)
+	page := runs[start:min(start+limit, total)]
+	resp := make([]RunSummaryDTO, len(page))
+	for i, s := range page {
+		resp[i] = SummaryToDTO(s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	writeJSON(w, resp)
+}
+
+// parseListParam parses a non-negative integer query parameter.
+// An empty value returns def.
+func parseListParam(value, name string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer: %w", name, contracts.ErrInvalidInput)
+	}
+	return n, nil
+}
+
+// HandlePruneRuns handles POST /api/v1/runs/prune.
+// Removes finished runs not updated within ?retention= (a duration such as
+// 30m or 24h; default 1h). With ?dry_run=true it reports those runs and
+// their ages instead, and removes nothing.
+func (h *Handlers) HandlePruneRuns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	retention := runRetention
+	if value := query.Get("retention"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			WriteError(w, fmt.Errorf("retention must be a positive duration: %w", contracts.ErrInvalidInput))
+			return
+		}
+		retention = d
+	}
+	dryRun := false
+	if value := query.Get("dry_run"); value != "" {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			WriteError(w, fmt.Errorf("dry_run must be a boolean: %w", contracts.ErrInvalidInput))
+			return
+		}
+		dryRun = b
+	}
+
+	resp := PruneResponse{DryRun: dryRun}
+	if dryRun {
+		candidates := h.store.PruneCandidates(retention)
+		resp.Count = len(candidates)
+		resp.Runs = make([]PruneCandidateDTO, len(candidates))
+		for i, c := range candidates {
+			resp.Runs[i] = PruneCandidateDTO{ID: string(c.ID), AgeMs: c.Age.Milliseconds()}
+		}
+	} else {
+		resp.Count = h.store.PruneCompleted(retention)
+		log.Printf("[AUDIT] event=runs_pruned retention_ms=%d count=%d", retention.Milliseconds(), resp.Count)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
 	writeJSON(w, resp)
 }
 
 // HandleGetStatus handles GET /api/v1/runs/{id}.
+// Optional ?expand=dag,policy,order,results adds the DAG, effective policy,
+// finish order, and sink task outputs.
 func (h *Handlers) HandleGetStatus(w http.ResponseWriter, r *http.Request) {
 	runID := r.PathValue("id")
 	if runID == "" {
@@ -144,6 +439,12 @@ func (h *Handlers) HandleGetStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expand, err := ParseExpand(r.URL.Query().Get("expand"))
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
 	// Use GetSnapshot to avoid data races with orchestrator goroutine
 	snap, exists := h.store.GetSnapshot(contracts.RunID(runID))
 	if !exists {
@@ -152,11 +453,142 @@ func (h *Handlers) HandleGetStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := SnapshotToResponse(snap)
+	resp.ApplyExpand(snap, expand)
 
 	w.Header().Set("Content-Type", "application/json")
 	writeJSON(w, resp)
 }
 
+// HandleStreamRun handles GET /api/v1/runs/{id}/events.
+// Streams run progress as Server-Sent Events until the run is done or the
+// client disconnects:
+//   - run: the run status, on connect and after every state change
+//   - task_state: a task's new state and the run's cumulative usage
+//   - task_progress: a partial output chunk from a streaming executor
+//   - done: the final run status, once the run is terminal
+func (h *Handlers) HandleStreamRun(w http.ResponseWriter, r *http.Request) {
+	runID := contracts.RunID(r.PathValue("id"))
+	if runID == "" {
+		WriteError(w, fmt.Errorf("missing run ID: %w", contracts.ErrInvalidInput))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, errors.New("response writer does not support streaming"))
+		return
+	}
+
+	events, unsubscribe, err := h.store.Subscribe(runID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	defer unsubscribe()
+
+	// The stream lasts as long as the run, so lift the server's WriteTimeout.
+	// Writers without deadlines (e.g. in tests) return ErrNotSupported.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.writeRunEvent(w, "run", runID); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-events:
+			// Forward queued events, then send one run status for all of them
+			changed := false
+		drain:
+			for ok {
+				if err := writeTaskEvent(w, ev); err != nil {
+					return
+				}
+				changed = changed || ev.Type != EventTaskProgress
+				select {
+				case ev, ok = <-events:
+				default:
+					break drain
+				}
+			}
+			if !ok {
+				// Channel closed: the run is done
+				h.writeRunEvent(w, "done", runID)
+				flusher.Flush()
+				return
+			}
+			if changed {
+				if err := h.writeRunEvent(w, "run", runID); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeRunEvent writes the run's current status as an SSE event.
+func (h *Handlers) writeRunEvent(w io.Writer, event string, runID contracts.RunID) error {
+	snap, exists := h.store.GetSnapshot(runID)
+	if !exists {
+		return fmt.Errorf("run %s: %w", runID, contracts.ErrRunNotFound)
+	}
+	return writeEvent(w, event, SnapshotToResponse(snap))
+}
+
+// writeTaskEvent writes a task_state or task_progress event.
+// Run state changes carry no data of their own and are skipped.
+func writeTaskEvent(w io.Writer, ev RunEvent) error {
+	if ev.Type == EventRunState {
+		return nil
+	}
+	return writeEvent(w, ev.Type, EventToDTO(ev))
+}
+
+// writeEvent writes one SSE event with v as its JSON data.
+func writeEvent(w io.Writer, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// HandleGetBundle handles GET /api/v1/runs/{id}/bundle.
+// Returns a ZIP archive of a finished run (see WriteBundle); 409 while the run is active.
+func (h *Handlers) HandleGetBundle(w http.ResponseWriter, r *http.Request) {
+	runID := r.PathValue("id")
+	if runID == "" {
+		WriteError(w, fmt.Errorf("missing run ID: %w", contracts.ErrInvalidInput))
+		return
+	}
+
+	bundle, err := h.store.GetBundle(contracts.RunID(runID))
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Buffer the archive so failures still produce a JSON error response
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, bundle); err != nil {
+		WriteError(w, fmt.Errorf("writing bundle for run %s: %w", runID, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "run-"+runID+"-bundle.zip"))
+	w.Write(buf.Bytes())
+}
+
 // HandleAbort handles POST /api/v1/runs/{id}/abort.
 func (h *Handlers) HandleAbort(w http.ResponseWriter, r *http.Request) {
 	runID := r.PathValue("id")
@@ -183,6 +615,154 @@ func (h *Handlers) HandleAbort(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, resp)
 }
 
+// HandleAbortAll handles POST /api/v1/runs/abort: it aborts every active run
+// and returns the IDs of the runs it cancelled. Runs already aborting or
+// finished are left alone, so repeating the request cancels nothing new.
+func (h *Handlers) HandleAbortAll(w http.ResponseWriter, r *http.Request) {
+	cancelled := h.store.CancelAll()
+
+	resp := AbortAllResponse{Count: len(cancelled), Runs: make([]string, len(cancelled))}
+	for i, id := range cancelled {
+		resp.Runs[i] = string(id)
+	}
+	log.Printf("[AUDIT] event=runs_aborted count=%d runs=%s", resp.Count, strings.Join(resp.Runs, ","))
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, resp)
+}
+
+// HandleCancelTask handles POST /api/v1/runs/{id}/tasks/{task}/cancel.
+// ?mode=soft skips the task and lets its dependents run without its output;
+// the default, mode=hard, fails the task and therefore the run. The cancel is
+// applied asynchronously: a running task's context is cancelled immediately,
+// a pending one is cancelled at the next batch boundary.
+func (h *Handlers) HandleCancelTask(w http.ResponseWriter, r *http.Request) {
+	runID := contracts.RunID(r.PathValue("id"))
+	taskID := contracts.TaskID(r.PathValue("task"))
+
+	mode := orchestration.CancelMode(r.URL.Query().Get("mode"))
+	if mode == "" {
+		mode = orchestration.CancelHard
+	}
+	if mode != orchestration.CancelHard && mode != orchestration.CancelSoft {
+		WriteError(w, fmt.Errorf("unknown cancel mode %q (want hard or soft): %w", mode, contracts.ErrInvalidInput))
+		return
+	}
+
+	snap, exists := h.store.GetSnapshot(runID)
+	if !exists {
+		WriteError(w, fmt.Errorf("run %s: %w", runID, contracts.ErrRunNotFound))
+		return
+	}
+	task, exists := snap.Tasks[taskID]
+	if !exists {
+		WriteError(w, fmt.Errorf("task %s in run %s: %w", taskID, runID, contracts.ErrTaskNotFound))
+		return
+	}
+	if isTerminalTask(task.State) {
+		WriteError(w, fmt.Errorf("task %s is %s: %w", taskID, task.State, ErrTaskTerminal))
+		return
+	}
+
+	controls := h.runControls(runID)
+	if controls == nil {
+		WriteError(w, fmt.Errorf("run %s: %w", runID, contracts.ErrRunCompleted))
+		return
+	}
+	controls.canceller.Cancel(taskID, mode)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, CancelTaskResponse{RunID: string(runID), TaskID: string(taskID), Mode: string(mode)})
+}
+
+// HandleUpdateRun handles PATCH /api/v1/runs/{id}.
+// Only budget_limit can be changed, and only raised: the new amount must not
+// be below the current limit or the run's usage. The orchestrator applies it
+// at the next batch boundary, so a near-complete run can finish.
+func (h *Handlers) HandleUpdateRun(w http.ResponseWriter, r *http.Request) {
+	runID := contracts.RunID(r.PathValue("id"))
+
+	var req UpdateRunRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBodySize)).Decode(&req); err != nil {
+		WriteError(w, fmt.Errorf("invalid JSON: %w", contracts.ErrInvalidInput))
+		return
+	}
+	if req.BudgetLimit == nil {
+		WriteError(w, fmt.Errorf("budget_limit is required: %w", contracts.ErrInvalidInput))
+		return
+	}
+	if c := req.BudgetLimit.Currency; c != "" && contracts.Currency(c) != h.defaultCurrency {
+		WriteError(w, fmt.Errorf("budget_limit.currency %q does not match server currency %q: %w",
+			c, h.defaultCurrency, contracts.ErrInvalidInput))
+		return
+	}
+	if req.BudgetLimit.Amount <= 0 {
+		WriteError(w, fmt.Errorf("budget_limit.amount must be positive: %w", contracts.ErrInvalidInput))
+		return
+	}
+	limit := contracts.Cost{Amount: req.BudgetLimit.Amount, Currency: h.defaultCurrency}
+
+	// The store rejects a limit below the current limit or usage
+	if err := h.store.SetBudgetLimit(runID, limit); err != nil {
+		WriteError(w, err)
+		return
+	}
+	controls := h.runControls(runID)
+	if controls == nil {
+		WriteError(w, fmt.Errorf("run %s: %w", runID, contracts.ErrRunCompleted))
+		return
+	}
+	controls.budget.Set(limit)
+
+	snap, exists := h.store.GetSnapshot(runID)
+	if !exists {
+		WriteError(w, fmt.Errorf("run %s: %w", runID, contracts.ErrRunNotFound))
+		return
+	}
+	resp := SnapshotToResponse(snap)
+	resp.ApplyExpand(snap, ExpandOptions{Policy: true})
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, resp)
+}
+
+// HandleAddNote handles POST /api/v1/runs/{id}/notes.
+// Appends a timestamped operator note to a run in any state and returns it
+// with 201 Created. Notes show in the run status and its audit file and bundle.
+func (h *Handlers) HandleAddNote(w http.ResponseWriter, r *http.Request) {
+	runID := contracts.RunID(r.PathValue("id"))
+
+	var req AddNoteRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBodySize)).Decode(&req); err != nil {
+		WriteError(w, fmt.Errorf("invalid JSON: %w", contracts.ErrInvalidInput))
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		WriteError(w, fmt.Errorf("text is required: %w", contracts.ErrInvalidInput))
+		return
+	}
+	if len(req.Text) > MaxNoteBytes {
+		WriteError(w, fmt.Errorf("text exceeds %d bytes: %w", MaxNoteBytes, contracts.ErrInvalidInput))
+		return
+	}
+
+	note, err := h.store.AddNote(runID, req.Text)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	traceID := ""
+	if snap, exists := h.store.GetSnapshot(runID); exists {
+		traceID = snap.TraceID
+	}
+	log.Printf("[AUDIT] event=run_note_added run_id=%s trace_id=%s bytes=%d", runID, traceID, len(note.Text))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, NoteDTO(note))
+}
+
 // HandleEnqueueTask handles POST /api/v1/runs/{id}/tasks.
 // V1: Returns 501 Not Implemented.
 func (h *Handlers) HandleEnqueueTask(w http.ResponseWriter, r *http.Request) {
@@ -207,6 +787,19 @@ func (h *Handlers) runOrchestrator(ctx context.Context, run *contracts.Run) {
 	if execFn == nil {
 		execFn = defaultExecutor
 	}
+	if len(h.executors) > 0 {
+		// Dispatch per task, falling back to the default executor
+		registry := orchestration.NewExecutorRegistry(execFn)
+		for name, fn := range h.executors {
+			registry.Register(name, fn)
+		}
+		execFn = registry.Execute
+	}
+
+	if err := h.waitForStart(ctx, run); err != nil {
+		h.finishRun(run.ID, err)
+		return
+	}
 
 	// Mark run as running in shadow state
 	h.store.SetShadowRunState(run.ID, contracts.RunRunning)
@@ -217,29 +810,156 @@ func (h *Handlers) runOrchestrator(ctx context.Context, run *contracts.Run) {
 		h.store.UpdateShadowState(run.ID)
 	}
 
+	executor := orchestration.NewParallelExecutorWithClock(run.Policy.MaxParallelism, execFn, h.clock)
+	if h.streamingExecutor != nil {
+		// Forward partial output to shadow state and event subscribers
+		onChunk := func(taskID contracts.TaskID, chunk string) {
+			h.store.AppendTaskOutput(run.ID, taskID, chunk)
+		}
+		executor = orchestration.NewStreamingParallelExecutor(run.Policy.MaxParallelism, h.streamingExecutor, onChunk)
+	}
+
+	// Pass provider headers to the executor in the task context
+	executor = headerInjector{ParallelExecutor: executor, defaults: h.providerHeaders}
+
+	// Publish task_state events as tasks start
+	executor = startNotifier{ParallelExecutor: executor, onStart: func(taskID contracts.TaskID) {
+		h.store.UpdateTaskRunning(run.ID, taskID)
+	}}
+
 	deps := orchestration.OrchestratorDeps{
 		Scheduler:      orchestration.NewScheduler(),
 		DepResolver:    orchestration.NewDependencyResolver(),
 		Queue:          orchestration.NewQueueManager(),
-		Executor:       orchestration.NewParallelExecutorFromPolicy(run.Policy, execFn),
+		Executor:       executor,
 		ContextBuilder: ctxpkg.NewContextBuilder(),
 		Compactor:      ctxpkg.NewContextCompactor(),
 		TokenEstimator: cost.NewTokenEstimator(),
-		CostCalc:       cost.NewCostCalculator(),
-		BudgetEnforcer: cost.NewBudgetEnforcer(),
+		CostCalc:       cost.NewCostCalculatorWithCatalog(nil, h.defaultCurrency),
+		BudgetEnforcer: cost.NewBudgetEnforcerWithPool(h.budgetPool),
 		UsageTracker:   cost.NewUsageTracker(),
 		Router:         ctxpkg.NewContextRouter(),
+		ModelCatalog:   cost.NewModelCatalog(),
+		Clock:          h.clock,
+	}
+	deps.OnBatchStart = func(batch int) {
+		h.store.SetBatch(run.ID, batch)
+	}
+	if controls := h.runControls(run.ID); controls != nil {
+		deps.Canceller = controls.canceller
+		deps.BudgetOverride = controls.budget
 	}
 
 	// Create orchestrator with progress callback
 	orch := orchestration.NewOrchestratorWithCallback(deps, onProgress)
 	err := orch.Run(ctx, run)
-	h.store.MarkDone(run.ID, err)
+	h.finishRun(run.ID, err)
+}
+
+// startNotifier calls onStart before delegating each task to the wrapped executor.
+type startNotifier struct {
+	contracts.ParallelExecutor
+	onStart func(contracts.TaskID)
+}
+
+func (n startNotifier) Execute(ctx context.Context, run *contracts.Run, taskID contracts.TaskID) (*contracts.TaskResult, error) {
+	n.onStart(taskID)
+	return n.ParallelExecutor.Execute(ctx, run, taskID)
+}
+
+// runControls returns the controls of an active run, or nil once it finished.
+func (h *Handlers) runControls(runID contracts.RunID) *runControls {
+	h.controlsMu.Lock()
+	defer h.controlsMu.Unlock()
+	return h.controls[runID]
+}
+
+// finishRun marks a run done and writes its audit file if configured.
+func (h *Handlers) finishRun(runID contracts.RunID, err error) {
+	h.controlsMu.Lock()
+	delete(h.controls, runID)
+	h.controlsMu.Unlock()
+
+	h.store.MarkDone(runID, err)
 
 	// Write audit file if configured
 	if h.auditDir != "" {
-		h.writeAuditFile(run.ID)
+		h.writeAuditFile(runID)
+	}
+}
+
+// waitForStart blocks a scheduled run until its StartAfter time, then moves it
+// back to RunPending. If ctx is cancelled first (abort), the run is marked
+// RunAborted and ctx.Err() is returned.
+func (h *Handlers) waitForStart(ctx context.Context, run *contracts.Run) error {
+	if run.State != contracts.RunScheduled {
+		return nil
+	}
+
+	select {
+	case <-h.clock.After(time.UnixMilli(int64(run.StartAfter)).Sub(h.clock.Now())):
+		run.State = contracts.RunPending
+		return nil
+	case <-ctx.Done():
+		run.State = contracts.RunAborted
+		log.Printf("[AUDIT] event=run_aborted run_id=%s trace_id=%s duration_ms=0 reason=aborted_while_scheduled",
+			run.ID, run.TraceID)
+		return ctx.Err()
+	}
+}
+
+// HandleRunMetrics handles GET /api/v1/runs/{id}/metrics.
+// Returns task counts per state, ready tasks, the current batch, elapsed
+// time and cumulative usage, read from shadow state.
+func (h *Handlers) HandleRunMetrics(w http.ResponseWriter, r *http.Request) {
+	runID := r.PathValue("id")
+	if runID == "" {
+		WriteError(w, fmt.Errorf("missing run ID: %w", contracts.ErrInvalidInput))
+		return
+	}
+
+	snap, exists := h.store.GetSnapshot(contracts.RunID(runID))
+	if !exists {
+		WriteError(w, fmt.Errorf("run %s: %w", runID, contracts.ErrRunNotFound))
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, SnapshotToMetrics(snap, time.Now()))
+}
+
+// HandleRunPlan handles GET /api/v1/runs/{id}/plan.
+// Returns the run's execution batches as layered from its DAG.
+func (h *Handlers) HandleRunPlan(w http.ResponseWriter, r *http.Request) {
+	runID := r.PathValue("id")
+	if runID == "" {
+		WriteError(w, fmt.Errorf("missing run ID: %w", contracts.ErrInvalidInput))
+		return
+	}
+
+	snap, exists := h.store.GetSnapshot(contracts.RunID(runID))
+	if !exists {
+		WriteError(w, fmt.Errorf("run %s: %w", runID, contracts.ErrRunNotFound))
+		return
+	}
+
+	resolver := orchestration.NewDependencyResolver()
+	dag := snapshotDAG(snap)
+	batches, err := resolver.Plan(dag)
+	if err != nil {
+		WriteError(w, fmt.Errorf("run %s: %w", runID, err))
+		return
+	}
+	resp := RunPlanResponse{ID: runID, Batches: make([][]string, len(batches))}
+	for i, batch := range batches {
+		resp.Batches[i] = taskIDStrings(batch)
+	}
+	path, length := resolver.CriticalPath(dag)
+	resp.CriticalPath = taskIDStrings(path)
+	resp.CriticalPathLength = length
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, resp)
 }
 
 // writeAuditFile writes the run audit to a JSON file in the configured audit directory.
@@ -267,7 +987,7 @@ func (h *Handlers) writeAuditFile(runID contracts.RunID) {
 		return
 	}
 
-	log.Printf("[AUDIT] event=audit_file_written run_id=%s path=%s", runID, filename)
+	log.Printf("[AUDIT] event=audit_file_written run_id=%s trace_id=%s path=%s", runID, snap.TraceID, filename)
 }
 
 // defaultExecutor is a fallback TaskExecutorFunc when none is provided.
@@ -282,23 +1002,167 @@ func defaultExecutor(ctx context.Context, task *contracts.Task) (*contracts.Task
 		Output: fmt.Sprintf("executed:%s", task.ID),
 		Usage: contracts.Usage{
 			Tokens: 100,
-			Cost:   contracts.Cost{Amount: 0.001, Currency: "USD"},
+			Cost:   contracts.Cost{Amount: 0.001}, // no currency: the run's own
 		},
 	}, nil
 }
 
+// startRunWarnings returns advisory findings about a valid request: legal
+// settings that are likely not what was intended. They never reject the run.
+func startRunWarnings(req *StartRunRequest) []string {
+	var warnings []string
+	if req.Policy.TimeoutMs == 0 {
+		var unbounded []string
+		for _, task := range req.Tasks {
+			if task.TimeoutMs == 0 {
+				unbounded = append(unbounded, task.ID)
+			}
+		}
+		if len(unbounded) == len(req.Tasks) {
+			warnings = append(warnings, "policy.timeout_ms is not set; tasks run without a timeout")
+		} else if len(unbounded) > 0 {
+			warnings = append(warnings, fmt.Sprintf(
+				"policy.timeout_ms is not set; tasks without timeout_ms run without a timeout (%s)", strings.Join(unbounded, ", ")))
+		}
+	}
+
+	hasDependents := make(map[string]bool)
+	for _, task := range req.Tasks {
+		for _, dep := range task.Deps {
+			hasDependents[dep] = true
+		}
+	}
+	for _, task := range req.Tasks {
+		if task.ContinueOnFail && hasDependents[task.ID] {
+			warnings = append(warnings, fmt.Sprintf(
+				"task %s: continue_on_fail is set and the task has dependents; they are skipped if it fails", task.ID))
+		}
+		for _, key := range task.FromMemory {
+			if _, ok := req.Memory[key]; !ok {
+				warnings = append(warnings, fmt.Sprintf(
+					"task %s: from_memory key %q is not in the seeded memory; it is left out unless written before the task starts", task.ID, key))
+			}
+		}
+	}
+	return warnings
+}
+
+// seedMemory returns the initial run memory: a copy of the request's memory.
+func seedMemory(memory map[string]string) map[string]string {
+	seeded := make(map[string]string, len(memory))
+	maps.Copy(seeded, memory)
+	return seeded
+}
+
+// KnownMetadataKeys returns the task metadata keys accepted under
+// ServerOptions.StrictMetadata: role and executor route tasks, outputs,
+// context_policy and budget_share are sent by workflow-client, and system and
+// description are conventional executor inputs.
+func KnownMetadataKeys() []string {
+	return []string{
+		"role", "system", "outputs", "description",
+		orchestration.MetadataExecutor, "context_policy", "budget_share",
+	}
+}
+
+// validateMetadataKeys returns ErrUnknownMetadataKey for the first task, in
+// request order, with a metadata key not in allowed (its keys in sorted
+// order). A nil allowed accepts any key.
+func validateMetadataKeys(tasks []TaskDTO, allowed map[string]bool) error {
+	if allowed == nil {
+		return nil
+	}
+	for _, task := range tasks {
+		for _, key := range slices.Sorted(maps.Keys(task.Metadata)) {
+			if !allowed[key] && !strings.HasPrefix(key, ProviderHeaderPrefix) {
+				return fmt.Errorf("task %s: metadata key %q: %w", task.ID, key, ErrUnknownMetadataKey)
+			}
+		}
+	}
+	return nil
+}
+
 // validateStartRunRequest validates a StartRunRequest.
-func validateStartRunRequest(req *StartRunRequest) error {
+// When budgetDisabled is set, an empty budget_limit is accepted.
+func validateStartRunRequest(req *StartRunRequest, budgetDisabled bool) error {
 	// Policy is required
 	if req.Policy.MaxParallelism <= 0 {
 		return fmt.Errorf("policy.max_parallelism must be > 0: %w", contracts.ErrInvalidInput)
 	}
 
+	for key := range req.Labels {
+		if key == "" || strings.Contains(key, "=") {
+			return fmt.Errorf("label key %q must be non-empty and must not contain '=': %w", key, contracts.ErrInvalidInput)
+		}
+	}
+
 	// Budget must be positive
-	if req.Policy.BudgetLimit.Amount <= 0 {
+	if !budgetDisabled && req.Policy.BudgetLimit.Amount <= 0 {
 		return fmt.Errorf("policy.budget_limit.amount must be > 0: %w", contracts.ErrInvalidInput)
 	}
 
+	if req.StartAfter < 0 {
+		return fmt.Errorf("start_after must be >= 0: %w", contracts.ErrInvalidInput)
+	}
+
+	if req.Policy.AbortGraceMs < 0 {
+		return fmt.Errorf("policy.abort_grace_ms must be >= 0: %w", contracts.ErrInvalidInput)
+	}
+
+	if cp := req.Policy.ContextPolicy; cp != nil && (cp.ForceCompactRatio < 0 || cp.ForceCompactRatio > 1) {
+		return fmt.Errorf("policy.context_policy.force_compact_ratio must be between 0 and 1: %w", contracts.ErrInvalidInput)
+	}
+
+	if cp := req.Policy.ContextPolicy; cp != nil {
+		switch cp.OutputCollision {
+		case "", contracts.OutputCollisionError, contracts.OutputCollisionOverwrite, contracts.OutputCollisionSuffix:
+		default:
+			return fmt.Errorf("policy.context_policy.output_collision must be error, overwrite or suffix, got %q: %w",
+				cp.OutputCollision, contracts.ErrInvalidInput)
+		}
+	}
+
+	switch req.Policy.SchedulingStrategy {
+	case contracts.SchedulingDefault, contracts.SchedulingCost:
+	default:
+		return fmt.Errorf("policy.scheduling_strategy must be empty or cost, got %q: %w",
+			req.Policy.SchedulingStrategy, contracts.ErrInvalidInput)
+	}
+
+	if req.Policy.ContextTimeoutMs < 0 {
+		return fmt.Errorf("policy.context_timeout_ms must be >= 0: %w", contracts.ErrInvalidInput)
+	}
+
+	if req.Policy.MaxContextBuilds < 0 {
+		return fmt.Errorf("policy.max_context_builds must be >= 0: %w", contracts.ErrInvalidInput)
+	}
+
+	if m := req.Policy.MaxFailures; m < 0 || (m >= 1 && m != math.Trunc(m)) {
+		return fmt.Errorf("policy.max_failures must be a task count or a fraction below 1, got %g: %w", m, contracts.ErrInvalidInput)
+	}
+
+	if soft := req.Policy.SoftLimit; soft != nil {
+		if soft.Amount <= 0 {
+			return fmt.Errorf("policy.soft_limit.amount must be > 0: %w", contracts.ErrInvalidInput)
+		}
+		if limit := req.Policy.BudgetLimit.Amount; limit > 0 && soft.Amount >= limit {
+			return fmt.Errorf("policy.soft_limit.amount must be below policy.budget_limit.amount: %w", contracts.ErrInvalidInput)
+		}
+	}
+
+	for key, limit := range req.Policy.ConcurrencyLimits {
+		if limit <= 0 {
+			return fmt.Errorf("policy.concurrency_limits[%s] must be > 0: %w", key, contracts.ErrInvalidInput)
+		}
+	}
+
+	// Model budgets must be positive
+	for model, budget := range req.Policy.ModelBudgets {
+		if budget.Amount <= 0 {
+			return fmt.Errorf("policy.model_budgets[%s].amount must be > 0: %w", model, contracts.ErrInvalidInput)
+		}
+	}
+
 	// At least one task required
 	if len(req.Tasks) == 0 {
 		return fmt.Errorf("at least one task is required: %w", contracts.ErrInvalidInput)
@@ -323,11 +1187,74 @@ func validateStartRunRequest(req *StartRunRequest) error {
 		if task.Model == "" {
 			return fmt.Errorf("task %s: model is required: %w", task.ID, contracts.ErrInvalidInput)
 		}
+
+		for _, category := range task.RetryOn {
+			if !slices.Contains(contracts.RetryCategories(), category) {
+				return fmt.Errorf("task %s: unknown retry_on category %q: %w", task.ID, category, contracts.ErrInvalidInput)
+			}
+		}
+
+		if r := task.Retry; r != nil && (r.MaxAttempts < 1 || r.BackoffMs < 0) {
+			return fmt.Errorf("task %s: retry.max_attempts must be >= 1 and retry.backoff_ms >= 0: %w", task.ID, contracts.ErrInvalidInput)
+		}
+
+		if task.TimeoutMs < 0 {
+			return fmt.Errorf("task %s: timeout_ms must be >= 0: %w", task.ID, contracts.ErrInvalidInput)
+		}
+
+		if task.MaxContextTokens < 0 {
+			return fmt.Errorf("task %s: max_context_tokens must be >= 0: %w", task.ID, contracts.ErrInvalidInput)
+		}
+
+		if task.RunIf != nil && task.RunIf.Key == "" {
+			return fmt.Errorf("task %s: run_if.key is required: %w", task.ID, contracts.ErrInvalidInput)
+		}
+
+		if slices.Contains(task.FromMemory, "") {
+			return fmt.Errorf("task %s: from_memory keys must be non-empty: %w", task.ID, contracts.ErrInvalidInput)
+		}
+
+		if err := ValidateProviderHeaders(metadataProviderHeaders(task.Metadata)); err != nil {
+			return fmt.Errorf("task %s: %w", task.ID, err)
+		}
 	}
 
 	return nil
 }
 
+// normalizeCurrencies fills empty budget currencies with the default currency.
+// Explicit currencies that differ from the default are rejected, because cost
+// estimates are always produced in the default currency.
+func normalizeCurrencies(policy *PolicyDTO, currency contracts.Currency) error {
+	normalize := func(field string, c *CostDTO) error {
+		if c.Currency == "" {
+			c.Currency = string(currency)
+			return nil
+		}
+		if contracts.Currency(c.Currency) != currency {
+			return fmt.Errorf("%s.currency %q does not match server currency %q: %w",
+				field, c.Currency, currency, contracts.ErrInvalidInput)
+		}
+		return nil
+	}
+
+	if err := normalize("policy.budget_limit", &policy.BudgetLimit); err != nil {
+		return err
+	}
+	if policy.SoftLimit != nil {
+		if err := normalize("policy.soft_limit", policy.SoftLimit); err != nil {
+			return err
+		}
+	}
+	for model, budget := range policy.ModelBudgets {
+		if err := normalize(fmt.Sprintf("policy.model_budgets[%s]", model), &budget); err != nil {
+			return err
+		}
+		policy.ModelBudgets[model] = budget
+	}
+	return nil
+}
+
 // generateRunID generates a unique run ID.
 func generateRunID() string {
 	return fmt.Sprintf("run-%d", timeNowFunc().UnixNano())
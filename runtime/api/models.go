@@ -2,6 +2,12 @@
 package api
 
 import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/anthropics/claude-workflow/runtime/contracts"
 )
 
@@ -11,17 +17,79 @@ import (
 
 // StartRunRequest is the request body for POST /api/v1/runs.
 type StartRunRequest struct {
-	ID     string    `json:"id,omitempty"`
-	Policy PolicyDTO `json:"policy"`
-	Tasks  []TaskDTO `json:"tasks"`
+	ID         string    `json:"id,omitempty"`
+	Policy     PolicyDTO `json:"policy"`
+	Tasks      []TaskDTO `json:"tasks"`
+	ConfigHash string    `json:"config_hash,omitempty"` // hash of the workflow config the run was built from
+
+	// Labels tag the run, e.g. {"team": "payments"}. Servers may cap the
+	// number of active runs per label (see ServerOptions.LabelLimits).
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// StartAfter delays the run until this Unix time in milliseconds.
+	// The run reports state "scheduled" until then (0 = start immediately).
+	StartAfter int64 `json:"start_after,omitempty"`
+
+	// Memory seeds the run memory that tasks read with from_memory and run_if.
+	Memory map[string]string `json:"memory,omitempty"`
+}
+
+// UpdateRunRequest is the request body for PATCH /api/v1/runs/{id}.
+type UpdateRunRequest struct {
+	BudgetLimit *CostDTO `json:"budget_limit"` // new limit; may only be raised
+}
+
+// AddNoteRequest is the request body for POST /api/v1/runs/{id}/notes.
+type AddNoteRequest struct {
+	Text string `json:"text"` // at most MaxNoteBytes
 }
 
 // PolicyDTO represents execution constraints for a run.
 type PolicyDTO struct {
-	TimeoutMs      int64             `json:"timeout_ms"`
-	MaxParallelism int               `json:"max_parallelism"`
-	BudgetLimit    CostDTO           `json:"budget_limit"`
-	ContextPolicy  *ContextPolicyDTO `json:"context_policy,omitempty"`
+	TimeoutMs      int64              `json:"timeout_ms"`
+	MaxParallelism int                `json:"max_parallelism"`
+	BudgetLimit    CostDTO            `json:"budget_limit"`
+	ModelBudgets   map[string]CostDTO `json:"model_budgets,omitempty"` // model ID -> spend cap
+	BudgetPool     string             `json:"budget_pool,omitempty"`   // label of a shared budget pool
+	ContextPolicy  *ContextPolicyDTO  `json:"context_policy,omitempty"`
+	AbortGraceMs   int64              `json:"abort_grace_ms,omitempty"` // let in-flight tasks finish after abort
+
+	// MaxContextBuilds bounds concurrent context builds during budget pre-checks (0 = sequential).
+	MaxContextBuilds int `json:"max_context_builds,omitempty"`
+
+	// RetryJitter randomizes retry backoff per task; JitterSeed makes it
+	// reproducible (0 = derived from the run ID).
+	RetryJitter bool  `json:"retry_jitter,omitempty"`
+	JitterSeed  int64 `json:"jitter_seed,omitempty"`
+
+	// ConcurrencyLimits caps concurrent tasks per concurrency_key (default 1).
+	ConcurrencyLimits map[string]int `json:"concurrency_limits,omitempty"`
+
+	// DedupIdenticalTasks executes tasks with identical input once and copies the result.
+	DedupIdenticalTasks bool `json:"dedup_identical_tasks,omitempty"`
+
+	// DryRun estimates every task and reports the batch plan without executing.
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// MaxFailures fails the run once more tasks have failed: a count (>= 1)
+	// or a fraction of the tasks (< 1). 0 = no limit.
+	MaxFailures float64 `json:"max_failures,omitempty"`
+
+	// ContextTimeoutMs bounds context compaction in the budget pre-check
+	// (failing the task with context_timeout); timeout_ms covers only the
+	// executor call. 0 = no limit.
+	ContextTimeoutMs int64 `json:"context_timeout_ms,omitempty"`
+
+	// SoftLimit sets the run's budget_warning once its cost reaches this
+	// amount, without stopping it (below budget_limit).
+	SoftLimit *CostDTO `json:"soft_limit,omitempty"`
+
+	// SchedulingStrategy "cost" starts the most expensive ready tasks first
+	// (by estimate); unset orders them by priority, then ID.
+	SchedulingStrategy string `json:"scheduling_strategy,omitempty"`
+
+	// BudgetDisabled is response-only; it is set by the server's NoBudget option.
+	BudgetDisabled bool `json:"budget_disabled,omitempty"`
 }
 
 // ContextPolicyDTO represents context management settings.
@@ -30,6 +98,15 @@ type ContextPolicyDTO struct {
 	Strategy  string `json:"strategy,omitempty"`
 	KeepLastN int    `json:"keep_last_n,omitempty"`
 	// truncate_to removed - out of scope V1
+
+	MaxRoutedValueBytes int `json:"max_routed_value_bytes,omitempty"` // 0 = unlimited
+
+	// ForceCompactRatio forces compaction once a task's context exceeds this
+	// fraction (0-1] of the model's context window (0 = disabled).
+	ForceCompactRatio float64 `json:"force_compact_ratio,omitempty"`
+
+	// OutputCollision is "error" (default), "overwrite" or "suffix".
+	OutputCollision string `json:"output_collision,omitempty"`
 }
 
 // TaskDTO represents a task in the request.
@@ -40,6 +117,39 @@ type TaskDTO struct {
 	Inputs   map[string]string `json:"inputs,omitempty"`
 	Metadata map[string]string `json:"metadata,omitempty"`
 	Deps     []string          `json:"deps,omitempty"`
+
+	RequireNonEmptyOutput bool     `json:"require_non_empty_output,omitempty"` // fail with empty_output on ""
+	RetryOn               []string `json:"retry_on,omitempty"`                 // timeout, rate_limit, transient
+	ConcurrencyKey        string   `json:"concurrency_key,omitempty"`          // serialize tasks sharing a resource
+
+	Retry          *RetryDTO `json:"retry,omitempty"`            // attempt cap and exponential backoff
+	ContinueOnFail bool      `json:"continue_on_fail,omitempty"` // failure skips dependents instead of failing the run
+	TimeoutMs      int64     `json:"timeout_ms,omitempty"`       // overrides policy.timeout_ms for this task
+
+	MaxContextTokens int64 `json:"max_context_tokens,omitempty"` // cap on the compacted context; fails with context_too_large
+
+	RunIf      *ConditionDTO `json:"run_if,omitempty"`      // skip the task unless the condition holds
+	FromMemory []string      `json:"from_memory,omitempty"` // memory keys added to inputs as "memory:<key>"
+	Priority   int           `json:"priority,omitempty"`    // higher starts first among ready tasks
+	Skip       bool          `json:"skip,omitempty"`        // submitted skipped; dependents run without its output
+}
+
+// ConditionDTO represents a task's run_if condition.
+type ConditionDTO struct {
+	Key    string `json:"key"`    // dependency ID, named output or memory key
+	Equals string `json:"equals"` // value the key must have for the task to run
+}
+
+// RetryDTO represents a task's retry policy.
+type RetryDTO struct {
+	MaxAttempts int   `json:"max_attempts"`         // total attempts, including the first
+	BackoffMs   int64 `json:"backoff_ms,omitempty"` // first retry delay; doubles per attempt
+}
+
+// NoteDTO represents an operator note on a run.
+type NoteDTO struct {
+	Text      string `json:"text"`
+	CreatedAt int64  `json:"created_at"` // Unix ms
 }
 
 // CostDTO represents a monetary cost.
@@ -61,19 +171,264 @@ type RunResponse struct {
 	Error     *ErrorDTO                `json:"error,omitempty"`
 	CreatedAt int64                    `json:"created_at"`
 	UpdatedAt int64                    `json:"updated_at,omitempty"`
+
+	// DroppedEvents counts progress events dropped for slow subscribers.
+	DroppedEvents int64 `json:"dropped_events,omitempty"`
+
+	ConfigHash string `json:"config_hash,omitempty"` // hash of the originating workflow config
+	TraceID    string `json:"trace_id,omitempty"`    // correlation ID in audit lines and executor context
+	StartAfter int64  `json:"start_after,omitempty"` // scheduled start, Unix ms
+
+	// StartedAt and FinishedAt (Unix ms) bound the run's execution, so
+	// started_at - created_at is the time it sat queued or scheduled.
+	// DurationMs is finished_at - started_at, set once both are.
+	StartedAt  int64  `json:"started_at,omitempty"`
+	FinishedAt int64  `json:"finished_at,omitempty"`
+	DurationMs *int64 `json:"duration_ms,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Progress is the percentage (0-100) of tasks that are completed, failed or skipped.
+	Progress float64 `json:"progress"`
+
+	// BudgetWarning is set once the run's cost reaches policy.soft_limit.
+	BudgetWarning bool `json:"budget_warning,omitempty"`
+
+	// TaskCounts counts tasks by outcome. usage covers completed and failed
+	// tasks only: skipped tasks never run.
+	TaskCounts *TaskCountsDTO `json:"task_counts,omitempty"`
+
+	// Notes are operator notes attached to the run, oldest first.
+	Notes []NoteDTO `json:"notes,omitempty"`
+
+	// Warnings are advisory findings about the submitted request (StartRun only).
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Dry run outcome (policy.dry_run): the batches the tasks would run in
+	// and the sum of their estimates.
+	Plan            [][]string `json:"plan,omitempty"`
+	ProjectedTokens int64      `json:"projected_tokens,omitempty"`
+	ProjectedCost   *CostDTO   `json:"projected_cost,omitempty"`
+
+	// Expanded sections, only present when requested via ?expand=
+	DAG    map[string]DAGNodeDTO `json:"dag,omitempty"`
+	Policy *PolicyDTO            `json:"policy,omitempty"` // effective policy after normalization
+	Order  []string              `json:"order,omitempty"`  // task IDs in the order they finished
+
+	// Results maps each completed sink task (no dependents) to its output.
+	Results map[string]string `json:"results,omitempty"`
+}
+
+// TaskCountsDTO counts a run's tasks by outcome (see contracts.TaskCounts).
+type TaskCountsDTO struct {
+	Completed  int `json:"completed"`
+	Failed     int `json:"failed"`
+	Skipped    int `json:"skipped"`
+	Unfinished int `json:"unfinished"` // pending, ready or running
+}
+
+// RunSummaryDTO is one run in the GET /api/v1/runs response.
+type RunSummaryDTO struct {
+	ID         string         `json:"id"`
+	State      string         `json:"state"`
+	CreatedAt  int64          `json:"created_at"`
+	UpdatedAt  int64          `json:"updated_at,omitempty"`
+	Usage      *UsageDTO      `json:"usage,omitempty"`
+	TaskCounts *TaskCountsDTO `json:"task_counts,omitempty"`
+}
+
+// PruneResponse is the response body for POST /api/v1/runs/prune.
+type PruneResponse struct {
+	DryRun bool                `json:"dry_run"`
+	Count  int                 `json:"count"`          // runs removed, or that would be with dry_run
+	Runs   []PruneCandidateDTO `json:"runs,omitempty"` // dry_run only, oldest first
+}
+
+// AbortAllResponse is the response body for POST /api/v1/runs/abort.
+// Count 0 with an empty Runs means no run was active.
+type AbortAllResponse struct {
+	Count int      `json:"count"` // runs cancelled by this request
+	Runs  []string `json:"runs"`  // their IDs, sorted; never null
+}
+
+// PruneCandidateDTO is a run that a prune would remove.
+type PruneCandidateDTO struct {
+	ID    string `json:"id"`
+	AgeMs int64  `json:"age_ms"` // time since the run last changed
+}
+
+// SummaryToDTO converts a RunSummary to its response DTO.
+func SummaryToDTO(s RunSummary) RunSummaryDTO {
+	dto := RunSummaryDTO{
+		ID:         string(s.ID),
+		State:      s.APIState,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+		TaskCounts: taskCountsToDTO(s.TaskCounts),
+	}
+	if s.Usage.Tokens > 0 || s.Usage.Cost.Amount > 0 {
+		dto.Usage = &UsageDTO{
+			Tokens: int64(s.Usage.Tokens),
+			Cost: &CostDTO{
+				Amount:   s.Usage.Cost.Amount,
+				Currency: string(s.Usage.Cost.Currency),
+			},
+		}
+	}
+	return dto
+}
+
+// RunMetricsResponse is the response body for GET /api/v1/runs/{id}/metrics.
+type RunMetricsResponse struct {
+	ID    string `json:"id"`
+	State string `json:"state"`
+
+	// Tasks counts tasks per state. Every task state is present (0 if no
+	// task is in it), so each poll yields the same series.
+	Tasks      map[string]int `json:"tasks"`
+	TotalTasks int            `json:"total_tasks"`
+	Ready      int            `json:"ready"` // pending tasks whose dependencies are done (0 once finished)
+
+	Batch     int   `json:"batch"`      // batch executing or last executed (0 = none yet)
+	ElapsedMs int64 `json:"elapsed_ms"` // since creation; until the last update once finished
+
+	Usage UsageDTO `json:"usage"` // cumulative; cost is always present
+
+	DroppedEvents int64 `json:"dropped_events"` // progress events dropped for slow subscribers
+}
+
+// RunPlanResponse is the response body for GET /api/v1/runs/{id}/plan.
+type RunPlanResponse struct {
+	ID string `json:"id"`
+
+	// Batches lists the execution waves of the run's DAG in order. Each holds
+	// the tasks that can run in parallel once the earlier waves are done,
+	// sorted by ID. It is the full plan, regardless of the run's progress.
+	Batches [][]string `json:"batches"`
+
+	// CriticalPath is the longest dependency chain, root first; it bounds
+	// how many waves the run needs. Of equally long chains it is the one
+	// smallest by task ID. CriticalPathLength counts its tasks.
+	CriticalPath       []string `json:"critical_path"`
+	CriticalPathLength int      `json:"critical_path_length"`
+}
+
+// SnapshotToMetrics computes run metrics from a snapshot. Ready tasks are
+// derived from task states and DAG edges, as Scheduler.NextReady would
+// report them, since the live run belongs to the orchestrator goroutine.
+func SnapshotToMetrics(snap *RunSnapshot, now time.Time) RunMetricsResponse {
+	m := RunMetricsResponse{
+		ID:         string(snap.ID),
+		State:      snap.APIState,
+		Tasks:      make(map[string]int),
+		TotalTasks: len(snap.Tasks),
+		Batch:      snap.Batch,
+		Usage: UsageDTO{
+			Tokens:       int64(snap.Usage.Tokens),
+			InputTokens:  int64(snap.Usage.InputTokens),
+			OutputTokens: int64(snap.Usage.OutputTokens),
+			Cost: &CostDTO{
+				Amount:   snap.Usage.Cost.Amount,
+				Currency: string(snap.Usage.Cost.Currency),
+			},
+		},
+		DroppedEvents: snap.DroppedEvents,
+	}
+	for _, state := range []contracts.TaskState{
+		contracts.TaskPending, contracts.TaskReady, contracts.TaskRunning,
+		contracts.TaskCompleted, contracts.TaskFailed, contracts.TaskSkipped,
+	} {
+		m.Tasks[state.String()] = 0
+	}
+
+	finished := isTerminalRun(snap.APIState)
+	for id, task := range snap.Tasks {
+		m.Tasks[task.State.String()]++
+		if !finished && isReady(snap, id, task) {
+			m.Ready++
+		}
+	}
+
+	end := now.UnixMilli()
+	if finished {
+		end = snap.UpdatedAt
+	}
+	m.ElapsedMs = max(end-snap.CreatedAt, 0)
+	return m
+}
+
+// isReady reports whether a pending task has every dependency completed or
+// skipped. Tasks of a snapshot without a DAG are never ready.
+func isReady(snap *RunSnapshot, id contracts.TaskID, task TaskSnapshot) bool {
+	if task.State != contracts.TaskPending && task.State != contracts.TaskReady {
+		return false
+	}
+	node, ok := snap.DAG[id]
+	if !ok {
+		return false
+	}
+	for _, dep := range node.Deps {
+		state := snap.Tasks[dep].State
+		if state != contracts.TaskCompleted && state != contracts.TaskSkipped {
+			return false
+		}
+	}
+	return true
+}
+
+// TaskEventDTO is the data of task_state and task_progress events
+// on GET /api/v1/runs/{id}/events.
+type TaskEventDTO struct {
+	TaskID string    `json:"task_id"`
+	State  string    `json:"state,omitempty"` // task_state
+	Chunk  string    `json:"chunk,omitempty"` // task_progress
+	Usage  *UsageDTO `json:"usage,omitempty"` // task_state: cumulative run usage
+}
+
+// EventToDTO converts a task event to its DTO.
+func EventToDTO(ev RunEvent) TaskEventDTO {
+	dto := TaskEventDTO{TaskID: string(ev.TaskID), Chunk: ev.Chunk}
+	if ev.Type == EventTaskState {
+		dto.State = ev.State.String()
+		dto.Usage = &UsageDTO{
+			Tokens: int64(ev.Usage.Tokens),
+			Cost: &CostDTO{
+				Amount:   ev.Usage.Cost.Amount,
+				Currency: string(ev.Usage.Cost.Currency),
+			},
+		}
+	}
+	return dto
+}
+
+// DAGNodeDTO represents a node's edges in the run DAG.
+type DAGNodeDTO struct {
+	Deps []string `json:"deps"`
+	Next []string `json:"next"`
 }
 
 // TaskStatusDTO represents the status of a single task.
 type TaskStatusDTO struct {
-	State  string    `json:"state"`
-	Output string    `json:"output,omitempty"`
-	Error  *ErrorDTO `json:"error,omitempty"`
+	State          string    `json:"state"`
+	Output         string    `json:"output,omitempty"`
+	PartialOutput  string    `json:"partial_output,omitempty"` // streamed output while running
+	Error          *ErrorDTO `json:"error,omitempty"`
+	EstimatedUsage *UsageDTO `json:"estimated_usage,omitempty"` // dry run estimate
 }
 
 // UsageDTO represents token and cost usage.
 type UsageDTO struct {
-	Tokens int64    `json:"tokens"`
-	Cost   *CostDTO `json:"cost,omitempty"`
+	Tokens       int64    `json:"tokens"`                  // total, input + output
+	InputTokens  int64    `json:"input_tokens,omitempty"`  // set when the executor reports the split
+	OutputTokens int64    `json:"output_tokens,omitempty"` // set when the executor reports the split
+	Cost         *CostDTO `json:"cost,omitempty"`
+}
+
+// CancelTaskResponse acknowledges a task cancel request.
+type CancelTaskResponse struct {
+	RunID  string `json:"run_id"`
+	TaskID string `json:"task_id"`
+	Mode   string `json:"mode"` // "hard" or "soft"
 }
 
 // ErrorDTO represents an error in the response.
@@ -95,17 +450,101 @@ func (p *PolicyDTO) ToRunPolicy() contracts.RunPolicy {
 			Amount:   p.BudgetLimit.Amount,
 			Currency: contracts.Currency(p.BudgetLimit.Currency),
 		},
+		BudgetPool:       p.BudgetPool,
+		AbortGraceMs:     p.AbortGraceMs,
+		MaxContextBuilds: p.MaxContextBuilds,
+		RetryJitter:      p.RetryJitter,
+		JitterSeed:       p.JitterSeed,
+
+		ConcurrencyLimits:   maps.Clone(p.ConcurrencyLimits),
+		DedupIdenticalTasks: p.DedupIdenticalTasks,
+		DryRun:              p.DryRun,
+		MaxFailures:         p.MaxFailures,
+		ContextTimeoutMs:    p.ContextTimeoutMs,
+		SchedulingStrategy:  p.SchedulingStrategy,
+	}
+	if p.SoftLimit != nil {
+		policy.SoftLimit = contracts.Cost{
+			Amount:   p.SoftLimit.Amount,
+			Currency: contracts.Currency(p.SoftLimit.Currency),
+		}
+	}
+	if len(p.ModelBudgets) > 0 {
+		policy.ModelBudgets = make(map[contracts.ModelID]contracts.Cost, len(p.ModelBudgets))
+		for model, budget := range p.ModelBudgets {
+			policy.ModelBudgets[contracts.ModelID(model)] = contracts.Cost{
+				Amount:   budget.Amount,
+				Currency: contracts.Currency(budget.Currency),
+			}
+		}
 	}
 	if p.ContextPolicy != nil {
 		policy.ContextPolicy = contracts.ContextPolicy{
 			MaxTokens: contracts.TokenCount(p.ContextPolicy.MaxTokens),
 			Strategy:  p.ContextPolicy.Strategy,
 			KeepLastN: p.ContextPolicy.KeepLastN,
+
+			MaxRoutedValueBytes: p.ContextPolicy.MaxRoutedValueBytes,
+			ForceCompactRatio:   p.ContextPolicy.ForceCompactRatio,
+			OutputCollision:     p.ContextPolicy.OutputCollision,
 		}
 	}
 	return policy
 }
 
+// PolicyToDTO converts contracts.RunPolicy to PolicyDTO.
+func PolicyToDTO(policy contracts.RunPolicy) *PolicyDTO {
+	dto := &PolicyDTO{
+		TimeoutMs:      policy.TimeoutMs,
+		MaxParallelism: policy.MaxParallelism,
+		BudgetLimit: CostDTO{
+			Amount:   policy.BudgetLimit.Amount,
+			Currency: string(policy.BudgetLimit.Currency),
+		},
+		BudgetPool:     policy.BudgetPool,
+		BudgetDisabled: policy.BudgetDisabled,
+		AbortGraceMs:   policy.AbortGraceMs,
+
+		MaxContextBuilds: policy.MaxContextBuilds,
+		RetryJitter:      policy.RetryJitter,
+		JitterSeed:       policy.JitterSeed,
+
+		ConcurrencyLimits:   maps.Clone(policy.ConcurrencyLimits),
+		DedupIdenticalTasks: policy.DedupIdenticalTasks,
+		DryRun:              policy.DryRun,
+		MaxFailures:         policy.MaxFailures,
+		ContextTimeoutMs:    policy.ContextTimeoutMs,
+		SchedulingStrategy:  policy.SchedulingStrategy,
+	}
+	if policy.SoftLimit.Amount > 0 {
+		dto.SoftLimit = &CostDTO{
+			Amount:   policy.SoftLimit.Amount,
+			Currency: string(policy.SoftLimit.Currency),
+		}
+	}
+	if len(policy.ModelBudgets) > 0 {
+		dto.ModelBudgets = make(map[string]CostDTO, len(policy.ModelBudgets))
+		for model, budget := range policy.ModelBudgets {
+			dto.ModelBudgets[string(model)] = CostDTO{
+				Amount:   budget.Amount,
+				Currency: string(budget.Currency),
+			}
+		}
+	}
+	if policy.ContextPolicy != (contracts.ContextPolicy{}) {
+		dto.ContextPolicy = &ContextPolicyDTO{
+			MaxTokens: int64(policy.ContextPolicy.MaxTokens),
+			Strategy:  policy.ContextPolicy.Strategy,
+			KeepLastN: policy.ContextPolicy.KeepLastN,
+
+			MaxRoutedValueBytes: policy.ContextPolicy.MaxRoutedValueBytes,
+			ForceCompactRatio:   policy.ContextPolicy.ForceCompactRatio,
+			OutputCollision:     policy.ContextPolicy.OutputCollision,
+		}
+	}
+	return dto
+}
+
 // ToTask converts TaskDTO to contracts.Task.
 func (t *TaskDTO) ToTask() *contracts.Task {
 	task := &contracts.Task{
@@ -113,10 +552,27 @@ func (t *TaskDTO) ToTask() *contracts.Task {
 		State: contracts.TaskPending,
 		Model: contracts.ModelID(t.Model),
 		Inputs: &contracts.TaskInput{
-			Prompt:   t.Prompt,
-			Inputs:   t.Inputs,
-			Metadata: t.Metadata,
+			Prompt:     t.Prompt,
+			Inputs:     t.Inputs,
+			Metadata:   t.Metadata,
+			FromMemory: t.FromMemory,
 		},
+		RequireNonEmptyOutput: t.RequireNonEmptyOutput,
+		RetryOn:               t.RetryOn,
+		ConcurrencyKey:        t.ConcurrencyKey,
+		ContinueOnFail:        t.ContinueOnFail,
+		TimeoutMs:             t.TimeoutMs,
+		MaxContextTokens:      contracts.TokenCount(t.MaxContextTokens),
+		Priority:              t.Priority,
+	}
+	if t.Skip {
+		task.State = contracts.TaskSkipped
+	}
+	if t.Retry != nil {
+		task.Retry = &contracts.RetryPolicy{MaxAttempts: t.Retry.MaxAttempts, BackoffMs: t.Retry.BackoffMs}
+	}
+	if t.RunIf != nil {
+		task.RunIf = &contracts.Condition{Key: t.RunIf.Key, Equals: t.RunIf.Equals}
 	}
 	if len(t.Deps) > 0 {
 		task.Deps = make([]contracts.TaskID, len(t.Deps))
@@ -131,6 +587,37 @@ func (t *TaskDTO) ToTask() *contracts.Task {
 // Converters: contracts → Response DTO
 // ============================================================================
 
+// TaskToDTO converts a task definition back to its request DTO.
+func TaskToDTO(task contracts.Task) TaskDTO {
+	dto := TaskDTO{
+		ID:    string(task.ID),
+		Model: string(task.Model),
+		Deps:  taskIDStrings(task.Deps),
+
+		RequireNonEmptyOutput: task.RequireNonEmptyOutput,
+		RetryOn:               task.RetryOn,
+		ConcurrencyKey:        task.ConcurrencyKey,
+		ContinueOnFail:        task.ContinueOnFail,
+		TimeoutMs:             task.TimeoutMs,
+		MaxContextTokens:      int64(task.MaxContextTokens),
+		Priority:              task.Priority,
+		Skip:                  task.State == contracts.TaskSkipped,
+	}
+	if task.Retry != nil {
+		dto.Retry = &RetryDTO{MaxAttempts: task.Retry.MaxAttempts, BackoffMs: task.Retry.BackoffMs}
+	}
+	if task.RunIf != nil {
+		dto.RunIf = &ConditionDTO{Key: task.RunIf.Key, Equals: task.RunIf.Equals}
+	}
+	if task.Inputs != nil {
+		dto.Prompt = task.Inputs.Prompt
+		dto.Inputs = task.Inputs.Inputs
+		dto.Metadata = redactMetadata(task.Inputs.Metadata)
+		dto.FromMemory = task.Inputs.FromMemory
+	}
+	return dto
+}
+
 // RunToResponse converts a contracts.Run to RunResponse.
 // The apiState parameter allows overriding the state (e.g., "aborting").
 func RunToResponse(run *contracts.Run, apiState string, createdAt, updatedAt int64) *RunResponse {
@@ -140,16 +627,29 @@ func RunToResponse(run *contracts.Run, apiState string, createdAt, updatedAt int
 	}
 
 	resp := &RunResponse{
-		ID:        string(run.ID),
-		State:     state,
-		CreatedAt: createdAt,
-		UpdatedAt: updatedAt,
+		ID:         string(run.ID),
+		State:      state,
+		CreatedAt:  createdAt,
+		UpdatedAt:  updatedAt,
+		ConfigHash: run.ConfigHash,
+		TraceID:    run.TraceID,
+		StartAfter: int64(run.StartAfter),
+		Labels:     run.Labels,
+		StartedAt:  int64(run.StartedAt),
+		FinishedAt: int64(run.FinishedAt),
+		DurationMs: runDuration(int64(run.StartedAt), int64(run.FinishedAt)),
+
+		BudgetWarning: run.BudgetWarning,
 	}
 
 	// Add task statuses
+	terminal := 0
 	if len(run.Tasks) > 0 {
 		resp.Tasks = make(map[string]TaskStatusDTO, len(run.Tasks))
 		for id, task := range run.Tasks {
+			if isTerminalTask(task.State) {
+				terminal++
+			}
 			taskDTO := TaskStatusDTO{
 				State: task.State.String(),
 			}
@@ -166,10 +666,17 @@ func RunToResponse(run *contracts.Run, apiState string, createdAt, updatedAt int
 		}
 	}
 
+	resp.Progress = progressPercent(terminal, len(run.Tasks))
+	if len(run.Tasks) > 0 {
+		resp.TaskCounts = taskCountsToDTO(contracts.CountTasks(run.Tasks))
+	}
+
 	// Add usage
 	if run.Usage.Tokens > 0 || run.Usage.Cost.Amount > 0 {
 		resp.Usage = &UsageDTO{
-			Tokens: int64(run.Usage.Tokens),
+			Tokens:       int64(run.Usage.Tokens),
+			InputTokens:  int64(run.Usage.InputTokens),
+			OutputTokens: int64(run.Usage.OutputTokens),
 			Cost: &CostDTO{
 				Amount:   run.Usage.Cost.Amount,
 				Currency: string(run.Usage.Cost.Currency),
@@ -180,6 +687,16 @@ func RunToResponse(run *contracts.Run, apiState string, createdAt, updatedAt int
 	return resp
 }
 
+// taskCountsToDTO converts task counts to their response DTO.
+func taskCountsToDTO(c contracts.TaskCounts) *TaskCountsDTO {
+	return &TaskCountsDTO{
+		Completed:  c.Completed,
+		Failed:     c.Failed,
+		Skipped:    c.Skipped,
+		Unfinished: c.Unfinished,
+	}
+}
+
 // ErrorToResponse converts an error to ErrorDTO with appropriate code.
 func ErrorToResponse(err error, code string) *ErrorDTO {
 	return &ErrorDTO{
@@ -188,6 +705,16 @@ func ErrorToResponse(err error, code string) *ErrorDTO {
 	}
 }
 
+// runDuration returns finished - started, or nil unless the run both
+// started and finished.
+func runDuration(started, finished int64) *int64 {
+	if started == 0 || finished == 0 {
+		return nil
+	}
+	d := max(finished-started, 0)
+	return &d
+}
+
 // SnapshotToResponse converts a RunSnapshot to RunResponse.
 // This is the thread-safe way to build API responses.
 func SnapshotToResponse(snap *RunSnapshot) *RunResponse {
@@ -196,6 +723,21 @@ func SnapshotToResponse(snap *RunSnapshot) *RunResponse {
 		State:     snap.APIState,
 		CreatedAt: snap.CreatedAt,
 		UpdatedAt: snap.UpdatedAt,
+
+		DroppedEvents: snap.DroppedEvents,
+		ConfigHash:    snap.ConfigHash,
+		TraceID:       snap.TraceID,
+		StartAfter:    snap.StartAfter,
+		Labels:        snap.Labels,
+		Progress:      snap.Progress,
+		BudgetWarning: snap.BudgetWarning,
+
+		StartedAt:  snap.StartedAt,
+		FinishedAt: snap.FinishedAt,
+		DurationMs: runDuration(snap.StartedAt, snap.FinishedAt),
+	}
+	if len(snap.Tasks) > 0 {
+		resp.TaskCounts = taskCountsToDTO(snap.TaskCounts)
 	}
 
 	// Add task statuses
@@ -203,8 +745,9 @@ func SnapshotToResponse(snap *RunSnapshot) *RunResponse {
 		resp.Tasks = make(map[string]TaskStatusDTO, len(snap.Tasks))
 		for id, task := range snap.Tasks {
 			taskDTO := TaskStatusDTO{
-				State:  task.State.String(),
-				Output: task.Output,
+				State:         task.State.String(),
+				Output:        task.Output,
+				PartialOutput: task.PartialOutput,
 			}
 			if task.Error != nil {
 				taskDTO.Error = &ErrorDTO{
@@ -212,6 +755,15 @@ func SnapshotToResponse(snap *RunSnapshot) *RunResponse {
 					Message: task.Error.Message,
 				}
 			}
+			if task.EstimatedUse.Tokens > 0 {
+				taskDTO.EstimatedUsage = &UsageDTO{
+					Tokens: int64(task.EstimatedUse.Tokens),
+					Cost: &CostDTO{
+						Amount:   task.EstimatedUse.Cost.Amount,
+						Currency: string(task.EstimatedUse.Cost.Currency),
+					},
+				}
+			}
 			resp.Tasks[string(id)] = taskDTO
 		}
 	}
@@ -219,7 +771,9 @@ func SnapshotToResponse(snap *RunSnapshot) *RunResponse {
 	// Add usage
 	if snap.Usage.Tokens > 0 || snap.Usage.Cost.Amount > 0 {
 		resp.Usage = &UsageDTO{
-			Tokens: int64(snap.Usage.Tokens),
+			Tokens:       int64(snap.Usage.Tokens),
+			InputTokens:  int64(snap.Usage.InputTokens),
+			OutputTokens: int64(snap.Usage.OutputTokens),
 			Cost: &CostDTO{
 				Amount:   snap.Usage.Cost.Amount,
 				Currency: string(snap.Usage.Cost.Currency),
@@ -227,6 +781,22 @@ func SnapshotToResponse(snap *RunSnapshot) *RunResponse {
 		}
 	}
 
+	if plan := snap.Plan; plan != nil {
+		resp.Plan = make([][]string, len(plan.Batches))
+		for i, batch := range plan.Batches {
+			resp.Plan[i] = taskIDStrings(batch)
+		}
+		resp.ProjectedTokens = int64(plan.ProjectedUsage.Tokens)
+		resp.ProjectedCost = &CostDTO{
+			Amount:   plan.ProjectedUsage.Cost.Amount,
+			Currency: string(plan.ProjectedUsage.Cost.Currency),
+		}
+	}
+
+	for _, note := range snap.Notes {
+		resp.Notes = append(resp.Notes, NoteDTO(note))
+	}
+
 	// Add error if present
 	if snap.Error != nil {
 		httpErr := MapError(snap.Error)
@@ -238,3 +808,110 @@ func SnapshotToResponse(snap *RunSnapshot) *RunResponse {
 
 	return resp
 }
+
+// Expand values accepted by GET /api/v1/runs/{id}?expand=.
+const (
+	ExpandDAG     = "dag"
+	ExpandPolicy  = "policy"
+	ExpandOrder   = "order"
+	ExpandResults = "results"
+)
+
+// ExpandOptions selects optional sections of a status response.
+type ExpandOptions struct {
+	DAG     bool
+	Policy  bool
+	Order   bool
+	Results bool
+}
+
+// ParseExpand parses a comma-separated expand list (e.g. "dag,policy").
+// Returns ErrInvalidInput for unknown values.
+func ParseExpand(raw string) (ExpandOptions, error) {
+	var opts ExpandOptions
+	if raw == "" {
+		return opts, nil
+	}
+	for _, value := range strings.Split(raw, ",") {
+		switch strings.TrimSpace(value) {
+		case ExpandDAG:
+			opts.DAG = true
+		case ExpandPolicy:
+			opts.Policy = true
+		case ExpandOrder:
+			opts.Order = true
+		case ExpandResults:
+			opts.Results = true
+		case "":
+		default:
+			return opts, fmt.Errorf("unknown expand value %q (want %s, %s, %s or %s): %w",
+				value, ExpandDAG, ExpandPolicy, ExpandOrder, ExpandResults, contracts.ErrInvalidInput)
+		}
+	}
+	return opts, nil
+}
+
+// ApplyExpand adds the sections selected by opts from snap to resp.
+func (r *RunResponse) ApplyExpand(snap *RunSnapshot, opts ExpandOptions) {
+	if opts.DAG && snap.DAG != nil {
+		r.DAG = make(map[string]DAGNodeDTO, len(snap.DAG))
+		for id, node := range snap.DAG {
+			r.DAG[string(id)] = DAGNodeDTO{
+				Deps: taskIDStrings(node.Deps),
+				Next: taskIDStrings(node.Next),
+			}
+		}
+	}
+	if opts.Policy {
+		r.Policy = PolicyToDTO(snap.Policy)
+	}
+	if opts.Order {
+		r.Order = taskIDStrings(snap.Order)
+	}
+	if opts.Results {
+		r.Results = sinkResults(snap)
+	}
+}
+
+// sinkResults returns the outputs of completed sink tasks, the nodes with no
+// dependents. Sinks that failed or were skipped are left out.
+func sinkResults(snap *RunSnapshot) map[string]string {
+	results := make(map[string]string)
+	for id, node := range snap.DAG {
+		if len(node.Next) > 0 {
+			continue
+		}
+		if task, ok := snap.Tasks[id]; ok && task.State == contracts.TaskCompleted {
+			results[string(id)] = task.Output
+		}
+	}
+	return results
+}
+
+// snapshotDAG rebuilds a contracts.DAG from a snapshot's edges. Pending
+// counts are those of a run that has not started.
+func snapshotDAG(snap *RunSnapshot) *contracts.DAG {
+	dag := &contracts.DAG{
+		Nodes: make(map[contracts.TaskID]*contracts.DAGNode, len(snap.DAG)),
+		Edges: make(map[contracts.TaskID][]contracts.TaskID, len(snap.DAG)),
+	}
+	for id, node := range snap.DAG {
+		dag.Nodes[id] = &contracts.DAGNode{
+			ID:      id,
+			Deps:    slices.Clone(node.Deps),
+			Next:    slices.Clone(node.Next),
+			Pending: len(node.Deps),
+		}
+		dag.Edges[id] = slices.Clone(node.Next)
+	}
+	return dag
+}
+
+// taskIDStrings converts task IDs to strings (never nil, so JSON shows []).
+func taskIDStrings(ids []contracts.TaskID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = string(id)
+	}
+	return out
+}
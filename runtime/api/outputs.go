@@ -0,0 +1,108 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+// OutputPersister saves the results of completed runs, e.g. for pickup by
+// other systems. See RunStore.SetOutputPersister.
+type OutputPersister interface {
+	// Persist stores the results of a completed run's sink tasks (the tasks
+	// no other task depends on), keyed by task ID.
+	Persist(id contracts.RunID, sinks map[contracts.TaskID]*contracts.TaskResult) error
+}
+
+// FileOutputPersister writes each run's sink results under <dir>/<run-id>/:
+// a task's output to <task-id>.txt and each named output to
+// <task-id>/<name>. IDs and names are escaped (see outputFileName), so each
+// is a single file name inside the run directory.
+type FileOutputPersister struct {
+	dir string
+}
+
+var _ OutputPersister = (*FileOutputPersister)(nil)
+
+// NewFileOutputPersister creates a FileOutputPersister writing to dir.
+// The directory is created on the first Persist.
+func NewFileOutputPersister(dir string) *FileOutputPersister {
+	return &FileOutputPersister{dir: dir}
+}
+
+// Persist writes the run's files into a temporary directory and renames it
+// to <dir>/<run-id>, so readers never see a partly written run. Files of an
+// earlier run with the same ID are replaced.
+func (p *FileOutputPersister) Persist(id contracts.RunID, sinks map[contracts.TaskID]*contracts.TaskResult) error {
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return fmt.Errorf("create dir %s: %w", p.dir, err)
+	}
+	tmp, err := os.MkdirTemp(p.dir, ".run-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	// MkdirTemp creates the directory private; the run directory is shared
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return fmt.Errorf("chmod temp dir: %w", err)
+	}
+
+	for tid, result := range sinks {
+		name := outputFileName(string(tid))
+		if err := os.WriteFile(filepath.Join(tmp, name+".txt"), []byte(result.Output), 0644); err != nil {
+			return fmt.Errorf("write output of task %s: %w", tid, err)
+		}
+		if len(result.Outputs) == 0 {
+			continue
+		}
+		taskDir := filepath.Join(tmp, name)
+		if err := os.Mkdir(taskDir, 0755); err != nil {
+			return fmt.Errorf("create dir for task %s: %w", tid, err)
+		}
+		for key, value := range result.Outputs {
+			if err := os.WriteFile(filepath.Join(taskDir, outputFileName(key)), []byte(value), 0644); err != nil {
+				return fmt.Errorf("write output %s of task %s: %w", key, tid, err)
+			}
+		}
+	}
+
+	runDir := p.RunDir(id)
+	if err := os.RemoveAll(runDir); err != nil {
+		return fmt.Errorf("replace %s: %w", runDir, err)
+	}
+	return os.Rename(tmp, runDir)
+}
+
+// RunDir returns the directory holding a run's files.
+func (p *FileOutputPersister) RunDir(id contracts.RunID) string {
+	return filepath.Join(p.dir, outputFileName(string(id)))
+}
+
+// outputFileName path-escapes s for use as a file name. "." and ".." are
+// escaped too, as they would otherwise leave the directory.
+func outputFileName(s string) string {
+	switch s {
+	case ".", "..":
+		return strings.ReplaceAll(s, ".", "%2E")
+	}
+	return url.PathEscape(s)
+}
+
+// sinkTaskResults returns the results of a run's completed sink tasks.
+// The caller must own the run (the orchestrator has finished).
+func sinkTaskResults(run *contracts.Run) map[contracts.TaskID]*contracts.TaskResult {
+	sinks := make(map[contracts.TaskID]*contracts.TaskResult)
+	for id, task := range run.Tasks {
+		if node, ok := run.DAG.Nodes[id]; ok && len(node.Next) > 0 {
+			continue
+		}
+		if task.State == contracts.TaskCompleted && task.Outputs != nil {
+			sinks[id] = task.Outputs
+		}
+	}
+	return sinks
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+// Persistence saves run snapshots so a RunStore survives sidecar restarts.
+// See NewRunStoreWithPersistence.
+type Persistence interface {
+	// Save stores the latest snapshot of a run, replacing any earlier one.
+	Save(snapshot *RunSnapshot) error
+	// LoadAll returns every stored snapshot, in no particular order.
+	LoadAll() ([]*RunSnapshot, error)
+}
+
+// PersistenceDeleter is implemented by persistence backends that can remove
+// a run, so pruned runs are not restored on the next start.
+type PersistenceDeleter interface {
+	Delete(id contracts.RunID) error
+}
+
+// FilePersistence stores one JSON file per run, run-<id>.json, in the audit
+// file format with the dag, policy and order sections expanded.
+type FilePersistence struct {
+	dir string
+}
+
+var (
+	_ Persistence        = (*FilePersistence)(nil)
+	_ PersistenceDeleter = (*FilePersistence)(nil)
+)
+
+// NewFilePersistence creates a FilePersistence writing to dir.
+// The directory is created on the first Save.
+func NewFilePersistence(dir string) *FilePersistence {
+	return &FilePersistence{dir: dir}
+}
+
+// Save writes the snapshot to its run file. The file is replaced atomically,
+// so a crash mid-write leaves the previous snapshot in place.
+func (p *FilePersistence) Save(snap *RunSnapshot) error {
+	data, err := marshalRunFile(snap)
+	if err != nil {
+		return fmt.Errorf("marshal run %s: %w", snap.ID, err)
+	}
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return fmt.Errorf("create dir %s: %w", p.dir, err)
+	}
+
+	tmp, err := os.CreateTemp(p.dir, ".run-*.json.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write run %s: %w", snap.ID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write run %s: %w", snap.ID, err)
+	}
+	return os.Rename(tmp.Name(), p.path(snap.ID))
+}
+
+// LoadAll reads every run file in the directory. A missing directory holds
+// no runs. Files that cannot be parsed fail the load.
+func (p *FilePersistence) LoadAll() ([]*RunSnapshot, error) {
+	files, err := filepath.Glob(filepath.Join(p.dir, "run-*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	snaps := make([]*RunSnapshot, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var resp RunResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", file, err)
+		}
+		if resp.ID == "" {
+			return nil, fmt.Errorf("parse %s: missing run id", file)
+		}
+		snaps = append(snaps, ResponseToSnapshot(&resp))
+	}
+	return snaps, nil
+}
+
+// Delete removes a run's file. Deleting a run without a file is not an error.
+func (p *FilePersistence) Delete(id contracts.RunID) error {
+	if err := os.Remove(p.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the run file of id.
+func (p *FilePersistence) path(id contracts.RunID) string {
+	return filepath.Join(p.dir, fmt.Sprintf("run-%s.json", id))
+}
+
+// marshalRunFile encodes a snapshot in the audit file format, with every
+// expandable section included so ResponseToSnapshot can restore it.
+func marshalRunFile(snap *RunSnapshot) ([]byte, error) {
+	resp := SnapshotToResponse(snap)
+	resp.ApplyExpand(snap, ExpandOptions{DAG: true, Policy: true, Order: true})
+	return json.MarshalIndent(resp, "", "  ")
+}
+
+// ResponseToSnapshot converts a stored RunResponse back to a RunSnapshot.
+// Unknown run states are kept as RunPending with APIState as stored; the
+// run error keeps its code (see MapError) but not its original error chain.
+func ResponseToSnapshot(resp *RunResponse) *RunSnapshot {
+	state, _ := parseRunState(resp.State)
+	snap := &RunSnapshot{
+		ID:        contracts.RunID(resp.ID),
+		State:     state,
+		Tasks:     make(map[contracts.TaskID]TaskSnapshot, len(resp.Tasks)),
+		CreatedAt: resp.CreatedAt,
+		UpdatedAt: resp.UpdatedAt,
+		APIState:  resp.State,
+
+		DroppedEvents: resp.DroppedEvents,
+		ConfigHash:    resp.ConfigHash,
+		TraceID:       resp.TraceID,
+		StartAfter:    resp.StartAfter,
+		Progress:      resp.Progress,
+		BudgetWarning: resp.BudgetWarning,
+
+		StartedAt:  resp.StartedAt,
+		FinishedAt: resp.FinishedAt,
+
+		Labels: resp.Labels,
+	}
+
+	for id, task := range resp.Tasks {
+		taskState, _ := parseTaskState(task.State)
+		snap.TaskCounts.Add(taskState)
+		ts := TaskSnapshot{
+			State:         taskState,
+			Output:        task.Output,
+			PartialOutput: task.PartialOutput,
+		}
+		if task.Error != nil {
+			ts.Error = &contracts.TaskError{Code: task.Error.Code, Message: task.Error.Message}
+		}
+		if u := task.EstimatedUsage; u != nil {
+			ts.EstimatedUse.Tokens = contracts.TokenCount(u.Tokens)
+			if u.Cost != nil {
+				ts.EstimatedUse.Cost = contracts.Cost{Amount: u.Cost.Amount, Currency: contracts.Currency(u.Cost.Currency)}
+			}
+		}
+		snap.Tasks[contracts.TaskID(id)] = ts
+	}
+
+	if resp.Usage != nil {
+		snap.Usage = contracts.Usage{
+			Tokens:       contracts.TokenCount(resp.Usage.Tokens),
+			InputTokens:  contracts.TokenCount(resp.Usage.InputTokens),
+			OutputTokens: contracts.TokenCount(resp.Usage.OutputTokens),
+		}
+		if resp.Usage.Cost != nil {
+			snap.Usage.Cost = contracts.Cost{
+				Amount:   resp.Usage.Cost.Amount,
+				Currency: contracts.Currency(resp.Usage.Cost.Currency),
+			}
+		}
+	}
+
+	if resp.Error != nil {
+		snap.Error = &HTTPError{http.StatusInternalServerError, ErrorCode(resp.Error.Code), errors.New(resp.Error.Message)}
+	}
+
+	if resp.DAG != nil {
+		snap.DAG = make(map[contracts.TaskID]DAGNodeSnapshot, len(resp.DAG))
+		for id, node := range resp.DAG {
+			snap.DAG[contracts.TaskID(id)] = DAGNodeSnapshot{
+				Deps: taskIDs(node.Deps),
+				Next: taskIDs(node.Next),
+			}
+		}
+	}
+	if resp.Policy != nil {
+		snap.Policy = resp.Policy.ToRunPolicy()
+		snap.Policy.BudgetDisabled = resp.Policy.BudgetDisabled
+	}
+	if len(resp.Order) > 0 {
+		snap.Order = taskIDs(resp.Order)
+	}
+	for _, note := range resp.Notes {
+		snap.Notes = append(snap.Notes, RunNote(note))
+	}
+	if resp.Plan != nil {
+		snap.Plan = &contracts.DryRunPlan{
+			Batches:        make([][]contracts.TaskID, len(resp.Plan)),
+			ProjectedUsage: contracts.Usage{Tokens: contracts.TokenCount(resp.ProjectedTokens)},
+		}
+		for i, batch := range resp.Plan {
+			snap.Plan.Batches[i] = taskIDs(batch)
+		}
+		if resp.ProjectedCost != nil {
+			snap.Plan.ProjectedUsage.Cost = contracts.Cost{
+				Amount:   resp.ProjectedCost.Amount,
+				Currency: contracts.Currency(resp.ProjectedCost.Currency),
+			}
+		}
+	}
+	return snap
+}
+
+// parseRunState parses a RunState from its String form.
+func parseRunState(s string) (contracts.RunState, bool) {
+	for _, state := range []contracts.RunState{
+		contracts.RunPending, contracts.RunRunning, contracts.RunCompleted,
+		contracts.RunFailed, contracts.RunAborted, contracts.RunScheduled,
+	} {
+		if state.String() == s {
+			return state, true
+		}
+	}
+	return contracts.RunPending, false
+}
+
+// parseTaskState parses a TaskState from its String form.
+func parseTaskState(s string) (contracts.TaskState, bool) {
+	for _, state := range []contracts.TaskState{
+		contracts.TaskPending, contracts.TaskReady, contracts.TaskRunning,
+		contracts.TaskCompleted, contracts.TaskFailed, contracts.TaskSkipped,
+	} {
+		if state.String() == s {
+			return state, true
+		}
+	}
+	return contracts.TaskPending, false
+}
+
+// taskIDs converts strings to task IDs.
+func taskIDs(ids []string) []contracts.TaskID {
+	out := make([]contracts.TaskID, len(ids))
+	for i, id := range ids {
+		out[i] = contracts.TaskID(id)
+	}
+	return out
+}
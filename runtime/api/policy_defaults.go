@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+// PolicyDefaults are house policy settings applied to every submitted run.
+// Defaults fill the fields a request leaves unset; Caps then clamp the result.
+type PolicyDefaults struct {
+	Defaults PolicyDTO  `json:"defaults"`
+	Caps     PolicyCaps `json:"caps"`
+}
+
+// PolicyCaps are upper bounds on submitted policy values (0 = no cap).
+// A capped field left unset by both the request and the defaults is set to the cap.
+type PolicyCaps struct {
+	TimeoutMs      int64   `json:"timeout_ms,omitempty"`
+	MaxParallelism int     `json:"max_parallelism,omitempty"`
+	BudgetLimit    float64 `json:"budget_limit,omitempty"` // amount in the server currency
+}
+
+// LoadPolicyDefaults reads PolicyDefaults from a JSON file.
+// Budget currencies are normalized to currency; other currencies are rejected.
+func LoadPolicyDefaults(path string, currency contracts.Currency) (*PolicyDefaults, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy defaults: %w", err)
+	}
+
+	var defaults PolicyDefaults
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&defaults); err != nil {
+		return nil, fmt.Errorf("parsing policy defaults %s: %w", path, err)
+	}
+
+	caps := defaults.Caps
+	if caps.TimeoutMs < 0 || caps.MaxParallelism < 0 || caps.BudgetLimit < 0 {
+		return nil, fmt.Errorf("policy defaults %s: caps must be >= 0: %w", path, contracts.ErrInvalidInput)
+	}
+	if err := normalizeCurrencies(&defaults.Defaults, currency); err != nil {
+		return nil, fmt.Errorf("policy defaults %s: %w", path, err)
+	}
+	return &defaults, nil
+}
+
+// Apply merges the defaults under policy and clamps it to the caps.
+// Nil PolicyDefaults leave policy unchanged.
+func (d *PolicyDefaults) Apply(policy *PolicyDTO) {
+	if d == nil {
+		return
+	}
+	def := d.Defaults
+
+	if policy.TimeoutMs == 0 {
+		policy.TimeoutMs = def.TimeoutMs
+	}
+	if policy.MaxParallelism == 0 {
+		policy.MaxParallelism = def.MaxParallelism
+	}
+	if policy.BudgetLimit.Amount == 0 {
+		policy.BudgetLimit = def.BudgetLimit
+	}
+	if policy.BudgetPool == "" {
+		policy.BudgetPool = def.BudgetPool
+	}
+	if policy.AbortGraceMs == 0 {
+		policy.AbortGraceMs = def.AbortGraceMs
+	}
+	if policy.MaxContextBuilds == 0 {
+		policy.MaxContextBuilds = def.MaxContextBuilds
+	}
+	if policy.ContextTimeoutMs == 0 {
+		policy.ContextTimeoutMs = def.ContextTimeoutMs
+	}
+	if policy.SchedulingStrategy == "" {
+		policy.SchedulingStrategy = def.SchedulingStrategy
+	}
+	if !policy.RetryJitter {
+		policy.RetryJitter = def.RetryJitter
+	}
+	if policy.JitterSeed == 0 {
+		policy.JitterSeed = def.JitterSeed
+	}
+	if !policy.DedupIdenticalTasks {
+		policy.DedupIdenticalTasks = def.DedupIdenticalTasks
+	}
+	policy.ModelBudgets = mergeUnder(policy.ModelBudgets, def.ModelBudgets)
+	policy.ConcurrencyLimits = mergeUnder(policy.ConcurrencyLimits, def.ConcurrencyLimits)
+	if def.ContextPolicy != nil {
+		if policy.ContextPolicy == nil {
+			policy.ContextPolicy = &ContextPolicyDTO{}
+		}
+		mergeContextPolicy(policy.ContextPolicy, def.ContextPolicy)
+	}
+
+	caps := d.Caps
+	if caps.TimeoutMs > 0 && (policy.TimeoutMs == 0 || policy.TimeoutMs > caps.TimeoutMs) {
+		policy.TimeoutMs = caps.TimeoutMs
+	}
+	if caps.MaxParallelism > 0 && (policy.MaxParallelism == 0 || policy.MaxParallelism > caps.MaxParallelism) {
+		policy.MaxParallelism = caps.MaxParallelism
+	}
+	if caps.BudgetLimit > 0 && (policy.BudgetLimit.Amount == 0 || policy.BudgetLimit.Amount > caps.BudgetLimit) {
+		policy.BudgetLimit.Amount = caps.BudgetLimit
+	}
+}
+
+// mergeUnder returns m with the entries of defaults it lacks added.
+// m is copied before it is modified.
+func mergeUnder[V any](m, defaults map[string]V) map[string]V {
+	if len(defaults) == 0 {
+		return m
+	}
+	merged := maps.Clone(defaults)
+	maps.Copy(merged, m)
+	return merged
+}
+
+// mergeContextPolicy fills the unset fields of cp from def.
+func mergeContextPolicy(cp, def *ContextPolicyDTO) {
+	if cp.MaxTokens == 0 {
+		cp.MaxTokens = def.MaxTokens
+	}
+	if cp.Strategy == "" {
+		cp.Strategy = def.Strategy
+	}
+	if cp.KeepLastN == 0 {
+		cp.KeepLastN = def.KeepLastN
+	}
+	if cp.MaxRoutedValueBytes == 0 {
+		cp.MaxRoutedValueBytes = def.MaxRoutedValueBytes
+	}
+	if cp.ForceCompactRatio == 0 {
+		cp.ForceCompactRatio = def.ForceCompactRatio
+	}
+	if cp.OutputCollision == "" {
+		cp.OutputCollision = def.OutputCollision
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+// ProviderHeaderPrefix marks task metadata keys that set an HTTP header on
+// the task's provider requests, e.g. "provider_header:X-Routing-Tag". They
+// add to or override ServerOptions.ProviderHeaders.
+const ProviderHeaderPrefix = "provider_header:"
+
+// RedactedValue replaces provider header values in logs and exports.
+const RedactedValue = "[REDACTED]"
+
+// ValidateProviderHeaders returns ErrInvalidInput for the first header, in
+// name order, whose name is not an HTTP token or whose value contains a
+// line break or NUL. Values are left out of the error.
+func ValidateProviderHeaders(headers map[string]string) error {
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		if !validHeaderName(name) {
+			return fmt.Errorf("provider header %q: invalid name: %w", name, contracts.ErrInvalidInput)
+		}
+		if strings.ContainsAny(headers[name], "\r\n\x00") {
+			return fmt.Errorf("provider header %s: value contains a line break or NUL: %w", name, contracts.ErrInvalidInput)
+		}
+	}
+	return nil
+}
+
+// validHeaderName reports whether name is a non-empty HTTP token.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// canonicalHeaders returns headers keyed by canonical header name, or nil
+// if there are none.
+func canonicalHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(headers))
+	for name, value := range headers {
+		out[http.CanonicalHeaderKey(name)] = value
+	}
+	return out
+}
+
+// metadataProviderHeaders returns the provider headers set in task metadata,
+// keyed by header name as written.
+func metadataProviderHeaders(metadata map[string]string) map[string]string {
+	var headers map[string]string
+	for key, value := range metadata {
+		if name, ok := strings.CutPrefix(key, ProviderHeaderPrefix); ok {
+			if headers == nil {
+				headers = make(map[string]string)
+			}
+			headers[name] = value
+		}
+	}
+	return headers
+}
+
+// taskProviderHeaders returns defaults overlaid with the provider headers of
+// task's metadata, or nil if there are none.
+func taskProviderHeaders(defaults map[string]string, task *contracts.Task) map[string]string {
+	var own map[string]string
+	if task != nil && task.Inputs != nil {
+		own = canonicalHeaders(metadataProviderHeaders(task.Inputs.Metadata))
+	}
+	if len(own) == 0 {
+		return defaults
+	}
+	headers := maps.Clone(defaults)
+	if headers == nil {
+		headers = make(map[string]string, len(own))
+	}
+	maps.Copy(headers, own)
+	return headers
+}
+
+// RedactHeaders returns headers with every value replaced by RedactedValue.
+func RedactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	out := make(map[string]string, len(headers))
+	for name := range headers {
+		out[name] = RedactedValue
+	}
+	return out
+}
+
+// redactMetadata returns metadata with the values of provider header keys
+// replaced by RedactedValue, or metadata itself if it has none.
+func redactMetadata(metadata map[string]string) map[string]string {
+	if metadataProviderHeaders(metadata) == nil {
+		return metadata
+	}
+	out := maps.Clone(metadata)
+	for key := range out {
+		if strings.HasPrefix(key, ProviderHeaderPrefix) {
+			out[key] = RedactedValue
+		}
+	}
+	return out
+}
+
+// headerInjector attaches the provider headers of each task to the context
+// passed to the wrapped executor (see contracts.ProviderHeadersFromContext).
+type headerInjector struct {
+	contracts.ParallelExecutor
+	defaults map[string]string // canonical ServerOptions.ProviderHeaders
+}
+
+func (i headerInjector) Execute(ctx context.Context, run *contracts.Run, taskID contracts.TaskID) (*contracts.TaskResult, error) {
+	if headers := taskProviderHeaders(i.defaults, run.Tasks[taskID]); headers != nil {
+		ctx = contracts.WithProviderHeaders(ctx, headers)
+	}
+	return i.ParallelExecutor.Execute(ctx, run, taskID)
+}
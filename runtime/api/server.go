@@ -4,30 +4,151 @@ import (
 	"context"
 	"net/http"
 	"time"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+	"github.com/anthropics/claude-workflow/runtime/internal/orchestration"
 )
 
 // Server represents the HTTP server for the runtime sidecar API.
 type Server struct {
-	store      *RunStore
+	store      Store
 	executor   TaskExecutorFunc
 	httpServer *http.Server
 	handlers   *Handlers
 	auditDir   string // directory for run audit JSON files (empty = disabled)
 }
 
+// ServerOptions provides optional customization for the sidecar server.
+type ServerOptions struct {
+	// AuditDir is the directory for run audit JSON files (empty = disabled).
+	AuditDir string
+
+	// DefaultCurrency is applied to budgets submitted without a currency.
+	// Explicit currencies that differ from it are rejected.
+	// If empty, defaults to USD.
+	DefaultCurrency contracts.Currency
+
+	// StreamingExecutor, if set, is used instead of the executor passed to
+	// NewServerWithOptions. Partial output chunks are exposed as the task's
+	// partial_output and published as task_progress events.
+	StreamingExecutor StreamingTaskExecutorFunc
+
+	// Executors routes tasks to executors by task metadata "executor"
+	// (explicit name) or "role". Tasks without a match use the default executor.
+	// Ignored when StreamingExecutor is set.
+	Executors map[string]TaskExecutorFunc
+
+	// BudgetPool is shared by all runs; runs with policy.budget_pool set are
+	// charged against it in addition to their own budget. If nil, labels are ignored.
+	BudgetPool contracts.BudgetPool
+
+	// EventBufferSize is the per-subscriber run event buffer.
+	// Events beyond it are dropped and counted in dropped_events.
+	// If zero, defaults to DefaultEventBufferSize.
+	EventBufferSize int
+
+	// NoBudget disables budget enforcement for every run (dev only).
+	// Usage is still tracked; budget_limit may be omitted.
+	NoBudget bool
+
+	// DefaultBudget is the budget, in DefaultCurrency, given to runs started
+	// via Handlers.StartRun without one (e.g. clones or resumed runs).
+	// Submitted runs must still set budget_limit. If zero, such runs fail
+	// every task with ErrBudgetNotSet.
+	DefaultBudget float64
+
+	// CompressMinBytes is the minimum status or list response size
+	// gzip-compressed for clients that accept it. If zero, defaults to
+	// DefaultCompressMinBytes; negative disables compression.
+	CompressMinBytes int
+
+	// PolicyDefaults are merged under the policy of every submitted run,
+	// then clamped to their caps (nil = none). See LoadPolicyDefaults.
+	PolicyDefaults *PolicyDefaults
+
+	// LabelLimits caps the active (not yet finished) runs per label, keyed
+	// "key=value" (e.g. "team=payments": 3). Submissions over a cap are
+	// rejected with 429 label_limit_exceeded. Labels without a limit are uncapped.
+	LabelLimits map[string]int
+
+	// MaxScheduledRuns caps the runs waiting for their start_after time
+	// (state "scheduled"). Scheduled submissions past it are rejected with
+	// 429 too_many_scheduled; runs that start immediately are not affected.
+	// 0 = no cap.
+	MaxScheduledRuns int
+
+	// StrictMetadata rejects submitted tasks whose metadata has a key other
+	// than KnownMetadataKeys or MetadataKeys with 400 unknown_metadata_key,
+	// catching typos such as "sytem". By default any key is accepted.
+	StrictMetadata bool
+
+	// MetadataKeys are accepted under StrictMetadata in addition to
+	// KnownMetadataKeys, e.g. keys read by custom executors.
+	MetadataKeys []string
+
+	// ProviderHeaders are HTTP headers, keyed by name, for every provider
+	// request, e.g. org IDs or routing tags required by a proxy. Task
+	// metadata keys ProviderHeaderPrefix+name add to or override them per
+	// task. Executors receive them through contracts.ProviderHeadersFromContext.
+	// Values are redacted in logs and exports. See ValidateProviderHeaders.
+	ProviderHeaders map[string]string
+
+	// Clock decides when scheduled runs start and is passed to each run's
+	// orchestrator and executor, so tests can control time. If nil,
+	// defaults to orchestration.SystemClock.
+	Clock orchestration.Clock
+
+	// Store backs run storage. If nil, an in-memory RunStore is used
+	// (with EventBufferSize).
+	Store Store
+
+	// OutputDir, if set, receives the sink outputs of every completed run
+	// under <OutputDir>/<run-id>/ (see FileOutputPersister). It applies
+	// when Store is a *RunStore (or nil).
+	OutputDir string
+}
+
 // NewServer creates a new Server instance.
 // auditDir specifies the directory for run audit JSON files (empty = disabled).
 func NewServer(addr string, executor TaskExecutorFunc, auditDir string) *Server {
-	store := NewRunStore()
-	handlers := NewHandlers(store, executor, auditDir)
+	return NewServerWithOptions(addr, executor, ServerOptions{AuditDir: auditDir})
+}
+
+// NewServerWithOptions creates a new Server instance with custom options.
+func NewServerWithOptions(addr string, executor TaskExecutorFunc, opts ServerOptions) *Server {
+	auditDir := opts.AuditDir
+	store := opts.Store
+	if store == nil {
+		store = NewRunStoreWithBufferSize(opts.EventBufferSize)
+	}
+	if rs, ok := store.(*RunStore); ok && opts.OutputDir != "" {
+		rs.SetOutputPersister(NewFileOutputPersister(opts.OutputDir))
+	}
+	handlers := NewHandlersWithOptions(store, executor, opts)
+
+	compressMin := opts.CompressMinBytes
+	if compressMin == 0 {
+		compressMin = DefaultCompressMinBytes
+	}
 
 	mux := http.NewServeMux()
 
 	// Register routes using Go 1.22+ method routing
 	mux.HandleFunc("POST /api/v1/runs", handlers.HandleStartRun)
-	mux.HandleFunc("GET /api/v1/runs/{id}", handlers.HandleGetStatus)
+	mux.HandleFunc("GET /api/v1/runs", gzipHandler(handlers.HandleListRuns, compressMin))
+	mux.HandleFunc("POST /api/v1/runs/prune", handlers.HandlePruneRuns)
+	mux.HandleFunc("POST /api/v1/runs/abort", handlers.HandleAbortAll)
+	mux.HandleFunc("GET /api/v1/runs/{id}", gzipHandler(handlers.HandleGetStatus, compressMin))
+	// The bundle is a deflate-compressed zip already; gzip would not shrink it
+	mux.HandleFunc("GET /api/v1/runs/{id}/bundle", handlers.HandleGetBundle)
+	mux.HandleFunc("GET /api/v1/runs/{id}/metrics", handlers.HandleRunMetrics)
+	mux.HandleFunc("GET /api/v1/runs/{id}/plan", handlers.HandleRunPlan)
+	mux.HandleFunc("GET /api/v1/runs/{id}/events", handlers.HandleStreamRun)
+	mux.HandleFunc("PATCH /api/v1/runs/{id}", handlers.HandleUpdateRun)
 	mux.HandleFunc("POST /api/v1/runs/{id}/abort", handlers.HandleAbort)
+	mux.HandleFunc("POST /api/v1/runs/{id}/notes", handlers.HandleAddNote)
 	mux.HandleFunc("POST /api/v1/runs/{id}/tasks", handlers.HandleEnqueueTask)
+	mux.HandleFunc("POST /api/v1/runs/{id}/tasks/{task}/cancel", handlers.HandleCancelTask)
 
 	return &Server{
 		store:    store,
@@ -55,7 +176,7 @@ func (s *Server) Start() error {
 func (s *Server) Shutdown(ctx context.Context) error {
 	// Cancel all active runs
 	cancelled := s.store.CancelAll()
-	if cancelled > 0 {
+	if len(cancelled) > 0 {
 		// Wait for runs to complete (use half the context deadline for this)
 		deadline, ok := ctx.Deadline()
 		if ok {
@@ -69,9 +190,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx)
 }
 
-// Store returns the RunStore for testing purposes.
+// Store returns the in-memory RunStore for testing purposes, or nil if
+// ServerOptions.Store supplied another backend.
 func (s *Server) Store() *RunStore {
-	return s.store
+	rs, _ := s.store.(*RunStore)
+	return rs
 }
 
 // Handlers returns the Handlers for testing purposes.
@@ -1,16 +1,31 @@
 package api
 
 import (
+	"archive/zip"
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/anthropics/claude-workflow/runtime/contracts"
+	"github.com/anthropics/claude-workflow/runtime/internal/orchestration"
 )
 
 // ============================================================================
@@ -160,6 +175,361 @@ func TestRunStore_UpdateTimestamp(t *testing.T) {
 // Handler Tests
 // ============================================================================
 
+// newPruneStore returns a store with finished runs last updated 3h, 2h and
+// 1m ago, and an active run last updated 5h ago.
+func newPruneStore(t *testing.T) *RunStore {
+	t.Helper()
+	store := NewRunStore()
+	ages := []struct {
+		id   contracts.RunID
+		age  time.Duration
+		done bool
+	}{
+		{"old-1", 3 * time.Hour, true},
+		{"old-2", 2 * time.Hour, true},
+		{"recent", time.Minute, true},
+		{"active", 5 * time.Hour, false},
+	}
+	for _, a := range ages {
+		_, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		if err := store.Create(&contracts.Run{ID: a.id, State: contracts.RunCompleted}, cancel); err != nil {
+			t.Fatalf("Create %s: %v", a.id, err)
+		}
+		if a.done {
+			store.MarkDone(a.id, nil)
+		}
+		entry, _ := store.Get(a.id)
+		entry.mu.Lock()
+		entry.UpdatedAt = time.Now().Add(-a.age)
+		entry.mu.Unlock()
+	}
+	return store
+}
+
+func TestRunStore_PruneCandidates(t *testing.T) {
+	store := newPruneStore(t)
+
+	candidates := store.PruneCandidates(time.Hour)
+	ids := make([]contracts.RunID, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.ID
+	}
+	if want := []contracts.RunID{"old-1", "old-2"}; !slices.Equal(ids, want) {
+		t.Fatalf("candidates = %v, want %v (oldest first)", ids, want)
+	}
+	if age := candidates[0].Age; age < 3*time.Hour || age > 3*time.Hour+time.Minute {
+		t.Errorf("old-1 age = %v, want ~3h", age)
+	}
+
+	// Listing candidates removes nothing
+	if got := len(store.List()); got != 4 {
+		t.Errorf("expected 4 runs after PruneCandidates, got %d", got)
+	}
+	if got := store.PruneCandidates(0); got != nil {
+		t.Errorf("expected no candidates for zero retention, got %v", got)
+	}
+
+	// The candidates are exactly what PruneCompleted removes
+	if removed := store.PruneCompleted(time.Hour); removed != 2 {
+		t.Errorf("PruneCompleted removed %d runs, want 2", removed)
+	}
+	for _, id := range ids {
+		if _, ok := store.Get(id); ok {
+			t.Errorf("expected %s to be pruned", id)
+		}
+	}
+}
+
+// newPersistedRun returns a two-task run (a -> b) with labels and a budget.
+func newPersistedRun(id contracts.RunID) *contracts.Run {
+	return &contracts.Run{
+		ID:      id,
+		State:   contracts.RunRunning,
+		TraceID: "trace-" + string(id),
+		Labels:  map[string]string{"team": "payments"},
+		Policy: contracts.RunPolicy{
+			MaxParallelism: 2,
+			BudgetLimit:    contracts.Cost{Amount: 1, Currency: "USD"},
+		},
+		DAG: &contracts.DAG{Nodes: map[contracts.TaskID]*contracts.DAGNode{
+			"a": {ID: "a", Next: []contracts.TaskID{"b"}},
+			"b": {ID: "b", Deps: []contracts.TaskID{"a"}, Pending: 1},
+		}},
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"a": {ID: "a", State: contracts.TaskPending},
+			"b": {ID: "b", State: contracts.TaskPending, Deps: []contracts.TaskID{"a"}},
+		},
+	}
+}
+
+func TestRunStore_Persistence(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewRunStoreWithPersistence(0, NewFilePersistence(dir))
+	if err != nil {
+		t.Fatalf("NewRunStoreWithPersistence: %v", err)
+	}
+
+	run := newPersistedRun("done-run")
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := store.Create(run, cancel); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "run-done-run.json")); err != nil {
+		t.Fatalf("run file not written on create: %v", err)
+	}
+
+	for _, id := range []contracts.TaskID{"a", "b"} {
+		run.Tasks[id].State = contracts.TaskCompleted
+		run.Tasks[id].Outputs = &contracts.TaskResult{Output: "out-" + string(id)}
+	}
+	run.Usage = contracts.Usage{Tokens: 200, Cost: contracts.Cost{Amount: 0.02, Currency: "USD"}}
+	run.State = contracts.RunCompleted
+	run.StartedAt = contracts.Timestamp(time.Now().UnixMilli())
+	run.FinishedAt = run.StartedAt + 250
+	store.MarkDone(run.ID, nil)
+	if _, err := store.AddNote(run.ID, "checked"); err != nil {
+		t.Fatalf("AddNote: %v", err)
+	}
+	want, _ := store.GetSnapshot(run.ID)
+
+	// A new store restores the finished run from disk
+	restored, err := NewRunStoreWithPersistence(0, NewFilePersistence(dir))
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	got, ok := restored.GetSnapshot(run.ID)
+	if !ok {
+		t.Fatal("run not restored")
+	}
+	if got.APIState != "completed" || got.State != contracts.RunCompleted {
+		t.Errorf("state = %s (%v), want completed", got.APIState, got.State)
+	}
+	if got.Tasks["b"].Output != "out-b" || got.Tasks["b"].State != contracts.TaskCompleted {
+		t.Errorf("task b = %+v, want completed with out-b", got.Tasks["b"])
+	}
+	if got.Usage != want.Usage {
+		t.Errorf("usage = %+v, want %+v", got.Usage, want.Usage)
+	}
+	if got.CreatedAt != want.CreatedAt || got.UpdatedAt != want.UpdatedAt {
+		t.Errorf("timestamps = %d/%d, want %d/%d", got.CreatedAt, got.UpdatedAt, want.CreatedAt, want.UpdatedAt)
+	}
+	if got.StartedAt != int64(run.StartedAt) || got.FinishedAt != int64(run.FinishedAt) {
+		t.Errorf("started/finished = %d/%d, want %d/%d", got.StartedAt, got.FinishedAt, run.StartedAt, run.FinishedAt)
+	}
+	if got.TraceID != "trace-done-run" || got.Labels["team"] != "payments" {
+		t.Errorf("trace/labels = %s %v", got.TraceID, got.Labels)
+	}
+	if got.Policy.BudgetLimit != run.Policy.BudgetLimit || !slices.Equal(got.DAG["a"].Next, []contracts.TaskID{"b"}) {
+		t.Errorf("policy/dag = %+v %+v", got.Policy, got.DAG)
+	}
+	if !slices.Equal(got.Order, want.Order) {
+		t.Errorf("order = %v, want %v", got.Order, want.Order)
+	}
+	if !slices.Equal(got.Notes, want.Notes) || len(got.Notes) != 1 {
+		t.Errorf("notes = %+v, want %+v", got.Notes, want.Notes)
+	}
+
+	// Restored runs are finished: not active, no label slot, abort rejected
+	if n := restored.ActiveLabelCount("team=payments"); n != 0 {
+		t.Errorf("active label count = %d, want 0", n)
+	}
+	if err := restored.Abort(run.ID); !errors.Is(err, contracts.ErrRunCompleted) {
+		t.Errorf("Abort restored run: %v, want ErrRunCompleted", err)
+	}
+	if _, err := restored.GetBundle(run.ID); err != nil {
+		t.Errorf("GetBundle restored run: %v", err)
+	}
+}
+
+func TestRunStore_PersistenceRestartInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewRunStoreWithPersistence(0, NewFilePersistence(dir))
+	if err != nil {
+		t.Fatalf("NewRunStoreWithPersistence: %v", err)
+	}
+
+	// The process stops after task a completed and while b was running
+	run := newPersistedRun("active-run")
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := store.Create(run, cancel); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	run.Tasks["a"].State = contracts.TaskCompleted
+	run.Tasks["a"].Outputs = &contracts.TaskResult{Output: "out-a"}
+	run.Tasks["b"].State = contracts.TaskRunning
+	run.StartedAt = contracts.Timestamp(time.Now().Add(-time.Minute).UnixMilli())
+	store.UpdateShadowState(run.ID)
+
+	restored, err := NewRunStoreWithPersistence(0, NewFilePersistence(dir))
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	snap, ok := restored.GetSnapshot(run.ID)
+	if !ok {
+		t.Fatal("run not restored")
+	}
+	if snap.APIState != "failed" {
+		t.Errorf("state = %s, want failed", snap.APIState)
+	}
+	if !errors.Is(snap.Error, ErrRestartInterrupted) {
+		t.Errorf("error = %v, want ErrRestartInterrupted", snap.Error)
+	}
+	if resp := SnapshotToResponse(snap); resp.Error == nil || resp.Error.Code != string(CodeRestartInterrupted) {
+		t.Errorf("response error = %+v, want code %s", resp.Error, CodeRestartInterrupted)
+	}
+	// The interrupted run is terminal, so it is finished as of the restart
+	if resp := SnapshotToResponse(snap); resp.FinishedAt == 0 || resp.DurationMs == nil || *resp.DurationMs < time.Minute.Milliseconds() {
+		t.Errorf("finished_at = %d, duration_ms = %v, want both set", resp.FinishedAt, resp.DurationMs)
+	}
+	if a := snap.Tasks["a"]; a.State != contracts.TaskCompleted || a.Output != "out-a" {
+		t.Errorf("task a = %+v, want completed result kept", a)
+	}
+	b := snap.Tasks["b"]
+	if b.State != contracts.TaskFailed || b.Error == nil || b.Error.Code != string(CodeRestartInterrupted) {
+		t.Errorf("task b = %+v, want failed with %s", b, CodeRestartInterrupted)
+	}
+
+	// The failure is persisted: a second restart keeps the code
+	again, err := NewRunStoreWithPersistence(0, NewFilePersistence(dir))
+	if err != nil {
+		t.Fatalf("second reload: %v", err)
+	}
+	snap, _ = again.GetSnapshot(run.ID)
+	if resp := SnapshotToResponse(snap); resp.State != "failed" || resp.Error == nil || resp.Error.Code != string(CodeRestartInterrupted) {
+		t.Errorf("after second restart: state %s error %+v", resp.State, resp.Error)
+	}
+}
+
+func TestRunStore_PersistencePrune(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewRunStoreWithPersistence(0, NewFilePersistence(dir))
+	if err != nil {
+		t.Fatalf("NewRunStoreWithPersistence: %v", err)
+	}
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := store.Create(&contracts.Run{ID: "old", State: contracts.RunCompleted}, cancel); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	store.MarkDone("old", nil)
+	entry, _ := store.Get("old")
+	entry.mu.Lock()
+	entry.UpdatedAt = time.Now().Add(-2 * time.Hour)
+	entry.mu.Unlock()
+
+	if removed := store.PruneCompleted(time.Hour); removed != 1 {
+		t.Fatalf("PruneCompleted removed %d, want 1", removed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "run-old.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("run file after prune: %v, want removed", err)
+	}
+}
+
+func TestResponseToSnapshot_DryRunPlan(t *testing.T) {
+	snap := &RunSnapshot{
+		ID:       "dry",
+		State:    contracts.RunCompleted,
+		APIState: "completed",
+		Tasks: map[contracts.TaskID]TaskSnapshot{
+			"A": {State: contracts.TaskSkipped, EstimatedUse: contracts.Usage{Tokens: 30, Cost: contracts.Cost{Amount: 0.01, Currency: "USD"}}},
+		},
+		Plan: &contracts.DryRunPlan{
+			Batches:        [][]contracts.TaskID{{"A"}},
+			ProjectedUsage: contracts.Usage{Tokens: 30, Cost: contracts.Cost{Amount: 0.01, Currency: "USD"}},
+		},
+	}
+
+	data, err := marshalRunFile(snap)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var resp RunResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	restored := ResponseToSnapshot(&resp)
+	if !reflect.DeepEqual(restored.Plan, snap.Plan) {
+		t.Errorf("plan = %+v, want %+v", restored.Plan, snap.Plan)
+	}
+	if got := restored.Tasks["A"].EstimatedUse; got != snap.Tasks["A"].EstimatedUse {
+		t.Errorf("estimated use = %+v, want %+v", got, snap.Tasks["A"].EstimatedUse)
+	}
+}
+
+func TestNewRunStoreWithPersistence_InvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "run-bad.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRunStoreWithPersistence(0, NewFilePersistence(dir)); err == nil {
+		t.Error("expected error for unparsable run file")
+	}
+}
+
+func TestHandlePruneRuns(t *testing.T) {
+	store := newPruneStore(t)
+	handlers := NewHandlers(store, nil, "")
+
+	prune := func(query string) (PruneResponse, *httptest.ResponseRecorder) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handlers.HandlePruneRuns(w, httptest.NewRequest("POST", "/api/v1/runs/prune"+query, nil))
+		var resp PruneResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+		}
+		return resp, w
+	}
+
+	resp, w := prune("?retention=90m&dry_run=true")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d - %s", w.Code, w.Body.String())
+	}
+	if !resp.DryRun || resp.Count != 2 || len(resp.Runs) != 2 {
+		t.Fatalf("unexpected dry run response: %+v", resp)
+	}
+	if resp.Runs[0].ID != "old-1" || resp.Runs[1].ID != "old-2" {
+		t.Errorf("expected old-1, old-2 oldest first, got %+v", resp.Runs)
+	}
+	if resp.Runs[1].AgeMs < (2 * time.Hour).Milliseconds() {
+		t.Errorf("expected old-2 age >= 2h, got %dms", resp.Runs[1].AgeMs)
+	}
+	if got := len(store.List()); got != 4 {
+		t.Fatalf("dry run removed runs: %d left, want 4", got)
+	}
+
+	// The default retention (1h) covers the same runs
+	resp, _ = prune("?dry_run=1")
+	if resp.Count != 2 {
+		t.Errorf("expected 2 candidates with default retention, got %d", resp.Count)
+	}
+
+	resp, w = prune("?retention=150m")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d - %s", w.Code, w.Body.String())
+	}
+	if resp.DryRun || resp.Count != 1 || resp.Runs != nil {
+		t.Errorf("unexpected prune response: %+v", resp)
+	}
+	if _, ok := store.Get("old-1"); ok {
+		t.Error("expected old-1 to be pruned")
+	}
+	if _, ok := store.Get("old-2"); !ok {
+		t.Error("expected old-2 to be kept")
+	}
+
+	for _, query := range []string{"?retention=0s", "?retention=soon", "?dry_run=maybe"} {
+		if _, w := prune(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
 func TestHandleStartRun_Success(t *testing.T) {
 	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
 		return &contracts.TaskResult{
@@ -304,6 +674,56 @@ func TestHandleAbort_AlreadyCompleted(t *testing.T) {
 	}
 }
 
+func TestHandleAbortAll(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	store := server.Store()
+
+	ctxs := make(map[contracts.RunID]context.Context)
+	for _, id := range []contracts.RunID{"active-2", "active-1", "done"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctxs[id] = ctx
+		if err := store.Create(&contracts.Run{ID: id, State: contracts.RunRunning}, cancel); err != nil {
+			t.Fatalf("Create %s: %v", id, err)
+		}
+	}
+	store.SetShadowRunState("done", contracts.RunCompleted)
+
+	abortAll := func() AbortAllResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		server.Handlers().HandleAbortAll(w, httptest.NewRequest("POST", "/api/v1/runs/abort", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp AbortAllResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+
+	resp := abortAll()
+	if resp.Count != 2 || !slices.Equal(resp.Runs, []string{"active-1", "active-2"}) {
+		t.Errorf("response = %+v, want the two active runs", resp)
+	}
+	for id, ctx := range ctxs {
+		if cancelled := ctx.Err() != nil; cancelled != (id != "done") {
+			t.Errorf("run %s cancelled = %v", id, cancelled)
+		}
+	}
+	if state := store.GetAPIState("active-1"); state != "aborting" {
+		t.Errorf("active-1 state = %s, want aborting", state)
+	}
+
+	// Repeating is a no-op: aborting runs are skipped, and 0 is not an error
+	w := httptest.NewRecorder()
+	server.Handlers().HandleAbortAll(w, httptest.NewRequest("POST", "/api/v1/runs/abort", nil))
+	if body := strings.TrimSpace(w.Body.String()); w.Code != http.StatusOK || body != `{"count":0,"runs":[]}` {
+		t.Errorf("second abort: %d %s, want 200 with empty runs", w.Code, body)
+	}
+}
+
 func TestHandleStartRun_MissingModel(t *testing.T) {
 	server := NewServer(":0", nil, "")
 
@@ -340,141 +760,2621 @@ func TestHandleStartRun_ZeroBudget(t *testing.T) {
 	}
 }
 
-func TestRunStore_GetSnapshot(t *testing.T) {
-	store := NewRunStore()
+func TestHandleStartRun_DefaultCurrencyNormalized(t *testing.T) {
+	server := NewServerWithOptions(":0", nil, ServerOptions{DefaultCurrency: "EUR"})
 
-	run := &contracts.Run{
-		ID:    "snap-1",
-		State: contracts.RunRunning,
-		Tasks: map[contracts.TaskID]*contracts.Task{
-			"A": {
-				ID:    "A",
-				State: contracts.TaskCompleted,
-				Outputs: &contracts.TaskResult{
-					Output: "result-A",
-				},
-			},
+	reqBody := `{
+		"id": "currency-run",
+		"policy": {
+			"max_parallelism": 1,
+			"budget_limit": {"amount": 1.0},
+			"model_budgets": {"claude-3-haiku-20240307": {"amount": 0.5}}
 		},
-		Usage: contracts.Usage{Tokens: 100},
-	}
+		"tasks": [{"id": "A", "prompt": "Hello", "model": "claude-3-haiku-20240307"}]
+	}`
 
-	_, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	req := httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody))
+	w := httptest.NewRecorder()
 
-	err := store.Create(run, cancel)
-	if err != nil {
-		t.Fatalf("Create failed: %v", err)
+	server.Handlers().HandleStartRun(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
 	}
 
-	snap, exists := store.GetSnapshot("snap-1")
+	entry, exists := server.Store().Get("currency-run")
 	if !exists {
-		t.Fatal("expected snapshot to exist")
+		t.Fatal("expected run to exist")
 	}
+	<-entry.Done
 
-	if snap.APIState != "running" {
-		t.Errorf("expected state 'running', got '%s'", snap.APIState)
+	policy := entry.Run.Policy
+	if policy.BudgetLimit.Currency != "EUR" {
+		t.Errorf("expected budget currency EUR, got %q", policy.BudgetLimit.Currency)
 	}
-
-	if snap.Tasks["A"].Output != "result-A" {
-		t.Errorf("expected task A output 'result-A', got '%s'", snap.Tasks["A"].Output)
+	if got := policy.ModelBudgets["claude-3-haiku-20240307"].Currency; got != "EUR" {
+		t.Errorf("expected model budget currency EUR, got %q", got)
 	}
 }
 
-func TestHandleEnqueueTask_NotImplemented(t *testing.T) {
+func TestHandleGetStatus_ConfigHashRoundTrip(t *testing.T) {
 	server := NewServer(":0", nil, "")
+	hash := "3f0a6a9e0c1b5d2e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e"
 
-	req := httptest.NewRequest("POST", "/api/v1/runs/any/tasks", nil)
-	req.SetPathValue("id", "any")
+	reqBody := `{
+		"id": "hashed-run",
+		"config_hash": "` + hash + `",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+	}`
+	req := httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody))
 	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
 
-	server.Handlers().HandleEnqueueTask(w, req)
+	req = httptest.NewRequest("GET", "/api/v1/runs/hashed-run", nil)
+	req.SetPathValue("id", "hashed-run")
+	w = httptest.NewRecorder()
+	server.Handlers().HandleGetStatus(w, req)
 
-	if w.Code != http.StatusNotImplemented {
-		t.Errorf("expected status 501, got %d", w.Code)
+	var resp RunResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
 	}
-
-	// Check Allow header
-	allow := w.Header().Get("Allow")
-	if allow != "POST /api/v1/runs" {
-		t.Errorf("expected Allow header 'POST /api/v1/runs', got '%s'", allow)
+	if resp.ConfigHash != hash {
+		t.Errorf("expected config_hash %s, got %q", hash, resp.ConfigHash)
 	}
 }
 
-// ============================================================================
-// Integration Tests
-// ============================================================================
-
-func TestServer_FullCycle(t *testing.T) {
-	completed := make(chan struct{})
-
-	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
-		return &contracts.TaskResult{
-			Output: "result:" + string(task.ID),
-			Usage:  contracts.Usage{Tokens: 50, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
-		}, nil
-	}
-
-	server := NewServer(":0", executor, "")
+func TestHandleGetStatus_Expand(t *testing.T) {
+	server := NewServer(":0", nil, "")
 
-	// 1. Start run
 	reqBody := `{
-		"id": "full-cycle",
-		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"id": "expand-run",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0}, "budget_pool": "team-a"},
 		"tasks": [
-			{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}
+			{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"},
+			{"id": "B", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["A"]}
 		]
 	}`
-
 	req := httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody))
 	w := httptest.NewRecorder()
 	server.Handlers().HandleStartRun(w, req)
-
 	if w.Code != http.StatusAccepted {
 		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
 	}
 
-	// 2. Poll GetStatus until complete
-	go func() {
-		for i := 0; i < 100; i++ {
-			time.Sleep(10 * time.Millisecond)
-
-			req := httptest.NewRequest("GET", "/api/v1/runs/full-cycle", nil)
-			req.SetPathValue("id", "full-cycle")
-			w := httptest.NewRecorder()
-			server.Handlers().HandleGetStatus(w, req)
-
-			var resp RunResponse
-			json.NewDecoder(w.Body).Decode(&resp)
-
-			if resp.State == "completed" {
-				close(completed)
-				return
-			}
-		}
-	}()
-
+	entry, _ := server.Store().Get("expand-run")
 	select {
-	case <-completed:
-		// Success
+	case <-entry.Done:
 	case <-time.After(5 * time.Second):
 		t.Fatal("timeout waiting for run to complete")
 	}
 
-	// 3. Verify final state
-	req = httptest.NewRequest("GET", "/api/v1/runs/full-cycle", nil)
-	req.SetPathValue("id", "full-cycle")
-	w = httptest.NewRecorder()
-	server.Handlers().HandleGetStatus(w, req)
-
+	getStatus := func(t *testing.T, expand string) RunResponse {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/v1/runs/expand-run?expand="+url.QueryEscape(expand), nil)
+		req.SetPathValue("id", "expand-run")
+		w := httptest.NewRecorder()
+		server.Handlers().HandleGetStatus(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GetStatus failed: %d - %s", w.Code, w.Body.String())
+		}
+		var resp RunResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		expand                         string
+		wantDAG, wantPolicy, wantOrder bool
+	}{
+		{"", false, false, false},
+		{"dag", true, false, false},
+		{"policy", false, true, false},
+		{"order", false, false, true},
+		{"dag,order", true, false, true},
+		{"dag, policy ,order", true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run("expand="+tt.expand, func(t *testing.T) {
+			resp := getStatus(t, tt.expand)
+
+			if (resp.DAG != nil) != tt.wantDAG {
+				t.Fatalf("dag present=%v, want %v", resp.DAG != nil, tt.wantDAG)
+			}
+			if tt.wantDAG {
+				if got := resp.DAG["B"].Deps; !reflect.DeepEqual(got, []string{"A"}) {
+					t.Errorf("expected B deps [A], got %v", got)
+				}
+				if got := resp.DAG["A"].Next; !reflect.DeepEqual(got, []string{"B"}) {
+					t.Errorf("expected A next [B], got %v", got)
+				}
+			}
+
+			if (resp.Policy != nil) != tt.wantPolicy {
+				t.Fatalf("policy present=%v, want %v", resp.Policy != nil, tt.wantPolicy)
+			}
+			if tt.wantPolicy {
+				// Effective policy reflects currency normalization
+				if resp.Policy.BudgetLimit.Currency != "USD" || resp.Policy.BudgetPool != "team-a" {
+					t.Errorf("unexpected policy: %+v", resp.Policy)
+				}
+			}
+
+			if (resp.Order != nil) != tt.wantOrder {
+				t.Fatalf("order present=%v, want %v", resp.Order != nil, tt.wantOrder)
+			}
+			if tt.wantOrder && !reflect.DeepEqual(resp.Order, []string{"A", "B"}) {
+				t.Errorf("expected order [A B], got %v", resp.Order)
+			}
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/runs/expand-run?expand=dag,tasks", nil)
+		req.SetPathValue("id", "expand-run")
+		w := httptest.NewRecorder()
+		server.Handlers().HandleGetStatus(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for unknown expand value, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleGetStatus_ExpandResults(t *testing.T) {
+	server := NewServer(":0", nil, "")
+
+	// Diamond: A -> B, C -> D; D is the only sink
+	reqBody := `{
+		"id": "results-run",
+		"policy": {"max_parallelism": 2, "budget_limit": {"amount": 1.0}},
+		"tasks": [
+			{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"},
+			{"id": "B", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["A"]},
+			{"id": "C", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["A"]},
+			{"id": "D", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["B", "C"]}
+		]
+	}`
+	req := httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody))
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+
+	entry, _ := server.Store().Get("results-run")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	getStatus := func(t *testing.T, expand string) RunResponse {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/v1/runs/results-run?expand="+expand, nil)
+		req.SetPathValue("id", "results-run")
+		w := httptest.NewRecorder()
+		server.Handlers().HandleGetStatus(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GetStatus failed: %d - %s", w.Code, w.Body.String())
+		}
+		var resp RunResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+
+	resp := getStatus(t, "results")
+	if want := map[string]string{"D": "executed:D"}; !reflect.DeepEqual(resp.Results, want) {
+		t.Errorf("expected results %v, got %v", want, resp.Results)
+	}
+	if resp.DAG != nil || resp.Order != nil {
+		t.Error("expected only results to be expanded")
+	}
+
+	if resp := getStatus(t, ""); resp.Results != nil {
+		t.Errorf("expected no results without expand, got %v", resp.Results)
+	}
+}
+
+func TestServer_OutputDir(t *testing.T) {
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		if task.Inputs.Prompt == "fail" {
+			return nil, errors.New("provider down")
+		}
+		return &contracts.TaskResult{
+			Output:  "out:" + string(task.ID),
+			Outputs: map[string]string{"notes/summary.md": "summary:" + string(task.ID)},
+			Usage:   contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+	dir := t.TempDir()
+	server := NewServerWithOptions(":0", executor, ServerOptions{OutputDir: dir})
+
+	startRun := func(t *testing.T, id, sinkPrompt string) {
+		t.Helper()
+		reqBody := `{
+			"id": "` + id + `",
+			"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0}},
+			"tasks": [
+				{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"},
+				{"id": "B", "prompt": "` + sinkPrompt + `", "model": "claude-3-haiku-20240307", "deps": ["A"]}
+			]
+		}`
+		req := httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody))
+		w := httptest.NewRecorder()
+		server.Handlers().HandleStartRun(w, req)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+		}
+		entry, _ := server.Store().Get(contracts.RunID(id))
+		select {
+		case <-entry.Done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for run to finish")
+		}
+	}
+
+	t.Run("completed", func(t *testing.T) {
+		startRun(t, "output-run", "Test")
+
+		runDir := filepath.Join(dir, "output-run")
+		data, err := os.ReadFile(filepath.Join(runDir, "B.txt"))
+		if err != nil {
+			t.Fatalf("read sink output: %v", err)
+		}
+		if string(data) != "out:B" {
+			t.Errorf("B.txt = %q, want %q", data, "out:B")
+		}
+		data, err = os.ReadFile(filepath.Join(runDir, "B", "notes%2Fsummary.md"))
+		if err != nil {
+			t.Fatalf("read named output: %v", err)
+		}
+		if string(data) != "summary:B" {
+			t.Errorf("named output = %q, want %q", data, "summary:B")
+		}
+		// A is not a sink
+		if _, err := os.Stat(filepath.Join(runDir, "A.txt")); !os.IsNotExist(err) {
+			t.Errorf("expected no output for non-sink task A, stat err = %v", err)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		startRun(t, "failed-output-run", "fail")
+
+		if snap, _ := server.Store().GetSnapshot("failed-output-run"); snap.State != contracts.RunFailed {
+			t.Fatalf("state = %v, want failed", snap.State)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "failed-output-run")); !os.IsNotExist(err) {
+			t.Errorf("expected no output dir for failed run, stat err = %v", err)
+		}
+	})
+}
+
+func TestServer_Progress(t *testing.T) {
+	release := make(chan struct{})
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return &contracts.TaskResult{
+			Output: "ok",
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServer(":0", executor, "")
+
+	reqBody := `{
+		"id": "progress-run",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [
+			{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"},
+			{"id": "B", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["A"]},
+			{"id": "C", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["B"]},
+			{"id": "D", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["C"]}
+		]
+	}`
+	req := httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody))
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+
+	getProgress := func() float64 {
+		req := httptest.NewRequest("GET", "/api/v1/runs/progress-run", nil)
+		req.SetPathValue("id", "progress-run")
+		w := httptest.NewRecorder()
+		server.Handlers().HandleGetStatus(w, req)
+		var resp RunResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Progress
+	}
+
+	if got := getProgress(); got != 0 {
+		t.Fatalf("expected progress 0 before any task completes, got %v", got)
+	}
+
+	for i, want := range []float64{25, 50, 75, 100} {
+		release <- struct{}{}
+		deadline := time.Now().Add(5 * time.Second)
+		for getProgress() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("task %d: timeout waiting for progress %v, got %v", i, want, getProgress())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	entry, _ := server.Store().Get("progress-run")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+	if got := getProgress(); got != 100 {
+		t.Errorf("expected progress 100 after completion, got %v", got)
+	}
+}
+
+func TestHandleStartRun_LabelLimit(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return &contracts.TaskResult{
+			Output: "ok",
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServerWithOptions(":0", executor, ServerOptions{
+		LabelLimits: map[string]int{"team=payments": 2},
+	})
+
+	start := func(id, labels string) *httptest.ResponseRecorder {
+		reqBody := `{
+			"id": "` + id + `",
+			"labels": ` + labels + `,
+			"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+			"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+		}`
+		w := httptest.NewRecorder()
+		server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+		return w
+	}
+
+	for _, id := range []string{"pay-1", "pay-2"} {
+		if w := start(id, `{"team": "payments", "env": "prod"}`); w.Code != http.StatusAccepted {
+			t.Fatalf("%s: expected 202, got %d - %s", id, w.Code, w.Body.String())
+		}
+	}
+	if got := server.Store().ActiveLabelCount("team=payments"); got != 2 {
+		t.Errorf("expected 2 active team=payments runs, got %d", got)
+	}
+
+	w := start("pay-3", `{"team": "payments"}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("pay-3: expected 429, got %d - %s", w.Code, w.Body.String())
+	}
+	var errResp ErrorDTO
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("invalid error response: %v", err)
+	}
+	if errResp.Code != string(CodeLabelLimitExceeded) {
+		t.Errorf("expected code %s, got %s", CodeLabelLimitExceeded, errResp.Code)
+	}
+	if _, ok := server.Store().Get("pay-3"); ok {
+		t.Error("rejected run must not be stored")
+	}
+
+	// A finished run frees its label slot
+	release <- struct{}{}
+	deadline := time.Now().Add(5 * time.Second)
+	for server.Store().ActiveLabelCount("team=payments") != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for a team=payments run to finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	w = start("pay-4", `{"team": "payments"}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("pay-4: expected 202 after a run finished, got %d - %s", w.Code, w.Body.String())
+	}
+	var resp RunResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if resp.Labels["team"] != "payments" {
+		t.Errorf("expected labels in response, got %v", resp.Labels)
+	}
+
+	// Other labels, and unlimited labels on the same runs, are not capped
+	if w := start("search-1", `{"team": "search", "env": "prod"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("search-1: expected 202, got %d - %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleStartRun_InvalidLabel(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	reqBody := `{
+		"labels": {"team=x": "payments"},
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+	}`
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d - %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleGetBundle(t *testing.T) {
+	release := make(chan struct{})
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return &contracts.TaskResult{
+			Output: "out:" + string(task.ID),
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServer(":0", executor, "")
+
+	reqBody := `{
+		"id": "bundle-run",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [
+			{"id": "A", "prompt": "First", "model": "claude-3-haiku-20240307", "metadata": {"role": "spec-analyst"}},
+			{"id": "B", "prompt": "Second", "model": "claude-3-haiku-20240307", "deps": ["A"], "max_context_tokens": 500}
+		],
+		"config_hash": "sha256:abc"
+	}`
+	req := httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody))
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+
+	getBundle := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/runs/"+id+"/bundle", nil)
+		req.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		server.Handlers().HandleGetBundle(w, req)
+		return w
+	}
+
+	// Active run: 409
+	if w := getBundle("bundle-run"); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), string(CodeRunNotTerminal)) {
+		t.Fatalf("expected 409 run_not_terminal for active run, got %d - %s", w.Code, w.Body.String())
+	}
+	if w := getBundle("missing-run"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown run, got %d", w.Code)
+	}
+
+	close(release)
+	entry, _ := server.Store().Get("bundle-run")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	w = getBundle("bundle-run")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetBundle failed: %d - %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("expected application/zip, got %q", ct)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+	var names []string
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		files[f.Name] = f
+	}
+	wantNames := []string{
+		BundleRunEntry, BundleRequestEntry, BundleDAGEntry, BundlePolicyEntry, BundleUsageEntry,
+		BundleTaskEntry("A"), BundleTaskEntry("B"),
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("bundle entries = %v, want %v", names, wantNames)
+	}
+
+	decode := func(name string, v any) {
+		t.Helper()
+		rc, err := files[name].Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", name, err)
+		}
+		defer rc.Close()
+		if err := json.NewDecoder(rc).Decode(v); err != nil {
+			t.Fatalf("decode %s: %v", name, err)
+		}
+	}
+
+	var run RunResponse
+	decode(BundleRunEntry, &run)
+	if run.State != "completed" || !reflect.DeepEqual(run.Order, []string{"A", "B"}) {
+		t.Errorf("unexpected run entry: state=%s order=%v", run.State, run.Order)
+	}
+
+	var startReq StartRunRequest
+	decode(BundleRequestEntry, &startReq)
+	if startReq.ID != "bundle-run" || startReq.ConfigHash != "sha256:abc" || len(startReq.Tasks) != 2 {
+		t.Fatalf("unexpected request entry: %+v", startReq)
+	}
+	if a := startReq.Tasks[0]; a.Prompt != "First" || a.Metadata["role"] != "spec-analyst" {
+		t.Errorf("unexpected task A definition: %+v", a)
+	}
+	if b := startReq.Tasks[1]; !reflect.DeepEqual(b.Deps, []string{"A"}) || len(b.Inputs) != 0 || b.MaxContextTokens != 500 {
+		t.Errorf("expected B as submitted (deps [A], no routed inputs, max_context_tokens 500), got %+v", b)
+	}
+
+	var dag map[string]DAGNodeDTO
+	decode(BundleDAGEntry, &dag)
+	if !reflect.DeepEqual(dag["A"].Next, []string{"B"}) {
+		t.Errorf("expected A next [B], got %v", dag["A"].Next)
+	}
+
+	var policy PolicyDTO
+	decode(BundlePolicyEntry, &policy)
+	if policy.MaxParallelism != 1 || policy.BudgetLimit.Amount != 1.0 {
+		t.Errorf("unexpected policy entry: %+v", policy)
+	}
+
+	var usage UsageDTO
+	decode(BundleUsageEntry, &usage)
+	if usage.Tokens != 20 {
+		t.Errorf("expected 20 tokens in usage entry, got %d", usage.Tokens)
+	}
+
+	var taskB TaskBundleDTO
+	decode(BundleTaskEntry("B"), &taskB)
+	if taskB.State != "completed" || taskB.Output != "out:B" || taskB.Prompt != "Second" {
+		t.Errorf("unexpected task B entry: %+v", taskB)
+	}
+	if taskB.Usage == nil || taskB.Usage.Tokens != 10 {
+		t.Errorf("expected task B usage of 10 tokens, got %+v", taskB.Usage)
+	}
+}
+
+func TestHandleStartRun_CurrencyMismatch(t *testing.T) {
+	server := NewServer(":0", nil, "")
+
+	reqBody := `{
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "EUR"}},
+		"tasks": [{"id": "A", "prompt": "Hello", "model": "claude-3-haiku-20240307"}]
+	}`
+
+	req := httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody))
+	w := httptest.NewRecorder()
+
+	server.Handlers().HandleStartRun(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRunStore_GetSnapshot(t *testing.T) {
+	store := NewRunStore()
+
+	run := &contracts.Run{
+		ID:    "snap-1",
+		State: contracts.RunRunning,
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"A": {
+				ID:    "A",
+				State: contracts.TaskCompleted,
+				Outputs: &contracts.TaskResult{
+					Output: "result-A",
+				},
+			},
+		},
+		Usage: contracts.Usage{Tokens: 100},
+	}
+
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := store.Create(run, cancel)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	snap, exists := store.GetSnapshot("snap-1")
+	if !exists {
+		t.Fatal("expected snapshot to exist")
+	}
+
+	if snap.APIState != "running" {
+		t.Errorf("expected state 'running', got '%s'", snap.APIState)
+	}
+
+	if snap.Tasks["A"].Output != "result-A" {
+		t.Errorf("expected task A output 'result-A', got '%s'", snap.Tasks["A"].Output)
+	}
+}
+
+func TestHandleEnqueueTask_NotImplemented(t *testing.T) {
+	server := NewServer(":0", nil, "")
+
+	req := httptest.NewRequest("POST", "/api/v1/runs/any/tasks", nil)
+	req.SetPathValue("id", "any")
+	w := httptest.NewRecorder()
+
+	server.Handlers().HandleEnqueueTask(w, req)
+
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+
+	// Check Allow header
+	allow := w.Header().Get("Allow")
+	if allow != "POST /api/v1/runs" {
+		t.Errorf("expected Allow header 'POST /api/v1/runs', got '%s'", allow)
+	}
+}
+
+// ============================================================================
+// Integration Tests
+// ============================================================================
+
+func TestServer_FullCycle(t *testing.T) {
+	completed := make(chan struct{})
+
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		return &contracts.TaskResult{
+			Output: "result:" + string(task.ID),
+			Usage:  contracts.Usage{Tokens: 50, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+
+	server := NewServer(":0", executor, "")
+
+	// 1. Start run
+	reqBody := `{
+		"id": "full-cycle",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [
+			{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}
+		]
+	}`
+
+	req := httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody))
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+
+	// 2. Poll GetStatus until complete
+	go func() {
+		for i := 0; i < 100; i++ {
+			time.Sleep(10 * time.Millisecond)
+
+			req := httptest.NewRequest("GET", "/api/v1/runs/full-cycle", nil)
+			req.SetPathValue("id", "full-cycle")
+			w := httptest.NewRecorder()
+			server.Handlers().HandleGetStatus(w, req)
+
+			var resp RunResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+
+			if resp.State == "completed" {
+				close(completed)
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-completed:
+		// Success
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	// 3. Verify final state
+	req = httptest.NewRequest("GET", "/api/v1/runs/full-cycle", nil)
+	req.SetPathValue("id", "full-cycle")
+	w = httptest.NewRecorder()
+	server.Handlers().HandleGetStatus(w, req)
+
+	var resp RunResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+
+	if resp.State != "completed" {
+		t.Errorf("expected state 'completed', got '%s'", resp.State)
+	}
+
+	if resp.Tasks == nil || resp.Tasks["A"].Output != "result:A" {
+		t.Errorf("expected task A output 'result:A', got: %+v", resp.Tasks)
+	}
+}
+
+func TestServer_StreamingPartialOutput(t *testing.T) {
+	start := make(chan struct{})
+	release := make(chan struct{})
+
+	streaming := func(ctx context.Context, task *contracts.Task, emit func(chunk string)) (*contracts.TaskResult, error) {
+		<-start
+		emit("hello ")
+		emit("world")
+		<-release
+		return &contracts.TaskResult{
+			Output: "hello world",
+			Usage:  contracts.Usage{Tokens: 50, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+
+	server := NewServerWithOptions(":0", nil, ServerOptions{StreamingExecutor: streaming})
+
+	reqBody := `{
+		"id": "stream-run",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [
+			{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}
+		]
+	}`
+
+	req := httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody))
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+
+	events, unsubscribe, err := server.Store().Subscribe("stream-run")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer unsubscribe()
+	close(start)
+
+	var chunks []string
+	for len(chunks) < 2 {
+		select {
+		case ev := <-events:
+			if ev.Type != EventTaskProgress {
+				continue // task and run state changes
+			}
+			if ev.TaskID != "A" {
+				t.Errorf("unexpected event: %+v", ev)
+			}
+			chunks = append(chunks, ev.Chunk)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for progress events")
+		}
+	}
+	if strings.Join(chunks, "") != "hello world" {
+		t.Errorf("unexpected chunks: %q", chunks)
+	}
+
+	// Partial output is visible while the task is still running
+	snap, _ := server.Store().GetSnapshot("stream-run")
+	if snap.Tasks["A"].PartialOutput != "hello world" {
+		t.Errorf("expected partial output 'hello world', got %q", snap.Tasks["A"].PartialOutput)
+	}
+	close(release)
+
+	// Event channel closes when the run is done
+	select {
+	case _, ok := <-events:
+		for ok {
+			_, ok = <-events
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event stream to close")
+	}
+
+	snap, _ = server.Store().GetSnapshot("stream-run")
+	if snap.Tasks["A"].Output != "hello world" || snap.Tasks["A"].PartialOutput != "" {
+		t.Errorf("unexpected final task state: %+v", snap.Tasks["A"])
+	}
+}
+
+func TestServer_StreamOutlivesWriteTimeout(t *testing.T) {
+	release := make(chan struct{})
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		<-release
+		return &contracts.TaskResult{
+			Output: "ok",
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.001, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServer(":0", executor, "")
+	ts := httptest.NewUnstartedServer(server.httpServer.Handler)
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	reqBody := `{
+		"id": "sse-long-run",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+	}`
+	resp, err := http.Post(ts.URL+"/api/v1/runs", "application/json", strings.NewReader(reqBody))
+	if err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(ts.URL + "/api/v1/runs/sse-long-run/events")
+	if err != nil {
+		t.Fatalf("GET events failed: %v", err)
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	if !scanner.Scan() || scanner.Text() != "event: run" {
+		t.Fatalf("expected the initial run event, got %q (err %v)", scanner.Text(), scanner.Err())
+	}
+
+	// Let the server's WriteTimeout pass before the run produces more events
+	time.Sleep(3 * ts.Config.WriteTimeout)
+	close(release)
+
+	done := false
+	for scanner.Scan() {
+		if scanner.Text() == "event: done" {
+			done = true
+			break
+		}
+	}
+	if !done {
+		t.Fatalf("stream ended without a done event (err %v)", scanner.Err())
+	}
+}
+
+func TestServer_StreamRunEvents(t *testing.T) {
+	subscribed := make(chan struct{})
+	release := make(chan struct{})
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		switch task.ID {
+		case "A":
+			<-subscribed
+		case "B":
+			<-release
+		}
+		return &contracts.TaskResult{
+			Output: "result:" + string(task.ID),
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.001, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServer(":0", executor, "")
+	ts := httptest.NewServer(server.httpServer.Handler)
+	defer ts.Close()
+
+	reqBody := `{
+		"id": "sse-run",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [
+			{"id": "A", "prompt": "First", "model": "claude-3-haiku-20240307"},
+			{"id": "B", "prompt": "Second", "model": "claude-3-haiku-20240307", "deps": ["A"]}
+		]
+	}`
+	resp, err := http.Post(ts.URL+"/api/v1/runs", "application/json", strings.NewReader(reqBody))
+	if err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(ts.URL + "/api/v1/runs/sse-run/events")
+	if err != nil {
+		t.Fatalf("GET events failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+	close(subscribed)
+
+	// Collect events until "done"; B is held until it is seen running
+	type sseEvent struct {
+		name string
+		data string
+	}
+	var got []sseEvent
+	var name string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev := sseEvent{name, strings.TrimPrefix(line, "data: ")}
+			got = append(got, ev)
+			if ev.name == "task_state" && strings.Contains(ev.data, `"task_id":"B","state":"running"`) {
+				close(release)
+			}
+		}
+		if name == "done" && line == "" {
+			break
+		}
+	}
+
+	var taskStates []TaskEventDTO
+	for _, ev := range got {
+		if ev.name != "task_state" {
+			continue
+		}
+		var dto TaskEventDTO
+		if err := json.Unmarshal([]byte(ev.data), &dto); err != nil {
+			t.Fatalf("invalid task_state data %q: %v", ev.data, err)
+		}
+		taskStates = append(taskStates, dto)
+	}
+	var transitions []string
+	for _, dto := range taskStates {
+		transitions = append(transitions, dto.TaskID+":"+dto.State)
+	}
+	want := []string{"B:running", "B:completed"}
+	if !slices.Contains(transitions, "A:completed") || len(transitions) < 2 ||
+		!slices.Equal(transitions[len(transitions)-2:], want) {
+		t.Errorf("unexpected task transitions: %v", transitions)
+	}
+	if last := taskStates[len(taskStates)-1]; last.Usage == nil || last.Usage.Tokens != 20 {
+		t.Errorf("expected cumulative usage of 20 tokens on the last event, got %+v", last.Usage)
+	}
+
+	if len(got) == 0 || got[0].name != "run" {
+		t.Fatalf("expected a run status first, got %+v", got)
+	}
+	final := got[len(got)-1]
+	if final.name != "done" {
+		t.Fatalf("expected done last, got %q", final.name)
+	}
+	var status RunResponse
+	if err := json.Unmarshal([]byte(final.data), &status); err != nil {
+		t.Fatalf("invalid done data: %v", err)
+	}
+	if status.State != "completed" {
+		t.Errorf("done state = %q, want completed", status.State)
+	}
+
+	// A finished run streams its final status right away
+	resp2, err := http.Get(ts.URL + "/api/v1/runs/sse-run/events")
+	if err != nil {
+		t.Fatalf("GET events failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp2.Body)
+	resp2.Body.Close()
+	if !strings.HasPrefix(string(body), "event: run\n") || !strings.Contains(string(body), "event: done\n") {
+		t.Errorf("unexpected stream for finished run: %s", body)
+	}
+
+	resp3, err := http.Get(ts.URL + "/api/v1/runs/missing/events")
+	if err != nil {
+		t.Fatalf("GET events failed: %v", err)
+	}
+	resp3.Body.Close()
+	if resp3.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown run: status %d, want 404", resp3.StatusCode)
+	}
+}
+
+func TestRunStore_EventBufferOverflow(t *testing.T) {
+	store := NewRunStoreWithBufferSize(2)
+	run := &contracts.Run{
+		ID:    "overflow",
+		State: contracts.RunRunning,
+		Tasks: map[contracts.TaskID]*contracts.Task{"A": {ID: "A", State: contracts.TaskRunning}},
+	}
+	if err := store.Create(run, func() {}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	events, unsubscribe, err := store.Subscribe("overflow")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer unsubscribe()
+
+	// Nobody reads: first 2 chunks fit in the buffer, the rest are dropped
+	for i := 0; i < 5; i++ {
+		store.AppendTaskOutput("overflow", "A", "x")
+	}
+
+	snap, _ := store.GetSnapshot("overflow")
+	if snap.DroppedEvents != 3 {
+		t.Errorf("expected 3 dropped events, got %d", snap.DroppedEvents)
+	}
+	if len(events) != 2 {
+		t.Errorf("expected 2 buffered events, got %d", len(events))
+	}
+	if snap.Tasks["A"].PartialOutput != "xxxxx" {
+		t.Errorf("expected partial output to keep all chunks, got %q", snap.Tasks["A"].PartialOutput)
+	}
+	if resp := SnapshotToResponse(snap); resp.DroppedEvents != 3 {
+		t.Errorf("expected dropped_events=3 in response, got %d", resp.DroppedEvents)
+	}
+	if m := SnapshotToMetrics(snap, time.Now()); m.DroppedEvents != 3 {
+		t.Errorf("expected dropped_events=3 in metrics, got %d", m.DroppedEvents)
+	}
+}
+
+func TestRunStore_SubscribeNotFound(t *testing.T) {
+	store := NewRunStore()
+	if _, _, err := store.Subscribe("missing"); !errors.Is(err, contracts.ErrRunNotFound) {
+		t.Errorf("expected ErrRunNotFound, got %v", err)
+	}
+}
+
+func TestServer_ExecutorsByMetadata(t *testing.T) {
+	var mu sync.Mutex
+	invoked := make(map[string]contracts.TaskID)
+	executorFor := func(name string) TaskExecutorFunc {
+		return func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+			mu.Lock()
+			invoked[name] = task.ID
+			mu.Unlock()
+			return &contracts.TaskResult{
+				Output: name + ":" + string(task.ID),
+				Usage:  contracts.Usage{Tokens: 50, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+			}, nil
+		}
+	}
+
+	server := NewServerWithOptions(":0", nil, ServerOptions{
+		Executors: map[string]TaskExecutorFunc{
+			"tool":        executorFor("tool"),
+			"spec-tester": executorFor("model"),
+		},
+	})
+
+	reqBody := `{
+		"id": "routed",
+		"policy": {"max_parallelism": 2, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [
+			{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307", "metadata": {"executor": "tool"}},
+			{"id": "B", "prompt": "Test", "model": "claude-3-haiku-20240307", "metadata": {"role": "spec-tester"}}
+		]
+	}`
+
+	req := httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody))
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+
+	entry, _ := server.Store().Get("routed")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	snap, _ := server.Store().GetSnapshot("routed")
+	if snap.State != contracts.RunCompleted {
+		t.Fatalf("expected completed, got %v", snap.State)
+	}
+	if invoked["tool"] != "A" || invoked["model"] != "B" {
+		t.Errorf("unexpected routing: %v", invoked)
+	}
+	if snap.Tasks["A"].Output != "tool:A" || snap.Tasks["B"].Output != "model:B" {
+		t.Errorf("unexpected outputs: A=%q B=%q", snap.Tasks["A"].Output, snap.Tasks["B"].Output)
+	}
+}
+
+func TestServer_NoBudget(t *testing.T) {
+	reqBody := `{
+		"id": "no-budget",
+		"policy": {"max_parallelism": 1},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+	}`
+
+	// Without NoBudget, a missing budget is rejected
+	strict := NewServer(":0", nil, "")
+	w := httptest.NewRecorder()
+	strict.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without NoBudget, got %d - %s", w.Code, w.Body.String())
+	}
+
+	server := NewServerWithOptions(":0", nil, ServerOptions{NoBudget: true})
+	w = httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+
+	entry, _ := server.Store().Get("no-budget")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	snap, _ := server.Store().GetSnapshot("no-budget")
+	if snap.State != contracts.RunCompleted {
+		t.Fatalf("expected completed, got %v", snap.State)
+	}
+	if snap.Usage.Tokens == 0 || snap.Usage.Cost.Amount == 0 {
+		t.Errorf("expected usage to be tracked, got %+v", snap.Usage)
+	}
+}
+
+// internalRun builds a single-task run as created in-process (no request validation).
+func internalRun(t *testing.T, id string, policy contracts.RunPolicy) *contracts.Run {
+	t.Helper()
+	task := contracts.Task{
+		ID:     "A",
+		State:  contracts.TaskPending,
+		Model:  "claude-3-haiku-20240307",
+		Inputs: &contracts.TaskInput{Prompt: "Test"},
+	}
+	dag, err := orchestration.NewDependencyResolver().BuildDAG([]contracts.Task{task})
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	return &contracts.Run{
+		ID:     contracts.RunID(id),
+		State:  contracts.RunPending,
+		Policy: policy,
+		DAG:    dag,
+		Tasks:  map[contracts.TaskID]*contracts.Task{task.ID: &task},
+		Memory: make(map[string]string),
+	}
+}
+
+func TestServer_StartRunDefaultBudget(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       ServerOptions
+		budget     contracts.Cost
+		wantState  contracts.RunState
+		wantBudget contracts.Cost
+	}{
+		{
+			name:       "zero budget without default fails",
+			budget:     contracts.Cost{},
+			wantState:  contracts.RunFailed,
+			wantBudget: contracts.Cost{},
+		},
+		{
+			name:       "zero budget gets default",
+			opts:       ServerOptions{DefaultBudget: 2.5},
+			budget:     contracts.Cost{},
+			wantState:  contracts.RunCompleted,
+			wantBudget: contracts.Cost{Amount: 2.5, Currency: "USD"},
+		},
+		{
+			name:       "default uses server currency",
+			opts:       ServerOptions{DefaultBudget: 3, DefaultCurrency: "EUR"},
+			budget:     contracts.Cost{},
+			wantState:  contracts.RunCompleted,
+			wantBudget: contracts.Cost{Amount: 3, Currency: "EUR"},
+		},
+		{
+			name:       "explicit budget kept",
+			opts:       ServerOptions{DefaultBudget: 2.5},
+			budget:     contracts.Cost{Amount: 1, Currency: "USD"},
+			wantState:  contracts.RunCompleted,
+			wantBudget: contracts.Cost{Amount: 1, Currency: "USD"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServerWithOptions(":0", nil, tt.opts)
+			run := internalRun(t, "internal", contracts.RunPolicy{MaxParallelism: 1, BudgetLimit: tt.budget})
+			if err := server.Handlers().StartRun(run); err != nil {
+				t.Fatalf("StartRun failed: %v", err)
+			}
+
+			entry, _ := server.Store().Get("internal")
+			select {
+			case <-entry.Done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for run to finish")
+			}
+
+			snap, _ := server.Store().GetSnapshot("internal")
+			if snap.State != tt.wantState {
+				t.Errorf("state = %v, want %v", snap.State, tt.wantState)
+			}
+			if snap.Policy.BudgetLimit != tt.wantBudget {
+				t.Errorf("budget = %+v, want %+v", snap.Policy.BudgetLimit, tt.wantBudget)
+			}
+			if snap.TraceID == "" {
+				t.Error("expected StartRun to assign a trace ID")
+			}
+		})
+	}
+}
+
+func TestServer_ScheduledRun(t *testing.T) {
+	started := make(chan time.Time, 1)
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		started <- time.Now()
+		return &contracts.TaskResult{
+			Output: "ok",
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServer(":0", executor, "")
+
+	startAfter := time.Now().Add(200 * time.Millisecond).UnixMilli()
+	reqBody := fmt.Sprintf(`{
+		"id": "scheduled",
+		"start_after": %d,
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+	}`, startAfter)
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+	var resp RunResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.State != "scheduled" {
+		t.Errorf("state = %q, want scheduled", resp.State)
+	}
+	if resp.StartAfter != startAfter {
+		t.Errorf("start_after = %d, want %d", resp.StartAfter, startAfter)
+	}
+
+	select {
+	case at := <-started:
+		if at.UnixMilli() < startAfter {
+			t.Errorf("task started %dms before start_after", startAfter-at.UnixMilli())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for scheduled run to start")
+	}
+
+	entry, _ := server.Store().Get("scheduled")
+	<-entry.Done
+	snap, _ := server.Store().GetSnapshot("scheduled")
+	if snap.State != contracts.RunCompleted {
+		t.Errorf("expected completed, got %v", snap.State)
+	}
+
+	// The run executed after sitting scheduled since creation
+	final := SnapshotToResponse(snap)
+	if !(final.CreatedAt <= final.StartAfter && final.StartAfter <= final.StartedAt && final.StartedAt <= final.FinishedAt) {
+		t.Errorf("want created_at %d <= start_after %d <= started_at %d <= finished_at %d",
+			final.CreatedAt, final.StartAfter, final.StartedAt, final.FinishedAt)
+	}
+	if final.DurationMs == nil || *final.DurationMs != final.FinishedAt-final.StartedAt {
+		t.Errorf("duration_ms = %v, want %d", final.DurationMs, final.FinishedAt-final.StartedAt)
+	}
+}
+
+// manualClock is a Clock frozen at now whose timers fire only when the
+// test sends on fire. Requested durations are reported on waits.
+type manualClock struct {
+	now   time.Time
+	waits chan time.Duration
+	fire  chan time.Time
+}
+
+func newManualClock(now time.Time) *manualClock {
+	return &manualClock{now: now, waits: make(chan time.Duration, 16), fire: make(chan time.Time)}
+}
+
+func (c *manualClock) Now() time.Time { return c.now }
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	c.waits <- d
+	return c.fire
+}
+
+func TestServer_ScheduledRunClock(t *testing.T) {
+	var calls atomic.Int32
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		calls.Add(1)
+		return &contracts.TaskResult{
+			Output: "ok",
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+	// The clock is years in the past, so start_after is already due by the
+	// system clock but an hour away by the server's
+	clock := newManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	server := NewServerWithOptions(":0", executor, ServerOptions{Clock: clock, MaxScheduledRuns: 1})
+
+	reqBody := fmt.Sprintf(`{
+		"id": "scheduled",
+		"start_after": %d,
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+	}`, clock.now.Add(time.Hour).UnixMilli())
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+	var resp RunResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.State != "scheduled" {
+		t.Errorf("state = %q, want scheduled", resp.State)
+	}
+	if got := server.Store().ScheduledCount(); got != 1 {
+		t.Errorf("scheduled count = %d, want 1", got)
+	}
+
+	select {
+	case d := <-clock.waits:
+		if d != time.Hour {
+			t.Errorf("waiting %v for start, want 1h", d)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the run to wait for its start")
+	}
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("executor called %d times before start_after", n)
+	}
+
+	clock.fire <- clock.now.Add(time.Hour)
+	entry, _ := server.Store().Get("scheduled")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for scheduled run to complete")
+	}
+	if snap, _ := server.Store().GetSnapshot("scheduled"); snap.State != contracts.RunCompleted {
+		t.Errorf("expected completed, got %v", snap.State)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("executor called %d times, want 1", n)
+	}
+}
+
+func TestServer_AbortScheduledRun(t *testing.T) {
+	var calls atomic.Int32
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		calls.Add(1)
+		return &contracts.TaskResult{Output: "ok"}, nil
+	}
+	server := NewServer(":0", executor, "")
+
+	reqBody := fmt.Sprintf(`{
+		"id": "scheduled",
+		"start_after": %d,
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+	}`, time.Now().Add(time.Hour).UnixMilli())
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+
+	if err := server.Store().Abort("scheduled"); err != nil {
+		t.Fatalf("Abort failed: %v", err)
+	}
+	entry, _ := server.Store().Get("scheduled")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for aborted run")
+	}
+
+	snap, _ := server.Store().GetSnapshot("scheduled")
+	if snap.State != contracts.RunAborted {
+		t.Errorf("expected aborted, got %v", snap.State)
+	}
+	if resp := SnapshotToResponse(snap); resp.StartedAt != 0 || resp.FinishedAt < resp.CreatedAt || resp.DurationMs != nil {
+		t.Errorf("never started: started_at = %d, finished_at = %d, duration_ms = %v", resp.StartedAt, resp.FinishedAt, resp.DurationMs)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("executor called %d times, want 0", n)
+	}
+}
+
+func TestHandleStartRun_MaxScheduledRuns(t *testing.T) {
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		return &contracts.TaskResult{
+			Output: "ok",
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServerWithOptions(":0", executor, ServerOptions{MaxScheduledRuns: 2})
+	defer server.Store().CancelAll()
+
+	later := time.Now().Add(time.Hour).UnixMilli()
+	start := func(id string, startAfter int64) *httptest.ResponseRecorder {
+		reqBody := fmt.Sprintf(`{
+			"id": %q,
+			"start_after": %d,
+			"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+			"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+		}`, id, startAfter)
+		w := httptest.NewRecorder()
+		server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+		return w
+	}
+
+	for _, id := range []string{"sched-1", "sched-2"} {
+		if w := start(id, later); w.Code != http.StatusAccepted {
+			t.Fatalf("%s: expected 202, got %d - %s", id, w.Code, w.Body.String())
+		}
+	}
+	if got := server.Store().ScheduledCount(); got != 2 {
+		t.Errorf("expected 2 scheduled runs, got %d", got)
+	}
+
+	w := start("sched-3", later)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("sched-3: expected 429, got %d - %s", w.Code, w.Body.String())
+	}
+	var errResp ErrorDTO
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("invalid error response: %v", err)
+	}
+	if errResp.Code != string(CodeTooManyScheduled) {
+		t.Errorf("expected code %s, got %s", CodeTooManyScheduled, errResp.Code)
+	}
+	if _, ok := server.Store().Get("sched-3"); ok {
+		t.Error("rejected run must not be stored")
+	}
+
+	// Runs that start immediately are not capped
+	if w := start("now", 0); w.Code != http.StatusAccepted {
+		t.Fatalf("now: expected 202, got %d - %s", w.Code, w.Body.String())
+	}
+	entry, _ := server.Store().Get("now")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for immediate run")
+	}
+	if snap, _ := server.Store().GetSnapshot("now"); snap.State != contracts.RunCompleted {
+		t.Errorf("now: expected completed, got %v", snap.State)
+	}
+
+	// An aborted scheduled run frees its slot
+	if err := server.Store().Abort("sched-1"); err != nil {
+		t.Fatalf("Abort failed: %v", err)
+	}
+	entry, _ = server.Store().Get("sched-1")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for aborted run")
+	}
+	if got := server.Store().ScheduledCount(); got != 1 {
+		t.Errorf("expected 1 scheduled run after abort, got %d", got)
+	}
+	if w := start("sched-4", later); w.Code != http.StatusAccepted {
+		t.Fatalf("sched-4: expected 202 after an abort, got %d - %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleStartRun_NegativeStartAfter(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	reqBody := `{
+		"start_after": -1,
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+	}`
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d - %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleStartRun_InvalidConcurrencyLimit(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	reqBody := `{
+		"policy": {"max_parallelism": 2, "budget_limit": {"amount": 1.0, "currency": "USD"}, "concurrency_limits": {"db": 0}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307", "concurrency_key": "db"}]
+	}`
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d - %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleStartRun_TaskTimeout(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	start := func(tasks string) *httptest.ResponseRecorder {
+		reqBody := `{
+			"policy": {"max_parallelism": 2, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+			"tasks": ` + tasks + `
+		}`
+		w := httptest.NewRecorder()
+		server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+		return w
+	}
+
+	w := start(`[{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307", "timeout_ms": -1}]`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for negative timeout_ms, got %d - %s", w.Code, w.Body.String())
+	}
+
+	// Tasks with their own timeout are not reported as unbounded
+	w = start(`[
+		{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307", "timeout_ms": 60000},
+		{"id": "B", "prompt": "Test", "model": "claude-3-haiku-20240307"}
+	]`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d - %s", w.Code, w.Body.String())
+	}
+	var resp RunResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	want := []string{"policy.timeout_ms is not set; tasks without timeout_ms run without a timeout (B)"}
+	if !slices.Equal(resp.Warnings, want) {
+		t.Errorf("warnings = %q, want %q", resp.Warnings, want)
+	}
+}
+
+func TestHandleStartRun_MaxContextTokens(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	reqBody := `{
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307", "max_context_tokens": -1}]
+	}`
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for negative max_context_tokens, got %d - %s", w.Code, w.Body.String())
+	}
+
+	dto := TaskDTO{ID: "A", Prompt: "Test", Model: "claude-3-haiku-20240307", MaxContextTokens: 500}
+	task := dto.ToTask()
+	if task.MaxContextTokens != 500 || TaskToDTO(*task).MaxContextTokens != 500 {
+		t.Errorf("max_context_tokens not carried over: task %d, dto %d", task.MaxContextTokens, TaskToDTO(*task).MaxContextTokens)
+	}
+}
+
+func TestHandleRunMetrics(t *testing.T) {
+	server := NewServer(":0", nil, "")
+
+	reqBody := `{
+		"id": "metrics-run",
+		"policy": {"max_parallelism": 2, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [
+			{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"},
+			{"id": "B", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["A"]},
+			{"id": "C", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["A"]},
+			{"id": "D", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["B", "C"]}
+		]
+	}`
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+	entry, _ := server.Store().Get("metrics-run")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	w = httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/runs/metrics-run/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("metrics failed: %d - %s", w.Code, w.Body.String())
+	}
+	var m RunMetricsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if m.State != "completed" || m.TotalTasks != 4 || m.Tasks["completed"] != 4 || m.Tasks["pending"] != 0 {
+		t.Errorf("unexpected counts: %+v", m)
+	}
+	if m.Ready != 0 {
+		t.Errorf("expected no ready tasks once finished, got %d", m.Ready)
+	}
+	// A, then B and C together, then D
+	if m.Batch != 3 {
+		t.Errorf("expected batch 3, got %d", m.Batch)
+	}
+	if m.Usage.Tokens != 400 || m.Usage.Cost == nil || m.Usage.Cost.Currency != "USD" {
+		t.Errorf("unexpected usage: %+v", m.Usage)
+	}
+
+	w = httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/runs/missing/metrics", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown run, got %d", w.Code)
+	}
+}
+
+func TestHandleRunPlan(t *testing.T) {
+	server := NewServer(":0", nil, "")
+
+	reqBody := `{
+		"id": "plan-run",
+		"policy": {"max_parallelism": 2, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [
+			{"id": "D", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["B", "C"]},
+			{"id": "C", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["A"]},
+			{"id": "B", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["A"]},
+			{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}
+		]
+	}`
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+	entry, _ := server.Store().Get("plan-run")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	// The plan covers the whole DAG, also once the run finished
+	w = httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/runs/plan-run/plan", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("plan failed: %d - %s", w.Code, w.Body.String())
+	}
+	var plan RunPlanResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &plan); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := [][]string{{"A"}, {"B", "C"}, {"D"}}
+	if plan.ID != "plan-run" || !reflect.DeepEqual(plan.Batches, want) {
+		t.Errorf("plan = %+v, want batches %v", plan, want)
+	}
+	if wantPath := []string{"A", "B", "D"}; !reflect.DeepEqual(plan.CriticalPath, wantPath) || plan.CriticalPathLength != 3 {
+		t.Errorf("critical path = %v (%d), want %v (3)", plan.CriticalPath, plan.CriticalPathLength, wantPath)
+	}
+
+	w = httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/runs/missing/plan", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown run, got %d", w.Code)
+	}
+}
+
+func TestSnapshotToMetrics_Running(t *testing.T) {
+	snap := &RunSnapshot{
+		ID:        "run-1",
+		State:     contracts.RunRunning,
+		APIState:  "running",
+		CreatedAt: 1_000,
+		UpdatedAt: 2_000,
+		Batch:     2,
+		Tasks: map[contracts.TaskID]TaskSnapshot{
+			"A": {State: contracts.TaskCompleted},
+			"B": {State: contracts.TaskRunning},
+			"C": {State: contracts.TaskPending},
+			"D": {State: contracts.TaskPending},
+		},
+		DAG: map[contracts.TaskID]DAGNodeSnapshot{
+			"A": {Next: []contracts.TaskID{"B", "C"}},
+			"B": {Deps: []contracts.TaskID{"A"}, Next: []contracts.TaskID{"D"}},
+			"C": {Deps: []contracts.TaskID{"A"}, Next: []contracts.TaskID{"D"}},
+			"D": {Deps: []contracts.TaskID{"B", "C"}},
+		},
+	}
+
+	m := SnapshotToMetrics(snap, time.UnixMilli(6_000))
+	want := map[string]int{"pending": 2, "ready": 0, "running": 1, "completed": 1, "failed": 0, "skipped": 0}
+	if !reflect.DeepEqual(m.Tasks, want) {
+		t.Errorf("tasks = %v, want %v", m.Tasks, want)
+	}
+	if m.Ready != 1 {
+		t.Errorf("ready = %d, want 1 (only C)", m.Ready)
+	}
+	if m.ElapsedMs != 5_000 {
+		t.Errorf("elapsed_ms = %d, want 5000 (until now while running)", m.ElapsedMs)
+	}
+	if m.Batch != 2 {
+		t.Errorf("batch = %d, want 2", m.Batch)
+	}
+}
+
+func TestHandleStartRun_DryRun(t *testing.T) {
+	var executed atomic.Int32
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		executed.Add(1)
+		return defaultExecutor(ctx, task)
+	}
+	server := NewServer(":0", executor, "")
+
+	reqBody := `{
+		"id": "dry-run",
+		"policy": {"max_parallelism": 2, "budget_limit": {"amount": 1.0, "currency": "USD"}, "dry_run": true},
+		"tasks": [
+			{"id": "A", "prompt": "Summarize the design document", "model": "claude-3-haiku-20240307"},
+			{"id": "B", "prompt": "Review the summary", "model": "claude-3-haiku-20240307", "deps": ["A"]},
+			{"id": "C", "prompt": "Test the summary", "model": "claude-3-haiku-20240307", "deps": ["A"]}
+		]
+	}`
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+	entry, _ := server.Store().Get("dry-run")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for dry run to complete")
+	}
+
+	if n := executed.Load(); n != 0 {
+		t.Fatalf("executor called %d times during a dry run", n)
+	}
+
+	snap, _ := server.Store().GetSnapshot("dry-run")
+	resp := SnapshotToResponse(snap)
+	if resp.State != "completed" {
+		t.Fatalf("expected completed, got %s", resp.State)
+	}
+	if want := [][]string{{"A"}, {"B", "C"}}; !reflect.DeepEqual(resp.Plan, want) {
+		t.Errorf("plan = %v, want %v", resp.Plan, want)
+	}
+	if resp.ProjectedCost == nil || resp.ProjectedCost.Amount <= 0 || resp.ProjectedCost.Currency != "USD" {
+		t.Errorf("unexpected projected_cost: %+v", resp.ProjectedCost)
+	}
+
+	var tokens int64
+	for id, task := range resp.Tasks {
+		if task.State != "skipped" || task.EstimatedUsage == nil {
+			t.Errorf("task %s = %+v, want skipped with estimated_usage", id, task)
+			continue
+		}
+		tokens += task.EstimatedUsage.Tokens
+	}
+	if resp.ProjectedTokens != tokens {
+		t.Errorf("projected_tokens = %d, want sum of task estimates %d", resp.ProjectedTokens, tokens)
+	}
+	if resp.Usage != nil {
+		t.Errorf("expected no usage for a dry run, got %+v", resp.Usage)
+	}
+}
+
+func TestHandleStartRun_RunIf(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	start := func(id, tasks string) *httptest.ResponseRecorder {
+		reqBody := `{
+			"id": "` + id + `",
+			"policy": {"max_parallelism": 2, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+			"tasks": ` + tasks + `
+		}`
+		w := httptest.NewRecorder()
+		server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+		return w
+	}
+
+	w := start("no-key", `[{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307", "run_if": {"equals": "true"}}]`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for run_if without key, got %d - %s", w.Code, w.Body.String())
+	}
+
+	w = start("run-if", `[
+		{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"},
+		{"id": "B", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["A"], "run_if": {"key": "A", "equals": "needs_review"}},
+		{"id": "C", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["B"]}
+	]`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d - %s", w.Code, w.Body.String())
+	}
+	entry, _ := server.Store().Get("run-if")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	snap, _ := server.Store().GetSnapshot("run-if")
+	resp := SnapshotToResponse(snap)
+	if resp.State != "completed" {
+		t.Fatalf("expected completed run, got %s", resp.State)
+	}
+	if b := resp.Tasks["B"]; b.State != "skipped" || b.Error == nil || b.Error.Code != "condition_not_met" {
+		t.Errorf("expected B skipped with condition_not_met, got %+v", b)
+	}
+	if c := resp.Tasks["C"]; c.State != "completed" {
+		t.Errorf("expected C completed, got %s", c.State)
+	}
+	if want := (TaskCountsDTO{Completed: 2, Skipped: 1}); resp.TaskCounts == nil || *resp.TaskCounts != want {
+		t.Errorf("task_counts = %+v, want %+v", resp.TaskCounts, want)
+	}
+}
+
+func TestHandleStartRun_FromMemory(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	reqBody := `{
+		"id": "from-memory",
+		"policy": {"max_parallelism": 1, "timeout_ms": 60000, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"memory": {"topic": "go", "style": "terse", "unused": "x"},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307", "from_memory": ["topic", "style", "absent"]}]
+	}`
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d - %s", w.Code, w.Body.String())
+	}
+	var resp RunResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	want := []string{`task A: from_memory key "absent" is not in the seeded memory; it is left out unless written before the task starts`}
+	if !slices.Equal(resp.Warnings, want) {
+		t.Errorf("warnings = %q, want %q", resp.Warnings, want)
+	}
+
+	entry, _ := server.Store().Get("from-memory")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	bundle, err := server.Store().GetBundle("from-memory")
+	if err != nil {
+		t.Fatalf("GetBundle: %v", err)
+	}
+	inputs := bundle.Tasks["A"].Inputs.Inputs
+	if len(inputs) != 2 || inputs["memory:topic"] != "go" || inputs["memory:style"] != "terse" {
+		t.Errorf("inputs = %v, want memory:topic and memory:style only", inputs)
+	}
+	// The submitted definition keeps the keys, not the resolved values
+	if spec := bundle.Specs["A"].Inputs; len(spec.Inputs) != 0 || len(spec.FromMemory) != 3 {
+		t.Errorf("spec inputs = %+v, want unresolved from_memory", spec)
+	}
+	if bundle.Memory["topic"] != "go" {
+		t.Errorf("bundle memory = %v, want seeded memory", bundle.Memory)
+	}
+
+	w = httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(`{
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307", "from_memory": [""]}]
+	}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for empty from_memory key, got %d - %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleStartRun_Priority(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	reqBody := `{
+		"id": "priority",
+		"policy": {"max_parallelism": 1, "timeout_ms": 60000, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [
+			{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"},
+			{"id": "B", "prompt": "Test", "model": "claude-3-haiku-20240307", "priority": 3}
+		]
+	}`
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d - %s", w.Code, w.Body.String())
+	}
+
+	entry, _ := server.Store().Get("priority")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	bundle, err := server.Store().GetBundle("priority")
+	if err != nil {
+		t.Fatalf("GetBundle: %v", err)
+	}
+	if got := bundle.Specs["B"].Priority; got != 3 {
+		t.Errorf("spec B priority = %d, want 3", got)
+	}
+	if got := bundle.Specs["A"].Priority; got != 0 {
+		t.Errorf("spec A priority = %d, want 0", got)
+	}
+}
+
+func TestHandleStartRun_Skip(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	reqBody := `{
+		"id": "skip",
+		"policy": {"max_parallelism": 1, "timeout_ms": 60000, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [
+			{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"},
+			{"id": "B", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["A"], "skip": true},
+			{"id": "C", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["B"]}
+		]
+	}`
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d - %s", w.Code, w.Body.String())
+	}
+
+	entry, _ := server.Store().Get("skip")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	snap, _ := server.Store().GetSnapshot("skip")
+	if snap.APIState != "completed" {
+		t.Fatalf("state = %s, want completed", snap.APIState)
+	}
+	if b := snap.Tasks["B"]; b.State != contracts.TaskSkipped || b.Error == nil || b.Error.Code != "submitted_skipped" {
+		t.Errorf("B = %v %+v, want skipped with submitted_skipped", b.State, b.Error)
+	}
+	if c := snap.Tasks["C"]; c.State != contracts.TaskCompleted {
+		t.Errorf("C = %v, want completed without B's output", c.State)
+	}
+
+	bundle, err := server.Store().GetBundle("skip")
+	if err != nil {
+		t.Fatalf("GetBundle: %v", err)
+	}
+	if !TaskToDTO(bundle.Specs["B"]).Skip || TaskToDTO(bundle.Specs["C"]).Skip {
+		t.Errorf("specs = %+v, want only B skipped", bundle.Specs)
+	}
+}
+
+func TestHandleStartRun_StrictMetadata(t *testing.T) {
+	strict := NewServerWithOptions(":0", nil, ServerOptions{StrictMetadata: true, MetadataKeys: []string{"ticket"}})
+	lenient := NewServer(":0", nil, "")
+	submit := func(server *Server, metadata string) *httptest.ResponseRecorder {
+		reqBody := `{
+			"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+			"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307", "metadata": ` + metadata + `}]
+		}`
+		w := httptest.NewRecorder()
+		server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+		return w
+	}
+
+	typo := `{"role": "spec-analyst", "sytem": "Be brief"}`
+	w := submit(strict, typo)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("typo under strict metadata: expected 400, got %d - %s", w.Code, w.Body.String())
+	}
+	var errResp ErrorDTO
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if errResp.Code != string(CodeUnknownMetadataKey) || !strings.Contains(errResp.Message, `"sytem"`) {
+		t.Errorf("error = %+v, want unknown_metadata_key naming sytem", errResp)
+	}
+
+	known := `{"role": "spec-analyst", "system": "Be brief", "description": "d", "outputs": "[]", "ticket": "T-1"}`
+	if w := submit(strict, known); w.Code != http.StatusAccepted {
+		t.Errorf("known and extra keys: expected 202, got %d - %s", w.Code, w.Body.String())
+	}
+	if w := submit(lenient, typo); w.Code != http.StatusAccepted {
+		t.Errorf("typo by default: expected 202, got %d - %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleStartRun_InvalidOutputCollision(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	reqBody := `{
+		"policy": {"max_parallelism": 2, "budget_limit": {"amount": 1.0, "currency": "USD"}, "context_policy": {"output_collision": "merge"}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+	}`
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d - %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleStartRun_InvalidMaxFailures(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	for _, maxFailures := range []string{"-1", "1.5"} {
+		reqBody := `{
+			"policy": {"max_parallelism": 2, "budget_limit": {"amount": 1.0, "currency": "USD"}, "max_failures": ` + maxFailures + `},
+			"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+		}`
+		w := httptest.NewRecorder()
+		server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("max_failures %s: expected 400, got %d - %s", maxFailures, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestHandleStartRun_SoftLimit(t *testing.T) {
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		return &contracts.TaskResult{
+			Output: "out:" + string(task.ID),
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.1, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServer(":0", executor, "")
+
+	getStatus := func(t *testing.T, id string) RunResponse {
+		t.Helper()
+		entry, _ := server.Store().Get(contracts.RunID(id))
+		select {
+		case <-entry.Done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for run to finish")
+		}
+		req := httptest.NewRequest("GET", "/api/v1/runs/"+id+"?expand=policy", nil)
+		req.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		server.Handlers().HandleGetStatus(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GetStatus failed: %d - %s", w.Code, w.Body.String())
+		}
+		var resp RunResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		id          string
+		softLimit   float64
+		wantWarning bool
+	}{
+		{id: "soft-crossed", softLimit: 0.15, wantWarning: true},
+		{id: "soft-not-crossed", softLimit: 0.5, wantWarning: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			reqBody := fmt.Sprintf(`{
+				"id": %q,
+				"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0}, "soft_limit": {"amount": %g}},
+				"tasks": [
+					{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"},
+					{"id": "B", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["A"]}
+				]
+			}`, tt.id, tt.softLimit)
+			w := httptest.NewRecorder()
+			server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+			}
+
+			resp := getStatus(t, tt.id)
+			if resp.State != "completed" {
+				t.Errorf("state = %s, want completed", resp.State)
+			}
+			if resp.BudgetWarning != tt.wantWarning {
+				t.Errorf("budget_warning = %v, want %v", resp.BudgetWarning, tt.wantWarning)
+			}
+			if soft := resp.Policy.SoftLimit; soft == nil || soft.Amount != tt.softLimit || soft.Currency != "USD" {
+				t.Errorf("policy.soft_limit = %+v, want %g USD", soft, tt.softLimit)
+			}
+		})
+	}
+}
+
+func TestHandleStartRun_InvalidSoftLimit(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	for _, softLimit := range []string{
+		`{"amount": 0}`,
+		`{"amount": 1.0}`, // not below budget_limit
+		`{"amount": 0.5, "currency": "EUR"}`,
+	} {
+		reqBody := `{
+			"policy": {"max_parallelism": 2, "budget_limit": {"amount": 1.0, "currency": "USD"}, "soft_limit": ` + softLimit + `},
+			"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+		}`
+		w := httptest.NewRecorder()
+		server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("soft_limit %s: expected 400, got %d - %s", softLimit, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestHandleStartRun_SchedulingStrategy(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	start := func(id, strategy string) *httptest.ResponseRecorder {
+		reqBody := `{
+			"id": "` + id + `",
+			"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}, "scheduling_strategy": "` + strategy + `"},
+			"tasks": [
+				{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"},
+				{"id": "B", "prompt": "A much longer prompt than the other task", "model": "claude-3-haiku-20240307"}
+			]
+		}`
+		w := httptest.NewRecorder()
+		server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+		return w
+	}
+
+	if w := start("by-cost", "cost"); w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d - %s", w.Code, w.Body.String())
+	}
+	entry, _ := server.Store().Get("by-cost")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+	if got := entry.Run.Policy.SchedulingStrategy; got != contracts.SchedulingCost {
+		t.Errorf("policy scheduling strategy = %q, want cost", got)
+	}
+	if state := entry.Run.State; state != contracts.RunCompleted {
+		t.Errorf("run state = %v, want completed", state)
+	}
+
+	if w := start("by-speed", "fastest"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown scheduling_strategy, got %d - %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleStartRun_Warnings(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	start := func(id, policy string) RunResponse {
+		t.Helper()
+		reqBody := `{
+			"id": "` + id + `",
+			"policy": ` + policy + `,
+			"tasks": [
+				{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307", "continue_on_fail": true},
+				{"id": "B", "prompt": "Test", "model": "claude-3-haiku-20240307", "deps": ["A"]}
+			]
+		}`
+		w := httptest.NewRecorder()
+		server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d - %s", w.Code, w.Body.String())
+		}
+		var resp RunResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		return resp
+	}
+
+	resp := start("warn-run", `{"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}}`)
+	want := []string{
+		"policy.timeout_ms is not set; tasks run without a timeout",
+		"task A: continue_on_fail is set and the task has dependents; they are skipped if it fails",
+	}
+	if !slices.Equal(resp.Warnings, want) {
+		t.Errorf("warnings = %q, want %q", resp.Warnings, want)
+	}
+
+	// Warnings are only part of the submission response
+	snap, _ := server.Store().GetSnapshot("warn-run")
+	if SnapshotToResponse(snap).Warnings != nil {
+		t.Error("expected no warnings in status responses")
+	}
+
+	resp = start("timeout-run", `{"max_parallelism": 1, "timeout_ms": 60000, "budget_limit": {"amount": 1.0, "currency": "USD"}}`)
+	if len(resp.Warnings) != 1 {
+		t.Errorf("expected only the continue_on_fail warning, got %q", resp.Warnings)
+	}
+}
+
+func TestHandleStartRun_PolicyDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	file := `{
+		"defaults": {
+			"max_parallelism": 2,
+			"budget_limit": {"amount": 1.0},
+			"context_policy": {"strategy": "truncate", "max_tokens": 4000}
+		},
+		"caps": {"max_parallelism": 4, "budget_limit": 5.0}
+	}`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	defaults, err := LoadPolicyDefaults(path, "USD")
+	if err != nil {
+		t.Fatalf("LoadPolicyDefaults: %v", err)
+	}
+
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		return &contracts.TaskResult{
+			Output: "ok",
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServerWithOptions(":0", executor, ServerOptions{PolicyDefaults: defaults})
+
+	start := func(id, policy string) *RunSnapshot {
+		t.Helper()
+		reqBody := `{"id": "` + id + `", "policy": ` + policy + `,
+			"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]}`
+		w := httptest.NewRecorder()
+		server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("%s: StartRun failed: %d - %s", id, w.Code, w.Body.String())
+		}
+		snap, _ := server.Store().GetSnapshot(contracts.RunID(id))
+		return snap
+	}
+
+	// Omitted fields inherit the defaults
+	snap := start("inherits", `{"context_policy": {"keep_last_n": 3}}`)
+	p := snap.Policy
+	if p.MaxParallelism != 2 || p.BudgetLimit.Amount != 1.0 || p.BudgetLimit.Currency != "USD" {
+		t.Errorf("expected default parallelism and budget, got %+v", p)
+	}
+	if cp := p.ContextPolicy; cp.Strategy != "truncate" || cp.MaxTokens != 4000 || cp.KeepLastN != 3 {
+		t.Errorf("expected merged context policy, got %+v", cp)
+	}
+
+	// Request values override the defaults, but not the caps
+	snap = start("clamped", `{"max_parallelism": 16, "budget_limit": {"amount": 3.0}}`)
+	if p := snap.Policy; p.MaxParallelism != 4 || p.BudgetLimit.Amount != 3.0 {
+		t.Errorf("expected parallelism clamped to 4 and budget 3.0, got %+v", p)
+	}
+}
+
+func TestServer_ListRuns(t *testing.T) {
+	store := NewRunStore()
+	base := time.Now()
+	for i, r := range []struct {
+		id    contracts.RunID
+		state contracts.RunState
+	}{
+		{"run-a", contracts.RunCompleted},
+		{"run-b", contracts.RunRunning},
+		{"run-c", contracts.RunRunning},
+		{"run-d", contracts.RunFailed},
+	} {
+		run := &contracts.Run{ID: r.id, State: r.state}
+		if err := store.Create(run, func() {}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		entry, _ := store.Get(r.id)
+		entry.CreatedAt = base.Add(time.Duration(i) * time.Second)
+	}
+	handler := NewServerWithOptions(":0", nil, ServerOptions{Store: store}).httpServer.Handler
+
+	list := func(query string) ([]RunSummaryDTO, *httptest.ResponseRecorder) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/runs"+query, nil))
+		var runs []RunSummaryDTO
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&runs); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return runs, w
+	}
+	ids := func(runs []RunSummaryDTO) []string {
+		out := make([]string, len(runs))
+		for i, r := range runs {
+			out[i] = r.ID
+		}
+		return out
+	}
+
+	runs, w := list("")
+	if got := ids(runs); !slices.Equal(got, []string{"run-d", "run-c", "run-b", "run-a"}) {
+		t.Errorf("expected newest first, got %v", got)
+	}
+	if total := w.Header().Get("X-Total-Count"); total != "4" {
+		t.Errorf("X-Total-Count = %q, want 4", total)
+	}
+
+	runs, w = list("?state=running&limit=1&offset=1")
+	if got := ids(runs); !slices.Equal(got, []string{"run-b"}) {
+		t.Errorf("expected [run-b], got %v", got)
+	}
+	if runs[0].State != "running" {
+		t.Errorf("state = %q, want running", runs[0].State)
+	}
+	if total := w.Header().Get("X-Total-Count"); total != "2" {
+		t.Errorf("X-Total-Count = %q, want 2", total)
+	}
+
+	if runs, _ := list("?offset=10"); len(runs) != 0 {
+		t.Errorf("expected empty page past the end, got %v", ids(runs))
+	}
+
+	for _, query := range []string{"?state=bogus", "?limit=0", "?limit=5000", "?offset=-1", "?limit=x"} {
+		if _, w := list(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestServer_UpdateRunBudget(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		amount := 0.005
+		if task.ID == "A" {
+			close(started)
+			<-release
+			amount = 0.009
+		}
+		return &contracts.TaskResult{
+			Output: "ok:" + string(task.ID),
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: amount, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServer(":0", executor, "")
+	handler := server.httpServer.Handler
+
+	// B's cost only fits once the budget has been raised.
+	reqBody := `{
+		"id": "raise-budget",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 0.01, "currency": "USD"}},
+		"tasks": [
+			{"id": "A", "prompt": "Slow", "model": "claude-3-haiku-20240307"},
+			{"id": "B", "prompt": "Next", "model": "claude-3-haiku-20240307", "deps": ["A"]}
+		]
+	}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+	<-started
+
+	patch := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("PATCH", path, bytes.NewBufferString(body)))
+		return w
+	}
+	if w := patch("/api/v1/runs/raise-budget", `{"budget_limit": {"amount": 0.005}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("lowered budget: expected 400, got %d", w.Code)
+	}
+	if w := patch("/api/v1/runs/raise-budget", `{"budget_limit": {"amount": 1.0, "currency": "EUR"}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("currency mismatch: expected 400, got %d", w.Code)
+	}
+	if w := patch("/api/v1/runs/raise-budget", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("missing budget_limit: expected 400, got %d", w.Code)
+	}
+	if w := patch("/api/v1/runs/missing", `{"budget_limit": {"amount": 1.0}}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown run: expected 404, got %d", w.Code)
+	}
+
+	w = patch("/api/v1/runs/raise-budget", `{"budget_limit": {"amount": 1.0}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("UpdateRun failed: %d - %s", w.Code, w.Body.String())
+	}
 	var resp RunResponse
-	json.NewDecoder(w.Body).Decode(&resp)
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Policy == nil || resp.Policy.BudgetLimit.Amount != 1.0 {
+		t.Errorf("response policy = %+v, want budget_limit 1.0", resp.Policy)
+	}
+	close(release)
 
-	if resp.State != "completed" {
-		t.Errorf("expected state 'completed', got '%s'", resp.State)
+	entry, _ := server.Store().Get("raise-budget")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	snap, _ := server.Store().GetSnapshot("raise-budget")
+	if snap.State != contracts.RunCompleted {
+		t.Fatalf("run state = %v, want completed (error: %v)", snap.State, snap.Error)
+	}
+	if b := snap.Tasks["B"]; b.State != contracts.TaskCompleted {
+		t.Errorf("task B state = %v, want completed", b.State)
+	}
+
+	if w := patch("/api/v1/runs/raise-budget", `{"budget_limit": {"amount": 2.0}}`); w.Code != http.StatusConflict {
+		t.Errorf("finished run: expected 409, got %d", w.Code)
+	}
+}
+
+func TestServer_AddNote(t *testing.T) {
+	server := NewServer(":0", nil, "")
+	handler := server.httpServer.Handler
+
+	reqBody := `{
+		"id": "noted",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+	}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+	entry, _ := server.Store().Get("noted")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", path, bytes.NewBufferString(body)))
+		return w
+	}
+	if w := post("/api/v1/runs/noted/notes", `{"text": "  "}`); w.Code != http.StatusBadRequest {
+		t.Errorf("blank note: expected 400, got %d", w.Code)
+	}
+	tooLong, _ := json.Marshal(AddNoteRequest{Text: strings.Repeat("x", MaxNoteBytes+1)})
+	if w := post("/api/v1/runs/noted/notes", string(tooLong)); w.Code != http.StatusBadRequest {
+		t.Errorf("oversized note: expected 400, got %d", w.Code)
+	}
+	if w := post("/api/v1/runs/missing/notes", `{"text": "hi"}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown run: expected 404, got %d", w.Code)
+	}
+
+	// Notes can be added to finished runs, and keep their order
+	for _, text := range []string{"retried after provider outage", "output checked"} {
+		w := post("/api/v1/runs/noted/notes", `{"text": "`+text+`"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("AddNote failed: %d - %s", w.Code, w.Body.String())
+		}
+		var note NoteDTO
+		if err := json.NewDecoder(w.Body).Decode(&note); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if note.Text != text || note.CreatedAt == 0 {
+			t.Errorf("note = %+v, want %q with a timestamp", note, text)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/runs/noted", nil))
+	var resp RunResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if len(resp.Notes) != 2 || resp.Notes[0].Text != "retried after provider outage" || resp.Notes[1].Text != "output checked" {
+		t.Errorf("status notes = %+v", resp.Notes)
+	}
+}
+
+func TestRunStore_AddNoteLimit(t *testing.T) {
+	store := NewRunStore()
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := store.Create(newPersistedRun("r"), cancel); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for i := range MaxRunNotes {
+		if _, err := store.AddNote("r", fmt.Sprintf("note %d", i)); err != nil {
+			t.Fatalf("AddNote %d: %v", i, err)
+		}
+	}
+	if _, err := store.AddNote("r", "one too many"); !errors.Is(err, contracts.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput past MaxRunNotes, got %v", err)
+	}
+}
+
+func TestRunStore_SetBudgetLimitOnlyRaises(t *testing.T) {
+	store := NewRunStore()
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	run := newPersistedRun("r")
+	if err := store.Create(run, cancel); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	run.Usage.Cost = contracts.Cost{Amount: 1.5, Currency: "USD"}
+	store.UpdateShadowState(run.ID)
+
+	usd := func(amount float64) contracts.Cost { return contracts.Cost{Amount: amount, Currency: "USD"} }
+	if err := store.SetBudgetLimit("r", usd(0.5)); !errors.Is(err, contracts.ErrInvalidInput) {
+		t.Errorf("below limit: expected ErrInvalidInput, got %v", err)
+	}
+	if err := store.SetBudgetLimit("r", usd(1.2)); !errors.Is(err, contracts.ErrInvalidInput) {
+		t.Errorf("below usage: expected ErrInvalidInput, got %v", err)
+	}
+	if err := store.SetBudgetLimit("r", usd(2)); err != nil {
+		t.Fatalf("raise: %v", err)
+	}
+	// The raised limit is the new floor
+	if err := store.SetBudgetLimit("r", usd(1.8)); !errors.Is(err, contracts.ErrInvalidInput) {
+		t.Errorf("below raised limit: expected ErrInvalidInput, got %v", err)
+	}
+	if snap, _ := store.GetSnapshot("r"); snap.Policy.BudgetLimit.Amount != 2 {
+		t.Errorf("budget_limit = %v, want 2", snap.Policy.BudgetLimit.Amount)
+	}
+}
+
+func TestServer_CancelTask(t *testing.T) {
+	started := make(chan struct{})
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		if task.ID == "A" {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &contracts.TaskResult{
+			Output: "ok:" + string(task.ID),
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServer(":0", executor, "")
+	handler := server.httpServer.Handler
+
+	reqBody := `{
+		"id": "cancel-task",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [
+			{"id": "A", "prompt": "Slow", "model": "claude-3-haiku-20240307"},
+			{"id": "B", "prompt": "Next", "model": "claude-3-haiku-20240307", "deps": ["A"]}
+		]
+	}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+	<-started
+
+	cancel := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", path, nil))
+		return w
+	}
+	if w := cancel("/api/v1/runs/cancel-task/tasks/A/cancel?mode=gentle"); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown mode: expected 400, got %d", w.Code)
+	}
+	if w := cancel("/api/v1/runs/cancel-task/tasks/Z/cancel"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown task: expected 404, got %d", w.Code)
+	}
+	if w := cancel("/api/v1/runs/missing/tasks/A/cancel"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown run: expected 404, got %d", w.Code)
+	}
+
+	w = cancel("/api/v1/runs/cancel-task/tasks/A/cancel?mode=soft")
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("CancelTask failed: %d - %s", w.Code, w.Body.String())
+	}
+	var ack CancelTaskResponse
+	if err := json.NewDecoder(w.Body).Decode(&ack); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if ack.Mode != "soft" || ack.TaskID != "A" {
+		t.Errorf("unexpected ack %+v", ack)
+	}
+
+	entry, _ := server.Store().Get("cancel-task")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	snap, _ := server.Store().GetSnapshot("cancel-task")
+	if snap.State != contracts.RunCompleted {
+		t.Errorf("run state = %v, want completed", snap.State)
+	}
+	if a := snap.Tasks["A"]; a.State != contracts.TaskSkipped || a.Error == nil || a.Error.Code != "soft_cancelled" {
+		t.Errorf("task A = %+v, want skipped with soft_cancelled", a)
+	}
+	if b := snap.Tasks["B"]; b.State != contracts.TaskCompleted {
+		t.Errorf("task B state = %v, want completed", b.State)
 	}
 
-	if resp.Tasks == nil || resp.Tasks["A"].Output != "result:A" {
-		t.Errorf("expected task A output 'result:A', got: %+v", resp.Tasks)
+	if w := cancel("/api/v1/runs/cancel-task/tasks/B/cancel"); w.Code != http.StatusConflict {
+		t.Errorf("finished task: expected 409, got %d", w.Code)
 	}
 }
 
@@ -555,3 +3455,671 @@ func TestServer_AbortRunning(t *testing.T) {
 		t.Fatal("timeout waiting for run to abort")
 	}
 }
+
+func TestServer_GzipStatus(t *testing.T) {
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		return &contracts.TaskResult{
+			Output: strings.Repeat("output ", 1000),
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServer(":0", executor, "")
+
+	reqBody := `{
+		"id": "gzip-run",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+	}`
+	req := httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody))
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+	entry, _ := server.Store().Get("gzip-run")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	plain := get("/api/v1/runs/gzip-run", "")
+	if plain.Code != http.StatusOK || plain.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("expected uncompressed 200, got %d encoding=%q", plain.Code, plain.Header().Get("Content-Encoding"))
+	}
+
+	compressed := get("/api/v1/runs/gzip-run", "deflate, gzip")
+	if compressed.Code != http.StatusOK || compressed.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip 200, got %d encoding=%q", compressed.Code, compressed.Header().Get("Content-Encoding"))
+	}
+	if compressed.Body.Len() >= plain.Body.Len() {
+		t.Errorf("expected compressed body smaller than %d bytes, got %d", plain.Body.Len(), compressed.Body.Len())
+	}
+	zr, err := gzip.NewReader(compressed.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if !bytes.Equal(decompressed, plain.Body.Bytes()) {
+		t.Error("decompressed body differs from uncompressed response")
+	}
+
+	// Small bodies (here a 404 error) are not compressed
+	small := get("/api/v1/runs/missing-run", "gzip")
+	if small.Code != http.StatusNotFound || small.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected uncompressed 404, got %d encoding=%q", small.Code, small.Header().Get("Content-Encoding"))
+	}
+	if !strings.Contains(small.Body.String(), string(CodeRunNotFound)) {
+		t.Errorf("expected run_not_found body, got %s", small.Body.String())
+	}
+
+	if refused := get("/api/v1/runs/gzip-run", "gzip;q=0"); refused.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected no compression when gzip is refused, got %q", refused.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestServer_GzipList(t *testing.T) {
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		return &contracts.TaskResult{
+			Output: strings.Repeat("output ", 1000),
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServerWithOptions(":0", executor, ServerOptions{CompressMinBytes: 64})
+
+	for _, id := range []string{"gzip-list-1", "gzip-list-2"} {
+		reqBody := `{
+			"id": "` + id + `",
+			"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+			"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+		}`
+		w := httptest.NewRecorder()
+		server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+		}
+		entry, _ := server.Store().Get(contracts.RunID(id))
+		select {
+		case <-entry.Done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for run to complete")
+		}
+	}
+
+	// The bundle is already a zip and is sent as is
+	bundleReq := httptest.NewRequest("GET", "/api/v1/runs/gzip-list-1/bundle", nil)
+	bundleReq.Header.Set("Accept-Encoding", "gzip")
+	bundle := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(bundle, bundleReq)
+	if bundle.Code != http.StatusOK || bundle.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("bundle: expected uncompressed 200, got %d encoding=%q", bundle.Code, bundle.Header().Get("Content-Encoding"))
+	}
+	if _, err := zip.NewReader(bytes.NewReader(bundle.Body.Bytes()), int64(bundle.Body.Len())); err != nil {
+		t.Fatalf("bundle: not a zip: %v", err)
+	}
+
+	for _, path := range []string{"/api/v1/runs", "/api/v1/runs/gzip-list-1"} {
+		plain := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(plain, httptest.NewRequest("GET", path, nil))
+		if plain.Code != http.StatusOK || plain.Header().Get("Content-Encoding") != "" {
+			t.Fatalf("%s: expected uncompressed 200, got %d encoding=%q", path, plain.Code, plain.Header().Get("Content-Encoding"))
+		}
+
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		compressed := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(compressed, req)
+		if compressed.Code != http.StatusOK || compressed.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("%s: expected gzip 200, got %d encoding=%q", path, compressed.Code, compressed.Header().Get("Content-Encoding"))
+		}
+		zr, err := gzip.NewReader(compressed.Body)
+		if err != nil {
+			t.Fatalf("%s: gzip reader: %v", path, err)
+		}
+		decompressed, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("%s: decompress: %v", path, err)
+		}
+		if !bytes.Equal(decompressed, plain.Body.Bytes()) {
+			t.Errorf("%s: decompressed body differs from uncompressed response", path)
+		}
+		// The list header survives compression
+		if path == "/api/v1/runs" && compressed.Header().Get("X-Total-Count") != "2" {
+			t.Errorf("X-Total-Count = %q, want 2", compressed.Header().Get("X-Total-Count"))
+		}
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, GZIP", true},
+		{"br;q=1.0, gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"*", true},
+		{"identity", false},
+	}
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestServer_TraceID(t *testing.T) {
+	seen := make(chan string, 1)
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		seen <- contracts.TraceIDFromContext(ctx)
+		return &contracts.TaskResult{
+			Output: "ok",
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServer(":0", executor, "")
+
+	reqBody := `{
+		"id": "trace-run",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307"}]
+	}`
+	req := httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody))
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+	var started RunResponse
+	if err := json.NewDecoder(w.Body).Decode(&started); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(started.TraceID) != 32 {
+		t.Fatalf("expected a 32-char trace_id, got %q", started.TraceID)
+	}
+
+	select {
+	case got := <-seen:
+		if got != started.TraceID {
+			t.Errorf("executor context trace ID = %q, want %q", got, started.TraceID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for executor")
+	}
+
+	entry, _ := server.Store().Get("trace-run")
+	<-entry.Done
+	req = httptest.NewRequest("GET", "/api/v1/runs/trace-run", nil)
+	req.SetPathValue("id", "trace-run")
+	w = httptest.NewRecorder()
+	server.Handlers().HandleGetStatus(w, req)
+	var status RunResponse
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if status.TraceID != started.TraceID {
+		t.Errorf("status trace_id = %q, want %q", status.TraceID, started.TraceID)
+	}
+}
+
+func TestServer_ProviderHeaders(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]http.Header) // by task ID (request path)
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[strings.TrimPrefix(r.URL.Path, "/")] = r.Header.Clone()
+		mu.Unlock()
+		io.WriteString(w, "ok")
+	}))
+	defer provider.Close()
+
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", provider.URL+"/"+string(task.ID), nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range contracts.ProviderHeadersFromContext(ctx) {
+			req.Header.Set(name, value)
+		}
+		resp, err := provider.Client().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		return &contracts.TaskResult{
+			Output: "ok",
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+	server := NewServerWithOptions(":0", executor, ServerOptions{
+		ProviderHeaders: map[string]string{"x-org-id": "acme", "X-Route": "blue"},
+		StrictMetadata:  true,
+	})
+
+	reqBody := `{
+		"id": "headers-run",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [
+			{"id": "A", "prompt": "First", "model": "claude-3-haiku-20240307", "metadata": {"provider_header:X-Route": "green", "provider_header:X-Tenant": "t-1"}},
+			{"id": "B", "prompt": "Second", "model": "claude-3-haiku-20240307", "deps": ["A"]}
+		]
+	}`
+	w := httptest.NewRecorder()
+	server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+	entry, _ := server.Store().Get("headers-run")
+	select {
+	case <-entry.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	want := map[string]map[string]string{
+		"A": {"X-Org-Id": "acme", "X-Route": "green", "X-Tenant": "t-1"},
+		"B": {"X-Org-Id": "acme", "X-Route": "blue", "X-Tenant": ""},
+	}
+	mu.Lock()
+	for id, headers := range want {
+		got, ok := received[id]
+		if !ok {
+			t.Errorf("task %s: no provider request", id)
+			continue
+		}
+		for name, value := range headers {
+			if got.Get(name) != value {
+				t.Errorf("task %s: header %s = %q, want %q", id, name, got.Get(name), value)
+			}
+		}
+	}
+	mu.Unlock()
+
+	// Exports redact header values
+	b, err := server.Store().GetBundle("headers-run")
+	if err != nil {
+		t.Fatalf("GetBundle: %v", err)
+	}
+	meta := TaskToDTO(b.Specs["A"]).Metadata
+	if meta["provider_header:X-Route"] != RedactedValue || meta["provider_header:X-Tenant"] != RedactedValue {
+		t.Errorf("request metadata = %v, want header values redacted", meta)
+	}
+	if meta := taskToBundleDTO(b.Tasks["A"]).Metadata; meta["provider_header:X-Route"] != RedactedValue {
+		t.Errorf("bundle task metadata = %v, want header values redacted", meta)
+	}
+	if got := b.Specs["A"].Inputs.Metadata["provider_header:X-Route"]; got != "green" {
+		t.Errorf("stored metadata = %q, redaction must not modify the run", got)
+	}
+
+	// Invalid header names and values are rejected
+	for _, metadata := range []string{`{"provider_header:X Route": "v"}`, `{"provider_header:X-Route": "a\r\nX-Evil: 1"}`} {
+		reqBody := `{
+			"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+			"tasks": [{"id": "A", "prompt": "Test", "model": "claude-3-haiku-20240307", "metadata": ` + metadata + `}]
+		}`
+		w := httptest.NewRecorder()
+		server.Handlers().HandleStartRun(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("metadata %s: expected 400, got %d - %s", metadata, w.Code, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), "X-Evil") {
+			t.Errorf("metadata %s: error leaks the header value: %s", metadata, w.Body.String())
+		}
+	}
+}
+
+// mapStore is a minimal Store, used to check that the server and handlers
+// depend only on the Store interface.
+type mapStore struct {
+	mu   sync.Mutex
+	runs map[contracts.RunID]*mapRun
+}
+
+type mapRun struct {
+	run     *contracts.Run
+	cancel  context.CancelFunc
+	done    chan struct{}
+	state   contracts.RunState
+	tasks   map[contracts.TaskID]TaskSnapshot
+	usage   contracts.Usage
+	err     error
+	created time.Time
+	budget  *contracts.Cost // set by SetBudgetLimit
+	notes   []RunNote
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{runs: make(map[contracts.RunID]*mapRun)}
+}
+
+func (m *mapStore) Create(run *contracts.Run, cancel context.CancelFunc) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.runs[run.ID]; ok {
+		return ErrRunExists
+	}
+	r := &mapRun{run: run, cancel: cancel, done: make(chan struct{}), state: run.State, created: time.Now()}
+	m.runs[run.ID] = r
+	m.syncLocked(r)
+	return nil
+}
+
+// syncLocked copies task state from the run; callers hold m.mu.
+func (m *mapStore) syncLocked(r *mapRun) {
+	r.tasks = make(map[contracts.TaskID]TaskSnapshot, len(r.run.Tasks))
+	for id, task := range r.run.Tasks {
+		ts := TaskSnapshot{State: task.State, Error: task.Error}
+		if task.Outputs != nil {
+			ts.Output = task.Outputs.Output
+		}
+		r.tasks[id] = ts
+	}
+	r.usage = r.run.Usage
+}
+
+func (m *mapStore) GetSnapshot(id contracts.RunID) (*RunSnapshot, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.runs[id]
+	if !ok {
+		return nil, false
+	}
+	policy := r.run.Policy
+	if r.budget != nil {
+		policy.BudgetLimit = *r.budget
+	}
+	return &RunSnapshot{
+		ID:        id,
+		State:     r.state,
+		Tasks:     maps.Clone(r.tasks),
+		Usage:     r.usage,
+		CreatedAt: r.created.UnixMilli(),
+		APIState:  r.state.String(),
+		Error:     r.err,
+		TraceID:   r.run.TraceID,
+		Policy:    policy,
+		Notes:     slices.Clone(r.notes),
+	}, true
+}
+
+func (m *mapStore) GetBundle(id contracts.RunID) (*RunBundle, error) {
+	snap, ok := m.GetSnapshot(id)
+	if !ok {
+		return nil, contracts.ErrRunNotFound
+	}
+	if !m.isDone(id) {
+		return nil, ErrRunNotTerminal
+	}
+	return &RunBundle{Snapshot: snap}, nil
+}
+
+func (m *mapStore) isDone(id contracts.RunID) bool {
+	m.mu.Lock()
+	r := m.runs[id]
+	m.mu.Unlock()
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *mapStore) Abort(id contracts.RunID) error {
+	m.mu.Lock()
+	r, ok := m.runs[id]
+	m.mu.Unlock()
+	if !ok {
+		return contracts.ErrRunNotFound
+	}
+	if m.isDone(id) {
+		return contracts.ErrRunCompleted
+	}
+	r.cancel()
+	return nil
+}
+
+func (m *mapStore) List() []RunSummary {
+	m.mu.Lock()
+	ids := slices.Collect(maps.Keys(m.runs))
+	m.mu.Unlock()
+	summaries := make([]RunSummary, 0, len(ids))
+	for _, id := range ids {
+		if snap, ok := m.GetSnapshot(id); ok {
+			summaries = append(summaries, RunSummary{
+				ID: id, APIState: snap.APIState, CreatedAt: snap.CreatedAt, UpdatedAt: snap.UpdatedAt, Usage: snap.Usage,
+			})
+		}
+	}
+	return summaries
+}
+
+func (m *mapStore) SetBudgetLimit(id contracts.RunID, limit contracts.Cost) error {
+	m.mu.Lock()
+	r, ok := m.runs[id]
+	m.mu.Unlock()
+	if !ok {
+		return contracts.ErrRunNotFound
+	}
+	if m.isDone(id) {
+		return contracts.ErrRunCompleted
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r.budget != nil && limit.Amount < r.budget.Amount {
+		return contracts.ErrInvalidInput
+	}
+	r.budget = &limit
+	return nil
+}
+
+func (m *mapStore) AddNote(id contracts.RunID, text string) (RunNote, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.runs[id]
+	if !ok {
+		return RunNote{}, contracts.ErrRunNotFound
+	}
+	note := RunNote{Text: text, CreatedAt: time.Now().UnixMilli()}
+	r.notes = append(r.notes, note)
+	return note, nil
+}
+
+func (m *mapStore) PruneCompleted(time.Duration) int { return 0 }
+
+func (m *mapStore) PruneCandidates(time.Duration) []PruneCandidate { return nil }
+
+func (m *mapStore) ActiveLabelCount(label string) int {
+	m.mu.Lock()
+	runs := slices.Collect(maps.Values(m.runs))
+	m.mu.Unlock()
+	n := 0
+	for _, r := range runs {
+		if slices.Contains(RunLabels(r.run.Labels), label) && !m.isDone(r.run.ID) {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *mapStore) ScheduledCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, r := range m.runs {
+		if r.state == contracts.RunScheduled {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *mapStore) SetShadowRunState(id contracts.RunID, state contracts.RunState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs[id].state = state
+}
+
+func (m *mapStore) UpdateShadowState(id contracts.RunID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.syncLocked(m.runs[id])
+}
+
+func (m *mapStore) UpdateTaskRunning(id contracts.RunID, taskID contracts.TaskID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ts := m.runs[id].tasks[taskID]
+	ts.State = contracts.TaskRunning
+	m.runs[id].tasks[taskID] = ts
+}
+
+func (m *mapStore) UpdateTimestamp(contracts.RunID) {}
+
+func (m *mapStore) SetBatch(contracts.RunID, int) {}
+
+// Subscribe returns a channel without events that closes when the run is done.
+func (m *mapStore) Subscribe(id contracts.RunID) (<-chan RunEvent, func(), error) {
+	m.mu.Lock()
+	r, ok := m.runs[id]
+	m.mu.Unlock()
+	if !ok {
+		return nil, nil, contracts.ErrRunNotFound
+	}
+	ch := make(chan RunEvent)
+	go func() {
+		<-r.done
+		close(ch)
+	}()
+	return ch, func() {}, nil
+}
+
+func (m *mapStore) AppendTaskOutput(id contracts.RunID, taskID contracts.TaskID, chunk string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ts := m.runs[id].tasks[taskID]
+	ts.PartialOutput += chunk
+	m.runs[id].tasks[taskID] = ts
+}
+
+func (m *mapStore) MarkDone(id contracts.RunID, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := m.runs[id]
+	m.syncLocked(r)
+	r.state = r.run.State
+	r.err = err
+	close(r.done)
+}
+
+func (m *mapStore) CancelAll() []contracts.RunID {
+	m.mu.Lock()
+	runs := slices.Collect(maps.Values(m.runs))
+	m.mu.Unlock()
+	cancelled := []contracts.RunID{}
+	for _, r := range runs {
+		if !m.isDone(r.run.ID) {
+			r.cancel()
+			cancelled = append(cancelled, r.run.ID)
+		}
+	}
+	slices.Sort(cancelled)
+	return cancelled
+}
+
+func (m *mapStore) WaitAll(timeout time.Duration) int {
+	m.mu.Lock()
+	runs := slices.Collect(maps.Values(m.runs))
+	m.mu.Unlock()
+	deadline := time.After(timeout)
+	waiting := len(runs)
+	for _, r := range runs {
+		select {
+		case <-r.done:
+			waiting--
+		case <-deadline:
+			return waiting
+		}
+	}
+	return 0
+}
+
+func TestServer_CustomStore(t *testing.T) {
+	store := newMapStore()
+	server := NewServerWithOptions(":0", nil, ServerOptions{Store: store})
+	if server.Store() != nil {
+		t.Error("expected Store() to be nil for a custom backend")
+	}
+	handler := server.httpServer.Handler
+
+	reqBody := `{
+		"id": "custom-store",
+		"policy": {"max_parallelism": 1, "budget_limit": {"amount": 1.0, "currency": "USD"}},
+		"tasks": [
+			{"id": "A", "prompt": "First", "model": "claude-3-haiku-20240307"},
+			{"id": "B", "prompt": "Second", "model": "claude-3-haiku-20240307", "deps": ["A"]}
+		]
+	}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("StartRun failed: %d - %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/runs", bytes.NewBufferString(reqBody)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("duplicate run: expected 409, got %d - %s", w.Code, w.Body.String())
+	}
+
+	select {
+	case <-store.runs["custom-store"].done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for run to complete")
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/runs/custom-store", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetStatus failed: %d - %s", w.Code, w.Body.String())
+	}
+	var status RunResponse
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if status.State != "completed" {
+		t.Errorf("state = %q, want completed", status.State)
+	}
+	for _, id := range []string{"A", "B"} {
+		if task := status.Tasks[id]; task.State != "completed" || task.Output == "" {
+			t.Errorf("task %s = %+v, want completed with output", id, task)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/runs/custom-store/abort", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("abort completed run: expected 409, got %d - %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/runs/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing run: expected 404, got %d", w.Code)
+	}
+}
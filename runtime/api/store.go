@@ -1,15 +1,76 @@
 package api
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/anthropics/claude-workflow/runtime/contracts"
+	"github.com/anthropics/claude-workflow/runtime/internal/audit"
 )
 
+// Store is the run storage the handlers depend on. RunStore is the in-memory
+// implementation; a shared backend (e.g. Redis or SQL) lets several sidecar
+// instances serve the same runs.
+//
+// Run is owned by the orchestrator goroutine while it executes. Implementations
+// must only read it in UpdateShadowState and MarkDone, which the orchestrator
+// calls at safe points, and serve reads from the copy taken there.
+type Store interface {
+	// Create stores a new run. Returns ErrRunExists if the ID already exists.
+	Create(run *contracts.Run, cancel context.CancelFunc) error
+	// GetSnapshot returns a copy of the run's state for API responses.
+	GetSnapshot(id contracts.RunID) (*RunSnapshot, bool)
+	// List returns a summary of every stored run, in no particular order.
+	List() []RunSummary
+	// GetBundle returns the exportable data of a finished run.
+	GetBundle(id contracts.RunID) (*RunBundle, error)
+	// Abort cancels an active run.
+	Abort(id contracts.RunID) error
+	// SetBudgetLimit raises the budget limit in an active run's policy.
+	SetBudgetLimit(id contracts.RunID, limit contracts.Cost) error
+	// AddNote appends an operator note to a run in any state.
+	AddNote(id contracts.RunID, text string) (RunNote, error)
+	// PruneCompleted removes finished runs older than retention.
+	PruneCompleted(retention time.Duration) int
+	// PruneCandidates returns the runs PruneCompleted(retention) would
+	// remove, without removing them.
+	PruneCandidates(retention time.Duration) []PruneCandidate
+	// ActiveLabelCount returns the number of runs with label ("key=value")
+	// that are not done yet. See RunLabels.
+	ActiveLabelCount(label string) int
+	// ScheduledCount returns the number of runs waiting for their scheduled
+	// start (shadow state RunScheduled).
+	ScheduledCount() int
+
+	// Subscribe returns a channel of run events, closed when the run is done,
+	// and a function to unsubscribe.
+	Subscribe(id contracts.RunID) (<-chan RunEvent, func(), error)
+
+	// SetShadowRunState, UpdateShadowState, UpdateTaskRunning, UpdateTimestamp,
+	// AppendTaskOutput, SetBatch and MarkDone are called by the run's
+	// orchestrator goroutine.
+	SetShadowRunState(id contracts.RunID, state contracts.RunState)
+	UpdateShadowState(id contracts.RunID)
+	UpdateTaskRunning(id contracts.RunID, taskID contracts.TaskID)
+	UpdateTimestamp(id contracts.RunID)
+	AppendTaskOutput(id contracts.RunID, taskID contracts.TaskID, chunk string)
+	SetBatch(id contracts.RunID, batch int)
+	MarkDone(id contracts.RunID, err error)
+
+	// CancelAll and WaitAll are used on shutdown; CancelAll also serves
+	// POST /api/v1/runs/abort.
+	CancelAll() []contracts.RunID
+	WaitAll(timeout time.Duration) int
+}
+
+var _ Store = (*RunStore)(nil)
+
 // RunEntry represents a run stored in the RunStore.
 type RunEntry struct {
 	mu sync.RWMutex // protects shadowState, Aborting, UpdatedAt
@@ -28,6 +89,42 @@ type RunEntry struct {
 	Aborting  bool // true after Abort() is called, until goroutine finishes
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	// subscribers receive run events; closed when the run is done.
+	subscribers   map[chan RunEvent]struct{}
+	eventsClosed  bool
+	droppedEvents int64 // events dropped because a subscriber buffer was full
+
+	// dag and policy are copied at create; the orchestrator mutates Run.DAG.
+	// policy.BudgetLimit may be raised later by SetBudgetLimit.
+	dag    map[contracts.TaskID]DAGNodeSnapshot
+	policy contracts.RunPolicy
+	specs  map[contracts.TaskID]contracts.Task // task definitions as submitted
+	memory map[string]string                   // run memory as seeded at create
+}
+
+// Event types published to RunStore subscribers.
+const (
+	// EventTaskProgress carries a partial output chunk from a streaming executor.
+	EventTaskProgress = "task_progress"
+	// EventTaskState carries a task's new state and the run's cumulative usage.
+	EventTaskState = "task_state"
+	// EventRunState signals a change of the run state.
+	EventRunState = "run_state"
+)
+
+// DefaultEventBufferSize is the default per-subscriber channel buffer.
+// Events are dropped for subscribers that fall behind, so publishing never blocks execution.
+const DefaultEventBufferSize = 64
+
+// RunEvent is a progress event published to RunStore subscribers.
+type RunEvent struct {
+	Type   string
+	RunID  contracts.RunID
+	TaskID contracts.TaskID
+	Chunk  string              // EventTaskProgress
+	State  contracts.TaskState // EventTaskState
+	Usage  contracts.Usage     // EventTaskState: run usage after the change
 }
 
 // RunShadowState is a thread-safe copy of Run state.
@@ -35,30 +132,261 @@ type RunShadowState struct {
 	State contracts.RunState
 	Tasks map[contracts.TaskID]TaskShadow
 	Usage contracts.Usage
+	Order []contracts.TaskID    // task IDs in the order they reached a terminal state
+	Batch int                   // number of the batch executing or last executed (0 = none yet)
+	Plan  *contracts.DryRunPlan // set when a dry run completes (deep copy)
+	Notes []RunNote             // operator notes, oldest first
+
+	BudgetWarning bool // usage reached policy.SoftLimit
+
+	// StartedAt and FinishedAt are Unix ms (0 = not yet). StartedAt is first
+	// stamped when the shadow turns running, then replaced by the
+	// orchestrator's Run.StartedAt; FinishedAt is stamped by MarkDone,
+	// or on restore for runs failed as restart_interrupted.
+	StartedAt  int64
+	FinishedAt int64
+}
+
+// Limits on operator notes (see RunStore.AddNote).
+const (
+	MaxNoteBytes = 4096 // maximum size of a note's text
+	MaxRunNotes  = 100  // maximum notes per run
+)
+
+// RunNote is a free-form note an operator attached to a run.
+type RunNote struct {
+	Text      string
+	CreatedAt int64 // Unix ms
 }
 
 // TaskShadow is a copy of task state.
 type TaskShadow struct {
-	State  contracts.TaskState
-	Output string
-	Error  *contracts.TaskError // deep copy
+	State         contracts.TaskState
+	Output        string
+	PartialOutput string               // streamed output so far, until the task completes
+	Error         *contracts.TaskError // deep copy
+	EstimatedUse  contracts.Usage      // dry run estimate
 }
 
 // RunStore provides thread-safe in-memory storage for runs.
 type RunStore struct {
-	mu   sync.RWMutex
-	runs map[contracts.RunID]*RunEntry
+	mu         sync.RWMutex
+	runs       map[contracts.RunID]*RunEntry
+	bufferSize int // per-subscriber event buffer
+
+	// activeLabels counts runs that are not done per label ("key=value").
+	// Incremented by Create, decremented when MarkDone first closes Done.
+	activeLabels map[string]int
+
+	// scheduled counts runs whose shadow state is RunScheduled.
+	// Incremented by Create, decremented when SetShadowRunState moves a run
+	// out of RunScheduled.
+	scheduled int
+
+	// persistence, if set, receives a snapshot of each run on create,
+	// progress and MarkDone (nil = in-memory only).
+	persistence Persistence
+
+	// outputs, if set, receives the sink results of each run that completes.
+	outputs OutputPersister
 }
 
 // NewRunStore creates a new RunStore.
 func NewRunStore() *RunStore {
+	return NewRunStoreWithBufferSize(DefaultEventBufferSize)
+}
+
+// NewRunStoreWithBufferSize creates a new RunStore with a custom per-subscriber
+// event buffer size. Non-positive sizes use DefaultEventBufferSize.
+func NewRunStoreWithBufferSize(size int) *RunStore {
+	if size <= 0 {
+		size = DefaultEventBufferSize
+	}
 	return &RunStore{
-		runs: make(map[contracts.RunID]*RunEntry),
+		runs:         make(map[contracts.RunID]*RunEntry),
+		bufferSize:   size,
+		activeLabels: make(map[string]int),
+	}
+}
+
+// NewRunStoreWithPersistence creates a RunStore that saves run snapshots to
+// p and restores the runs p holds. Restored runs are finished: their tasks
+// are not re-executed. A run that was still active when it was saved (its
+// goroutine died with the previous process) is restored as failed with
+// ErrRestartInterrupted, as are its unfinished tasks. A nil p behaves like
+// NewRunStoreWithBufferSize. Returns an error if p cannot be loaded.
+func NewRunStoreWithPersistence(size int, p Persistence) (*RunStore, error) {
+	s := NewRunStoreWithBufferSize(size)
+	if p == nil {
+		return s, nil
+	}
+
+	snaps, err := p.LoadAll()
+	if err != nil {
+		return nil, fmt.Errorf("load runs: %w", err)
+	}
+	for _, snap := range snaps {
+		if !isTerminalRun(snap.APIState) {
+			interruptSnapshot(snap)
+			if err := p.Save(snap); err != nil {
+				audit.Log("event=run_persist_failed run_id=%s trace_id=%s error_msg=%s", snap.ID, snap.TraceID, err.Error())
+			}
+			audit.Log("event=run_restart_interrupted run_id=%s trace_id=%s", snap.ID, snap.TraceID)
+		}
+		s.runs[snap.ID] = restoredEntry(snap)
+	}
+	s.persistence = p
+	return s, nil
+}
+
+// isTerminalRun reports whether an API run state is final.
+func isTerminalRun(apiState string) bool {
+	switch apiState {
+	case contracts.RunCompleted.String(), contracts.RunFailed.String(), contracts.RunAborted.String():
+		return true
+	}
+	return false
+}
+
+// interruptSnapshot fails a run restored while it was still active, along
+// with each of its tasks that had not finished.
+func interruptSnapshot(snap *RunSnapshot) {
+	err := fmt.Errorf("run %s: %w", snap.ID, ErrRestartInterrupted)
+	snap.State = contracts.RunFailed
+	snap.APIState = contracts.RunFailed.String()
+	snap.Error = err
+
+	var failed []contracts.TaskID
+	for id, task := range snap.Tasks {
+		if isTerminalTask(task.State) {
+			continue
+		}
+		task.State = contracts.TaskFailed
+		task.PartialOutput = ""
+		task.Error = &contracts.TaskError{
+			Code:    string(CodeRestartInterrupted),
+			Message: err.Error(),
+		}
+		snap.Tasks[id] = task
+		failed = append(failed, id)
+	}
+	slices.Sort(failed)
+	snap.Order = append(snap.Order, failed...)
+	snap.Progress = 100
+	snap.UpdatedAt = time.Now().UnixMilli()
+	if snap.FinishedAt == 0 {
+		snap.FinishedAt = snap.UpdatedAt
+	}
+}
+
+// restoredEntry builds the entry of a finished run from its snapshot.
+// Its Done channel is closed and it has no task definitions (specs).
+func restoredEntry(snap *RunSnapshot) *RunEntry {
+	done := make(chan struct{})
+	close(done)
+
+	shadow := &RunShadowState{
+		State: snap.State,
+		Tasks: make(map[contracts.TaskID]TaskShadow, len(snap.Tasks)),
+		Usage: snap.Usage,
+		Order: slices.Clone(snap.Order),
+		Plan:  copyPlan(snap.Plan),
+		Notes: slices.Clone(snap.Notes),
+
+		BudgetWarning: snap.BudgetWarning,
+
+		StartedAt:  snap.StartedAt,
+		FinishedAt: snap.FinishedAt,
+	}
+	tasks := make(map[contracts.TaskID]*contracts.Task, len(snap.Tasks))
+	for id, ts := range snap.Tasks {
+		shadow.Tasks[id] = TaskShadow(ts)
+		task := &contracts.Task{ID: id, State: ts.State, Error: ts.Error, EstimatedUse: ts.EstimatedUse}
+		if ts.State == contracts.TaskCompleted {
+			task.Outputs = &contracts.TaskResult{Output: ts.Output}
+		}
+		if node, ok := snap.DAG[id]; ok {
+			task.Deps = slices.Clone(node.Deps)
+		}
+		tasks[id] = task
+	}
+
+	return &RunEntry{
+		Run: &contracts.Run{
+			ID:         snap.ID,
+			State:      snap.State,
+			Tasks:      tasks,
+			Policy:     snap.Policy,
+			Usage:      snap.Usage,
+			ConfigHash: snap.ConfigHash,
+			TraceID:    snap.TraceID,
+			StartAfter: contracts.Timestamp(snap.StartAfter),
+			Labels:     snap.Labels,
+			StartedAt:  contracts.Timestamp(snap.StartedAt),
+			FinishedAt: contracts.Timestamp(snap.FinishedAt),
+		},
+		Done:          done,
+		Error:         snap.Error,
+		shadowState:   shadow,
+		CreatedAt:     time.UnixMilli(snap.CreatedAt),
+		UpdatedAt:     time.UnixMilli(snap.UpdatedAt),
+		eventsClosed:  true,
+		droppedEvents: snap.DroppedEvents,
+		dag:           snap.DAG,
+		policy:        snap.Policy,
+	}
+}
+
+// SetOutputPersister makes MarkDone hand the sink results of every completed
+// run to p (nil disables it). Failed and aborted runs are not persisted.
+// Call it before runs are started.
+func (s *RunStore) SetOutputPersister(p OutputPersister) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outputs = p
+}
+
+// persistOutputs hands the sink results of a completed run to the output
+// persister. Failures are logged; the run stays completed.
+// The orchestrator must have finished with the run.
+func (s *RunStore) persistOutputs(p OutputPersister, run *contracts.Run) {
+	sinks := sinkTaskResults(run)
+	if len(sinks) == 0 {
+		return
+	}
+	if err := p.Persist(run.ID, sinks); err != nil {
+		audit.Log("event=run_outputs_persist_failed run_id=%s trace_id=%s error_msg=%s", run.ID, run.TraceID, err.Error())
+		return
+	}
+	audit.Log("event=run_outputs_persisted run_id=%s trace_id=%s tasks=%d", run.ID, run.TraceID, len(sinks))
+}
+
+// persist saves the run's current snapshot if a persistence backend is set.
+// Failures are logged; the run goes on in memory.
+func (s *RunStore) persist(id contracts.RunID) {
+	if s.persistence == nil {
+		return
+	}
+	snap, exists := s.GetSnapshot(id)
+	if !exists {
+		return
+	}
+	if err := s.persistence.Save(snap); err != nil {
+		audit.Log("event=run_persist_failed run_id=%s trace_id=%s error_msg=%s", id, snap.TraceID, err.Error())
 	}
 }
 
 // Create stores a new run. Returns ErrRunExists if the ID already exists.
 func (s *RunStore) Create(run *contracts.Run, cancel context.CancelFunc) error {
+	if err := s.create(run, cancel); err != nil {
+		return err
+	}
+	s.persist(run.ID)
+	return nil
+}
+
+// create adds the run's entry under s.mu.
+func (s *RunStore) create(run *contracts.Run, cancel context.CancelFunc) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -95,10 +423,101 @@ func (s *RunStore) Create(run *contracts.Run, cancel context.CancelFunc) error {
 		shadowState: shadow,
 		CreatedAt:   now,
 		UpdatedAt:   now,
+		dag:         copyDAG(run.DAG),
+		policy:      run.Policy,
+		specs:       copyTaskSpecs(run.Tasks),
+		memory:      maps.Clone(run.Memory),
+	}
+	for _, label := range RunLabels(run.Labels) {
+		s.activeLabels[label]++
+	}
+	if run.State == contracts.RunScheduled {
+		s.scheduled++
 	}
 	return nil
 }
 
+// RunLabels returns a run's labels as sorted "key=value" strings, the form
+// used by ActiveLabelCount and ServerOptions.LabelLimits.
+func RunLabels(labels map[string]string) []string {
+	out := make([]string, 0, len(labels))
+	for k, v := range labels {
+		out = append(out, k+"="+v)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// ActiveLabelCount returns the number of runs with label ("key=value")
+// that are not done yet.
+func (s *RunStore) ActiveLabelCount(label string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.activeLabels[label]
+}
+
+// ScheduledCount returns the number of runs waiting for their scheduled start.
+func (s *RunStore) ScheduledCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.scheduled
+}
+
+// copyDAG copies the immutable structure (deps and next) of a DAG.
+func copyDAG(dag *contracts.DAG) map[contracts.TaskID]DAGNodeSnapshot {
+	if dag == nil {
+		return nil
+	}
+	nodes := make(map[contracts.TaskID]DAGNodeSnapshot, len(dag.Nodes))
+	for id, node := range dag.Nodes {
+		nodes[id] = DAGNodeSnapshot{
+			Deps: slices.Clone(node.Deps),
+			Next: slices.Clone(node.Next),
+		}
+	}
+	return nodes
+}
+
+// copyTaskSpecs copies the submitted definition of each task.
+// Inputs are cloned because routing adds dependency outputs to them.
+func copyTaskSpecs(tasks map[contracts.TaskID]*contracts.Task) map[contracts.TaskID]contracts.Task {
+	specs := make(map[contracts.TaskID]contracts.Task, len(tasks))
+	for id, task := range tasks {
+		spec := contracts.Task{
+			ID:    task.ID,
+			State: task.State,
+			Deps:  slices.Clone(task.Deps),
+			Model: task.Model,
+
+			RequireNonEmptyOutput: task.RequireNonEmptyOutput,
+			RetryOn:               slices.Clone(task.RetryOn),
+			ConcurrencyKey:        task.ConcurrencyKey,
+			ContinueOnFail:        task.ContinueOnFail,
+			TimeoutMs:             task.TimeoutMs,
+			Priority:              task.Priority,
+			MaxContextTokens:      task.MaxContextTokens,
+		}
+		if task.Retry != nil {
+			retry := *task.Retry
+			spec.Retry = &retry
+		}
+		if task.RunIf != nil {
+			cond := *task.RunIf
+			spec.RunIf = &cond
+		}
+		if task.Inputs != nil {
+			spec.Inputs = &contracts.TaskInput{
+				Prompt:     task.Inputs.Prompt,
+				Inputs:     maps.Clone(task.Inputs.Inputs),
+				Metadata:   maps.Clone(task.Inputs.Metadata),
+				FromMemory: slices.Clone(task.Inputs.FromMemory),
+			}
+		}
+		specs[id] = spec
+	}
+	return specs
+}
+
 // Get retrieves a run entry by ID.
 // WARNING: The returned entry contains a pointer to Run which may be modified
 // by the orchestrator goroutine. Use GetSnapshot for safe concurrent access.
@@ -120,13 +539,44 @@ type RunSnapshot struct {
 	UpdatedAt int64
 	APIState  string // "aborting" if abort was called but not finished
 	Error     error
+
+	DroppedEvents int64   // events dropped for slow subscribers
+	ConfigHash    string  // immutable after create
+	TraceID       string  // immutable after create
+	StartAfter    int64   // immutable after create
+	Progress      float64 // percent of tasks in a terminal state (0-100)
+	Batch         int     // orchestrator batch executing or last executed (0 = none yet)
+	BudgetWarning bool    // usage reached policy.SoftLimit
+
+	StartedAt  int64 // Unix ms the run started executing (0 = not yet)
+	FinishedAt int64 // Unix ms the run reached a terminal state (0 = not yet)
+
+	TaskCounts contracts.TaskCounts // tasks by outcome; skipped tasks add no Usage
+
+	Labels map[string]string // immutable after create
+
+	DAG    map[contracts.TaskID]DAGNodeSnapshot // immutable after create
+	Policy contracts.RunPolicy                  // effective policy; only BudgetLimit changes after create
+	Order  []contracts.TaskID                   // terminal order so far
+
+	Plan *contracts.DryRunPlan // dry run outcome (nil unless the run was a dry run)
+
+	Notes []RunNote // operator notes, oldest first
+}
+
+// DAGNodeSnapshot is a copy of a DAG node's edges.
+type DAGNodeSnapshot struct {
+	Deps []contracts.TaskID
+	Next []contracts.TaskID
 }
 
 // TaskSnapshot is a thread-safe copy of task state.
 type TaskSnapshot struct {
-	State  contracts.TaskState
-	Output string
-	Error  *contracts.TaskError
+	State         contracts.TaskState
+	Output        string
+	PartialOutput string
+	Error         *contracts.TaskError
+	EstimatedUse  contracts.Usage
 }
 
 // GetSnapshot returns a thread-safe copy of run state for API responses.
@@ -143,6 +593,12 @@ func (s *RunStore) GetSnapshot(id contracts.RunID) (*RunSnapshot, bool) {
 	createdAt := entry.CreatedAt.UnixMilli() // immutable after create
 	runErr := entry.Error
 	runID := entry.Run.ID
+	configHash := entry.Run.ConfigHash     // immutable after create
+	traceID := entry.Run.TraceID           // immutable after create
+	startAfter := entry.Run.StartAfter     // immutable after create
+	labels := maps.Clone(entry.Run.Labels) // immutable after create
+	dag := entry.dag                       // immutable after create
+	policy := entry.policy                 // protected by s.mu
 	s.mu.RUnlock()
 
 	// Lock entry's shadowState for reading (also protects Aborting and UpdatedAt)
@@ -164,10 +620,18 @@ func (s *RunStore) GetSnapshot(id contracts.RunID) (*RunSnapshot, bool) {
 
 	// Copy tasks from shadow (already deep-copied)
 	tasks := make(map[contracts.TaskID]TaskSnapshot, len(shadow.Tasks))
+	terminal := 0
+	var counts contracts.TaskCounts
 	for id, task := range shadow.Tasks {
+		if isTerminalTask(task.State) {
+			terminal++
+		}
+		counts.Add(task.State)
 		ts := TaskSnapshot{
-			State:  task.State,
-			Output: task.Output,
+			State:         task.State,
+			Output:        task.Output,
+			PartialOutput: task.PartialOutput,
+			EstimatedUse:  task.EstimatedUse,
 		}
 		if task.Error != nil {
 			ts.Error = &contracts.TaskError{
@@ -187,9 +651,129 @@ func (s *RunStore) GetSnapshot(id contracts.RunID) (*RunSnapshot, bool) {
 		UpdatedAt: updatedAt,
 		APIState:  apiState,
 		Error:     runErr,
+
+		DroppedEvents: entry.droppedEvents,
+		ConfigHash:    configHash,
+		TraceID:       traceID,
+		StartAfter:    int64(startAfter),
+		Progress:      progressPercent(terminal, len(tasks)),
+		Batch:         shadow.Batch,
+		BudgetWarning: shadow.BudgetWarning,
+
+		StartedAt:  shadow.StartedAt,
+		FinishedAt: shadow.FinishedAt,
+
+		TaskCounts: counts,
+
+		Labels: labels,
+
+		DAG:    dag,
+		Policy: policy,
+		Order:  slices.Clone(shadow.Order),
+
+		Plan: copyPlan(shadow.Plan),
+
+		Notes: slices.Clone(shadow.Notes),
 	}, true
 }
 
+// RunSummary is the state of a run shown in run listings.
+type RunSummary struct {
+	ID        contracts.RunID
+	APIState  string // as in RunSnapshot
+	CreatedAt int64
+	UpdatedAt int64
+	Usage     contracts.Usage
+
+	TaskCounts contracts.TaskCounts
+}
+
+// List returns a summary of every stored run, in no particular order.
+// Like GetSnapshot, it reads shadowState and never the live Run.
+func (s *RunStore) List() []RunSummary {
+	type listed struct {
+		entry *RunEntry
+		id    contracts.RunID
+		done  bool
+	}
+	s.mu.RLock()
+	entries := make([]listed, 0, len(s.runs))
+	for id, entry := range s.runs {
+		entries = append(entries, listed{entry: entry, id: id, done: s.isDone(entry)})
+	}
+	s.mu.RUnlock()
+
+	summaries := make([]RunSummary, 0, len(entries))
+	for _, l := range entries {
+		l.entry.mu.RLock()
+		shadow := l.entry.shadowState
+		if shadow == nil {
+			l.entry.mu.RUnlock()
+			continue
+		}
+		apiState := shadow.State.String()
+		if l.entry.Aborting && !l.done {
+			apiState = "aborting"
+		}
+		var counts contracts.TaskCounts
+		for _, task := range shadow.Tasks {
+			counts.Add(task.State)
+		}
+		summaries = append(summaries, RunSummary{
+			ID:        l.id,
+			APIState:  apiState,
+			CreatedAt: l.entry.CreatedAt.UnixMilli(),
+			UpdatedAt: l.entry.UpdatedAt.UnixMilli(),
+			Usage:     shadow.Usage,
+
+			TaskCounts: counts,
+		})
+		l.entry.mu.RUnlock()
+	}
+	return summaries
+}
+
+// RunBundle is the data exported for a finished run.
+type RunBundle struct {
+	Snapshot *RunSnapshot
+	Specs    map[contracts.TaskID]contracts.Task // task definitions as submitted
+	Tasks    map[contracts.TaskID]contracts.Task // final task state, including routed inputs
+	Memory   map[string]string                   // run memory as seeded at create
+}
+
+// GetBundle returns the exportable data of a finished run. Returns:
+// - ErrRunNotFound if the run doesn't exist
+// - ErrRunNotTerminal if the run is still pending or running
+func (s *RunStore) GetBundle(id contracts.RunID) (*RunBundle, error) {
+	s.mu.RLock()
+	entry, exists := s.runs[id]
+	done := exists && s.isDone(entry)
+	s.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("run %s: %w", id, contracts.ErrRunNotFound)
+	}
+	if !done {
+		return nil, fmt.Errorf("run %s: %w", id, ErrRunNotTerminal)
+	}
+
+	snap, exists := s.GetSnapshot(id)
+	if !exists {
+		return nil, fmt.Errorf("run %s: %w", id, contracts.ErrRunNotFound)
+	}
+
+	// Safe to read Run directly: the orchestrator finished before Done was closed
+	tasks := make(map[contracts.TaskID]contracts.Task, len(entry.Run.Tasks))
+	for tid, task := range entry.Run.Tasks {
+		tasks[tid] = *task
+	}
+	return &RunBundle{
+		Snapshot: snap,
+		Specs:    entry.specs,
+		Tasks:    tasks,
+		Memory:   entry.memory,
+	}, nil
+}
+
 // Abort cancels a running run. Returns:
 // - ErrRunNotFound if the run doesn't exist
 // - ErrRunCompleted if the run is already in a terminal state
@@ -238,10 +822,77 @@ func (s *RunStore) Abort(id contracts.RunID) error {
 	return nil
 }
 
-// UpdateShadowState updates the shadow state for tasks.
-// Run.State is updated separately in SetShadowRunState to avoid race with orchestrator.
+// SetBudgetLimit raises the budget limit in the run's effective policy.
+// The orchestrator applies it separately (see orchestration.BudgetOverride).
+// Returns:
+// - ErrRunNotFound if the run doesn't exist
+// - ErrRunCompleted if the run has finished
+// - ErrInvalidInput if limit is below the current limit or the run's usage
+func (s *RunStore) SetBudgetLimit(id contracts.RunID, limit contracts.Cost) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, exists := s.runs[id]
+	if !exists {
+		return fmt.Errorf("run %s: %w", id, contracts.ErrRunNotFound)
+	}
+	if s.isDone(entry) {
+		return fmt.Errorf("run %s: %w", id, contracts.ErrRunCompleted)
+	}
+
+	// Compare and raise under both locks, so concurrent updates cannot
+	// lower the limit or undercut usage recorded in between
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if current := entry.policy.BudgetLimit.Amount; limit.Amount < current {
+		return fmt.Errorf("budget_limit can only be raised (current %.4f): %w", current, contracts.ErrInvalidInput)
+	}
+	if entry.shadowState != nil {
+		if used := entry.shadowState.Usage.Cost.Amount; limit.Amount < used {
+			return fmt.Errorf("budget_limit is below current usage %.4f: %w", used, contracts.ErrInvalidInput)
+		}
+	}
+
+	entry.policy.BudgetLimit = limit
+	entry.UpdatedAt = time.Now()
+	return nil
+}
+
+// AddNote appends a note with the current time to a run, in any state, and
+// persists the run. The run's UpdatedAt is unchanged. Returns:
+// - ErrRunNotFound if the run doesn't exist
+// - ErrInvalidInput if the run already has MaxRunNotes notes
+func (s *RunStore) AddNote(id contracts.RunID, text string) (RunNote, error) {
+	s.mu.RLock()
+	entry, exists := s.runs[id]
+	s.mu.RUnlock()
+	if !exists {
+		return RunNote{}, fmt.Errorf("run %s: %w", id, contracts.ErrRunNotFound)
+	}
+
+	entry.mu.Lock()
+	if len(entry.shadowState.Notes) >= MaxRunNotes {
+		entry.mu.Unlock()
+		return RunNote{}, fmt.Errorf("run %s already has %d notes: %w", id, MaxRunNotes, contracts.ErrInvalidInput)
+	}
+	note := RunNote{Text: text, CreatedAt: time.Now().UnixMilli()}
+	entry.shadowState.Notes = append(entry.shadowState.Notes, note)
+	entry.mu.Unlock()
+
+	s.persist(id)
+	return note, nil
+}
+
+// UpdateShadowState updates the shadow state for tasks and persists the
+// updated snapshot. Run.State is updated separately in SetShadowRunState to
+// avoid race with orchestrator.
 // IMPORTANT: Only call when orchestrator has finished (e.g., from MarkDone).
 func (s *RunStore) UpdateShadowState(id contracts.RunID) {
+	s.updateShadowState(id)
+	s.persist(id)
+}
+
+// updateShadowState copies task states and usage from the run to its shadow.
+func (s *RunStore) updateShadowState(id contracts.RunID) {
 	s.mu.RLock()
 	entry, exists := s.runs[id]
 	if !exists {
@@ -257,10 +908,21 @@ func (s *RunStore) UpdateShadowState(id contracts.RunID) {
 
 	// Update usage (struct copy, safe)
 	entry.shadowState.Usage = run.Usage
+	entry.shadowState.BudgetWarning = run.BudgetWarning
+	if run.StartedAt > 0 {
+		entry.shadowState.StartedAt = int64(run.StartedAt)
+	}
 
 	// Update task states - orchestrator has finished modifying at this point
+	var finished, changed []contracts.TaskID
 	for id, task := range run.Tasks {
-		ts := TaskShadow{State: task.State}
+		if !isTerminalTask(entry.shadowState.Tasks[id].State) && isTerminalTask(task.State) {
+			finished = append(finished, id)
+		}
+		if prev, ok := entry.shadowState.Tasks[id]; !ok || prev.State != task.State {
+			changed = append(changed, id)
+		}
+		ts := TaskShadow{State: task.State, EstimatedUse: task.EstimatedUse}
 		if task.Outputs != nil {
 			ts.Output = task.Outputs.Output
 		}
@@ -276,8 +938,24 @@ func (s *RunStore) UpdateShadowState(id contracts.RunID) {
 				Message: existing.Error.Message,
 			}
 		}
+		// Preserve streamed output until the final output replaces it
+		if task.State != contracts.TaskCompleted {
+			ts.PartialOutput = entry.shadowState.Tasks[id].PartialOutput
+		}
 		entry.shadowState.Tasks[id] = ts
 	}
+	// Tasks finishing in the same update have no observable order; sort for determinism
+	slices.Sort(finished)
+	entry.shadowState.Order = append(entry.shadowState.Order, finished...)
+
+	slices.Sort(changed)
+	for _, taskID := range changed {
+		entry.publishTaskState(id, taskID)
+	}
+
+	if run.Plan != nil && entry.shadowState.Plan == nil {
+		entry.shadowState.Plan = copyPlan(run.Plan)
+	}
 
 	// Also update timestamp
 	entry.UpdatedAt = time.Now()
@@ -317,9 +995,13 @@ func (s *RunStore) UpdateTaskRunning(id contracts.RunID, taskID contracts.TaskID
 	}
 
 	task := entry.shadowState.Tasks[taskID]
+	if task.State == contracts.TaskRunning {
+		return
+	}
 	task.State = contracts.TaskRunning
 	entry.shadowState.Tasks[taskID] = task
 	entry.UpdatedAt = time.Now()
+	entry.publishTaskState(id, taskID)
 }
 
 // UpdateTaskSuccess updates shadow state for a completed task and usage.
@@ -340,17 +1022,24 @@ func (s *RunStore) UpdateTaskSuccess(id contracts.RunID, taskID contracts.TaskID
 	}
 
 	task := entry.shadowState.Tasks[taskID]
+	if !isTerminalTask(task.State) {
+		entry.shadowState.Order = append(entry.shadowState.Order, taskID)
+	}
 	task.State = contracts.TaskCompleted
+	task.PartialOutput = ""
 	if result != nil {
 		task.Output = result.Output
 		entry.shadowState.Usage.Tokens += result.Usage.Tokens
-		entry.shadowState.Usage.Cost.Amount += result.Usage.Cost.Amount
-		if entry.shadowState.Usage.Cost.Currency == "" {
-			entry.shadowState.Usage.Cost.Currency = result.Usage.Cost.Currency
+		entry.shadowState.Usage.InputTokens += result.Usage.InputTokens
+		entry.shadowState.Usage.OutputTokens += result.Usage.OutputTokens
+		// Mismatched currencies are rejected by the budget enforcer; keep the shadow as is
+		if total, err := entry.shadowState.Usage.Cost.Add(result.Usage.Cost); err == nil {
+			entry.shadowState.Usage.Cost = total
 		}
 	}
 	entry.shadowState.Tasks[taskID] = task
 	entry.UpdatedAt = time.Now()
+	entry.publishTaskState(id, taskID)
 }
 
 // UpdateTaskFailure updates shadow state for a failed task.
@@ -371,6 +1060,9 @@ func (s *RunStore) UpdateTaskFailure(id contracts.RunID, taskID contracts.TaskID
 	}
 
 	task := entry.shadowState.Tasks[taskID]
+	if !isTerminalTask(task.State) {
+		entry.shadowState.Order = append(entry.shadowState.Order, taskID)
+	}
 	task.State = contracts.TaskFailed
 	if err != nil {
 		task.Error = &contracts.TaskError{
@@ -380,6 +1072,7 @@ func (s *RunStore) UpdateTaskFailure(id contracts.RunID, taskID contracts.TaskID
 	}
 	entry.shadowState.Tasks[taskID] = task
 	entry.UpdatedAt = time.Now()
+	entry.publishTaskState(id, taskID)
 }
 
 // SetShadowRunState updates the Run.State in shadow.
@@ -394,8 +1087,39 @@ func (s *RunStore) SetShadowRunState(id contracts.RunID, state contracts.RunStat
 	s.mu.RUnlock()
 
 	entry.mu.Lock()
-	defer entry.mu.Unlock()
+	prev := entry.shadowState.State
+	if prev == state {
+		entry.mu.Unlock()
+		return
+	}
 	entry.shadowState.State = state
+	if state == contracts.RunRunning && entry.shadowState.StartedAt == 0 {
+		entry.shadowState.StartedAt = time.Now().UnixMilli()
+	}
+	entry.publish(RunEvent{Type: EventRunState, RunID: id})
+	entry.mu.Unlock()
+
+	// s.mu is taken after entry.mu is released, as MarkDone takes them in
+	// the opposite order
+	if prev == contracts.RunScheduled {
+		s.mu.Lock()
+		s.scheduled--
+		s.mu.Unlock()
+	}
+}
+
+// SetBatch records the number of the batch the orchestrator is executing.
+func (s *RunStore) SetBatch(id contracts.RunID, batch int) {
+	s.mu.RLock()
+	entry, exists := s.runs[id]
+	s.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	entry.shadowState.Batch = batch
 }
 
 // UpdateTimestamp updates the UpdatedAt timestamp for a run.
@@ -407,8 +1131,11 @@ func (s *RunStore) UpdateTimestamp(id contracts.RunID) {
 // MarkDone marks a run as completed, updating the error and closing the Done channel.
 // Should be called when the orchestrator.Run goroutine finishes.
 func (s *RunStore) MarkDone(id contracts.RunID, err error) {
+	// Persist the final snapshot last, after s.mu is released
+	defer s.persist(id)
+
 	// First update shadow task states one final time
-	s.UpdateShadowState(id)
+	s.updateShadowState(id)
 
 	s.mu.Lock()
 	entry, exists := s.runs[id]
@@ -418,11 +1145,17 @@ func (s *RunStore) MarkDone(id contracts.RunID, err error) {
 	}
 	// Get final run state (safe now - orchestrator has finished)
 	finalState := entry.Run.State
+	outputs := s.outputs
 	s.mu.Unlock()
 
 	// Update shadow with final run state
 	s.SetShadowRunState(id, finalState)
 
+	// Write outputs before Done is closed, so waiters find them
+	if outputs != nil && finalState == contracts.RunCompleted {
+		s.persistOutputs(outputs, entry.Run)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -434,6 +1167,14 @@ func (s *RunStore) MarkDone(id contracts.RunID, err error) {
 	entry.Error = err
 	entry.mu.Lock()
 	entry.UpdatedAt = time.Now()
+	if entry.shadowState.FinishedAt == 0 {
+		// The orchestrator's FinishedAt when it ran, else (a scheduled run
+		// cancelled before its start) now
+		entry.shadowState.FinishedAt = int64(entry.Run.FinishedAt)
+		if entry.shadowState.FinishedAt == 0 {
+			entry.shadowState.FinishedAt = entry.UpdatedAt.UnixMilli()
+		}
+	}
 	entry.mu.Unlock()
 
 	// Close Done channel to signal completion
@@ -442,7 +1183,127 @@ func (s *RunStore) MarkDone(id contracts.RunID, err error) {
 		// already closed
 	default:
 		close(entry.Done)
+		s.releaseLabels(entry.Run.Labels)
+	}
+
+	// End event streams
+	entry.mu.Lock()
+	entry.closeSubscribers()
+	dropped := entry.droppedEvents
+	entry.mu.Unlock()
+
+	if dropped > 0 {
+		audit.Log("event=events_dropped run_id=%s trace_id=%s dropped_events=%d", id, entry.Run.TraceID, dropped)
+	}
+}
+
+// releaseLabels decrements the active counts of a finished run's labels.
+// Callers hold s.mu.
+func (s *RunStore) releaseLabels(labels map[string]string) {
+	for _, label := range RunLabels(labels) {
+		if s.activeLabels[label]--; s.activeLabels[label] <= 0 {
+			delete(s.activeLabels, label)
+		}
+	}
+}
+
+// AppendTaskOutput appends a partial output chunk to the task's shadow state
+// and publishes an EventTaskProgress event to subscribers.
+func (s *RunStore) AppendTaskOutput(id contracts.RunID, taskID contracts.TaskID, chunk string) {
+	s.mu.RLock()
+	entry, exists := s.runs[id]
+	if !exists {
+		s.mu.RUnlock()
+		return
+	}
+	s.mu.RUnlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.shadowState == nil {
+		return
+	}
+
+	task := entry.shadowState.Tasks[taskID]
+	task.PartialOutput += chunk
+	entry.shadowState.Tasks[taskID] = task
+	entry.UpdatedAt = time.Now()
+
+	entry.publish(RunEvent{
+		Type:   EventTaskProgress,
+		RunID:  id,
+		TaskID: taskID,
+		Chunk:  chunk,
+	})
+}
+
+// Subscribe returns a channel of events for a run and a function to unsubscribe.
+// The channel is closed when the run is done (or immediately if it already is).
+// Returns ErrRunNotFound if the run doesn't exist.
+func (s *RunStore) Subscribe(id contracts.RunID) (<-chan RunEvent, func(), error) {
+	s.mu.RLock()
+	entry, exists := s.runs[id]
+	s.mu.RUnlock()
+	if !exists {
+		return nil, nil, fmt.Errorf("run %s: %w", id, contracts.ErrRunNotFound)
+	}
+
+	ch := make(chan RunEvent, s.bufferSize)
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.eventsClosed {
+		close(ch)
+		return ch, func() {}, nil
+	}
+	if entry.subscribers == nil {
+		entry.subscribers = make(map[chan RunEvent]struct{})
+	}
+	entry.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		entry.mu.Lock()
+		defer entry.mu.Unlock()
+		if _, ok := entry.subscribers[ch]; ok {
+			delete(entry.subscribers, ch)
+			close(ch)
+		}
 	}
+	return ch, unsubscribe, nil
+}
+
+// publish sends an event to all subscribers without blocking (must hold entry.mu).
+func (e *RunEntry) publish(ev RunEvent) {
+	for ch := range e.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// Subscriber is behind, drop event
+			e.droppedEvents++
+		}
+	}
+}
+
+// publishTaskState publishes an EventTaskState event for a task's shadow state (must hold entry.mu).
+func (e *RunEntry) publishTaskState(id contracts.RunID, taskID contracts.TaskID) {
+	e.publish(RunEvent{
+		Type:   EventTaskState,
+		RunID:  id,
+		TaskID: taskID,
+		State:  e.shadowState.Tasks[taskID].State,
+		Usage:  e.shadowState.Usage,
+	})
+}
+
+// closeSubscribers closes all subscriber channels (must hold entry.mu).
+func (e *RunEntry) closeSubscribers() {
+	for ch := range e.subscribers {
+		close(ch)
+	}
+	e.subscribers = nil
+	e.eventsClosed = true
 }
 
 // IsAborting returns true if Abort was called but the run hasn't finished yet.
@@ -519,14 +1380,15 @@ func (s *RunStore) GetTimestamps(id contracts.RunID) (createdAt, updatedAt int64
 	return createdAt, updatedAt
 }
 
-// CancelAll cancels all active runs. Used for graceful shutdown.
-// Returns the number of runs that were cancelled.
-func (s *RunStore) CancelAll() int {
+// CancelAll cancels all active runs. Used for graceful shutdown and bulk abort.
+// Runs already aborting or finished are skipped, so repeated calls are safe.
+// Returns the IDs of the runs that were cancelled, sorted.
+func (s *RunStore) CancelAll() []contracts.RunID {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	cancelled := 0
-	for _, entry := range s.runs {
+	cancelled := []contracts.RunID{}
+	for id, entry := range s.runs {
 		// Skip already completed or aborting runs (read under entry.mu)
 		entry.mu.RLock()
 		aborting := entry.Aborting
@@ -551,8 +1413,9 @@ func (s *RunStore) CancelAll() int {
 		if entry.Cancel != nil {
 			entry.Cancel()
 		}
-		cancelled++
+		cancelled = append(cancelled, id)
 	}
+	slices.Sort(cancelled)
 	return cancelled
 }
 
@@ -601,19 +1464,62 @@ func (s *RunStore) WaitAll(timeout time.Duration) int {
 	}
 }
 
-// PruneCompleted removes completed runs older than the retention duration.
+// PruneCandidate is a finished run old enough to be pruned.
+type PruneCandidate struct {
+	ID  contracts.RunID
+	Age time.Duration // time since the run last changed (usually its finish)
+}
+
+// PruneCompleted removes completed runs older than the retention duration,
+// along with their persisted snapshots if the backend is a PersistenceDeleter.
 // Returns the number of removed runs.
 func (s *RunStore) PruneCompleted(retention time.Duration) int {
 	if retention <= 0 {
 		return 0
 	}
 
-	cutoff := time.Now().Add(-retention)
-	removed := 0
-
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	expired := s.expiredLocked(time.Now(), retention)
+	for _, c := range expired {
+		delete(s.runs, c.ID)
+	}
+	s.mu.Unlock()
 
+	if deleter, ok := s.persistence.(PersistenceDeleter); ok {
+		for _, c := range expired {
+			if err := deleter.Delete(c.ID); err != nil {
+				audit.Log("event=run_persist_failed run_id=%s error_msg=%s", c.ID, err.Error())
+			}
+		}
+	}
+	return len(expired)
+}
+
+// PruneCandidates returns the completed runs older than the retention
+// duration, oldest first, without removing them.
+func (s *RunStore) PruneCandidates(retention time.Duration) []PruneCandidate {
+	if retention <= 0 {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expired := s.expiredLocked(time.Now(), retention)
+	slices.SortFunc(expired, func(a, b PruneCandidate) int {
+		if a.Age != b.Age {
+			return cmp.Compare(b.Age, a.Age)
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return expired
+}
+
+// expiredLocked returns the completed runs last updated more than retention
+// before now. Callers hold s.mu.
+func (s *RunStore) expiredLocked(now time.Time, retention time.Duration) []PruneCandidate {
+	cutoff := now.Add(-retention)
+	var expired []PruneCandidate
 	for id, entry := range s.runs {
 		if !s.isDone(entry) {
 			continue
@@ -622,10 +1528,38 @@ func (s *RunStore) PruneCompleted(retention time.Duration) int {
 		updatedAt := entry.UpdatedAt
 		entry.mu.RUnlock()
 		if updatedAt.Before(cutoff) {
-			delete(s.runs, id)
-			removed++
+			expired = append(expired, PruneCandidate{ID: id, Age: now.Sub(updatedAt)})
 		}
 	}
+	return expired
+}
+
+// progressPercent returns terminal/total as a percentage; an empty run is 100% done.
+func progressPercent(terminal, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(terminal) * 100 / float64(total)
+}
+
+// copyPlan deep-copies a dry run plan (nil-safe).
+func copyPlan(plan *contracts.DryRunPlan) *contracts.DryRunPlan {
+	if plan == nil {
+		return nil
+	}
+	out := &contracts.DryRunPlan{
+		Batches:        make([][]contracts.TaskID, len(plan.Batches)),
+		ProjectedUsage: plan.ProjectedUsage,
+	}
+	for i, batch := range plan.Batches {
+		out.Batches[i] = slices.Clone(batch)
+	}
+	return out
+}
 
-	return removed
+// isTerminalTask reports whether a task state is final.
+func isTerminalTask(state contracts.TaskState) bool {
+	return state == contracts.TaskCompleted ||
+		state == contracts.TaskFailed ||
+		state == contracts.TaskSkipped
 }
@@ -5,33 +5,141 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/anthropics/claude-workflow/runtime/api"
 	"github.com/anthropics/claude-workflow/runtime/contracts"
+	"github.com/anthropics/claude-workflow/runtime/internal/audit"
+	"github.com/anthropics/claude-workflow/runtime/internal/cost"
 )
 
 func main() {
 	// Parse flags
 	addr := flag.String("addr", ":8080", "HTTP server address")
 	auditDir := flag.String("audit-dir", "", "Directory for run audit JSON files (optional)")
+	defaultCurrency := flag.String("default-currency", "USD", "Currency applied to budgets submitted without one")
+	budgetPools := flag.String("budget-pools", "", "Shared budget pools as label=amount,... in the default currency (optional)")
+	auditBufferSize := flag.Int("audit-buffer-size", api.DefaultEventBufferSize, "Per-subscriber run event buffer; overflowing events are dropped and counted")
+	noBudget := flag.Bool("no-budget", false, "Disable budget enforcement for all runs (dev only; usage is still tracked)")
+	defaultBudget := flag.Float64("default-budget", 0, "Budget in the default currency for internally created runs without one (0 = none)")
+	policyDefaults := flag.String("policy-defaults", "", "JSON file with policy defaults merged under every submitted run and caps clamping it (optional)")
+	labelLimits := flag.String("label-limits", "", "Maximum active runs per label as key=value=N,... (optional)")
+	maxScheduledRuns := flag.Int("max-scheduled-runs", 0, "Maximum runs waiting for their start_after time; more are rejected with too_many_scheduled (0 = no limit)")
+	stateDir := flag.String("state-dir", "", "Directory persisting runs and budget pool usage across restarts, one JSON file per run (optional; may equal -audit-dir)")
+	compressMinBytes := flag.Int("compress-min-bytes", api.DefaultCompressMinBytes, "Minimum status or list response size to gzip for clients that accept it (negative disables)")
+	auditLog := flag.String("audit-log", "", "File that also receives the sidecar log, including [AUDIT] lines (optional)")
+	auditLogMaxBytes := flag.Int64("audit-log-max-bytes", 10<<20, "Size at which -audit-log is rotated (0 disables rotation)")
+	auditLogMaxFiles := flag.Int("audit-log-max-files", 5, "Rotated -audit-log files to keep as <file>.1, <file>.2, ...")
+	outputDir := flag.String("output-dir", "", "Directory receiving the sink task outputs of each completed run as <dir>/<run-id>/ (optional)")
+	strictMetadata := flag.Bool("strict-metadata", false, "Reject tasks with metadata keys other than the known ones and -metadata-keys (unknown_metadata_key)")
+	metadataKeys := flag.String("metadata-keys", "", "Extra task metadata keys accepted with -strict-metadata, comma-separated (optional)")
+	providerHeaders := flag.String("provider-headers", "", "HTTP headers for every provider request as Name=value,...; values are redacted in logs (optional)")
 	flag.Parse()
 
+	if *auditLog != "" {
+		sink, err := audit.NewRotatingFile(*auditLog, *auditLogMaxBytes, *auditLogMaxFiles)
+		if err != nil {
+			log.Fatalf("Invalid -audit-log: %v", err)
+		}
+		defer sink.Close()
+		log.SetOutput(io.MultiWriter(os.Stderr, sink))
+	}
+
 	log.Printf("Starting runtime sidecar on %s", *addr)
 	if *auditDir != "" {
 		log.Printf("Audit files will be written to: %s", *auditDir)
 	}
+	if *noBudget {
+		log.Printf("WARNING: -no-budget is set; budget checks are DISABLED for all runs. Do not use in production.")
+	}
+
+	if *defaultBudget < 0 {
+		log.Fatalf("Invalid -default-budget: must be >= 0")
+	}
+
+	pools, err := parseBudgetPools(*budgetPools, contracts.Currency(*defaultCurrency))
+	if err != nil {
+		log.Fatalf("Invalid -budget-pools: %v", err)
+	}
+
+	limits, err := parseLabelLimits(*labelLimits)
+	if err != nil {
+		log.Fatalf("Invalid -label-limits: %v", err)
+	}
+	if *maxScheduledRuns < 0 {
+		log.Fatalf("Invalid -max-scheduled-runs: must be >= 0")
+	}
+
+	headers, err := parseProviderHeaders(*providerHeaders)
+	if err != nil {
+		log.Fatalf("Invalid -provider-headers: %v", err)
+	}
+	if len(headers) > 0 {
+		log.Printf("Provider headers: %v", api.RedactHeaders(headers))
+	}
+
+	var defaults *api.PolicyDefaults
+	if *policyDefaults != "" {
+		defaults, err = api.LoadPolicyDefaults(*policyDefaults, contracts.Currency(*defaultCurrency))
+		if err != nil {
+			log.Fatalf("Invalid -policy-defaults: %v", err)
+		}
+		log.Printf("Policy defaults loaded from: %s", *policyDefaults)
+	}
+
+	// Restore runs persisted by a previous process
+	var store api.Store
+	if *stateDir != "" {
+		store, err = api.NewRunStoreWithPersistence(*auditBufferSize, api.NewFilePersistence(*stateDir))
+		if err != nil {
+			log.Fatalf("Invalid -state-dir: %v", err)
+		}
+		log.Printf("Runs are persisted to: %s", *stateDir)
+	}
+
+	// Pool usage survives restarts alongside the runs
+	budgetPool := cost.NewBudgetPool(pools)
+	if *stateDir != "" {
+		budgetPool, err = cost.NewBudgetPoolWithStore(pools, cost.NewFilePoolUsageStore(filepath.Join(*stateDir, "budget-pools.json")))
+		if err != nil {
+			log.Fatalf("Invalid -state-dir: %v", err)
+		}
+	}
+	if *outputDir != "" {
+		log.Printf("Completed run outputs will be written to: %s", *outputDir)
+	}
 
 	// Create executor (mock for now)
 	executor := mockExecutor
 
 	// Create and start server
-	server := api.NewServer(*addr, executor, *auditDir)
+	server := api.NewServerWithOptions(*addr, executor, api.ServerOptions{
+		AuditDir:         *auditDir,
+		DefaultCurrency:  contracts.Currency(*defaultCurrency),
+		EventBufferSize:  *auditBufferSize,
+		BudgetPool:       budgetPool,
+		NoBudget:         *noBudget,
+		DefaultBudget:    *defaultBudget,
+		PolicyDefaults:   defaults,
+		LabelLimits:      limits,
+		MaxScheduledRuns: *maxScheduledRuns,
+		Store:            store,
+		OutputDir:        *outputDir,
+		StrictMetadata:   *strictMetadata,
+		MetadataKeys:     parseMetadataKeys(*metadataKeys),
+		ProviderHeaders:  headers,
+
+		CompressMinBytes: *compressMinBytes,
+	})
 
 	// Handle graceful shutdown
 	done := make(chan struct{})
@@ -59,6 +167,79 @@ func main() {
 	log.Println("Server stopped")
 }
 
+// parseBudgetPools parses "label=amount,..." into pool limits.
+func parseBudgetPools(spec string, currency contracts.Currency) (map[string]contracts.Cost, error) {
+	pools := make(map[string]contracts.Cost)
+	if spec == "" {
+		return pools, nil
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		label, amountStr, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || label == "" {
+			return nil, fmt.Errorf("entry %q: want label=amount", entry)
+		}
+		amount, err := strconv.ParseFloat(amountStr, 64)
+		if err != nil || amount <= 0 {
+			return nil, fmt.Errorf("entry %q: amount must be a positive number", entry)
+		}
+		pools[label] = contracts.Cost{Amount: amount, Currency: currency}
+	}
+	return pools, nil
+}
+
+// parseLabelLimits parses "key=value=N,..." into active-run limits keyed "key=value".
+func parseLabelLimits(spec string) (map[string]int, error) {
+	limits := make(map[string]int)
+	if spec == "" {
+		return limits, nil
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		i := strings.LastIndex(entry, "=")
+		key, _, ok := strings.Cut(entry[:max(i, 0)], "=")
+		if i < 0 || !ok || key == "" {
+			return nil, fmt.Errorf("entry %q: want key=value=N", entry)
+		}
+		n, err := strconv.Atoi(entry[i+1:])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("entry %q: limit must be a positive integer", entry)
+		}
+		limits[entry[:i]] = n
+	}
+	return limits, nil
+}
+
+// parseMetadataKeys parses "key,..." into metadata keys, skipping empty entries.
+func parseMetadataKeys(spec string) []string {
+	var keys []string
+	for _, key := range strings.Split(spec, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// parseProviderHeaders parses "Name=value,..." into provider headers.
+// Errors name the entry's header, never its value.
+func parseProviderHeaders(spec string) (map[string]string, error) {
+	headers := make(map[string]string)
+	if spec == "" {
+		return headers, nil
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("entry %q: want Name=value", name)
+		}
+		headers[name] = value
+	}
+	if err := api.ValidateProviderHeaders(headers); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
 // mockExecutor is a placeholder executor for testing.
 // In production, this would call an LLM API.
 func mockExecutor(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
@@ -73,7 +254,7 @@ func mockExecutor(ctx context.Context, task *contracts.Task) (*contracts.TaskRes
 		Output: fmt.Sprintf("mock result for task %s", task.ID),
 		Usage: contracts.Usage{
 			Tokens: 100,
-			Cost:   contracts.Cost{Amount: 0.001, Currency: "USD"},
+			Cost:   contracts.Cost{Amount: 0.001}, // no currency: the run's own
 		},
 	}, nil
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// abortCmd: POST /api/v1/runs/{id}/abort and report the run's state
+func abortCmd(args []string) int {
+	fs := flag.NewFlagSet("abort", flag.ContinueOnError)
+	id := fs.String("id", "", "Run ID")
+	addr := fs.String("addr", "http://localhost:8080", "Sidecar address")
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	if *id == "" {
+		return fail(validationError{errors.New("--id is required")})
+	}
+
+	resp, err := http.Post(*addr+"/api/v1/runs/"+*id+"/abort", "application/json", nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return fail(apiError(body, resp.StatusCode))
+	}
+
+	var run runResponse
+	if err := json.Unmarshal(body, &run); err != nil {
+		return fail(fmt.Errorf("parsing response: %w", err))
+	}
+	fmt.Printf("run_id=%s state=%s\n", run.ID, run.State)
+	return exitOK
+}
+
+// abortAllResponse is the POST /api/v1/runs/abort response.
+type abortAllResponse struct {
+	Count int      `json:"count"`
+	Runs  []string `json:"runs"`
+}
+
+// abortAllCmd: POST /api/v1/runs/abort and report the aborted runs
+func abortAllCmd(args []string) int {
+	fs := flag.NewFlagSet("abort-all", flag.ContinueOnError)
+	addr := fs.String("addr", "http://localhost:8080", "Sidecar address")
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	resp, err := http.Post(*addr+"/api/v1/runs/abort", "application/json", nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return fail(apiError(body, resp.StatusCode))
+	}
+
+	var result abortAllResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fail(fmt.Errorf("parsing response: %w", err))
+	}
+	if err := writeAbortAllResult(os.Stdout, result); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+// writeAbortAllResult prints each aborted run ID and the count, or that no
+// run was active.
+func writeAbortAllResult(out io.Writer, result abortAllResponse) error {
+	if result.Count == 0 {
+		_, err := fmt.Fprintln(out, "no active runs to abort")
+		return err
+	}
+	for _, id := range result.Runs {
+		if _, err := fmt.Fprintln(out, id); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(out, "aborted %d runs\n", result.Count)
+	return err
+}
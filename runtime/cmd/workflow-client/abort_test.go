@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAbortCmd(t *testing.T) {
+	var method, path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.Write([]byte(`{"id":"run-1","state":"aborting","created_at":1}`))
+	}))
+	defer srv.Close()
+
+	if code := run([]string{"abort", "--id", "run-1", "--addr", srv.URL}); code != exitOK {
+		t.Fatalf("expected exit 0, got %d", code)
+	}
+	if method != http.MethodPost || path != "/api/v1/runs/run-1/abort" {
+		t.Errorf("unexpected request %s %s", method, path)
+	}
+
+	if code := run([]string{"abort", "--addr", srv.URL}); code != exitValidation {
+		t.Errorf("expected exit %d without --id, got %d", exitValidation, code)
+	}
+
+	notFound := jsonServer(t, http.StatusNotFound, `{"code":"run_not_found","message":"run run-2: run not found"}`)
+	if code := run([]string{"abort", "--id", "run-2", "--addr", notFound.URL}); code == exitOK {
+		t.Error("expected non-zero exit code for an API error")
+	}
+}
+
+func TestWriteAbortAllResult(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeAbortAllResult(&buf, abortAllResponse{Count: 2, Runs: []string{"run-1", "run-2"}}); err != nil {
+		t.Fatalf("writeAbortAllResult: %v", err)
+	}
+	if want := "run-1\nrun-2\naborted 2 runs\n"; buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := writeAbortAllResult(&buf, abortAllResponse{Runs: []string{}}); err != nil {
+		t.Fatalf("writeAbortAllResult: %v", err)
+	}
+	if buf.String() != "no active runs to abort\n" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestAbortAllCmd(t *testing.T) {
+	var method, path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.Write([]byte(`{"count":0,"runs":[]}`))
+	}))
+	defer srv.Close()
+
+	if code := run([]string{"abort-all", "--addr", srv.URL}); code != exitOK {
+		t.Fatalf("expected exit 0 when no run is active, got %d", code)
+	}
+	if method != http.MethodPost || path != "/api/v1/runs/abort" {
+		t.Errorf("unexpected request %s %s", method, path)
+	}
+
+	failing := jsonServer(t, http.StatusInternalServerError, `{"code":"internal_error","message":"boom"}`)
+	if code := run([]string{"abort-all", "--addr", failing.URL}); code == exitOK {
+		t.Error("expected non-zero exit code for an API error")
+	}
+}
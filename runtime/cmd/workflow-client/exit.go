@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/anthropics/claude-workflow/runtime/config"
+)
+
+// Exit codes let scripts distinguish failure modes.
+const (
+	exitOK         = 0 // success
+	exitError      = 1 // generic error (I/O, unexpected response)
+	exitValidation = 2 // invalid flags, workflow config or request rejected by the API
+	exitConnection = 3 // sidecar unreachable
+	exitRunFailed  = 4 // run failed or was aborted
+	exitTimeout    = 5 // request or run timed out
+)
+
+// validationError marks an error caused by invalid flags or workflow config.
+type validationError struct {
+	err error
+}
+
+func (e validationError) Error() string { return e.err.Error() }
+func (e validationError) Unwrap() error { return e.err }
+
+// apiStatusError is an error response returned by the sidecar.
+type apiStatusError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Body       string
+}
+
+func (e *apiStatusError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("[%s] %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// apiError converts an API error body into an error.
+func apiError(body []byte, statusCode int) error {
+	// API returns flat ErrorDTO: {"code":"...","message":"..."}
+	apiErr := &apiStatusError{StatusCode: statusCode, Body: string(body)}
+	var errResp errorDTO
+	if json.Unmarshal(body, &errResp) == nil && errResp.Code != "" {
+		apiErr.Code = errResp.Code
+		apiErr.Message = errResp.Message
+	}
+	return apiErr
+}
+
+// exitCodeFor maps an error to its exit code.
+func exitCodeFor(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var valErr validationError
+	if errors.As(err, &valErr) {
+		return exitValidation
+	}
+
+	var apiErr *apiStatusError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == "timeout" || apiErr.StatusCode == http.StatusGatewayTimeout:
+			return exitTimeout
+		case apiErr.Code == "invalid_input", apiErr.Code == "dag_cycle",
+			apiErr.Code == "dag_invalid", apiErr.Code == "dep_not_found",
+			apiErr.Code == "" && apiErr.StatusCode == http.StatusBadRequest:
+			return exitValidation
+		default:
+			return exitError
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return exitTimeout
+	}
+	// File access is a generic error. Checked before net.Error because a
+	// *fs.PathError can wrap an error that implements it (e.g. a deadline
+	// exceeded on the file), which would otherwise read as a connection failure
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return exitError
+	}
+	// Transport errors from net/http (*url.Error) implement net.Error
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return exitTimeout
+		}
+		return exitConnection
+	}
+	return exitError
+}
+
+// runExitCode maps a run's state to an exit code.
+// Non-terminal and completed runs are a success.
+func runExitCode(run *runResponse) int {
+	switch run.State {
+	case "failed", "aborted":
+		if run.Error != nil && (run.Error.Code == "timeout" || run.Error.Code == "context_timeout") {
+			return exitTimeout
+		}
+		return exitRunFailed
+	default:
+		return exitOK
+	}
+}
+
+// fail prints err to stderr and returns its exit code.
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	return exitCodeFor(err)
+}
+
+// loadConfig loads a workflow config from a file or an http(s) URL, marking
+// parse and validation failures as validation errors. Every validation
+// failure is reported, one per line. File access and fetch errors are
+// returned as is.
+func loadConfig(path string) (*config.WorkflowConfig, error) {
+	loader := &config.Loader{AllErrors: true}
+	if isConfigURL(path) {
+		data, err := fetchConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg, err := loader.LoadFromBytes(data)
+		if err != nil {
+			return nil, validationError{fmt.Errorf("loading config %s: %w", path, err)}
+		}
+		return cfg, nil
+	}
+
+	cfg, err := loader.LoadFromFile(path)
+	if err != nil {
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			return nil, err
+		}
+		return nil, validationError{err}
+	}
+	return cfg, nil
+}
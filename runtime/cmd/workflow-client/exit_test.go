@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// jsonServer returns a server that answers every request with status and body.
+func jsonServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// writeTemp writes content to a file in a temp dir and returns its path.
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	return path
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	request := writeTemp(t, "run.json", `{"id":"run-1"}`)
+	invalidConfig := writeTemp(t, "workflow.json", `{"workflow":{"name":"bad","steps":[]}}`)
+	validConfig := writeTemp(t, "valid.json",
+		`{"workflow":{"name":"ok","type":"custom","steps":[{"id":"a","role":"spec-analyst"}]}}`)
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	unreachable := closed.URL
+	closed.Close()
+
+	tests := []struct {
+		name string
+		args func(t *testing.T) []string
+		want int
+	}{
+		{
+			name: "no subcommand",
+			args: func(t *testing.T) []string { return nil },
+			want: exitValidation,
+		},
+		{
+			name: "unknown subcommand",
+			args: func(t *testing.T) []string { return []string{"frobnicate"} },
+			want: exitValidation,
+		},
+		{
+			name: "unknown flag",
+			args: func(t *testing.T) []string { return []string{"status", "--bogus"} },
+			want: exitValidation,
+		},
+		{
+			name: "missing required flag",
+			args: func(t *testing.T) []string { return []string{"submit"} },
+			want: exitValidation,
+		},
+		{
+			name: "invalid workflow config",
+			args: func(t *testing.T) []string { return []string{"submit-config", "--file", invalidConfig} },
+			want: exitValidation,
+		},
+		{
+			name: "unreadable workflow config",
+			args: func(t *testing.T) []string {
+				return []string{"submit-config", "--file", filepath.Join(t.TempDir(), "missing.json")}
+			},
+			want: exitError,
+		},
+		{
+			name: "file and url",
+			args: func(t *testing.T) []string {
+				return []string{"submit-config", "--file", validConfig, "--url", "http://example.com/w.json"}
+			},
+			want: exitValidation,
+		},
+		{
+			name: "workflow config URL not found",
+			args: func(t *testing.T) []string {
+				srv := jsonServer(t, http.StatusNotFound, `not found`)
+				return []string{"submit-config", "--url", srv.URL + "/workflow.json"}
+			},
+			want: exitError,
+		},
+		{
+			name: "json with show-outputs",
+			args: func(t *testing.T) []string { return []string{"status", "--id", "run-1", "--json", "--show-outputs"} },
+			want: exitValidation,
+		},
+		{
+			name: "full without show-outputs",
+			args: func(t *testing.T) []string { return []string{"status", "--id", "run-1", "--full"} },
+			want: exitValidation,
+		},
+		{
+			name: "unknown models format",
+			args: func(t *testing.T) []string { return []string{"models", "--format", "xml"} },
+			want: exitValidation,
+		},
+		{
+			name: "sidecar unreachable",
+			args: func(t *testing.T) []string {
+				return []string{"submit-config", "--file", validConfig, "--addr", unreachable}
+			},
+			want: exitConnection,
+		},
+		{
+			name: "request rejected",
+			args: func(t *testing.T) []string {
+				srv := jsonServer(t, http.StatusBadRequest, `{"code":"dag_cycle","message":"cycle"}`)
+				return []string{"submit", "--file", request, "--addr", srv.URL}
+			},
+			want: exitValidation,
+		},
+		{
+			name: "run not found",
+			args: func(t *testing.T) []string {
+				srv := jsonServer(t, http.StatusNotFound, `{"code":"run_not_found","message":"no run"}`)
+				return []string{"status", "--id", "run-1", "--addr", srv.URL}
+			},
+			want: exitError,
+		},
+		{
+			name: "request timed out",
+			args: func(t *testing.T) []string {
+				srv := jsonServer(t, http.StatusGatewayTimeout, `{"code":"timeout","message":"deadline"}`)
+				return []string{"status", "--id", "run-1", "--addr", srv.URL}
+			},
+			want: exitTimeout,
+		},
+		{
+			name: "status of running run",
+			args: func(t *testing.T) []string {
+				srv := jsonServer(t, http.StatusOK, `{"id":"run-1","state":"running"}`)
+				return []string{"status", "--id", "run-1", "--addr", srv.URL}
+			},
+			want: exitOK,
+		},
+		{
+			name: "status of failed run",
+			args: func(t *testing.T) []string {
+				srv := jsonServer(t, http.StatusOK,
+					`{"id":"run-1","state":"failed","error":{"code":"task_failed","message":"boom"}}`)
+				return []string{"status", "--id", "run-1", "--addr", srv.URL}
+			},
+			want: exitRunFailed,
+		},
+		{
+			name: "json status of failed run",
+			args: func(t *testing.T) []string {
+				srv := jsonServer(t, http.StatusOK,
+					`{"id":"run-1","state":"failed","error":{"code":"task_failed","message":"boom"}}`)
+				return []string{"status", "--id", "run-1", "--addr", srv.URL, "--json"}
+			},
+			want: exitRunFailed,
+		},
+		{
+			name: "status of timed out run",
+			args: func(t *testing.T) []string {
+				srv := jsonServer(t, http.StatusOK,
+					`{"id":"run-1","state":"failed","error":{"code":"timeout","message":"deadline"}}`)
+				return []string{"status", "--id", "run-1", "--addr", srv.URL}
+			},
+			want: exitTimeout,
+		},
+		{
+			name: "status of run whose context timed out",
+			args: func(t *testing.T) []string {
+				srv := jsonServer(t, http.StatusOK,
+					`{"id":"run-1","state":"failed","error":{"code":"context_timeout","message":"slow summarizer"}}`)
+				return []string{"status", "--id", "run-1", "--addr", srv.URL}
+			},
+			want: exitTimeout,
+		},
+		{
+			name: "streamed run aborted",
+			args: func(t *testing.T) []string {
+				srv := jsonServer(t, http.StatusOK, `{"id":"run-1","state":"aborted"}`)
+				return []string{"submit", "--file", request, "--addr", srv.URL, "--stream"}
+			},
+			want: exitRunFailed,
+		},
+		{
+			name: "streamed run completed",
+			args: func(t *testing.T) []string {
+				srv := jsonServer(t, http.StatusOK, `{"id":"run-1","state":"completed"}`)
+				return []string{"submit", "--file", request, "--addr", srv.URL, "--stream"}
+			},
+			want: exitOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := run(tt.args(t)); got != tt.want {
+				t.Errorf("exit code = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
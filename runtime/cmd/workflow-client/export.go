@@ -0,0 +1,106 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// exportCmd: GET /api/v1/runs/{id}/bundle and unpack it into a directory
+func exportCmd(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	id := fs.String("id", "", "Run ID")
+	addr := fs.String("addr", "http://localhost:8080", "Sidecar address")
+	out := fs.String("out", "", "Output directory (default: run-<id>-bundle)")
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	if *id == "" {
+		return fail(validationError{errors.New("--id is required")})
+	}
+	dir := *out
+	if dir == "" {
+		dir = "run-" + *id + "-bundle"
+	}
+
+	// GET request
+	resp, err := http.Get(*addr + "/api/v1/runs/" + *id + "/bundle")
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fail(err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return fail(apiError(body, resp.StatusCode))
+	}
+
+	files, err := unpackBundle(body, dir)
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("run_id=%s bundle=%s files=%d\n", *id, dir, files)
+	return exitOK
+}
+
+// unpackBundle extracts a ZIP bundle into dir and returns the number of files written.
+// Entries that would escape dir are rejected.
+func unpackBundle(data []byte, dir string) (int, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return 0, fmt.Errorf("reading bundle: %w", err)
+	}
+
+	for _, f := range zr.File {
+		if !filepath.IsLocal(f.Name) {
+			return 0, fmt.Errorf("bundle entry %q escapes output directory", f.Name)
+		}
+	}
+
+	files := 0
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return files, err
+		}
+		if err := extractFile(f, path); err != nil {
+			return files, err
+		}
+		files++
+	}
+	return files, nil
+}
+
+// extractFile writes a single bundle entry to path.
+func extractFile(f *zip.File, path string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, rc); err != nil {
+		dst.Close()
+		return fmt.Errorf("extracting %s: %w", f.Name, err)
+	}
+	return dst.Close()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// zipBundle builds a ZIP archive from name → content entries.
+func zipBundle(t *testing.T, entries map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range entries {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create %s: %v", name, err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExportCmd_UnpacksBundle(t *testing.T) {
+	entries := map[string]string{
+		"run.json":     `{"id":"run-1","state":"completed"}`,
+		"request.json": `{"id":"run-1"}`,
+		"tasks/A.json": `{"id":"A","output":"ok"}`,
+	}
+	bundle := zipBundle(t, entries)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/runs/run-1/bundle" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write(bundle)
+	}))
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), "out")
+	if code := run([]string{"export", "--id", "run-1", "--addr", srv.URL, "--out", dir}); code != exitOK {
+		t.Fatalf("expected exit code %d, got %d", exitOK, code)
+	}
+
+	for name, want := range entries {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("reading %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestExportCmd_RunNotTerminal(t *testing.T) {
+	srv := jsonServer(t, http.StatusConflict, `{"code":"run_not_terminal","message":"run is not in a terminal state"}`)
+
+	dir := filepath.Join(t.TempDir(), "out")
+	if code := run([]string{"export", "--id", "run-1", "--addr", srv.URL, "--out", dir}); code != exitError {
+		t.Errorf("expected exit code %d, got %d", exitError, code)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected no output directory, got err=%v", err)
+	}
+}
+
+func TestUnpackBundle_RejectsEscapingEntries(t *testing.T) {
+	data := zipBundle(t, map[string]string{"../evil.json": "{}"})
+
+	dir := t.TempDir()
+	if _, err := unpackBundle(data, filepath.Join(dir, "out")); err == nil {
+		t.Fatal("expected error for entry outside the output directory")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.json")); !os.IsNotExist(err) {
+		t.Errorf("expected escaping entry not to be written, got err=%v", err)
+	}
+}
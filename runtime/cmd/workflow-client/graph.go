@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/anthropics/claude-workflow/runtime/config"
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+	"github.com/anthropics/claude-workflow/runtime/internal/orchestration"
+)
+
+// graphNode is a task rendered by the graph subcommand.
+type graphNode struct {
+	ID     string
+	Detail string // second label line: step role, or task state for a run
+	Deps   []string
+}
+
+// graphRunResponse is the part of GET /api/v1/runs/{id}?expand=dag the graph
+// subcommand reads.
+type graphRunResponse struct {
+	ID    string                   `json:"id"`
+	Tasks map[string]taskStatusDTO `json:"tasks"`
+	DAG   map[string]struct {
+		Deps []string `json:"deps"`
+	} `json:"dag"`
+}
+
+// graphCmd: render a workflow config or a submitted run as Graphviz DOT
+func graphCmd(args []string) int {
+	fs := flag.NewFlagSet("graph", flag.ContinueOnError)
+	file := fs.String("file", "", "Workflow config JSON/YAML file path or http(s) URL")
+	id := fs.String("id", "", "Run ID (render a submitted run instead of a config)")
+	addr := fs.String("addr", "http://localhost:8080", "Sidecar address (with --id)")
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	var (
+		name  string
+		nodes []graphNode
+		err   error
+	)
+	switch {
+	case *file != "" && *id != "":
+		return fail(validationError{errors.New("--file and --id are mutually exclusive")})
+	case *file != "":
+		var cfg *config.WorkflowConfig
+		if cfg, err = loadConfig(*file); err != nil {
+			return fail(err)
+		}
+		if _, err = configWarnings(cfg); err != nil {
+			return fail(err)
+		}
+		name = cfg.Workflow.Name
+		nodes, err = configGraph(cfg)
+	case *id != "":
+		name = *id
+		nodes, err = fetchRunGraph(*addr, *id)
+	default:
+		return fail(validationError{errors.New("--file or --id is required")})
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	writeDOT(os.Stdout, name, nodes)
+	return exitOK
+}
+
+// configGraph builds the DAG of the tasks submit-config would send and
+// returns its nodes labeled by role. Disabled steps are left out, as they
+// are not submitted.
+func configGraph(cfg *config.WorkflowConfig) ([]graphNode, error) {
+	req := convertWorkflowConfig(cfg, cfg.Workflow.Name)
+	tasks := make([]contracts.Task, len(req.Tasks))
+	roles := make(map[contracts.TaskID]string, len(req.Tasks))
+	for i, task := range req.Tasks {
+		tasks[i] = contracts.Task{ID: contracts.TaskID(task.ID)}
+		for _, dep := range task.Deps {
+			tasks[i].Deps = append(tasks[i].Deps, contracts.TaskID(dep))
+		}
+		roles[tasks[i].ID] = task.Metadata["role"]
+	}
+
+	resolver := orchestration.NewDependencyResolver()
+	dag, err := resolver.BuildDAG(tasks)
+	if err != nil {
+		return nil, validationError{err}
+	}
+	if err := resolver.Validate(dag); err != nil {
+		return nil, validationError{err}
+	}
+
+	nodes := make([]graphNode, 0, len(dag.Nodes))
+	for id, node := range dag.Nodes {
+		nodes = append(nodes, graphNode{ID: string(id), Detail: roles[id], Deps: taskIDStrings(node.Deps)})
+	}
+	return nodes, nil
+}
+
+// fetchRunGraph reads a run's DAG from the sidecar and returns its nodes
+// labeled by task state.
+func fetchRunGraph(addr, id string) ([]graphNode, error) {
+	resp, err := http.Get(addr + "/api/v1/runs/" + id + "?expand=dag")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return nil, apiError(body, resp.StatusCode)
+	}
+
+	var run graphRunResponse
+	if err := json.Unmarshal(body, &run); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	nodes := make([]graphNode, 0, len(run.DAG))
+	for taskID, node := range run.DAG {
+		nodes = append(nodes, graphNode{ID: taskID, Detail: run.Tasks[taskID].State, Deps: node.Deps})
+	}
+	return nodes, nil
+}
+
+// writeDOT writes nodes as a Graphviz digraph, one edge per dependency
+// pointing from the dependency to its dependent. Nodes are sorted by ID so
+// the output is stable.
+func writeDOT(out io.Writer, name string, nodes []graphNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	fmt.Fprintf(out, "digraph %s {\n", dotQuote(name))
+	fmt.Fprintf(out, "  rankdir=LR;\n")
+	fmt.Fprintf(out, "  node [shape=box];\n")
+	for _, n := range nodes {
+		label := dotEscape(n.ID)
+		if n.Detail != "" {
+			label += `\n` + dotEscape(n.Detail)
+		}
+		fmt.Fprintf(out, "  %s [label=\"%s\"];\n", dotQuote(n.ID), label)
+	}
+	for _, n := range nodes {
+		for _, dep := range n.Deps {
+			fmt.Fprintf(out, "  %s -> %s;\n", dotQuote(dep), dotQuote(n.ID))
+		}
+	}
+	fmt.Fprintf(out, "}\n")
+}
+
+// dotQuote returns s as a quoted DOT ID.
+func dotQuote(s string) string {
+	return `"` + dotEscape(s) + `"`
+}
+
+// dotEscape escapes backslashes and double quotes for a quoted DOT string.
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// taskIDStrings converts task IDs to strings.
+func taskIDStrings(ids []contracts.TaskID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = string(id)
+	}
+	return out
+}
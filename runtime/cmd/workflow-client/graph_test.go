@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/claude-workflow/runtime/config"
+)
+
+func TestConfigGraph_DOT(t *testing.T) {
+	path := writeTemp(t, "workflow.yaml", `
+workflow:
+  name: feature-dev
+  type: custom
+  steps:
+    - id: analyze
+      role: spec-analyst
+      outputs: [spec.md]
+    - id: build
+      role: spec-developer
+      depends_on: [analyze]
+      outputs: [code]
+    - id: lint
+      role: spec-validator
+      depends_on: [build]
+      disabled: true
+    - id: review
+      role: spec-reviewer
+      depends_on: [analyze, lint]
+      outputs: [review.md]
+`)
+	cfg, err := config.NewLoader().LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	nodes, err := configGraph(cfg)
+	if err != nil {
+		t.Fatalf("configGraph: %v", err)
+	}
+
+	var out bytes.Buffer
+	writeDOT(&out, cfg.Workflow.Name, nodes)
+	dot := out.String()
+
+	for _, want := range []string{
+		`digraph "feature-dev" {`,
+		`"analyze" [label="analyze\nspec-analyst"];`,
+		`"build" [label="build\nspec-developer"];`,
+		`"review" [label="review\nspec-reviewer"];`,
+		`"analyze" -> "build";`,
+		`"analyze" -> "review";`,
+		// The disabled step is bypassed: review inherits its dependency
+		`"build" -> "review";`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT missing %q:\n%s", want, dot)
+		}
+	}
+	if strings.Contains(dot, "lint") {
+		t.Errorf("DOT contains disabled step:\n%s", dot)
+	}
+	if !strings.HasSuffix(dot, "}\n") {
+		t.Errorf("DOT not closed:\n%s", dot)
+	}
+}
+
+func TestWriteDOT_Escapes(t *testing.T) {
+	var out bytes.Buffer
+	writeDOT(&out, `say "hi"`, []graphNode{{ID: `a\b`, Detail: `x"y`}})
+	want := "digraph \"say \\\"hi\\\"\" {\n" +
+		"  rankdir=LR;\n" +
+		"  node [shape=box];\n" +
+		"  \"a\\\\b\" [label=\"a\\\\b\\nx\\\"y\"];\n" +
+		"}\n"
+	if out.String() != want {
+		t.Errorf("unexpected DOT:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestFetchRunGraph(t *testing.T) {
+	srv := jsonServer(t, http.StatusOK, `{
+		"id": "run-1",
+		"state": "running",
+		"tasks": {"A": {"state": "completed"}, "B": {"state": "running"}},
+		"dag": {"A": {"deps": [], "next": ["B"]}, "B": {"deps": ["A"], "next": []}}
+	}`)
+
+	nodes, err := fetchRunGraph(srv.URL, "run-1")
+	if err != nil {
+		t.Fatalf("fetchRunGraph: %v", err)
+	}
+	var out bytes.Buffer
+	writeDOT(&out, "run-1", nodes)
+	for _, want := range []string{
+		`"A" [label="A\ncompleted"];`,
+		`"B" [label="B\nrunning"];`,
+		`"A" -> "B";`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("DOT missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestGraphCmd_Flags(t *testing.T) {
+	if code := run([]string{"graph"}); code != exitValidation {
+		t.Errorf("expected exit %d without --file or --id, got %d", exitValidation, code)
+	}
+	if code := run([]string{"graph", "--file", "w.json", "--id", "run-1"}); code != exitValidation {
+		t.Errorf("expected exit %d with both --file and --id, got %d", exitValidation, code)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// runSummary is one run in the GET /api/v1/runs response.
+type runSummary struct {
+	ID        string `json:"id"`
+	State     string `json:"state"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at,omitempty"`
+	Usage     *struct {
+		Tokens int64    `json:"tokens"`
+		Cost   *costDTO `json:"cost,omitempty"`
+	} `json:"usage,omitempty"`
+}
+
+// listCmd: GET /api/v1/runs and print one line per run
+func listCmd(args []string) int {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	addr := fs.String("addr", "http://localhost:8080", "Sidecar address")
+	state := fs.String("state", "", "Only list runs in this state (e.g. running, failed)")
+	limit := fs.Int("limit", 50, "Maximum number of runs to list (1-1000)")
+	offset := fs.Int("offset", 0, "Number of runs to skip")
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	query := url.Values{}
+	if *state != "" {
+		query.Set("state", *state)
+	}
+	query.Set("limit", strconv.Itoa(*limit))
+	query.Set("offset", strconv.Itoa(*offset))
+
+	resp, err := http.Get(*addr + "/api/v1/runs?" + query.Encode())
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return fail(apiError(body, resp.StatusCode))
+	}
+
+	var runs []runSummary
+	if err := json.Unmarshal(body, &runs); err != nil {
+		return fail(fmt.Errorf("parsing response: %w", err))
+	}
+	total, _ := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+
+	if err := writeRunList(os.Stdout, runs, *offset, total); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+// writeRunList prints runs as a table followed by the page position.
+func writeRunList(out io.Writer, runs []runSummary, offset, total int) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\tSTATE\tCREATED\tTOKENS\tCOST\n")
+	for _, r := range runs {
+		created := time.UnixMilli(r.CreatedAt).UTC().Format(time.RFC3339)
+		tokens, cost := "0", "-"
+		if r.Usage != nil {
+			tokens = strconv.FormatInt(r.Usage.Tokens, 10)
+			if r.Usage.Cost != nil {
+				cost = fmt.Sprintf("%.4f %s", r.Usage.Cost.Amount, r.Usage.Cost.Currency)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.ID, r.State, created, tokens, cost)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(runs) == 0 {
+		_, err := fmt.Fprintf(out, "no runs (total %d)\n", total)
+		return err
+	}
+	_, err := fmt.Fprintf(out, "runs %d-%d of %d\n", offset+1, offset+len(runs), total)
+	return err
+}
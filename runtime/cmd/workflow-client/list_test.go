@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteRunList(t *testing.T) {
+	runs := []runSummary{
+		{ID: "run-b", State: "running", CreatedAt: 1700000001000},
+		{ID: "run-a", State: "completed", CreatedAt: 1700000000000},
+	}
+	runs[1].Usage = &struct {
+		Tokens int64    `json:"tokens"`
+		Cost   *costDTO `json:"cost,omitempty"`
+	}{Tokens: 120, Cost: &costDTO{Amount: 0.012, Currency: "USD"}}
+
+	var buf bytes.Buffer
+	if err := writeRunList(&buf, runs, 10, 12); err != nil {
+		t.Fatalf("writeRunList: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"ID", "run-b", "running", "2023-11-14T22:13:21Z",
+		"run-a", "120", "0.0120 USD", "runs 11-12 of 12",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestListCmd(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Header().Set("X-Total-Count", "1")
+		w.Write([]byte(`[{"id":"run-a","state":"failed","created_at":1700000000000}]`))
+	}))
+	defer srv.Close()
+
+	if code := run([]string{"list", "--addr", srv.URL, "--state", "failed", "--limit", "5"}); code != exitOK {
+		t.Fatalf("expected exit 0, got %d", code)
+	}
+	if query != "limit=5&offset=0&state=failed" {
+		t.Errorf("unexpected query %q", query)
+	}
+
+	bad := jsonServer(t, http.StatusBadRequest, `{"code":"invalid_input","message":"unknown state"}`)
+	if code := run([]string{"list", "--addr", bad.URL, "--state", "bogus"}); code != exitValidation {
+		t.Errorf("expected validation exit code, got %d", code)
+	}
+}
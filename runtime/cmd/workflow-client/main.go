@@ -3,14 +3,18 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/anthropics/claude-workflow/runtime/config"
 )
@@ -36,100 +40,172 @@ const (
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run dispatches a subcommand and returns the process exit code.
+func run(args []string) int {
+	if len(args) < 1 {
 		printUsage()
-		os.Exit(1)
+		return exitValidation
 	}
 
-	switch os.Args[1] {
+	switch args[0] {
 	case "submit":
-		submitCmd(os.Args[2:])
+		return submitCmd(args[1:])
 	case "submit-config":
-		submitConfigCmd(os.Args[2:])
+		return submitConfigCmd(args[1:])
+	case "validate":
+		return validateCmd(args[1:])
 	case "status":
-		statusCmd(os.Args[2:])
+		return statusCmd(args[1:])
+	case "list":
+		return listCmd(args[1:])
+	case "watch":
+		return watchCmd(args[1:])
+	case "prune":
+		return pruneCmd(args[1:])
+	case "abort":
+		return abortCmd(args[1:])
+	case "abort-all":
+		return abortAllCmd(args[1:])
+	case "models":
+		return modelsCmd(args[1:])
+	case "export":
+		return exportCmd(args[1:])
+	case "graph":
+		return graphCmd(args[1:])
 	default:
 		printUsage()
-		os.Exit(1)
+		return exitValidation
 	}
 }
 
 func printUsage() {
 	fmt.Fprintf(os.Stderr, `Usage:
-  workflow-client submit --file <path> --addr <url>
-  workflow-client submit-config --file <workflow.json> [--addr <url>] [--run-id <id>]
-  workflow-client status --id <run-id> --addr <url>
+  workflow-client submit --file <path> --addr <url> [--stream]
+  workflow-client submit-config --file <workflow.json|yaml|url> | --url <url> [--addr <url>] [--run-id <id> | --unique-id] [--stream]
+  workflow-client validate --file <workflow.json|yaml|url>
+  workflow-client status --id <run-id> --addr <url> [--show-outputs [--full] | --json]
+  workflow-client list [--addr <url>] [--state <state>] [--limit <n>] [--offset <n>]
+  workflow-client watch --id <run-id> [--addr <url>]
+  workflow-client prune [--addr <url>] [--retention <duration>] [--dry-run]
+  workflow-client abort --id <run-id> [--addr <url>]
+  workflow-client abort-all [--addr <url>]
+  workflow-client models [--file <workflow.json|yaml|url>] [--format text|json]
+  workflow-client export --id <run-id> [--addr <url>] [--out <dir>]
+  workflow-client graph --file <workflow.json|yaml|url> | --id <run-id> [--addr <url>]
+
+Exit codes:
+  0  success
+  1  generic error
+  2  validation error (flags, workflow config, or request rejected by the API)
+  3  connection error (sidecar unreachable)
+  4  run failed or was aborted (status, --stream)
+  5  timeout (request or run timed out)
 `)
 }
 
+// flagExitCode returns the exit code for a flag parsing error.
+// The flag set has already printed the error and usage.
+func flagExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitValidation
+}
+
 // submitCmd: POST /api/v1/runs
-func submitCmd(args []string) {
-	fs := flag.NewFlagSet("submit", flag.ExitOnError)
+func submitCmd(args []string) int {
+	fs := flag.NewFlagSet("submit", flag.ContinueOnError)
 	file := fs.String("file", "", "JSON file path (StartRunRequest)")
 	addr := fs.String("addr", "http://localhost:8080", "Sidecar address")
-	fs.Parse(args)
+	stream := fs.Bool("stream", false, "Stream run progress until terminal state")
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
 
 	if *file == "" {
-		fmt.Fprintln(os.Stderr, "error: --file is required")
-		os.Exit(1)
+		return fail(validationError{errors.New("--file is required")})
 	}
 
 	// Read JSON file
 	data, err := os.ReadFile(*file)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		return fail(err)
 	}
 
 	// POST request
 	resp, err := http.Post(*addr+"/api/v1/runs", "application/json", bytes.NewReader(data))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		return fail(err)
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode >= 400 {
-		printAPIError(body, resp.StatusCode)
-		os.Exit(1)
+		return fail(apiError(body, resp.StatusCode))
 	}
 
 	// Parse response
 	var run runResponse
 	if err := json.Unmarshal(body, &run); err != nil {
-		fmt.Fprintf(os.Stderr, "error parsing response: %v\n", err)
-		os.Exit(1)
+		return fail(fmt.Errorf("parsing response: %w", err))
 	}
 
 	fmt.Printf("run_id=%s state=%s\n", run.ID, run.State)
+	printWarnings(run.Warnings)
+
+	if *stream {
+		return followRun(*addr, run.ID)
+	}
+	return exitOK
 }
 
 // submitConfigCmd: convert WorkflowConfig → StartRunRequest and POST /api/v1/runs
-func submitConfigCmd(args []string) {
-	fs := flag.NewFlagSet("submit-config", flag.ExitOnError)
-	file := fs.String("file", "", "Workflow config JSON file path")
+func submitConfigCmd(args []string) int {
+	fs := flag.NewFlagSet("submit-config", flag.ContinueOnError)
+	file := fs.String("file", "", "Workflow config JSON/YAML file path or http(s) URL")
+	url := fs.String("url", "", "Workflow config http(s) URL")
 	addr := fs.String("addr", "http://localhost:8080", "Sidecar address")
 	runID := fs.String("run-id", "", "Override run ID (default: workflow.name)")
-	fs.Parse(args)
+	uniqueID := fs.Bool("unique-id", false, "Suffix the workflow.name run ID with a timestamp and short hash, so resubmissions don't collide")
+	stream := fs.Bool("stream", false, "Stream run progress until terminal state")
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
 
-	if *file == "" {
-		fmt.Fprintln(os.Stderr, "error: --file is required")
-		os.Exit(1)
+	switch {
+	case *runID != "" && *uniqueID:
+		return fail(validationError{errors.New("--run-id and --unique-id are mutually exclusive")})
+	case *file != "" && *url != "":
+		return fail(validationError{errors.New("--file and --url are mutually exclusive")})
+	case *url != "" && !isConfigURL(*url):
+		return fail(validationError{fmt.Errorf("--url %q must be an http:// or https:// URL", *url)})
+	case *url != "":
+		*file = *url
+	case *file == "":
+		return fail(validationError{errors.New("--file or --url is required")})
 	}
 
 	// Load and validate workflow config
-	loader := config.NewLoader()
-	cfg, err := loader.LoadFromFile(*file)
+	cfg, err := loadConfig(*file)
+	if err != nil {
+		return fail(err)
+	}
+	warnings, err := configWarnings(cfg)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		return fail(err)
 	}
+	printConfigWarnings(os.Stderr, warnings)
 
 	// Determine run ID
 	id := *runID
-	if id == "" {
+	switch {
+	case *uniqueID:
+		id = uniqueRunID(cfg.Workflow.Name, time.Now())
+	case id == "":
 		id = cfg.Workflow.Name
 	}
 
@@ -139,41 +215,74 @@ func submitConfigCmd(args []string) {
 	// Marshal to JSON
 	data, err := json.Marshal(req)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		return fail(err)
 	}
 
 	// POST request
 	resp, err := http.Post(*addr+"/api/v1/runs", "application/json", bytes.NewReader(data))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		return fail(err)
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode >= 400 {
-		printAPIError(body, resp.StatusCode)
-		os.Exit(1)
+		err := apiError(body, resp.StatusCode)
+		if *runID == "" && !*uniqueID {
+			err = withRunIDHint(err, id)
+		}
+		return fail(err)
 	}
 
 	// Parse response
 	var run runResponse
 	if err := json.Unmarshal(body, &run); err != nil {
-		fmt.Fprintf(os.Stderr, "error parsing response: %v\n", err)
-		os.Exit(1)
+		return fail(fmt.Errorf("parsing response: %w", err))
 	}
 
 	fmt.Printf("run_id=%s state=%s\n", run.ID, run.State)
+	printWarnings(run.Warnings)
+
+	if *stream {
+		return followRun(*addr, run.ID)
+	}
+	return exitOK
+}
+
+// uniqueRunID returns name suffixed with now (UTC, to the second) and a short
+// hash of name and now, e.g. "feature-dev-20250101T120000-1a2b3c".
+func uniqueRunID(name string, now time.Time) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s/%d", name, now.UnixNano()))
+	return fmt.Sprintf("%s-%s-%x", name, now.UTC().Format("20060102T150405"), sum[:3])
+}
+
+// withRunIDHint adds a hint to a run_exists (409) error for a run ID that
+// defaulted to workflow.name. Other errors are returned as is.
+func withRunIDHint(err error, id string) error {
+	var apiErr *apiStatusError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict || apiErr.Code != "run_exists" {
+		return err
+	}
+	return fmt.Errorf("%w\nhint: run ID %q defaults to workflow.name; pass --run-id <id> or --unique-id to submit it again", err, id)
 }
 
 // convertWorkflowConfig converts a WorkflowConfig to StartRunRequest.
 func convertWorkflowConfig(cfg *config.WorkflowConfig, runID string) *startRunRequest {
 	tasks := make([]taskDTO, 0, len(cfg.Workflow.Steps))
+	deps := effectiveDeps(cfg.Workflow.Steps)
 
 	for _, step := range cfg.Workflow.Steps {
-		model := getModelForRole(cfg, step.Role)
+		// Disabled steps are not submitted; dependents inherit their deps.
+		// Skipped steps are submitted skipped, so they show in the run.
+		if step.Disabled {
+			continue
+		}
+
+		model := step.Model
+		if model == "" {
+			model = getModelForRole(cfg, step.Role)
+		}
 
 		// Build metadata
 		metadata := map[string]string{
@@ -183,13 +292,31 @@ func convertWorkflowConfig(cfg *config.WorkflowConfig, runID string) *startRunRe
 			outputsJSON, _ := json.Marshal(step.Outputs)
 			metadata["outputs"] = string(outputsJSON)
 		}
+		// The runtime has no per-task context policy or budget share;
+		// pass them to executors as metadata
+		if step.ContextPolicy != nil {
+			policyJSON, _ := json.Marshal(step.ContextPolicy)
+			metadata["context_policy"] = string(policyJSON)
+		}
+		if step.BudgetShare > 0 {
+			metadata["budget_share"] = strconv.FormatFloat(step.BudgetShare, 'g', -1, 64)
+		}
 
 		task := taskDTO{
 			ID:       step.ID,
 			Prompt:   fmt.Sprintf("Execute %s step: %s", step.Role, step.ID),
 			Model:    model,
-			Deps:     step.DependsOn,
+			Deps:     deps[step.ID],
 			Metadata: metadata,
+			RetryOn:  step.RetryOn,
+
+			ConcurrencyKey: step.ConcurrencyKey,
+			ContinueOnFail: step.ContinueOnFail,
+			TimeoutMs:      step.TimeoutMs,
+			Skip:           step.Skip,
+		}
+		if step.Retry != nil {
+			task.Retry = &retryDTO{MaxAttempts: step.Retry.MaxAttempts, BackoffMs: step.Retry.BackoffMs}
 		}
 		tasks = append(tasks, task)
 	}
@@ -212,6 +339,7 @@ func convertWorkflowConfig(cfg *config.WorkflowConfig, runID string) *startRunRe
 		if cfg.Workflow.Policy.MaxParallelism > 0 {
 			policy.MaxParallelism = cfg.Workflow.Policy.MaxParallelism
 		}
+		policy.ConcurrencyLimits = cfg.Workflow.Policy.ConcurrencyLimits
 		if cfg.Workflow.Policy.BudgetLimit != nil {
 			if cfg.Workflow.Policy.BudgetLimit.Amount > 0 {
 				policy.BudgetLimit.Amount = cfg.Workflow.Policy.BudgetLimit.Amount
@@ -222,11 +350,55 @@ func convertWorkflowConfig(cfg *config.WorkflowConfig, runID string) *startRunRe
 		}
 	}
 
+	// Link the run to the exact config; Hash only fails on nil/unmarshalable config
+	configHash, _ := config.Hash(cfg)
+
 	return &startRunRequest{
-		ID:     runID,
-		Policy: policy,
-		Tasks:  tasks,
+		ID:         runID,
+		Policy:     policy,
+		Tasks:      tasks,
+		ConfigHash: configHash,
+	}
+}
+
+// effectiveDeps returns the dependencies of each step with disabled steps
+// replaced by their own effective dependencies, so ordering is preserved
+// when a disabled step is removed from the chain.
+// Assumes steps were validated (no cycles, all deps exist).
+func effectiveDeps(steps []config.Step) map[string][]string {
+	byID := make(map[string]config.Step, len(steps))
+	for _, step := range steps {
+		byID[step.ID] = step
+	}
+
+	result := make(map[string][]string, len(steps))
+	var resolve func(id string) []string
+	resolve = func(id string) []string {
+		if deps, ok := result[id]; ok {
+			return deps
+		}
+		var deps []string
+		seen := make(map[string]bool)
+		for _, depID := range byID[id].DependsOn {
+			candidates := []string{depID}
+			if byID[depID].Disabled {
+				candidates = resolve(depID)
+			}
+			for _, c := range candidates {
+				if !seen[c] {
+					seen[c] = true
+					deps = append(deps, c)
+				}
+			}
+		}
+		result[id] = deps
+		return deps
 	}
+
+	for _, step := range steps {
+		resolve(step.ID)
+	}
+	return result
 }
 
 // getModelForRole resolves model for a role with fallback chain:
@@ -234,56 +406,112 @@ func convertWorkflowConfig(cfg *config.WorkflowConfig, runID string) *startRunRe
 // 2. roleToModel[role] (CLI default)
 // 3. defaultModel + warning
 func getModelForRole(cfg *config.WorkflowConfig, role string) string {
-	// 1. Check config models
-	if cfg.Workflow.Models != nil {
-		if model, ok := cfg.Workflow.Models[role]; ok {
-			return model
-		}
+	model, source := resolveModel(cfg, role)
+	if source == modelSourceFallback {
+		fmt.Fprintf(os.Stderr, "warning: unknown role %q, using default model\n", role)
 	}
-	// 2. Check CLI fallback
-	if model, ok := roleToModel[role]; ok {
-		return model
-	}
-	// 3. Default + warning
-	fmt.Fprintf(os.Stderr, "warning: unknown role %q, using default model\n", role)
-	return defaultModel
+	return model
 }
 
 // statusCmd: GET /api/v1/runs/{id}
-func statusCmd(args []string) {
-	fs := flag.NewFlagSet("status", flag.ExitOnError)
+func statusCmd(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
 	id := fs.String("id", "", "Run ID")
 	addr := fs.String("addr", "http://localhost:8080", "Sidecar address")
-	fs.Parse(args)
+	showOutputs := fs.Bool("show-outputs", false, "Print each completed task's output")
+	full := fs.Bool("full", false, "With --show-outputs, print outputs without truncation")
+	asJSON := fs.Bool("json", false, "Print the full run response as indented JSON")
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
 
 	if *id == "" {
-		fmt.Fprintln(os.Stderr, "error: --id is required")
-		os.Exit(1)
+		return fail(validationError{errors.New("--id is required")})
+	}
+	if *full && !*showOutputs {
+		return fail(validationError{errors.New("--full requires --show-outputs")})
+	}
+	if *asJSON && *showOutputs {
+		return fail(validationError{errors.New("--json already includes outputs; drop --show-outputs")})
 	}
 
 	// GET request
 	resp, err := http.Get(*addr + "/api/v1/runs/" + *id)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		return fail(err)
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode >= 400 {
-		printAPIError(body, resp.StatusCode)
-		os.Exit(1)
+		return fail(apiError(body, resp.StatusCode))
 	}
 
 	// Parse response
 	var run runResponse
 	if err := json.Unmarshal(body, &run); err != nil {
-		fmt.Fprintf(os.Stderr, "error parsing response: %v\n", err)
-		os.Exit(1)
+		return fail(fmt.Errorf("parsing response: %w", err))
 	}
 
-	fmt.Printf("run_id=%s state=%s\n", run.ID, run.State)
+	if *asJSON {
+		if err := writeIndentedJSON(os.Stdout, body); err != nil {
+			return fail(err)
+		}
+		return runExitCode(&run)
+	}
+
+	printRunSummary(os.Stdout, &run)
+	if *showOutputs {
+		printTaskOutputs(os.Stdout, &run, *full)
+	}
+	return runExitCode(&run)
+}
+
+// writeIndentedJSON writes a JSON response body indented, keeping every
+// field the sidecar sent.
+func writeIndentedJSON(out io.Writer, body []byte) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(body), "", "  "); err != nil {
+		return fmt.Errorf("formatting response: %w", err)
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(out)
+	return err
+}
+
+// outputPreviewRunes is how much of each output status --show-outputs prints without --full.
+const outputPreviewRunes = 500
+
+// printTaskOutputs prints the output of each completed task, sorted by task ID.
+// Unless full is set, outputs longer than outputPreviewRunes are truncated.
+func printTaskOutputs(out io.Writer, run *runResponse, full bool) {
+	taskIDs := make([]string, 0, len(run.Tasks))
+	for id, task := range run.Tasks {
+		if task.State == "completed" {
+			taskIDs = append(taskIDs, id)
+		}
+	}
+	sort.Strings(taskIDs)
+
+	for _, id := range taskIDs {
+		output := run.Tasks[id].Output
+		fmt.Fprintf(out, "--- %s output ---\n", id)
+		if runes := []rune(output); !full && len(runes) > outputPreviewRunes {
+			fmt.Fprintf(out, "%s\n... (truncated, %d of %d characters shown; use --full)\n",
+				string(runes[:outputPreviewRunes]), outputPreviewRunes, len(runes))
+			continue
+		}
+		fmt.Fprintln(out, output)
+	}
+}
+
+// printRunSummary prints the run state, task summary and run-level error.
+func printRunSummary(out io.Writer, run *runResponse) {
+	fmt.Fprintf(out, "run_id=%s state=%s\n", run.ID, run.State)
+	if run.ConfigHash != "" {
+		fmt.Fprintf(out, "config_hash=%s\n", run.ConfigHash)
+	}
 
 	// Print tasks summary (with error codes for failed tasks)
 	if len(run.Tasks) > 0 {
@@ -303,36 +531,29 @@ func statusCmd(args []string) {
 				parts = append(parts, fmt.Sprintf("%s=%s", id, task.State))
 			}
 		}
-		fmt.Printf("tasks: %s\n", strings.Join(parts, ", "))
+		fmt.Fprintf(out, "tasks: %s\n", strings.Join(parts, ", "))
 	}
 
 	// Print run-level error if present
 	if run.Error != nil {
-		fmt.Printf("error: [%s] %s\n", run.Error.Code, run.Error.Message)
-	}
-}
-
-func printAPIError(body []byte, statusCode int) {
-	// API returns flat ErrorDTO: {"code":"...","message":"..."}
-	var errResp errorDTO
-	if json.Unmarshal(body, &errResp) == nil && errResp.Code != "" {
-		fmt.Fprintf(os.Stderr, "error: [%s] %s\n", errResp.Code, errResp.Message)
-	} else {
-		fmt.Fprintf(os.Stderr, "error: HTTP %d: %s\n", statusCode, string(body))
+		fmt.Fprintf(out, "error: [%s] %s\n", run.Error.Code, run.Error.Message)
 	}
 }
 
 // runResponse mirrors api.RunResponse (minimal fields)
 type runResponse struct {
-	ID    string                   `json:"id"`
-	State string                   `json:"state"`
-	Tasks map[string]taskStatusDTO `json:"tasks,omitempty"`
-	Error *errorDTO                `json:"error,omitempty"`
+	ID         string                   `json:"id"`
+	State      string                   `json:"state"`
+	Tasks      map[string]taskStatusDTO `json:"tasks,omitempty"`
+	Error      *errorDTO                `json:"error,omitempty"`
+	ConfigHash string                   `json:"config_hash,omitempty"`
+	Warnings   []string                 `json:"warnings,omitempty"`
 }
 
 type taskStatusDTO struct {
-	State string    `json:"state"`
-	Error *errorDTO `json:"error,omitempty"`
+	State  string    `json:"state"`
+	Output string    `json:"output,omitempty"`
+	Error  *errorDTO `json:"error,omitempty"`
 }
 
 type errorDTO struct {
@@ -342,15 +563,18 @@ type errorDTO struct {
 
 // Request DTOs for submit-config
 type startRunRequest struct {
-	ID     string    `json:"id,omitempty"`
-	Policy policyDTO `json:"policy"`
-	Tasks  []taskDTO `json:"tasks"`
+	ID         string    `json:"id,omitempty"`
+	Policy     policyDTO `json:"policy"`
+	Tasks      []taskDTO `json:"tasks"`
+	ConfigHash string    `json:"config_hash,omitempty"`
 }
 
 type policyDTO struct {
 	TimeoutMs      int64   `json:"timeout_ms"`
 	MaxParallelism int     `json:"max_parallelism"`
 	BudgetLimit    costDTO `json:"budget_limit"`
+
+	ConcurrencyLimits map[string]int `json:"concurrency_limits,omitempty"`
 }
 
 type costDTO struct {
@@ -364,4 +588,16 @@ type taskDTO struct {
 	Model    string            `json:"model"`
 	Deps     []string          `json:"deps,omitempty"`
 	Metadata map[string]string `json:"metadata,omitempty"`
+	RetryOn  []string          `json:"retry_on,omitempty"`
+
+	ConcurrencyKey string    `json:"concurrency_key,omitempty"`
+	Retry          *retryDTO `json:"retry,omitempty"`
+	ContinueOnFail bool      `json:"continue_on_fail,omitempty"`
+	TimeoutMs      int64     `json:"timeout_ms,omitempty"`
+	Skip           bool      `json:"skip,omitempty"`
+}
+
+type retryDTO struct {
+	MaxAttempts int   `json:"max_attempts"`
+	BackoffMs   int64 `json:"backoff_ms,omitempty"`
 }
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/claude-workflow/runtime/config"
+)
+
+func TestConvertWorkflowConfig_SkipsDisabledSteps(t *testing.T) {
+	cfg := &config.WorkflowConfig{
+		Workflow: config.Workflow{
+			Name: "disabled-flow",
+			Type: config.WorkflowTypeCustom,
+			Steps: []config.Step{
+				{ID: "a", Role: "spec-analyst"},
+				{ID: "b", Role: "spec-architect", DependsOn: []string{"a"}, Disabled: true},
+				{ID: "c", Role: "spec-developer", DependsOn: []string{"b"}},
+			},
+		},
+	}
+
+	req := convertWorkflowConfig(cfg, "run-1")
+
+	if len(req.Tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %d", len(req.Tasks))
+	}
+	if req.Tasks[0].ID != "a" || req.Tasks[1].ID != "c" {
+		t.Errorf("expected tasks [a c], got [%s %s]", req.Tasks[0].ID, req.Tasks[1].ID)
+	}
+	if !reflect.DeepEqual(req.Tasks[1].Deps, []string{"a"}) {
+		t.Errorf("expected c to inherit deps [a], got %v", req.Tasks[1].Deps)
+	}
+}
+
+func TestConvertWorkflowConfig_SubmitsSkippedSteps(t *testing.T) {
+	cfg, err := config.NewLoader().LoadFromBytes([]byte(`{
+		"workflow": {
+			"name": "toggled",
+			"type": "spec-default",
+			"optional_enabled": ["spec-reviewer"],
+			"optional_mode": "skip",
+			"steps": [
+				{"id": "analysis", "role": "spec-analyst"},
+				{"id": "architecture", "role": "spec-architect", "depends_on": ["analysis"]},
+				{"id": "implementation", "role": "spec-developer", "depends_on": ["architecture"]},
+				{"id": "validation", "role": "spec-validator", "depends_on": ["implementation"]},
+				{"id": "testing", "role": "spec-tester", "depends_on": ["validation"]}
+			]
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	req := convertWorkflowConfig(cfg, "run-1")
+
+	if len(req.Tasks) != 5 {
+		t.Fatalf("expected 5 tasks, got %d", len(req.Tasks))
+	}
+	for _, task := range req.Tasks {
+		if task.Skip != (task.ID == "testing") {
+			t.Errorf("task %s skip = %v", task.ID, task.Skip)
+		}
+	}
+}
+
+func TestConvertWorkflowConfig_StepOverrides(t *testing.T) {
+	cfg := &config.WorkflowConfig{
+		Workflow: config.Workflow{
+			Name: "override-flow",
+			Type: config.WorkflowTypeCustom,
+			Steps: []config.Step{
+				{ID: "a", Role: "spec-analyst"},
+				{
+					ID:            "b",
+					Role:          "spec-architect",
+					Model:         "claude-3-haiku-20240307",
+					TimeoutMs:     60000,
+					ContextPolicy: &config.ContextPolicyConfig{Strategy: "truncate", MaxTokens: 4000},
+					BudgetShare:   0.25,
+				},
+			},
+		},
+	}
+
+	req := convertWorkflowConfig(cfg, "run-1")
+
+	if req.Tasks[0].Model != getModelForRole(cfg, "spec-analyst") {
+		t.Errorf("expected role model for a, got %s", req.Tasks[0].Model)
+	}
+	if req.Tasks[0].TimeoutMs != 0 {
+		t.Errorf("expected no timeout_ms for a, got %d", req.Tasks[0].TimeoutMs)
+	}
+
+	b := req.Tasks[1]
+	if b.Model != "claude-3-haiku-20240307" {
+		t.Errorf("expected step model for b, got %s", b.Model)
+	}
+	if b.TimeoutMs != 60000 {
+		t.Errorf("expected timeout_ms 60000 for b, got %d", b.TimeoutMs)
+	}
+	want := map[string]string{
+		"role":           "spec-architect",
+		"context_policy": `{"strategy":"truncate","max_tokens":4000}`,
+		"budget_share":   "0.25",
+	}
+	if !reflect.DeepEqual(b.Metadata, want) {
+		t.Errorf("expected metadata %v, got %v", want, b.Metadata)
+	}
+}
+
+func TestConvertWorkflowConfig_SetsConfigHash(t *testing.T) {
+	cfg := &config.WorkflowConfig{
+		Version: config.CurrentConfigVersion,
+		Workflow: config.Workflow{
+			Name:  "hash-flow",
+			Type:  config.WorkflowTypeCustom,
+			Steps: []config.Step{{ID: "a", Role: "spec-analyst"}},
+		},
+	}
+
+	want, err := config.Hash(cfg)
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+
+	req := convertWorkflowConfig(cfg, "run-1")
+	if req.ConfigHash != want {
+		t.Errorf("expected config hash %s, got %s", want, req.ConfigHash)
+	}
+	if again := convertWorkflowConfig(cfg, "run-2"); again.ConfigHash != req.ConfigHash {
+		t.Errorf("expected stable hash across submissions, got %s and %s", req.ConfigHash, again.ConfigHash)
+	}
+}
+
+func TestPrintTaskOutputs(t *testing.T) {
+	long := strings.Repeat("é", outputPreviewRunes+10)
+	run := &runResponse{
+		ID:    "run-1",
+		State: "failed",
+		Tasks: map[string]taskStatusDTO{
+			"b": {State: "completed", Output: long},
+			"a": {State: "completed", Output: "short"},
+			"c": {State: "failed", Error: &errorDTO{Code: "task_failed"}},
+			"d": {State: "running"},
+		},
+	}
+
+	var buf bytes.Buffer
+	printTaskOutputs(&buf, run, false)
+	got := buf.String()
+	want := "--- a output ---\nshort\n--- b output ---\n" + strings.Repeat("é", outputPreviewRunes) +
+		"\n... (truncated, 500 of 510 characters shown; use --full)\n"
+	if got != want {
+		t.Errorf("truncated output mismatch:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	buf.Reset()
+	printTaskOutputs(&buf, run, true)
+	want = "--- a output ---\nshort\n--- b output ---\n" + long + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("full output mismatch:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestWriteIndentedJSON(t *testing.T) {
+	body := `{"id":"run-1","state":"failed","tasks":{"a":{"state":"completed","output":"ok"},` +
+		`"b":{"state":"failed","error":{"code":"task_timeout","message":"slow"}}},` +
+		`"error":{"code":"timeout","message":"task b timed out"},"progress":100}` + "\n"
+
+	var buf bytes.Buffer
+	if err := writeIndentedJSON(&buf, []byte(body)); err != nil {
+		t.Fatalf("writeIndentedJSON: %v", err)
+	}
+	got := buf.String()
+	if !strings.HasPrefix(got, "{\n  \"id\": \"run-1\",\n") || !strings.HasSuffix(got, "}\n") {
+		t.Errorf("output not indented:\n%s", got)
+	}
+
+	// Task error codes and the run error survive, as in the text output
+	var run runResponse
+	if err := json.Unmarshal(buf.Bytes(), &run); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	if b := run.Tasks["b"]; b.Error == nil || b.Error.Code != "task_timeout" {
+		t.Errorf("task b error = %+v, want task_timeout", b.Error)
+	}
+	if run.Error == nil || run.Error.Code != "timeout" {
+		t.Errorf("run error = %+v, want timeout", run.Error)
+	}
+	// Fields the client does not model are kept
+	if !strings.Contains(got, `"progress": 100`) {
+		t.Errorf("expected progress in output:\n%s", got)
+	}
+
+	if err := writeIndentedJSON(&buf, []byte("not json")); err == nil {
+		t.Error("expected an error for a non-JSON body")
+	}
+}
+
+func TestUniqueRunID(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	id := uniqueRunID("feature-dev", now)
+	if !strings.HasPrefix(id, "feature-dev-20250102T030405-") || len(id) != len("feature-dev-20250102T030405-")+6 {
+		t.Errorf("unexpected id %q", id)
+	}
+	if again := uniqueRunID("feature-dev", now); again != id {
+		t.Errorf("not deterministic: %q != %q", again, id)
+	}
+	if later := uniqueRunID("feature-dev", now.Add(time.Millisecond)); later == id {
+		t.Errorf("ids within the same second collide: %q", later)
+	}
+}
+
+func TestSubmitConfigCmd_UniqueID(t *testing.T) {
+	cfgSrv := jsonServer(t, http.StatusOK,
+		`{"workflow":{"name":"remote","type":"custom","steps":[{"id":"a","role":"spec-analyst"}]}}`)
+	var gotID string
+	sidecar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req startRunRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		gotID = req.ID
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"id": req.ID, "state": "pending"})
+	}))
+	defer sidecar.Close()
+
+	if got := run([]string{"submit-config", "--url", cfgSrv.URL, "--addr", sidecar.URL, "--unique-id"}); got != exitOK {
+		t.Fatalf("exit code = %d, want %d", got, exitOK)
+	}
+	if !strings.HasPrefix(gotID, "remote-") || gotID == "remote" {
+		t.Errorf("run ID = %q, want remote-<timestamp>-<hash>", gotID)
+	}
+
+	if got := run([]string{"submit-config", "--url", cfgSrv.URL, "--addr", sidecar.URL, "--unique-id", "--run-id", "x"}); got != exitValidation {
+		t.Errorf("exit code = %d, want %d for --run-id with --unique-id", got, exitValidation)
+	}
+}
+
+func TestWithRunIDHint(t *testing.T) {
+	exists := apiError([]byte(`{"code":"run_exists","message":"run remote: run already exists"}`), http.StatusConflict)
+	err := withRunIDHint(exists, "remote")
+	for _, want := range []string{"run already exists", `run ID "remote" defaults to workflow.name`, "--run-id", "--unique-id"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err, want)
+		}
+	}
+	if exitCodeFor(err) != exitCodeFor(exists) {
+		t.Errorf("hint changed exit code: %d != %d", exitCodeFor(err), exitCodeFor(exists))
+	}
+
+	other := apiError([]byte(`{"code":"invalid_input","message":"bad"}`), http.StatusBadRequest)
+	if withRunIDHint(other, "remote") != other {
+		t.Error("hint added to a non-409 error")
+	}
+}
+
+func TestSubmitConfigCmd_RunExists(t *testing.T) {
+	cfgSrv := jsonServer(t, http.StatusOK,
+		`{"workflow":{"name":"remote","type":"custom","steps":[{"id":"a","role":"spec-analyst"}]}}`)
+	sidecar := jsonServer(t, http.StatusConflict, `{"code":"run_exists","message":"run remote: run already exists"}`)
+
+	if got := run([]string{"submit-config", "--url", cfgSrv.URL, "--addr", sidecar.URL}); got != exitError {
+		t.Errorf("exit code = %d, want %d", got, exitError)
+	}
+}
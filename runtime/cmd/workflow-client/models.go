@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/anthropics/claude-workflow/runtime/config"
+)
+
+// Model sources reported by the models subcommand.
+const (
+	modelSourceConfig   = "config"   // workflow.models override
+	modelSourceBuiltin  = "builtin"  // roleToModel
+	modelSourceFallback = "fallback" // defaultModel
+)
+
+// modelMapping is a resolved role → model entry.
+type modelMapping struct {
+	Role   string `json:"role"`
+	Model  string `json:"model"`
+	Source string `json:"source"`
+}
+
+// modelsReport is the JSON output of the models subcommand.
+type modelsReport struct {
+	DefaultModel string         `json:"default_model"`
+	Roles        []modelMapping `json:"roles"`
+}
+
+// modelsCmd: print role → model mappings, optionally resolved against a config
+func modelsCmd(args []string) int {
+	fs := flag.NewFlagSet("models", flag.ContinueOnError)
+	file := fs.String("file", "", "Workflow config JSON file path or http(s) URL (optional, applies workflow.models)")
+	format := fs.String("format", "text", "Output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	var cfg *config.WorkflowConfig
+	if *file != "" {
+		loaded, err := loadConfig(*file)
+		if err != nil {
+			return fail(err)
+		}
+		cfg = loaded
+	}
+
+	if err := writeModels(os.Stdout, cfg, *format); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+// writeModels writes the resolved model table in the given format.
+// cfg may be nil, in which case only built-in mappings are listed.
+func writeModels(out io.Writer, cfg *config.WorkflowConfig, format string) error {
+	report := modelsReport{
+		DefaultModel: defaultModel,
+		Roles:        resolveModels(cfg),
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "text":
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "ROLE\tMODEL\tSOURCE\n")
+		for _, m := range report.Roles {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", m.Role, m.Model, m.Source)
+		}
+		fmt.Fprintf(tw, "(default)\t%s\t%s\n", report.DefaultModel, modelSourceFallback)
+		return tw.Flush()
+	default:
+		return validationError{fmt.Errorf("unknown format %q (want text or json)", format)}
+	}
+}
+
+// resolveModels returns mappings for all built-in roles plus any roles
+// referenced by cfg (models overrides and step roles), sorted by role.
+func resolveModels(cfg *config.WorkflowConfig) []modelMapping {
+	roles := make(map[string]struct{}, len(roleToModel))
+	for role := range roleToModel {
+		roles[role] = struct{}{}
+	}
+	if cfg != nil {
+		for role := range cfg.Workflow.Models {
+			roles[role] = struct{}{}
+		}
+		for _, step := range cfg.Workflow.Steps {
+			roles[step.Role] = struct{}{}
+		}
+	}
+
+	result := make([]modelMapping, 0, len(roles))
+	for role := range roles {
+		model, source := resolveModel(cfg, role)
+		result = append(result, modelMapping{Role: role, Model: model, Source: source})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Role < result[j].Role })
+	return result
+}
+
+// resolveModel resolves model for a role and reports where it came from.
+// See getModelForRole for the fallback chain; cfg may be nil.
+func resolveModel(cfg *config.WorkflowConfig, role string) (string, string) {
+	if cfg != nil && cfg.Workflow.Models != nil {
+		if model, ok := cfg.Workflow.Models[role]; ok {
+			return model, modelSourceConfig
+		}
+	}
+	if model, ok := roleToModel[role]; ok {
+		return model, modelSourceBuiltin
+	}
+	return defaultModel, modelSourceFallback
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/claude-workflow/runtime/config"
+)
+
+func TestWriteModels_Text(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeModels(&out, nil, "text"); err != nil {
+		t.Fatalf("writeModels failed: %v", err)
+	}
+
+	for role, model := range roleToModel {
+		if !strings.Contains(out.String(), role) || !strings.Contains(out.String(), model) {
+			t.Errorf("expected %s → %s in output:\n%s", role, model, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "(default)") {
+		t.Errorf("expected default model row in output:\n%s", out.String())
+	}
+}
+
+func TestWriteModels_JSONWithConfigOverrides(t *testing.T) {
+	cfg := &config.WorkflowConfig{
+		Workflow: config.Workflow{
+			Name:   "override-flow",
+			Models: map[string]string{"spec-developer": "claude-opus-4-20250514"},
+			Steps: []config.Step{
+				{ID: "a", Role: "spec-developer"},
+				{ID: "b", Role: "code-reviewer"},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := writeModels(&out, cfg, "json"); err != nil {
+		t.Fatalf("writeModels failed: %v", err)
+	}
+
+	var report modelsReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if report.DefaultModel != defaultModel {
+		t.Errorf("expected default_model %s, got %s", defaultModel, report.DefaultModel)
+	}
+
+	byRole := make(map[string]modelMapping)
+	for _, m := range report.Roles {
+		byRole[m.Role] = m
+	}
+	want := map[string]modelMapping{
+		"spec-developer": {Role: "spec-developer", Model: "claude-opus-4-20250514", Source: modelSourceConfig},
+		"spec-analyst":   {Role: "spec-analyst", Model: roleToModel["spec-analyst"], Source: modelSourceBuiltin},
+		"code-reviewer":  {Role: "code-reviewer", Model: defaultModel, Source: modelSourceFallback},
+	}
+	for role, w := range want {
+		if byRole[role] != w {
+			t.Errorf("role %s: expected %+v, got %+v", role, w, byRole[role])
+		}
+	}
+}
+
+func TestWriteModels_UnknownFormat(t *testing.T) {
+	if err := writeModels(&bytes.Buffer{}, nil, "yaml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
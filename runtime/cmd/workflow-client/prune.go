@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// pruneResponse is the POST /api/v1/runs/prune response.
+type pruneResponse struct {
+	DryRun bool `json:"dry_run"`
+	Count  int  `json:"count"`
+	Runs   []struct {
+		ID    string `json:"id"`
+		AgeMs int64  `json:"age_ms"`
+	} `json:"runs,omitempty"`
+}
+
+// pruneCmd: POST /api/v1/runs/prune and report the removed (or, with
+// --dry-run, removable) runs
+func pruneCmd(args []string) int {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	addr := fs.String("addr", "http://localhost:8080", "Sidecar address")
+	retention := fs.Duration("retention", time.Hour, "Remove finished runs not updated within this duration")
+	dryRun := fs.Bool("dry-run", false, "List the runs that would be removed without removing them")
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+	if *retention <= 0 {
+		return fail(validationError{errors.New("--retention must be positive")})
+	}
+
+	query := url.Values{}
+	query.Set("retention", retention.String())
+	query.Set("dry_run", strconv.FormatBool(*dryRun))
+
+	resp, err := http.Post(*addr+"/api/v1/runs/prune?"+query.Encode(), "application/json", nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return fail(apiError(body, resp.StatusCode))
+	}
+
+	var result pruneResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fail(fmt.Errorf("parsing response: %w", err))
+	}
+	if err := writePruneResult(os.Stdout, result); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+// writePruneResult prints the pruned run count, and with a dry run each
+// run that would be removed with its age.
+func writePruneResult(out io.Writer, result pruneResponse) error {
+	if !result.DryRun {
+		_, err := fmt.Fprintf(out, "pruned %d runs\n", result.Count)
+		return err
+	}
+	for _, r := range result.Runs {
+		age := (time.Duration(r.AgeMs) * time.Millisecond).Round(time.Second)
+		if _, err := fmt.Fprintf(out, "%s\t%s\n", r.ID, age); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(out, "would prune %d runs (dry run)\n", result.Count)
+	return err
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWritePruneResult(t *testing.T) {
+	var result pruneResponse
+	result.DryRun = true
+	result.Count = 1
+	result.Runs = append(result.Runs, struct {
+		ID    string `json:"id"`
+		AgeMs int64  `json:"age_ms"`
+	}{ID: "old-1", AgeMs: 3*3600*1000 + 400})
+
+	var buf bytes.Buffer
+	if err := writePruneResult(&buf, result); err != nil {
+		t.Fatalf("writePruneResult: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"old-1\t3h0m0s", "would prune 1 runs (dry run)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := writePruneResult(&buf, pruneResponse{Count: 2}); err != nil {
+		t.Fatalf("writePruneResult: %v", err)
+	}
+	if buf.String() != "pruned 2 runs\n" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestPruneCmd(t *testing.T) {
+	var method, query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, query = r.Method, r.URL.RawQuery
+		w.Write([]byte(`{"dry_run":true,"count":0}`))
+	}))
+	defer srv.Close()
+
+	if code := run([]string{"prune", "--addr", srv.URL, "--retention", "24h", "--dry-run"}); code != exitOK {
+		t.Fatalf("expected exit 0, got %d", code)
+	}
+	if method != http.MethodPost || query != "dry_run=true&retention=24h0m0s" {
+		t.Errorf("unexpected request %s ?%s", method, query)
+	}
+
+	if code := run([]string{"prune", "--addr", srv.URL, "--retention", "0s"}); code != exitValidation {
+		t.Errorf("expected validation exit code for zero retention, got %d", code)
+	}
+}
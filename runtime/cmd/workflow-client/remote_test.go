@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/claude-workflow/runtime/config"
+)
+
+func TestLoadConfig_URL(t *testing.T) {
+	valid := `{"workflow":{"name":"remote","type":"custom","steps":[{"id":"a","role":"spec-analyst"}]}}`
+
+	srv := jsonServer(t, http.StatusOK, valid)
+	cfg, err := loadConfig(srv.URL + "/workflow.json")
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if cfg.Workflow.Name != "remote" {
+		t.Errorf("expected workflow remote, got %q", cfg.Workflow.Name)
+	}
+
+	missing := jsonServer(t, http.StatusNotFound, `not found`)
+	_, err = loadConfig(missing.URL + "/workflow.json")
+	if err == nil || !strings.Contains(err.Error(), "HTTP 404") {
+		t.Errorf("expected HTTP 404 error, got %v", err)
+	}
+	if exitCodeFor(err) != exitError {
+		t.Errorf("expected generic error exit code, got %d", exitCodeFor(err))
+	}
+
+	invalid := jsonServer(t, http.StatusOK, `{"workflow":{"name":"bad","steps":[]}}`)
+	_, err = loadConfig(invalid.URL + "/workflow.json")
+	if !errors.Is(err, config.ErrNoSteps) {
+		t.Errorf("expected ErrNoSteps, got %v", err)
+	}
+	if exitCodeFor(err) != exitValidation {
+		t.Errorf("expected validation exit code, got %d", exitCodeFor(err))
+	}
+}
+
+func TestSubmitConfigCmd_URL(t *testing.T) {
+	cfgSrv := jsonServer(t, http.StatusOK,
+		`{"workflow":{"name":"remote","type":"custom","steps":[{"id":"a","role":"spec-analyst"}]}}`)
+	sidecar := jsonServer(t, http.StatusAccepted, `{"id":"remote","state":"pending"}`)
+
+	if got := run([]string{"submit-config", "--url", cfgSrv.URL, "--addr", sidecar.URL}); got != exitOK {
+		t.Errorf("exit code = %d, want %d", got, exitOK)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// pollInterval controls how often run status is polled when the events
+// endpoint is unavailable.
+var pollInterval = 500 * time.Millisecond
+
+// errEventsUnavailable is returned when the sidecar does not serve the events endpoint.
+var errEventsUnavailable = errors.New("events endpoint unavailable")
+
+// watchCmd: follow an existing run until it reaches a terminal state
+func watchCmd(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	id := fs.String("id", "", "Run ID")
+	addr := fs.String("addr", "http://localhost:8080", "Sidecar address")
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	if *id == "" {
+		return fail(validationError{errors.New("--id is required")})
+	}
+	return followRun(*addr, *id)
+}
+
+// followRun watches a run until it reaches a terminal state and returns
+// the exit code for its final state (see runExitCode).
+func followRun(addr, runID string) int {
+	final, err := watchRun(addr, runID, os.Stdout)
+	if err != nil {
+		return fail(err)
+	}
+	return runExitCode(final)
+}
+
+// watchRun follows a run until it reaches a terminal state, printing progress to out.
+// Uses GET /api/v1/runs/{id}/events (SSE) and falls back to polling
+// GET /api/v1/runs/{id} if the events endpoint is unavailable or the stream
+// ends before the run is terminal.
+// Returns the final run status.
+func watchRun(addr, runID string, out io.Writer) (*runResponse, error) {
+	final, err := streamRun(addr, runID, out)
+	if err == nil {
+		return final, nil
+	}
+	if !errors.Is(err, errEventsUnavailable) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	return pollRun(addr, runID, out)
+}
+
+// streamRun reads run snapshots from the SSE events endpoint.
+// Each "run" or "done" event's data is a JSON run status; progress is printed
+// whenever the run or task states change. Other event types are ignored.
+// Returns errEventsUnavailable if the endpoint is not served, and
+// io.ErrUnexpectedEOF if the stream closes before a terminal state.
+func streamRun(addr, runID string, out io.Writer) (*runResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, addr+"/api/v1/runs/"+runID+"/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil, errEventsUnavailable
+	}
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, apiError(body, resp.StatusCode)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return nil, errEventsUnavailable
+	}
+
+	var last, event string
+	var data strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event:") {
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			continue
+		}
+		if strings.HasPrefix(line, "data:") {
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+
+		// Blank line: dispatch accumulated event.
+		// Only run snapshots are tracked; other events (e.g. task_state) are skipped.
+		payload := data.String()
+		typ := event
+		data.Reset()
+		event = ""
+		if typ != "" && typ != "run" && typ != "done" {
+			continue
+		}
+
+		var run runResponse
+		if err := json.Unmarshal([]byte(payload), &run); err != nil {
+			return nil, fmt.Errorf("parsing event: %w", err)
+		}
+
+		last = printIfChanged(out, &run, last)
+		if isTerminalState(run.State) {
+			return &run, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// pollRun polls run status until a terminal state is reached.
+func pollRun(addr, runID string, out io.Writer) (*runResponse, error) {
+	var last string
+	for {
+		resp, err := http.Get(addr + "/api/v1/runs/" + runID)
+		if err != nil {
+			return nil, err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode >= 400 {
+			return nil, apiError(body, resp.StatusCode)
+		}
+
+		var run runResponse
+		if err := json.Unmarshal(body, &run); err != nil {
+			return nil, fmt.Errorf("parsing response: %w", err)
+		}
+
+		last = printIfChanged(out, &run, last)
+		if isTerminalState(run.State) {
+			return &run, nil
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// printIfChanged prints the run summary if it differs from the last printed one.
+// Returns the summary that is now current.
+func printIfChanged(out io.Writer, run *runResponse, last string) string {
+	var sb strings.Builder
+	printRunSummary(&sb, run)
+	summary := sb.String()
+	if summary != last {
+		io.WriteString(out, summary)
+	}
+	return summary
+}
+
+// isTerminalState reports whether the API run state is terminal.
+func isTerminalState(state string) bool {
+	switch state {
+	case "completed", "failed", "aborted":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchRun_StreamsEventsToCompletion(t *testing.T) {
+	events := []string{
+		`{"id":"run-1","state":"running","tasks":{"a":{"state":"running"}}}`,
+		`{"id":"run-1","state":"running","tasks":{"a":{"state":"running"}}}`,
+		`{"id":"run-1","state":"completed","tasks":{"a":{"state":"completed"}}}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/runs/run-1/events" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			fmt.Fprintf(w, "event: run\ndata: %s\n\n", e)
+			fmt.Fprint(w, "event: task_progress\ndata: {\"task_id\":\"a\",\"chunk\":\"partial\"}\n\n")
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	final, err := watchRun(srv.URL, "run-1", &out)
+	if err != nil {
+		t.Fatalf("watchRun failed: %v", err)
+	}
+	if final.State != "completed" {
+		t.Errorf("expected completed, got %s", final.State)
+	}
+
+	want := "run_id=run-1 state=running\ntasks: a=running\n" +
+		"run_id=run-1 state=completed\ntasks: a=completed\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWatchRun_StreamsFailedRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"run-1","state":"failed","tasks":{"a":{"state":"failed","error":{"code":"task_failed","message":"boom"}}}}`+"\n\n")
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	final, err := watchRun(srv.URL, "run-1", &out)
+	if err != nil {
+		t.Fatalf("watchRun failed: %v", err)
+	}
+	if final.State != "failed" {
+		t.Errorf("expected failed, got %s", final.State)
+	}
+	if !strings.Contains(out.String(), "a=failed(task_failed)") {
+		t.Errorf("expected failed task in output, got %q", out.String())
+	}
+}
+
+func TestWatchRun_FallsBackToPolling(t *testing.T) {
+	oldInterval := pollInterval
+	pollInterval = time.Millisecond
+	defer func() { pollInterval = oldInterval }()
+
+	var mu sync.Mutex
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/events") {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		polls++
+		n := polls
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if n < 3 {
+			fmt.Fprint(w, `{"id":"run-1","state":"running"}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"run-1","state":"completed"}`)
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	final, err := watchRun(srv.URL, "run-1", &out)
+	if err != nil {
+		t.Fatalf("watchRun failed: %v", err)
+	}
+	if final.State != "completed" {
+		t.Errorf("expected completed, got %s", final.State)
+	}
+	if polls != 3 {
+		t.Errorf("expected 3 polls, got %d", polls)
+	}
+	want := "run_id=run-1 state=running\nrun_id=run-1 state=completed\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWatchRun_StreamClosedEarlyFallsBackToPolling(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/events") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, `data: {"id":"run-1","state":"running"}`+"\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"run-1","state":"aborted"}`)
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	final, err := watchRun(srv.URL, "run-1", &out)
+	if err != nil {
+		t.Fatalf("watchRun failed: %v", err)
+	}
+	if final.State != "aborted" {
+		t.Errorf("expected aborted, got %s", final.State)
+	}
+}
+
+func TestWatchRun_RunNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"code":"run_not_found","message":"run run-1: run not found"}`)
+	}))
+	defer srv.Close()
+
+	_, err := watchRun(srv.URL, "run-1", &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "run_not_found") {
+		t.Fatalf("expected run_not_found error, got %v", err)
+	}
+}
+
+func TestWatchCmd(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: run\ndata: {\"id\":\"run-1\",\"state\":\"running\",\"tasks\":{\"a\":{\"state\":\"running\"}}}\n\n")
+		fmt.Fprint(w, "event: task_state\ndata: {\"task_id\":\"a\",\"state\":\"failed\",\"usage\":{\"tokens\":10}}\n\n")
+		fmt.Fprint(w, "event: done\ndata: {\"id\":\"run-1\",\"state\":\"failed\",\"tasks\":{\"a\":{\"state\":\"failed\"}}}\n\n")
+	}))
+	defer srv.Close()
+
+	if code := run([]string{"watch", "--id", "run-1", "--addr", srv.URL}); code != exitRunFailed {
+		t.Errorf("expected exit %d for a failed run, got %d", exitRunFailed, code)
+	}
+	if code := run([]string{"watch", "--addr", srv.URL}); code != exitValidation {
+		t.Errorf("expected exit %d without --id, got %d", exitValidation, code)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/anthropics/claude-workflow/runtime/config"
+)
+
+// validateCmd: load and validate a workflow config without submitting it
+func validateCmd(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	file := fs.String("file", "", "Workflow config JSON/YAML file path or http(s) URL")
+	strictModels := fs.Bool("strict-models", false, "Fail on workflow.models entries for roles no step uses")
+	if err := fs.Parse(args); err != nil {
+		return flagExitCode(err)
+	}
+
+	if *file == "" {
+		return fail(validationError{errors.New("--file is required")})
+	}
+
+	cfg, err := loadConfig(*file)
+	if err != nil {
+		return fail(err)
+	}
+	if *strictModels {
+		if err := strictModelsErrors(cfg); err != nil {
+			return fail(err)
+		}
+	}
+	warnings, err := configWarnings(cfg)
+	if err != nil {
+		return fail(err)
+	}
+
+	printConfigWarnings(os.Stdout, warnings)
+	fmt.Printf("valid: workflow=%s steps=%d warnings=%d\n", cfg.Workflow.Name, len(cfg.Workflow.Steps), len(warnings))
+	return exitOK
+}
+
+// strictModelsErrors validates cfg with StrictModels and returns every
+// failure as config.ValidationErrors, or nil if there are none.
+func strictModelsErrors(cfg *config.WorkflowConfig) error {
+	if errs := (&config.Validator{StrictModels: true}).ValidateAll(cfg); len(errs) > 0 {
+		return validationError{config.ValidationErrors(errs)}
+	}
+	return nil
+}
+
+// configWarnings returns the advisory warnings for a loaded config.
+func configWarnings(cfg *config.WorkflowConfig) ([]config.Warning, error) {
+	warnings, err := config.NewValidator().ValidateWithWarnings(cfg)
+	if err != nil {
+		return nil, validationError{err}
+	}
+	return warnings, nil
+}
+
+// printConfigWarnings prints one "warning:" line per config warning.
+func printConfigWarnings(out io.Writer, warnings []config.Warning) {
+	for _, w := range warnings {
+		fmt.Fprintf(out, "warning: %s\n", w)
+	}
+}
+
+// printWarnings prints the warnings of a submission response to stderr.
+func printWarnings(warnings []string) {
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+}
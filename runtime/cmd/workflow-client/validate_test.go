@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/claude-workflow/runtime/config"
+)
+
+func TestValidateCmd(t *testing.T) {
+	smelly := writeTemp(t, "smelly.yaml", `
+workflow:
+  name: smelly
+  type: custom
+  steps:
+    - id: a
+      role: researcher
+      outputs: [notes.md]
+    - id: b
+      role: researcher
+`)
+	invalid := writeTemp(t, "invalid.json", `{"workflow":{"name":"bad","steps":[]}}`)
+	strayModel := writeTemp(t, "stray.json", `{"workflow":{"name":"stray","type":"custom",`+
+		`"models":{"writter":"claude-opus-4-20250514"},"steps":[{"id":"a","role":"writer"}]}}`)
+
+	if code := run([]string{"validate", "--file", smelly}); code != exitOK {
+		t.Errorf("expected warnings not to fail validation, got exit %d", code)
+	}
+	if code := run([]string{"validate", "--file", invalid}); code != exitValidation {
+		t.Errorf("expected exit %d for an invalid config, got %d", exitValidation, code)
+	}
+	if code := run([]string{"validate", "--file", strayModel}); code != exitOK {
+		t.Errorf("expected an unused model mapping to warn, got exit %d", code)
+	}
+	if code := run([]string{"validate", "--strict-models", "--file", strayModel}); code != exitValidation {
+		t.Errorf("expected exit %d for an unused model mapping with --strict-models, got %d", exitValidation, code)
+	}
+	if code := run([]string{"validate"}); code != exitValidation {
+		t.Errorf("expected exit %d without --file, got %d", exitValidation, code)
+	}
+
+	cfg, err := config.NewLoader().LoadFromFile(smelly)
+	if err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	warnings, err := configWarnings(cfg)
+	if err != nil {
+		t.Fatalf("configWarnings: %v", err)
+	}
+	var out bytes.Buffer
+	printConfigWarnings(&out, warnings)
+	want := "warning: step.id=b: step has no outputs\n" +
+		"warning: workflow has 2 root steps (a, b); spec workflows assume a single root\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestStrictModelsErrors(t *testing.T) {
+	path := writeTemp(t, "strays.json", `{"workflow":{"name":"strays","type":"custom",`+
+		`"models":{"writter":"claude-opus-4-20250514","reviewr":"claude-3-haiku-20240307"},`+
+		`"steps":[{"id":"a","role":"writer"}]}}`)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+
+	err = strictModelsErrors(cfg)
+	if code := exitCodeFor(err); code != exitValidation {
+		t.Fatalf("expected exit %d, got %d (%v)", exitValidation, code, err)
+	}
+	// Both unused mappings are reported, not just the first
+	for _, want := range []string{"2 validation errors:", "reviewr", "writter"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err.Error(), want)
+		}
+	}
+}
+
+func TestLoadConfig_AllErrors(t *testing.T) {
+	path := writeTemp(t, "broken.json", `{"workflow":{"name":"broken","type":"custom","steps":[
+		{"id":"a","role":"researcher","depends_on":["missing"]},
+		{"id":"a","role":"writer","retry_on":["sometimes"]}]}}`)
+
+	_, err := loadConfig(path)
+	if code := exitCodeFor(err); code != exitValidation {
+		t.Fatalf("expected exit %d, got %d (%v)", exitValidation, code, err)
+	}
+	for _, want := range []string{
+		"3 validation errors:",
+		"\n  - step.id=a: duplicate step.id",
+		"\n  - step.id=a retry_on=sometimes: unknown retry_on category",
+		"\n  - step.id=a depends_on=missing: depends_on references unknown step id",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err.Error(), want)
+		}
+	}
+}
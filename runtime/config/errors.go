@@ -1,18 +1,28 @@
 package config
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // Sentinel errors for workflow configuration validation.
 var (
 	// ErrConfigEmpty is returned when the config data is empty (zero bytes).
 	ErrConfigEmpty = errors.New("workflow configuration is empty")
 
+	// ErrUnsupportedConfigVersion is returned when the config version is not supported.
+	ErrUnsupportedConfigVersion = errors.New("unsupported config version")
+
 	// ErrWorkflowNameEmpty is returned when workflow.name is empty.
 	ErrWorkflowNameEmpty = errors.New("workflow.name is required")
 
 	// ErrNoSteps is returned when workflow.steps is empty.
 	ErrNoSteps = errors.New("workflow.steps must not be empty")
 
+	// ErrNoEnabledSteps is returned when every step in workflow.steps is disabled.
+	ErrNoEnabledSteps = errors.New("workflow.steps has no enabled steps")
+
 	// ErrStepIDEmpty is returned when a step has an empty id.
 	ErrStepIDEmpty = errors.New("step.id is required")
 
@@ -22,9 +32,34 @@ var (
 	// ErrStepRoleEmpty is returned when a step has an empty role.
 	ErrStepRoleEmpty = errors.New("step.role is required")
 
+	// ErrUnknownRetryCategory is returned when retry_on contains an unknown category.
+	ErrUnknownRetryCategory = errors.New("unknown retry_on category")
+
+	// ErrInvalidRetry is returned when a step's retry has max_attempts < 1 or a negative backoff_ms.
+	ErrInvalidRetry = errors.New("step.retry needs max_attempts >= 1 and backoff_ms >= 0")
+
+	// ErrInvalidStepTimeout is returned when a step's timeout_ms is negative.
+	ErrInvalidStepTimeout = errors.New("step.timeout_ms must not be negative")
+
+	// ErrInvalidBudgetShare is returned when a step's budget_share is outside 0-1.
+	ErrInvalidBudgetShare = errors.New("step.budget_share must be between 0 and 1")
+
+	// ErrModelEmpty is returned when a workflow.models entry has an empty model ID.
+	ErrModelEmpty = errors.New("workflow.models entry has an empty model")
+
+	// ErrUnusedModelMapping is returned by a Validator with StrictModels when
+	// workflow.models maps a role that no step uses.
+	ErrUnusedModelMapping = errors.New("workflow.models maps a role no step uses")
+
+	// ErrInvalidConcurrencyLimit is returned when a policy.concurrency_limits value is not positive.
+	ErrInvalidConcurrencyLimit = errors.New("policy.concurrency_limits values must be positive")
+
 	// ErrDependencyNotFound is returned when depends_on references a non-existent id.
 	ErrDependencyNotFound = errors.New("depends_on references unknown step id")
 
+	// ErrDuplicateOutput is returned when two steps declare the same output path.
+	ErrDuplicateOutput = errors.New("output declared by more than one step")
+
 	// ErrCycleDetected is returned when a cycle is detected in step dependencies.
 	ErrCycleDetected = errors.New("cycle detected in step dependencies")
 
@@ -40,6 +75,9 @@ var (
 	// ErrOptionalRolePlacement is returned when optional role depends on non-validator step.
 	ErrOptionalRolePlacement = errors.New("optional role must depend on spec-validator")
 
+	// ErrRequiredAfterValidator is returned when a required step depends on spec-validator.
+	ErrRequiredAfterValidator = errors.New("required role must not depend on spec-validator")
+
 	// ErrUnknownRole is returned when a role is neither required nor optional for spec-default.
 	ErrUnknownRole = errors.New("unknown role for spec-default workflow")
 
@@ -48,4 +86,32 @@ var (
 
 	// ErrOptionalNotAllowed is returned when optional_enabled contains a role not in optional_roles.
 	ErrOptionalNotAllowed = errors.New("optional_enabled contains role not in optional_roles")
+
+	// ErrInvalidOptionalMode is returned when optional_mode is neither empty nor "skip".
+	ErrInvalidOptionalMode = errors.New("optional_mode must be empty or skip")
+
+	// ErrTooManyOptionalSteps is returned when optional-role steps exceed max_optional_steps.
+	ErrTooManyOptionalSteps = errors.New("too many optional steps")
 )
+
+// ValidationErrors holds every violation Validator.ValidateAll reported, in
+// order. errors.Is and errors.As match any of them.
+type ValidationErrors []error
+
+// Error returns the single error's message, or a count followed by one
+// "  - " line per error.
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d validation errors:", len(e))
+	for _, err := range e {
+		b.WriteString("\n  - ")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the individual errors.
+func (e ValidationErrors) Unwrap() []error { return e }
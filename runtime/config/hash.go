@@ -0,0 +1,25 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Hash returns the hex SHA-256 of the canonicalized config.
+// The canonical form is the JSON encoding of the parsed config (fixed field
+// order, sorted map keys, no insignificant whitespace), so configs that differ
+// only in formatting or key order hash the same.
+// Hash the config returned by Loader, after defaults and wildcards are applied.
+func Hash(cfg *WorkflowConfig) (string, error) {
+	if cfg == nil {
+		return "", ErrConfigEmpty
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("canonicalizing config: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
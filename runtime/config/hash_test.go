@@ -0,0 +1,60 @@
+package config
+
+import "testing"
+
+func TestHash_StableForIdenticalConfigs(t *testing.T) {
+	a := []byte(`{"workflow": {"name": "hash-flow", "type": "custom",
+		"models": {"build": "m1", "lint": "m2"},
+		"steps": [{"id": "a", "role": "lint"}, {"id": "b", "role": "build", "depends_on": ["a"]}]}}`)
+	// Same config: different whitespace, key order, and explicit default version
+	b := []byte(`{
+		"version": "1",
+		"workflow": {
+			"steps": [
+				{"role": "lint", "id": "a"},
+				{"depends_on": ["a"], "role": "build", "id": "b"}
+			],
+			"models": {"lint": "m2", "build": "m1"},
+			"type": "custom",
+			"name": "hash-flow"
+		}
+	}`)
+
+	l := NewLoader()
+	cfgA, err := l.LoadFromBytes(a)
+	if err != nil {
+		t.Fatalf("load a: %v", err)
+	}
+	cfgB, err := l.LoadFromBytes(b)
+	if err != nil {
+		t.Fatalf("load b: %v", err)
+	}
+
+	hashA, err := Hash(cfgA)
+	if err != nil {
+		t.Fatalf("Hash a: %v", err)
+	}
+	hashB, err := Hash(cfgB)
+	if err != nil {
+		t.Fatalf("Hash b: %v", err)
+	}
+	if hashA != hashB {
+		t.Errorf("expected identical hashes, got %s and %s", hashA, hashB)
+	}
+	if len(hashA) != 64 {
+		t.Errorf("expected 64-char hex SHA-256, got %q", hashA)
+	}
+
+	// Any semantic change changes the hash
+	cfgB.Workflow.Steps[1].Role = "test"
+	hashC, _ := Hash(cfgB)
+	if hashC == hashA {
+		t.Error("expected different hash after config change")
+	}
+}
+
+func TestHash_NilConfig(t *testing.T) {
+	if _, err := Hash(nil); err == nil {
+		t.Fatal("expected error for nil config")
+	}
+}
@@ -1,20 +1,32 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Loader loads and parses workflow configuration files.
-type Loader struct{}
+type Loader struct {
+	// AllErrors reports every validation failure as ValidationErrors
+	// (Validator.ValidateAll) instead of only the first.
+	AllErrors bool
+}
 
 // NewLoader creates a new configuration loader.
 func NewLoader() *Loader {
 	return &Loader{}
 }
 
-// LoadFromFile loads and parses a workflow configuration from a JSON file.
+// LoadFromFile loads and parses a workflow configuration from a JSON or YAML file.
+// Files named *.yaml or *.yml are parsed as YAML; others are detected by content
+// (see LoadFromBytes).
 // Returns the validated WorkflowConfig or an error.
 // File errors are wrapped with context (use os.IsNotExist to check for missing file).
 func (l *Loader) LoadFromFile(path string) (*WorkflowConfig, error) {
@@ -23,7 +35,13 @@ func (l *Loader) LoadFromFile(path string) (*WorkflowConfig, error) {
 		return nil, fmt.Errorf("reading config %s: %w", path, err)
 	}
 
-	cfg, err := l.LoadFromBytes(data)
+	var cfg *WorkflowConfig
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		cfg, err = l.LoadFromYAML(data)
+	default:
+		cfg, err = l.LoadFromBytes(data)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("loading config %s: %w", path, err)
 	}
@@ -31,25 +49,156 @@ func (l *Loader) LoadFromFile(path string) (*WorkflowConfig, error) {
 	return cfg, nil
 }
 
-// LoadFromBytes parses workflow configuration from raw JSON bytes.
+// LoadFromBytes parses workflow configuration from raw JSON or YAML bytes.
+// Data whose first non-blank character is '{' is parsed as JSON, anything
+// else as YAML (see LoadFromYAML).
 // Returns the validated WorkflowConfig or an error.
 // Empty data (len==0) returns ErrConfigEmpty.
 // Parse errors are wrapped (use json.SyntaxError to check for parse failures).
+// A missing version defaults to CurrentConfigVersion; unknown versions return
+// ErrUnsupportedConfigVersion.
 func (l *Loader) LoadFromBytes(data []byte) (*WorkflowConfig, error) {
 	if len(data) == 0 {
 		return nil, ErrConfigEmpty
 	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] != '{' {
+		return l.LoadFromYAML(data)
+	}
 
 	var config WorkflowConfig
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("parsing JSON: %w", err)
 	}
+	return l.finish(&config)
+}
+
+// LoadFromYAML parses workflow configuration from YAML bytes.
+// Keys are the same as in JSON. The YAML is converted to JSON and decoded
+// like LoadFromBytes, so both formats produce identical configs and errors.
+// Empty data or an empty document returns ErrConfigEmpty.
+func (l *Loader) LoadFromYAML(data []byte) (*WorkflowConfig, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing YAML: %w", err)
+	}
+	if doc == nil {
+		return nil, ErrConfigEmpty
+	}
+	jsonData, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("converting YAML: %w", err)
+	}
+
+	var config WorkflowConfig
+	if err := json.Unmarshal(jsonData, &config); err != nil {
+		return nil, fmt.Errorf("parsing YAML: %w", err)
+	}
+	return l.finish(&config)
+}
+
+// finish applies versioning, defaults and wildcards to a parsed config and
+// validates it.
+func (l *Loader) finish(config *WorkflowConfig) (*WorkflowConfig, error) {
+	// Check schema version before validating fields that depend on it
+	if config.Version == "" {
+		config.Version = CurrentConfigVersion
+	}
+	if !slices.Contains(SupportedConfigVersions(), config.Version) {
+		return nil, fmt.Errorf("version %q (supported: %s): %w",
+			config.Version, strings.Join(SupportedConfigVersions(), ", "), ErrUnsupportedConfigVersion)
+	}
+
+	// Fill unset step fields from workflow.defaults
+	applyStepDefaults(&config.Workflow)
+
+	// Expand depends_on wildcards; cycles they introduce are caught by validation
+	expandDependsOnAll(config.Workflow.Steps)
 
 	// Validate the configuration
 	validator := NewValidator()
-	if err := validator.Validate(&config); err != nil {
+	if l.AllErrors {
+		if errs := validator.ValidateAll(config); len(errs) > 0 {
+			return nil, ValidationErrors(errs)
+		}
+	} else if err := validator.Validate(config); err != nil {
 		return nil, err
 	}
 
-	return &config, nil
+	// Skip optional steps that are not enabled; validation allowed them
+	markSkippedOptional(&config.Workflow)
+
+	return config, nil
+}
+
+// markSkippedOptional sets Skip on the steps of a spec-default workflow with
+// optional_mode skip whose optional role is not in optional_enabled.
+// An empty optional_enabled enables every optional role.
+func markSkippedOptional(wf *Workflow) {
+	if wf.Type != WorkflowTypeSpecDefault || wf.OptionalMode != OptionalModeSkip || len(wf.OptionalEnabled) == 0 {
+		return
+	}
+	optional := effectiveOptionalRoles(wf)
+	for i := range wf.Steps {
+		role := wf.Steps[i].Role
+		if slices.Contains(optional, Role(role)) && !slices.Contains(wf.OptionalEnabled, role) {
+			wf.Steps[i].Skip = true
+		}
+	}
+}
+
+// expandDependsOnAll replaces DependsOnAll in each step's depends_on with the
+// ids of all other steps (in declaration order), keeping explicit entries and
+// dropping duplicates.
+func expandDependsOnAll(steps []Step) {
+	for i := range steps {
+		if !slices.Contains(steps[i].DependsOn, DependsOnAll) {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		var deps []string
+		add := func(id string) {
+			if !seen[id] {
+				seen[id] = true
+				deps = append(deps, id)
+			}
+		}
+		for _, dep := range steps[i].DependsOn {
+			if dep != DependsOnAll {
+				add(dep)
+				continue
+			}
+			for _, other := range steps {
+				if other.ID != steps[i].ID {
+					add(other.ID)
+				}
+			}
+		}
+		steps[i].DependsOn = deps
+	}
+}
+
+// applyStepDefaults copies workflow.defaults into each step field the step
+// leaves unset. A step's context_policy replaces the default one as a whole.
+func applyStepDefaults(wf *Workflow) {
+	d := wf.Defaults
+	if d == nil {
+		return
+	}
+	for i := range wf.Steps {
+		step := &wf.Steps[i]
+		if step.Model == "" {
+			step.Model = d.Model
+		}
+		if step.TimeoutMs == 0 {
+			step.TimeoutMs = d.TimeoutMs
+		}
+		if step.ContextPolicy == nil && d.ContextPolicy != nil {
+			cp := *d.ContextPolicy
+			step.ContextPolicy = &cp
+		}
+		if step.BudgetShare == 0 {
+			step.BudgetShare = d.BudgetShare
+		}
+	}
 }
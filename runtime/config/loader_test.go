@@ -1,10 +1,12 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -275,3 +277,330 @@ func TestLoader_LoadFromBytes_WithoutPolicy(t *testing.T) {
 		t.Fatalf("expected policy to be nil, got %+v", cfg.Workflow.Policy)
 	}
 }
+
+func TestLoader_LoadFromBytes_Version(t *testing.T) {
+	steps := `"steps": [
+		{"id": "a", "role": "spec-analyst"},
+		{"id": "b", "role": "spec-architect", "depends_on": ["a"]},
+		{"id": "c", "role": "spec-developer", "depends_on": ["b"]},
+		{"id": "d", "role": "spec-validator", "depends_on": ["c"]}
+	]`
+
+	tests := []struct {
+		name        string
+		version     string
+		wantVersion string
+		wantErr     error
+	}{
+		{"supported", `"version": "1",`, "1", nil},
+		{"missing defaults to current", ``, CurrentConfigVersion, nil},
+		{"unsupported", `"version": "2",`, "", ErrUnsupportedConfigVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(`{` + tt.version + `"workflow": {"name": "test", ` + steps + `}}`)
+
+			cfg, err := NewLoader().LoadFromBytes(data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if cfg.Version != tt.wantVersion {
+				t.Errorf("expected version %q, got %q", tt.wantVersion, cfg.Version)
+			}
+		})
+	}
+}
+
+func TestLoader_LoadFromBytes_DependsOnAll(t *testing.T) {
+	data := []byte(`{
+		"workflow": {
+			"name": "sink-flow",
+			"type": "custom",
+			"steps": [
+				{"id": "a", "role": "lint"},
+				{"id": "b", "role": "test"},
+				{"id": "c", "role": "build", "depends_on": ["a"]},
+				{"id": "report", "role": "report", "depends_on": ["*"]}
+			]
+		}
+	}`)
+
+	cfg, err := NewLoader().LoadFromBytes(data)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got := cfg.Workflow.Steps[3].DependsOn
+	want := []string{"a", "b", "c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected report deps %v, got %v", want, got)
+	}
+	if !reflect.DeepEqual(cfg.Workflow.Steps[2].DependsOn, []string{"a"}) {
+		t.Errorf("expected explicit deps untouched, got %v", cfg.Workflow.Steps[2].DependsOn)
+	}
+}
+
+func TestLoader_LoadFromBytes_OptionalModeSkip(t *testing.T) {
+	data := []byte(`{
+		"workflow": {
+			"name": "toggled",
+			"type": "spec-default",
+			"optional_enabled": ["spec-reviewer"],
+			"optional_mode": "skip",
+			"steps": [
+				{"id": "analysis", "role": "spec-analyst"},
+				{"id": "architecture", "role": "spec-architect", "depends_on": ["analysis"]},
+				{"id": "implementation", "role": "spec-developer", "depends_on": ["architecture"]},
+				{"id": "validation", "role": "spec-validator", "depends_on": ["implementation"]},
+				{"id": "testing", "role": "spec-tester", "depends_on": ["validation"]},
+				{"id": "review", "role": "spec-reviewer", "depends_on": ["validation"]}
+			]
+		}
+	}`)
+
+	cfg, err := NewLoader().LoadFromBytes(data)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var skipped []string
+	for _, step := range cfg.Workflow.Steps {
+		if step.Skip {
+			skipped = append(skipped, step.ID)
+		}
+	}
+	if !reflect.DeepEqual(skipped, []string{"testing"}) {
+		t.Errorf("expected only testing skipped, got %v", skipped)
+	}
+
+	// The default mode rejects the step
+	strict := bytes.Replace(data, []byte(`"optional_mode": "skip",`), nil, 1)
+	if _, err := NewLoader().LoadFromBytes(strict); !errors.Is(err, ErrUnknownRole) {
+		t.Fatalf("expected ErrUnknownRole, got %v", err)
+	}
+}
+
+func TestLoader_LoadFromBytes_DependsOnAllCycle(t *testing.T) {
+	// A sink that something else depends on creates a cycle
+	data := []byte(`{
+		"workflow": {
+			"name": "sink-cycle",
+			"type": "custom",
+			"steps": [
+				{"id": "a", "role": "lint"},
+				{"id": "report", "role": "report", "depends_on": ["*"]},
+				{"id": "publish", "role": "publish", "depends_on": ["report"]}
+			]
+		}
+	}`)
+
+	_, err := NewLoader().LoadFromBytes(data)
+	if !errors.Is(err, ErrCycleDetected) {
+		t.Fatalf("expected ErrCycleDetected, got %v", err)
+	}
+}
+
+func TestLoader_LoadFromBytes_StepDefaults(t *testing.T) {
+	data := []byte(`{
+		"workflow": {
+			"name": "defaults-flow",
+			"type": "custom",
+			"defaults": {
+				"model": "claude-3-haiku-20240307",
+				"timeout_ms": 60000,
+				"context_policy": {"strategy": "truncate", "max_tokens": 4000},
+				"budget_share": 0.25
+			},
+			"steps": [
+				{"id": "a", "role": "lint"},
+				{"id": "b", "role": "build", "model": "claude-3-opus-20240229", "timeout_ms": 120000,
+				 "context_policy": {"strategy": "summarize"}, "budget_share": 0.5}
+			]
+		}
+	}`)
+
+	cfg, err := NewLoader().LoadFromBytes(data)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	a, b := cfg.Workflow.Steps[0], cfg.Workflow.Steps[1]
+	if a.Model != "claude-3-haiku-20240307" || a.TimeoutMs != 60000 || a.BudgetShare != 0.25 {
+		t.Errorf("expected defaults on step a, got model=%s timeout_ms=%d budget_share=%g",
+			a.Model, a.TimeoutMs, a.BudgetShare)
+	}
+	if want := (ContextPolicyConfig{Strategy: "truncate", MaxTokens: 4000}); a.ContextPolicy == nil || *a.ContextPolicy != want {
+		t.Errorf("expected default context policy %+v on step a, got %+v", want, a.ContextPolicy)
+	}
+	if a.ContextPolicy == cfg.Workflow.Defaults.ContextPolicy {
+		t.Error("expected step a to get its own copy of the default context policy")
+	}
+
+	if b.Model != "claude-3-opus-20240229" || b.TimeoutMs != 120000 || b.BudgetShare != 0.5 {
+		t.Errorf("expected explicit values on step b, got model=%s timeout_ms=%d budget_share=%g",
+			b.Model, b.TimeoutMs, b.BudgetShare)
+	}
+	if want := (ContextPolicyConfig{Strategy: "summarize"}); b.ContextPolicy == nil || *b.ContextPolicy != want {
+		t.Errorf("expected explicit context policy %+v on step b, got %+v", want, b.ContextPolicy)
+	}
+}
+
+func TestLoader_LoadFromBytes_InvalidStepDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults string
+		wantErr  error
+	}{
+		{"negative timeout", `{"timeout_ms": -1}`, ErrInvalidStepTimeout},
+		{"budget share above 1", `{"budget_share": 1.5}`, ErrInvalidBudgetShare},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(`{"workflow": {"name": "bad", "type": "custom", "defaults": ` + tt.defaults + `,
+				"steps": [{"id": "a", "role": "lint"}]}}`)
+			_, err := NewLoader().LoadFromBytes(data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+const yamlWorkflow = `
+# Comments are allowed in YAML configs
+workflow:
+  name: yaml-flow
+  models:
+    spec-analyst: claude-3-haiku-20240307
+  optional_roles: [spec-tester]
+  steps:
+    - id: a
+      role: spec-analyst
+      outputs: [requirements.md]
+    - id: b
+      role: spec-architect
+      depends_on: [a]
+    - id: c
+      role: spec-developer
+      depends_on: [b]
+    - id: d
+      role: spec-validator
+      depends_on: [c]
+`
+
+const jsonWorkflow = `{
+	"workflow": {
+		"name": "yaml-flow",
+		"models": {"spec-analyst": "claude-3-haiku-20240307"},
+		"optional_roles": ["spec-tester"],
+		"steps": [
+			{"id": "a", "role": "spec-analyst", "outputs": ["requirements.md"]},
+			{"id": "b", "role": "spec-architect", "depends_on": ["a"]},
+			{"id": "c", "role": "spec-developer", "depends_on": ["b"]},
+			{"id": "d", "role": "spec-validator", "depends_on": ["c"]}
+		]
+	}
+}`
+
+func TestLoader_LoadFromYAML_MatchesJSON(t *testing.T) {
+	l := NewLoader()
+	fromYAML, err := l.LoadFromYAML([]byte(yamlWorkflow))
+	if err != nil {
+		t.Fatalf("LoadFromYAML: %v", err)
+	}
+	fromJSON, err := l.LoadFromBytes([]byte(jsonWorkflow))
+	if err != nil {
+		t.Fatalf("LoadFromBytes: %v", err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Errorf("YAML config differs from JSON:\nyaml: %+v\njson: %+v", fromYAML, fromJSON)
+	}
+
+	// Content sniffing routes YAML through LoadFromBytes too
+	sniffed, err := l.LoadFromBytes([]byte(yamlWorkflow))
+	if err != nil {
+		t.Fatalf("LoadFromBytes(yaml): %v", err)
+	}
+	if !reflect.DeepEqual(sniffed, fromJSON) {
+		t.Errorf("sniffed YAML config differs from JSON: %+v", sniffed)
+	}
+}
+
+func TestLoader_LoadFromYAML_Errors(t *testing.T) {
+	l := NewLoader()
+	for _, data := range []string{"", "# only a comment\n"} {
+		if _, err := l.LoadFromYAML([]byte(data)); !errors.Is(err, ErrConfigEmpty) {
+			t.Errorf("%q: expected ErrConfigEmpty, got %v", data, err)
+		}
+	}
+
+	if _, err := l.LoadFromYAML([]byte("workflow: [unclosed")); err == nil {
+		t.Error("expected parse error for malformed YAML")
+	}
+
+	// Validation errors are the same as for JSON
+	_, err := l.LoadFromYAML([]byte("workflow:\n  name: x\n  steps: []\n"))
+	if !errors.Is(err, ErrNoSteps) {
+		t.Errorf("expected ErrNoSteps, got %v", err)
+	}
+}
+
+func TestLoader_LoadFromFile_YAML(t *testing.T) {
+	for _, name := range []string{"workflow.yaml", "workflow.yml"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(yamlWorkflow), 0644); err != nil {
+			t.Fatalf("failed to write temp file: %v", err)
+		}
+
+		cfg, err := NewLoader().LoadFromFile(path)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		if cfg.Workflow.Name != "yaml-flow" || len(cfg.Workflow.Steps) != 4 {
+			t.Errorf("%s: unexpected config %+v", name, cfg.Workflow)
+		}
+	}
+}
+
+func TestLoader_AllErrors(t *testing.T) {
+	data := []byte(`{
+		"workflow": {
+			"name": "",
+			"type": "custom",
+			"steps": [
+				{"id": "a", "role": "researcher", "depends_on": ["missing"]},
+				{"id": "a", "role": "writer"}
+			]
+		}
+	}`)
+
+	_, err := NewLoader().LoadFromBytes(data)
+	if !errors.Is(err, ErrWorkflowNameEmpty) || errors.Is(err, ErrStepIDDuplicate) {
+		t.Fatalf("expected only the first error by default, got %v", err)
+	}
+
+	_, err = (&Loader{AllErrors: true}).LoadFromBytes(data)
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 3 {
+		t.Fatalf("expected 3 ValidationErrors, got %v", err)
+	}
+	for _, want := range []error{ErrWorkflowNameEmpty, ErrStepIDDuplicate, ErrDependencyNotFound} {
+		if !errors.Is(err, want) {
+			t.Errorf("expected errors.Is(err, %v)", want)
+		}
+	}
+	wantMsg := "3 validation errors:\n" +
+		"  - workflow.name is required\n" +
+		"  - step.id=a: duplicate step.id\n" +
+		"  - step.id=a depends_on=missing: depends_on references unknown step id"
+	if err.Error() != wantMsg {
+		t.Errorf("message = %q, want %q", err.Error(), wantMsg)
+	}
+}
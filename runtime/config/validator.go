@@ -1,9 +1,21 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
 
 // Validator validates workflow configurations.
-type Validator struct{}
+type Validator struct {
+	// StrictModels makes a workflow.models entry for a role no step uses an
+	// error (ErrUnusedModelMapping). Otherwise ValidateWithWarnings reports
+	// it as a warning.
+	StrictModels bool
+}
 
 // NewValidator creates a new configuration validator.
 func NewValidator() *Validator {
@@ -11,20 +23,35 @@ func NewValidator() *Validator {
 }
 
 // Validate performs comprehensive validation of a WorkflowConfig.
-// Returns nil if valid, or an error describing the first validation failure.
+// Returns nil if valid, or an error describing the first validation failure
+// (the first error ValidateAll reports).
 func (v *Validator) Validate(cfg *WorkflowConfig) error {
+	if errs := v.ValidateAll(cfg); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ValidateAll validates cfg like Validate but reports every violation
+// instead of stopping at the first, in the order Validate checks them.
+// Checks that would only repeat an earlier error are left out: role order
+// and dependency chain checks need each required role exactly once.
+// Returns nil if cfg is valid.
+func (v *Validator) ValidateAll(cfg *WorkflowConfig) []error {
 	if cfg == nil {
-		return ErrConfigEmpty
+		return []error{ErrConfigEmpty}
 	}
 
+	var errs []error
+
 	// 1. Validate workflow.name is not empty
 	if cfg.Workflow.Name == "" {
-		return ErrWorkflowNameEmpty
+		errs = append(errs, ErrWorkflowNameEmpty)
 	}
 
 	// 2. Validate steps is not empty
 	if len(cfg.Workflow.Steps) == 0 {
-		return ErrNoSteps
+		return append(errs, ErrNoSteps)
 	}
 
 	// 3. Validate each step has id and role, collect id set
@@ -32,49 +59,228 @@ func (v *Validator) Validate(cfg *WorkflowConfig) error {
 	roleSet := make(map[Role]bool)
 
 	for i, step := range cfg.Workflow.Steps {
-		if step.ID == "" {
-			return fmt.Errorf("step[%d]: %w", i, ErrStepIDEmpty)
+		switch {
+		case step.ID == "":
+			errs = append(errs, fmt.Errorf("step[%d]: %w", i, ErrStepIDEmpty))
+		case stepIDs[step.ID]:
+			errs = append(errs, fmt.Errorf("step.id=%s: %w", step.ID, ErrStepIDDuplicate))
+		default:
+			stepIDs[step.ID] = true
 		}
 
-		if stepIDs[step.ID] {
-			return fmt.Errorf("step.id=%s: %w", step.ID, ErrStepIDDuplicate)
+		if step.Role == "" {
+			errs = append(errs, fmt.Errorf("step[%d] id=%s: %w", i, step.ID, ErrStepRoleEmpty))
+		} else {
+			roleSet[Role(step.Role)] = true
 		}
-		stepIDs[step.ID] = true
 
-		if step.Role == "" {
-			return fmt.Errorf("step[%d] id=%s: %w", i, step.ID, ErrStepRoleEmpty)
+		for _, category := range step.RetryOn {
+			if !slices.Contains(contracts.RetryCategories(), category) {
+				errs = append(errs, fmt.Errorf("step.id=%s retry_on=%s: %w", step.ID, category, ErrUnknownRetryCategory))
+			}
+		}
+		if r := step.Retry; r != nil && (r.MaxAttempts < 1 || r.BackoffMs < 0) {
+			errs = append(errs, fmt.Errorf("step.id=%s retry=%+v: %w", step.ID, *r, ErrInvalidRetry))
 		}
 
-		roleSet[Role(step.Role)] = true
+		if step.TimeoutMs < 0 {
+			errs = append(errs, fmt.Errorf("step.id=%s timeout_ms=%d: %w", step.ID, step.TimeoutMs, ErrInvalidStepTimeout))
+		}
+		if step.BudgetShare < 0 || step.BudgetShare > 1 {
+			errs = append(errs, fmt.Errorf("step.id=%s budget_share=%g: %w", step.ID, step.BudgetShare, ErrInvalidBudgetShare))
+		}
+	}
+
+	// 3a. Validate concurrency limits are positive
+	if cfg.Workflow.Policy != nil {
+		keys := make([]string, 0, len(cfg.Workflow.Policy.ConcurrencyLimits))
+		for key := range cfg.Workflow.Policy.ConcurrencyLimits {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			if limit := cfg.Workflow.Policy.ConcurrencyLimits[key]; limit <= 0 {
+				errs = append(errs, fmt.Errorf("policy.concurrency_limits[%s]=%d: %w", key, limit, ErrInvalidConcurrencyLimit))
+			}
+		}
+	}
+
+	// 3a''. Validate workflow.models: non-empty IDs, only roles in use
+	errs = append(errs, v.validateModels(cfg.Workflow.Models, roleSet)...)
+
+	// 3a'. Validate optional_mode
+	switch cfg.Workflow.OptionalMode {
+	case OptionalModeReject, OptionalModeSkip:
+	default:
+		errs = append(errs, fmt.Errorf("optional_mode=%s: %w", cfg.Workflow.OptionalMode, ErrInvalidOptionalMode))
+	}
+
+	// 3b. Validate at least one step is enabled (all-disabled would be a no-op run)
+	enabled := 0
+	for _, step := range cfg.Workflow.Steps {
+		if !step.Disabled {
+			enabled++
+		}
+	}
+	if enabled == 0 {
+		errs = append(errs, ErrNoEnabledSteps)
 	}
 
 	// 4. Validate depends_on references existing ids
 	for _, step := range cfg.Workflow.Steps {
 		for _, depID := range step.DependsOn {
 			if !stepIDs[depID] {
-				return fmt.Errorf("step.id=%s depends_on=%s: %w",
-					step.ID, depID, ErrDependencyNotFound)
+				errs = append(errs, fmt.Errorf("step.id=%s depends_on=%s: %w",
+					step.ID, depID, ErrDependencyNotFound))
 			}
 		}
 	}
 
+	// 4a. Validate no two steps declare the same output
+	errs = append(errs, v.validateUniqueOutputs(cfg.Workflow.Steps)...)
+
 	// 5. Validate no cycles (DFS with color marking)
 	if err := v.detectCycle(cfg.Workflow.Steps); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	// 6. Type-based validation dispatch
 	switch cfg.Workflow.Type {
 	case WorkflowTypeSpecDefault:
 		// Strict canonical validation
-		return v.validateSpecDefault(&cfg.Workflow, cfg.Workflow.Steps, roleSet)
+		errs = append(errs, v.validateSpecDefault(&cfg.Workflow, cfg.Workflow.Steps, roleSet)...)
 	case WorkflowTypeCustom:
 		// Skip required role checking entirely
-		return nil
 	default:
 		// type == "" (empty): current behavior - required roles must be present
-		return v.validateRequiredRolesPresent(roleSet)
+		errs = append(errs, v.validateRequiredRolesPresent(roleSet)...)
+	}
+	return errs
+}
+
+// Warning is an advisory validation finding: the config is valid but likely
+// not what was intended.
+type Warning struct {
+	StepID  string // empty for workflow-level warnings
+	Message string
+}
+
+// String formats the warning like validation errors.
+func (w Warning) String() string {
+	if w.StepID == "" {
+		return w.Message
+	}
+	return fmt.Sprintf("step.id=%s: %s", w.StepID, w.Message)
+}
+
+// ValidateWithWarnings validates cfg like Validate and, if it is valid, also
+// returns advisory warnings in step order. Warnings never fail validation.
+func (v *Validator) ValidateWithWarnings(cfg *WorkflowConfig) ([]Warning, error) {
+	if err := v.Validate(cfg); err != nil {
+		return nil, err
+	}
+
+	steps := cfg.Workflow.Steps
+	disabled := make(map[string]bool)
+	for _, step := range steps {
+		if step.Disabled {
+			disabled[step.ID] = true
+		}
+	}
+
+	var warnings []Warning
+	var roots []string
+	warnedDisabled := make(map[string]bool)
+	for _, step := range steps {
+		if step.Disabled {
+			continue
+		}
+		if len(step.Outputs) == 0 {
+			warnings = append(warnings, Warning{StepID: step.ID, Message: "step has no outputs"})
+		}
+		if len(step.DependsOn) == 0 {
+			roots = append(roots, step.ID)
+		}
+		for _, depID := range step.DependsOn {
+			if disabled[depID] && !warnedDisabled[depID] {
+				warnedDisabled[depID] = true
+				warnings = append(warnings, Warning{StepID: depID,
+					Message: "disabled step has enabled dependents; they inherit its dependencies"})
+			}
+		}
+	}
+
+	if !v.StrictModels {
+		for _, role := range unusedModelRoles(cfg.Workflow.Models, steps) {
+			warnings = append(warnings, Warning{Message: fmt.Sprintf(
+				"workflow.models maps role %s, which no step uses", role)})
+		}
 	}
+
+	// Spec workflows hand one analysis down a single chain
+	if len(roots) > 1 {
+		warnings = append(warnings, Warning{Message: fmt.Sprintf(
+			"workflow has %d root steps (%s); spec workflows assume a single root",
+			len(roots), strings.Join(roots, ", "))})
+	}
+	return warnings, nil
+}
+
+// validateModels returns ErrModelEmpty for each workflow.models entry with
+// a blank model ID and, with StrictModels, ErrUnusedModelMapping for each
+// entry whose role no step uses (typically a typo). Roles are checked in
+// sorted order so the errors are deterministic.
+func (v *Validator) validateModels(models map[string]string, roleSet map[Role]bool) []error {
+	roles := make([]string, 0, len(models))
+	for role := range models {
+		roles = append(roles, role)
+	}
+	slices.Sort(roles)
+
+	var errs []error
+	for _, role := range roles {
+		if strings.TrimSpace(models[role]) == "" {
+			errs = append(errs, fmt.Errorf("models[%s]: %w", role, ErrModelEmpty))
+		} else if v.StrictModels && !roleSet[Role(role)] {
+			errs = append(errs, fmt.Errorf("models[%s]: %w", role, ErrUnusedModelMapping))
+		}
+	}
+	return errs
+}
+
+// unusedModelRoles returns the sorted workflow.models roles no step uses.
+func unusedModelRoles(models map[string]string, steps []Step) []string {
+	var unused []string
+	for role := range models {
+		if !slices.ContainsFunc(steps, func(s Step) bool { return s.Role == role }) {
+			unused = append(unused, role)
+		}
+	}
+	slices.Sort(unused)
+	return unused
+}
+
+// validateUniqueOutputs returns ErrDuplicateOutput for each step that
+// declares an output path an earlier step already declared; they would
+// overwrite each other's file. Paths are compared after path.Clean, so
+// "src/" and "./src" are the same output. A step listing a path twice is
+// not an error.
+func (v *Validator) validateUniqueOutputs(steps []Step) []error {
+	var errs []error
+	owner := make(map[string]string)
+	for _, step := range steps {
+		for _, output := range step.Outputs {
+			p := path.Clean(output)
+			prev, ok := owner[p]
+			if !ok {
+				owner[p] = step.ID
+			} else if prev != step.ID {
+				errs = append(errs, fmt.Errorf("output=%s declared by step.id=%s and step.id=%s: %w",
+					output, prev, step.ID, ErrDuplicateOutput))
+			}
+		}
+	}
+	return errs
 }
 
 // detectCycle uses DFS with color marking to detect cycles in dependencies.
@@ -133,32 +339,74 @@ func (v *Validator) hasCycle(node string, colors map[string]int, adj map[string]
 
 // validateRequiredRolesPresent checks that all required roles are present (no order).
 // Used for type == "" (empty) backwards compatibility.
-func (v *Validator) validateRequiredRolesPresent(roleSet map[Role]bool) error {
+func (v *Validator) validateRequiredRolesPresent(roleSet map[Role]bool) []error {
+	var errs []error
 	for _, requiredRole := range RequiredRoles() {
 		if !roleSet[requiredRole] {
-			return fmt.Errorf("role=%s: %w", requiredRole, ErrRequiredRoleMissing)
+			errs = append(errs, fmt.Errorf("role=%s: %w", requiredRole, ErrRequiredRoleMissing))
+		}
+	}
+	return errs
+}
+
+// validateValidatorIsSink returns ErrRequiredAfterValidator if any required
+// step depends on the spec-validator step, directly or through other steps.
+// Assumes dependencies exist and are acyclic.
+func (v *Validator) validateValidatorIsSink(steps []Step, requiredSet map[Role]bool) error {
+	var validatorID string
+	dependents := make(map[string][]string)
+	for _, step := range steps {
+		if Role(step.Role) == RoleSpecValidator {
+			validatorID = step.ID
+		}
+		for _, depID := range step.DependsOn {
+			dependents[depID] = append(dependents[depID], step.ID)
+		}
+	}
+	if validatorID == "" {
+		return nil
+	}
+
+	roleByID := make(map[string]string, len(steps))
+	for _, step := range steps {
+		roleByID[step.ID] = step.Role
+	}
+
+	// Walk everything downstream of the validator
+	visited := map[string]bool{validatorID: true}
+	queue := []string{validatorID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range dependents[id] {
+			if visited[next] {
+				continue
+			}
+			if requiredSet[Role(roleByID[next])] {
+				return fmt.Errorf("step.id=%s (role=%s) must not depend on %s: %w",
+					next, roleByID[next], validatorID, ErrRequiredAfterValidator)
+			}
+			visited[next] = true
+			queue = append(queue, next)
 		}
 	}
 	return nil
 }
 
 // validateSpecDefault performs strict canonical validation for spec-default workflow.
-func (v *Validator) validateSpecDefault(wf *Workflow, steps []Step, roleSet map[Role]bool) error {
+// The order and chain checks (5-7) are skipped while a required role is
+// missing or duplicated, since they would only repeat that error.
+func (v *Validator) validateSpecDefault(wf *Workflow, steps []Step, roleSet map[Role]bool) []error {
 	requiredRoles := RequiredRoles()
+	var errs []error
 
 	// 1. Determine effective optional roles
-	var effectiveOptional []Role
-	if len(wf.OptionalRoles) > 0 {
-		for _, r := range wf.OptionalRoles {
-			effectiveOptional = append(effectiveOptional, Role(r))
-		}
-	} else {
-		effectiveOptional = OptionalRoles() // default
-	}
+	effectiveOptional := effectiveOptionalRoles(wf)
 
 	// 2. Determine allowed optional roles for steps
 	// If optional_enabled empty → all effectiveOptional allowed
-	// If optional_enabled set → only those roles allowed
+	// If optional_enabled set → only those roles allowed, unless
+	// optional_mode is skip: the others are allowed and skipped
 	var allowedOptional []Role
 	if len(wf.OptionalEnabled) > 0 {
 		// Build effective optional set for validation
@@ -169,10 +417,14 @@ func (v *Validator) validateSpecDefault(wf *Workflow, steps []Step, roleSet map[
 		// Validate optional_enabled is subset of effectiveOptional
 		for _, r := range wf.OptionalEnabled {
 			if !effectiveOptionalSet[Role(r)] {
-				return fmt.Errorf("role=%s: %w", r, ErrOptionalNotAllowed)
+				errs = append(errs, fmt.Errorf("role=%s: %w", r, ErrOptionalNotAllowed))
+				continue
 			}
 			allowedOptional = append(allowedOptional, Role(r))
 		}
+		if wf.OptionalMode == OptionalModeSkip {
+			allowedOptional = effectiveOptional
+		}
 	} else {
 		// Empty optional_enabled → all effectiveOptional allowed
 		allowedOptional = effectiveOptional
@@ -189,12 +441,28 @@ func (v *Validator) validateSpecDefault(wf *Workflow, steps []Step, roleSet map[
 	}
 
 	// 3. Check all roles are either required or allowed optional
+	// (an empty role is already reported as ErrStepRoleEmpty)
 	for _, step := range steps {
 		role := Role(step.Role)
-		if !requiredSet[role] && !optionalSet[role] {
-			return fmt.Errorf("step.id=%s role=%s: %w", step.ID, step.Role, ErrUnknownRole)
+		if role != "" && !requiredSet[role] && !optionalSet[role] {
+			errs = append(errs, fmt.Errorf("step.id=%s role=%s: %w", step.ID, step.Role, ErrUnknownRole))
+		}
+	}
+
+	// 3a. Check optional-role step count is within limit
+	maxOptional := wf.MaxOptionalSteps
+	if maxOptional <= 0 {
+		maxOptional = DefaultMaxOptionalSteps
+	}
+	optionalCount := 0
+	for _, step := range steps {
+		if optionalSet[Role(step.Role)] {
+			optionalCount++
 		}
 	}
+	if optionalCount > maxOptional {
+		errs = append(errs, fmt.Errorf("%d optional steps, max %d: %w", optionalCount, maxOptional, ErrTooManyOptionalSteps))
+	}
 
 	// 4. Check required roles are present exactly once
 	roleCounts := make(map[Role]int)
@@ -204,15 +472,20 @@ func (v *Validator) validateSpecDefault(wf *Workflow, steps []Step, roleSet map[
 			roleCounts[role]++
 		}
 	}
+	countsOK := true
 	for _, reqRole := range requiredRoles {
-		count := roleCounts[reqRole]
-		if count == 0 {
-			return fmt.Errorf("role=%s: %w", reqRole, ErrRequiredRoleMissing)
-		}
-		if count > 1 {
-			return fmt.Errorf("role=%s: %w", reqRole, ErrRequiredRoleDuplicate)
+		switch count := roleCounts[reqRole]; {
+		case count == 0:
+			errs = append(errs, fmt.Errorf("role=%s: %w", reqRole, ErrRequiredRoleMissing))
+			countsOK = false
+		case count > 1:
+			errs = append(errs, fmt.Errorf("role=%s: %w", reqRole, ErrRequiredRoleDuplicate))
+			countsOK = false
 		}
 	}
+	if !countsOK {
+		return errs
+	}
 
 	// 5. Check required roles are in canonical order
 	// Find steps with required roles and check their order matches
@@ -224,16 +497,24 @@ func (v *Validator) validateSpecDefault(wf *Workflow, steps []Step, roleSet map[
 		}
 	}
 
-	// Check order matches canonical order
+	// Check order matches canonical order; one misplaced step shifts the
+	// rest, so only the first mismatch is reported
 	for i, step := range requiredSteps {
 		expectedRole := requiredRoles[i]
 		actualRole := Role(step.Role)
 		if actualRole != expectedRole {
-			return fmt.Errorf("step.id=%s: expected role=%s at position %d, got %s: %w",
-				step.ID, expectedRole, i, actualRole, ErrRequiredRoleOrder)
+			errs = append(errs, fmt.Errorf("step.id=%s: expected role=%s at position %d, got %s: %w",
+				step.ID, expectedRole, i, actualRole, ErrRequiredRoleOrder))
+			break
 		}
 	}
 
+	// 5a. Check spec-validator is the sink of the required chain:
+	// only optional steps may depend on it, directly or transitively
+	if err := v.validateValidatorIsSink(steps, requiredSet); err != nil {
+		errs = append(errs, err)
+	}
+
 	// 6. Check dependency chain for required steps
 	// Each required step (except first) must depend on the previous required step
 	stepByRole := make(map[Role]Step)
@@ -259,8 +540,8 @@ func (v *Validator) validateSpecDefault(wf *Workflow, steps []Step, roleSet map[
 			}
 		}
 		if !dependsOnPrev {
-			return fmt.Errorf("step.id=%s (role=%s) must depend on step.id=%s (role=%s): %w",
-				currentStep.ID, currentRole, prevStep.ID, prevRole, ErrInvalidDependencyChain)
+			errs = append(errs, fmt.Errorf("step.id=%s (role=%s) must depend on step.id=%s (role=%s): %w",
+				currentStep.ID, currentRole, prevStep.ID, prevRole, ErrInvalidDependencyChain))
 		}
 	}
 
@@ -278,11 +559,24 @@ func (v *Validator) validateSpecDefault(wf *Workflow, steps []Step, roleSet map[
 				}
 			}
 			if !dependsOnValidator {
-				return fmt.Errorf("step.id=%s (role=%s) must depend on %s: %w",
-					step.ID, step.Role, validatorStep.ID, ErrOptionalRolePlacement)
+				errs = append(errs, fmt.Errorf("step.id=%s (role=%s) must depend on %s: %w",
+					step.ID, step.Role, validatorStep.ID, ErrOptionalRolePlacement))
 			}
 		}
 	}
 
-	return nil
+	return errs
+}
+
+// effectiveOptionalRoles returns the workflow's optional_roles, or the
+// default OptionalRoles if it sets none.
+func effectiveOptionalRoles(wf *Workflow) []Role {
+	if len(wf.OptionalRoles) == 0 {
+		return OptionalRoles()
+	}
+	roles := make([]Role, len(wf.OptionalRoles))
+	for i, r := range wf.OptionalRoles {
+		roles[i] = Role(r)
+	}
+	return roles
 }
@@ -2,6 +2,9 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -41,6 +44,36 @@ func TestValidator_NoSteps(t *testing.T) {
 	}
 }
 
+func TestValidator_RetryOn(t *testing.T) {
+	v := NewValidator()
+	newConfig := func(retryOn ...string) *WorkflowConfig {
+		return &WorkflowConfig{
+			Workflow: Workflow{
+				Name:  "test",
+				Type:  WorkflowTypeCustom,
+				Steps: []Step{{ID: "a", Role: "spec-analyst", RetryOn: retryOn}},
+			},
+		}
+	}
+
+	if err := v.Validate(newConfig("timeout", "rate_limit", "transient")); err != nil {
+		t.Fatalf("expected known categories to validate, got %v", err)
+	}
+	if err := v.Validate(newConfig("timeout", "schema")); !errors.Is(err, ErrUnknownRetryCategory) {
+		t.Fatalf("expected ErrUnknownRetryCategory, got %v", err)
+	}
+
+	cfg := newConfig()
+	cfg.Workflow.Steps[0].Retry = &RetryConfig{MaxAttempts: 3, BackoffMs: 500}
+	if err := v.Validate(cfg); err != nil {
+		t.Fatalf("expected retry policy to validate, got %v", err)
+	}
+	cfg.Workflow.Steps[0].Retry = &RetryConfig{MaxAttempts: 0}
+	if err := v.Validate(cfg); !errors.Is(err, ErrInvalidRetry) {
+		t.Fatalf("expected ErrInvalidRetry, got %v", err)
+	}
+}
+
 func TestValidator_StepIDEmpty(t *testing.T) {
 	v := NewValidator()
 	cfg := &WorkflowConfig{
@@ -252,6 +285,46 @@ func TestValidator_ValidConfig_WithOutputs(t *testing.T) {
 	}
 }
 
+func TestValidator_DuplicateOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs [2][]string
+		wantErr bool
+	}{
+		{name: "distinct", outputs: [2][]string{{"requirements.md"}, {"architecture.md"}}},
+		{name: "repeated within a step", outputs: [2][]string{{"notes.md", "notes.md"}, {"design.md"}}},
+		{name: "same path", outputs: [2][]string{{"requirements.md"}, {"design.md", "requirements.md"}}, wantErr: true},
+		{name: "same cleaned path", outputs: [2][]string{{"src/"}, {"./src"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &WorkflowConfig{
+				Workflow: Workflow{
+					Name: "output-flow",
+					Type: WorkflowTypeCustom,
+					Steps: []Step{
+						{ID: "analysis", Role: "analyst", Outputs: tt.outputs[0]},
+						{ID: "architecture", Role: "architect", DependsOn: []string{"analysis"}, Outputs: tt.outputs[1]},
+					},
+				},
+			}
+			err := NewValidator().Validate(cfg)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrDuplicateOutput) {
+				t.Fatalf("expected ErrDuplicateOutput, got %v", err)
+			}
+			if msg := err.Error(); !strings.Contains(msg, "step.id=analysis") || !strings.Contains(msg, "step.id=architecture") {
+				t.Errorf("error %q should name both steps", msg)
+			}
+		})
+	}
+}
+
 func TestValidator_CustomWorkflow_WithoutRequiredRoles(t *testing.T) {
 	// Custom workflow with different roles - type=custom skips required role check
 	v := NewValidator()
@@ -662,6 +735,40 @@ func TestValidator_SpecDefault_OptionalEnabledRejectsNonEnabledDefault(t *testin
 	}
 }
 
+func TestValidator_SpecDefault_OptionalModeSkip(t *testing.T) {
+	// optional_mode skip allows optional steps that are not enabled
+	v := NewValidator()
+	cfg := &WorkflowConfig{
+		Workflow: Workflow{
+			Name:            "optional-mode-skip",
+			Type:            WorkflowTypeSpecDefault,
+			OptionalEnabled: []string{"spec-reviewer"},
+			OptionalMode:    OptionalModeSkip,
+			Steps: []Step{
+				{ID: "analysis", Role: "spec-analyst"},
+				{ID: "architecture", Role: "spec-architect", DependsOn: []string{"analysis"}},
+				{ID: "implementation", Role: "spec-developer", DependsOn: []string{"architecture"}},
+				{ID: "validation", Role: "spec-validator", DependsOn: []string{"implementation"}},
+				{ID: "testing", Role: "spec-tester", DependsOn: []string{"validation"}}, // Not enabled
+			},
+		},
+	}
+	if err := v.Validate(cfg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Roles outside optional_roles are still unknown
+	cfg.Workflow.Steps[4].Role = "spec-qa"
+	if err := v.Validate(cfg); !errors.Is(err, ErrUnknownRole) {
+		t.Fatalf("expected ErrUnknownRole, got %v", err)
+	}
+
+	cfg.Workflow.OptionalMode = "ignore"
+	if err := v.Validate(cfg); !errors.Is(err, ErrInvalidOptionalMode) {
+		t.Fatalf("expected ErrInvalidOptionalMode, got %v", err)
+	}
+}
+
 func TestValidator_SpecDefault_OptionalRoleMustDependOnValidator(t *testing.T) {
 	// Even with custom optional_roles, placement rule applies
 	v := NewValidator()
@@ -684,3 +791,325 @@ func TestValidator_SpecDefault_OptionalRoleMustDependOnValidator(t *testing.T) {
 		t.Fatalf("expected ErrOptionalRolePlacement, got %v", err)
 	}
 }
+
+func TestValidator_SpecDefault_RequiredAfterValidator(t *testing.T) {
+	// Validator deps are kept acyclic so the sink check, not cycle detection, fires
+	v := NewValidator()
+	cfg := &WorkflowConfig{
+		Workflow: Workflow{
+			Name: "required-after-validator",
+			Type: WorkflowTypeSpecDefault,
+			Steps: []Step{
+				{ID: "analysis", Role: "spec-analyst"},
+				{ID: "architecture", Role: "spec-architect", DependsOn: []string{"analysis"}},
+				{ID: "implementation", Role: "spec-developer", DependsOn: []string{"architecture", "validation"}}, // Wrong
+				{ID: "validation", Role: "spec-validator", DependsOn: []string{"architecture"}},
+			},
+		},
+	}
+	err := v.Validate(cfg)
+	if !errors.Is(err, ErrRequiredAfterValidator) {
+		t.Fatalf("expected ErrRequiredAfterValidator, got %v", err)
+	}
+}
+
+func TestValidator_SpecDefault_RequiredAfterValidatorViaOptional(t *testing.T) {
+	v := NewValidator()
+	cfg := &WorkflowConfig{
+		Workflow: Workflow{
+			Name: "required-after-optional",
+			Type: WorkflowTypeSpecDefault,
+			Steps: []Step{
+				{ID: "analysis", Role: "spec-analyst"},
+				{ID: "architecture", Role: "spec-architect", DependsOn: []string{"analysis"}},
+				{ID: "implementation", Role: "spec-developer", DependsOn: []string{"architecture", "testing"}}, // Wrong
+				{ID: "validation", Role: "spec-validator", DependsOn: []string{"architecture"}},
+				{ID: "testing", Role: "spec-tester", DependsOn: []string{"validation"}},
+			},
+		},
+	}
+	err := v.Validate(cfg)
+	if !errors.Is(err, ErrRequiredAfterValidator) {
+		t.Fatalf("expected ErrRequiredAfterValidator, got %v", err)
+	}
+}
+
+func TestValidator_SpecDefault_OptionalStepsAtLimit(t *testing.T) {
+	v := NewValidator()
+	cfg := &WorkflowConfig{
+		Workflow: Workflow{
+			Name:             "optional-at-limit",
+			Type:             WorkflowTypeSpecDefault,
+			MaxOptionalSteps: 2,
+			Steps: []Step{
+				{ID: "analysis", Role: "spec-analyst"},
+				{ID: "architecture", Role: "spec-architect", DependsOn: []string{"analysis"}},
+				{ID: "implementation", Role: "spec-developer", DependsOn: []string{"architecture"}},
+				{ID: "validation", Role: "spec-validator", DependsOn: []string{"implementation"}},
+				{ID: "testing", Role: "spec-tester", DependsOn: []string{"validation"}},
+				{ID: "review", Role: "spec-reviewer", DependsOn: []string{"validation"}},
+			},
+		},
+	}
+	err := v.Validate(cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestValidator_SpecDefault_TooManyOptionalSteps(t *testing.T) {
+	v := NewValidator()
+	cfg := &WorkflowConfig{
+		Workflow: Workflow{
+			Name:             "optional-over-limit",
+			Type:             WorkflowTypeSpecDefault,
+			MaxOptionalSteps: 2,
+			Steps: []Step{
+				{ID: "analysis", Role: "spec-analyst"},
+				{ID: "architecture", Role: "spec-architect", DependsOn: []string{"analysis"}},
+				{ID: "implementation", Role: "spec-developer", DependsOn: []string{"architecture"}},
+				{ID: "validation", Role: "spec-validator", DependsOn: []string{"implementation"}},
+				{ID: "testing", Role: "spec-tester", DependsOn: []string{"validation"}},
+				{ID: "review", Role: "spec-reviewer", DependsOn: []string{"validation"}},
+				{ID: "testing2", Role: "spec-tester", DependsOn: []string{"validation"}},
+			},
+		},
+	}
+	err := v.Validate(cfg)
+	if !errors.Is(err, ErrTooManyOptionalSteps) {
+		t.Fatalf("expected ErrTooManyOptionalSteps, got %v", err)
+	}
+}
+
+func TestValidator_SpecDefault_TooManyOptionalSteps_DefaultLimit(t *testing.T) {
+	v := NewValidator()
+	steps := []Step{
+		{ID: "analysis", Role: "spec-analyst"},
+		{ID: "architecture", Role: "spec-architect", DependsOn: []string{"analysis"}},
+		{ID: "implementation", Role: "spec-developer", DependsOn: []string{"architecture"}},
+		{ID: "validation", Role: "spec-validator", DependsOn: []string{"implementation"}},
+	}
+	for i := 0; i <= DefaultMaxOptionalSteps; i++ {
+		steps = append(steps, Step{
+			ID:        fmt.Sprintf("testing-%d", i),
+			Role:      "spec-tester",
+			DependsOn: []string{"validation"},
+		})
+	}
+	cfg := &WorkflowConfig{
+		Workflow: Workflow{
+			Name:  "optional-over-default",
+			Type:  WorkflowTypeSpecDefault,
+			Steps: steps,
+		},
+	}
+	err := v.Validate(cfg)
+	if !errors.Is(err, ErrTooManyOptionalSteps) {
+		t.Fatalf("expected ErrTooManyOptionalSteps, got %v", err)
+	}
+}
+
+func TestValidator_AllStepsDisabled(t *testing.T) {
+	v := NewValidator()
+	cfg := &WorkflowConfig{
+		Workflow: Workflow{
+			Name: "all-disabled",
+			Type: WorkflowTypeCustom,
+			Steps: []Step{
+				{ID: "a", Role: "worker", Disabled: true},
+				{ID: "b", Role: "worker", DependsOn: []string{"a"}, Disabled: true},
+			},
+		},
+	}
+	err := v.Validate(cfg)
+	if !errors.Is(err, ErrNoEnabledSteps) {
+		t.Fatalf("expected ErrNoEnabledSteps, got %v", err)
+	}
+}
+
+func TestValidator_SomeStepsDisabled(t *testing.T) {
+	v := NewValidator()
+	cfg := &WorkflowConfig{
+		Workflow: Workflow{
+			Name: "some-disabled",
+			Type: WorkflowTypeCustom,
+			Steps: []Step{
+				{ID: "a", Role: "worker", Disabled: true},
+				{ID: "b", Role: "worker", DependsOn: []string{"a"}},
+			},
+		},
+	}
+	err := v.Validate(cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestValidator_ValidateWithWarnings(t *testing.T) {
+	v := NewValidator()
+	cfg := &WorkflowConfig{
+		Workflow: Workflow{
+			Name: "smelly",
+			Type: WorkflowTypeCustom,
+			Steps: []Step{
+				{ID: "a", Role: "researcher", Outputs: []string{"notes.md"}},
+				{ID: "b", Role: "researcher"},
+				{ID: "draft", Role: "writer", Disabled: true, DependsOn: []string{"a"}},
+				{ID: "c", Role: "writer", DependsOn: []string{"b", "draft"}, Outputs: []string{"report.md"}},
+			},
+		},
+	}
+	warnings, err := v.ValidateWithWarnings(cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var got []string
+	for _, w := range warnings {
+		got = append(got, w.String())
+	}
+	want := []string{
+		"step.id=b: step has no outputs",
+		"step.id=draft: disabled step has enabled dependents; they inherit its dependencies",
+		"workflow has 2 root steps (a, b); spec workflows assume a single root",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+
+	// Hard errors stay fatal and produce no warnings
+	cfg.Workflow.Steps[3].DependsOn = []string{"missing"}
+	warnings, err = v.ValidateWithWarnings(cfg)
+	if !errors.Is(err, ErrDependencyNotFound) || warnings != nil {
+		t.Errorf("expected ErrDependencyNotFound and no warnings, got %v, %v", warnings, err)
+	}
+
+	// A clean config has none
+	warnings, err = v.ValidateWithWarnings(&WorkflowConfig{Workflow: Workflow{
+		Name: "clean",
+		Steps: []Step{
+			{ID: "analysis", Role: "spec-analyst", Outputs: []string{"requirements.md"}},
+			{ID: "architecture", Role: "spec-architect", DependsOn: []string{"analysis"}, Outputs: []string{"design.md"}},
+			{ID: "implementation", Role: "spec-developer", DependsOn: []string{"architecture"}, Outputs: []string{"code"}},
+			{ID: "validation", Role: "spec-validator", DependsOn: []string{"implementation"}, Outputs: []string{"report.md"}},
+		},
+	}})
+	if err != nil || len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v, %v", warnings, err)
+	}
+}
+
+func TestValidator_Models(t *testing.T) {
+	newCfg := func(models map[string]string) *WorkflowConfig {
+		return &WorkflowConfig{Workflow: Workflow{
+			Name:   "models",
+			Type:   WorkflowTypeCustom,
+			Models: models,
+			Steps: []Step{
+				{ID: "a", Role: "researcher", Outputs: []string{"notes.md"}},
+				{ID: "b", Role: "writer", DependsOn: []string{"a"}, Outputs: []string{"report.md"}},
+			},
+		}}
+	}
+	typo := map[string]string{"researcher": "claude-sonnet-4-20250514", "writter": "claude-opus-4-20250514"}
+
+	// Lenient: the stray entry is a warning
+	warnings, err := NewValidator().ValidateWithWarnings(newCfg(typo))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "workflow.models maps role writter, which no step uses"
+	if len(warnings) != 1 || warnings[0].String() != want {
+		t.Errorf("warnings = %v, want [%s]", warnings, want)
+	}
+
+	// Strict: it fails validation
+	strict := &Validator{StrictModels: true}
+	err = strict.Validate(newCfg(typo))
+	if !errors.Is(err, ErrUnusedModelMapping) || !strings.Contains(err.Error(), "models[writter]") {
+		t.Errorf("expected ErrUnusedModelMapping for writter, got %v", err)
+	}
+	if err := strict.Validate(newCfg(map[string]string{"writer": "claude-opus-4-20250514"})); err != nil {
+		t.Errorf("expected no error for used roles, got %v", err)
+	}
+
+	// An empty model ID always fails
+	for _, v := range []*Validator{NewValidator(), strict} {
+		err := v.Validate(newCfg(map[string]string{"writer": " "}))
+		if !errors.Is(err, ErrModelEmpty) {
+			t.Errorf("StrictModels=%v: expected ErrModelEmpty, got %v", v.StrictModels, err)
+		}
+	}
+}
+
+func TestValidator_ValidateAll(t *testing.T) {
+	v := NewValidator()
+	cfg := &WorkflowConfig{
+		Workflow: Workflow{
+			Name: "broken",
+			Type: WorkflowTypeSpecDefault,
+			Steps: []Step{
+				{ID: "", Role: "spec-analyst"},
+				{ID: "dev", Role: "spec-developer", DependsOn: []string{"arch"}},
+				{ID: "arch", Role: "spec-architect", DependsOn: []string{"dev"}},
+				{ID: "val", Role: "spec-validator", DependsOn: []string{"missing"}},
+				{ID: "val", Role: "spec-tester", DependsOn: []string{"dev"}},
+			},
+		},
+	}
+
+	errs := v.ValidateAll(cfg)
+	for _, want := range []error{
+		ErrStepIDEmpty,
+		ErrStepIDDuplicate,
+		ErrDependencyNotFound,
+		ErrCycleDetected,
+		ErrRequiredRoleOrder,
+	} {
+		if !slices.ContainsFunc(errs, func(err error) bool { return errors.Is(err, want) }) {
+			t.Errorf("expected %v among %v", want, errs)
+		}
+	}
+
+	// Validate stops at the first of them
+	if err := v.Validate(cfg); len(errs) == 0 || err == nil || err.Error() != errs[0].Error() {
+		t.Errorf("Validate = %v, want the first ValidateAll error %v", err, errs)
+	}
+
+	// A valid config has none
+	cfg = &WorkflowConfig{Workflow: Workflow{
+		Name: "ok",
+		Steps: []Step{
+			{ID: "analysis", Role: "spec-analyst"},
+			{ID: "architecture", Role: "spec-architect", DependsOn: []string{"analysis"}},
+			{ID: "implementation", Role: "spec-developer", DependsOn: []string{"architecture"}},
+			{ID: "validation", Role: "spec-validator", DependsOn: []string{"implementation"}},
+		},
+	}}
+	if errs := v.ValidateAll(cfg); errs != nil {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
+
+func TestValidator_ValidateAll_RequiredRoleCounts(t *testing.T) {
+	// Missing and duplicate required roles are all reported; order and
+	// chain checks that would only repeat them are skipped
+	cfg := &WorkflowConfig{Workflow: Workflow{
+		Name: "counts",
+		Type: WorkflowTypeSpecDefault,
+		Steps: []Step{
+			{ID: "a1", Role: "spec-analyst"},
+			{ID: "a2", Role: "spec-analyst"},
+			{ID: "dev", Role: "spec-developer", DependsOn: []string{"a1"}},
+		},
+	}}
+	errs := NewValidator().ValidateAll(cfg)
+	want := []error{ErrRequiredRoleDuplicate, ErrRequiredRoleMissing, ErrRequiredRoleMissing}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), errs)
+	}
+	for i, err := range errs {
+		if !errors.Is(err, want[i]) {
+			t.Errorf("errs[%d] = %v, want %v", i, err, want[i])
+		}
+	}
+}
@@ -3,9 +3,19 @@ package config
 
 // WorkflowConfig represents the root configuration structure.
 type WorkflowConfig struct {
+	Version  string   `json:"version,omitempty"` // schema version (default: CurrentConfigVersion)
 	Workflow Workflow `json:"workflow"`
 }
 
+// CurrentConfigVersion is the config schema version produced by this release.
+// Configs without a version are treated as this version.
+const CurrentConfigVersion = "1"
+
+// SupportedConfigVersions returns the config schema versions the loader accepts.
+func SupportedConfigVersions() []string {
+	return []string{CurrentConfigVersion}
+}
+
 // WorkflowType defines the type of workflow for validation purposes.
 type WorkflowType string
 
@@ -16,23 +26,73 @@ const (
 	WorkflowTypeCustom WorkflowType = "custom"
 )
 
+// OptionalMode defines how a spec-default workflow treats steps whose
+// optional role is not in optional_enabled.
+type OptionalMode string
+
+const (
+	// OptionalModeReject fails validation with ErrUnknownRole (default).
+	OptionalModeReject OptionalMode = ""
+	// OptionalModeSkip keeps the steps but marks them Skip, so they are
+	// submitted already skipped.
+	OptionalModeSkip OptionalMode = "skip"
+)
+
 // Workflow defines a named workflow with a list of steps.
 type Workflow struct {
-	Name            string            `json:"name"`
-	Type            WorkflowType      `json:"type,omitempty"`
-	Steps           []Step            `json:"steps"`
-	Models          map[string]string `json:"models,omitempty"`           // role -> model mapping
-	Policy          *PolicyConfig     `json:"policy,omitempty"`           // execution policy
-	OptionalRoles   []string          `json:"optional_roles,omitempty"`   // allowed optional roles (default: spec-tester, spec-reviewer)
-	OptionalEnabled []string          `json:"optional_enabled,omitempty"` // enabled subset of optional_roles
+	Name             string            `json:"name"`
+	Type             WorkflowType      `json:"type,omitempty"`
+	Steps            []Step            `json:"steps"`
+	Models           map[string]string `json:"models,omitempty"`             // role -> model mapping
+	Policy           *PolicyConfig     `json:"policy,omitempty"`             // execution policy
+	OptionalRoles    []string          `json:"optional_roles,omitempty"`     // allowed optional roles (default: spec-tester, spec-reviewer)
+	OptionalEnabled  []string          `json:"optional_enabled,omitempty"`   // enabled subset of optional_roles
+	OptionalMode     OptionalMode      `json:"optional_mode,omitempty"`      // handling of optional steps not in optional_enabled
+	MaxOptionalSteps int               `json:"max_optional_steps,omitempty"` // limit on optional-role steps (default: DefaultMaxOptionalSteps)
+	Defaults         *StepDefaults     `json:"defaults,omitempty"`           // applied to steps that leave a field unset
 }
 
 // Step defines a single step in the workflow.
 type Step struct {
 	ID        string   `json:"id"`
 	Role      string   `json:"role"`
-	DependsOn []string `json:"depends_on,omitempty"`
+	DependsOn []string `json:"depends_on,omitempty"` // "*" = all other steps
 	Outputs   []string `json:"outputs,omitempty"`
+	Disabled  bool     `json:"disabled,omitempty"` // excluded from submitted tasks
+	Skip      bool     `json:"-"`                  // set by the loader under optional_mode skip; submitted skipped
+	RetryOn   []string `json:"retry_on,omitempty"` // failure categories to retry: timeout, rate_limit, transient
+
+	Retry          *RetryConfig `json:"retry,omitempty"`            // attempt cap and exponential backoff
+	ContinueOnFail bool         `json:"continue_on_fail,omitempty"` // a failure skips dependents instead of failing the run
+
+	Model         string               `json:"model,omitempty"`          // overrides workflow.models for this step
+	TimeoutMs     int64                `json:"timeout_ms,omitempty"`     // per-step timeout hint for executors
+	ContextPolicy *ContextPolicyConfig `json:"context_policy,omitempty"` // per-step context policy hint for executors
+	BudgetShare   float64              `json:"budget_share,omitempty"`   // fraction (0-1] of the run budget for this step
+
+	ConcurrencyKey string `json:"concurrency_key,omitempty"` // steps sharing a key don't run concurrently
+}
+
+// StepDefaults holds step fields applied to every step that doesn't set its own.
+// The loader merges them into the steps before validation.
+type StepDefaults struct {
+	Model         string               `json:"model,omitempty"`
+	TimeoutMs     int64                `json:"timeout_ms,omitempty"`
+	ContextPolicy *ContextPolicyConfig `json:"context_policy,omitempty"`
+	BudgetShare   float64              `json:"budget_share,omitempty"`
+}
+
+// RetryConfig represents a step's retry policy.
+type RetryConfig struct {
+	MaxAttempts int   `json:"max_attempts"`         // total attempts, including the first
+	BackoffMs   int64 `json:"backoff_ms,omitempty"` // first retry delay; doubles per attempt
+}
+
+// ContextPolicyConfig represents a step's context policy.
+type ContextPolicyConfig struct {
+	Strategy  string `json:"strategy,omitempty"`
+	MaxTokens int    `json:"max_tokens,omitempty"`
+	KeepLastN int    `json:"keep_last_n,omitempty"`
 }
 
 // PolicyConfig represents execution policy for a workflow.
@@ -40,6 +100,8 @@ type PolicyConfig struct {
 	TimeoutMs      int64         `json:"timeout_ms,omitempty"`
 	MaxParallelism int           `json:"max_parallelism,omitempty"`
 	BudgetLimit    *BudgetConfig `json:"budget_limit,omitempty"`
+
+	ConcurrencyLimits map[string]int `json:"concurrency_limits,omitempty"` // concurrency_key -> max concurrent steps (default 1)
 }
 
 // BudgetConfig represents budget constraints.
@@ -48,6 +110,14 @@ type BudgetConfig struct {
 	Currency string  `json:"currency"`
 }
 
+// DependsOnAll is a depends_on wildcard meaning "every other step".
+// The loader expands it into explicit dependencies before validation.
+const DependsOnAll = "*"
+
+// DefaultMaxOptionalSteps is the default limit on optional-role steps in a
+// spec-default workflow when max_optional_steps is not set.
+const DefaultMaxOptionalSteps = 16
+
 // Role represents an agent role identifier.
 type Role string
 
@@ -5,15 +5,21 @@ import "errors"
 // Sentinel errors for the runtime layer.
 var (
 	// Budget errors
-	ErrBudgetExceeded = errors.New("budget exceeded")
-	ErrBudgetNotSet   = errors.New("budget not set")
+	ErrBudgetExceeded      = errors.New("budget exceeded")
+	ErrBudgetNotSet        = errors.New("budget not set")
+	ErrModelBudgetExceeded = errors.New("model budget exceeded")
+	ErrBudgetPoolExhausted = errors.New("budget pool exhausted")
+	ErrCurrencyMismatch    = errors.New("currency mismatch")
 
 	// Task errors
 	ErrTaskNotFound   = errors.New("task not found")
 	ErrTaskNotReady   = errors.New("task not ready for execution")
 	ErrTaskFailed     = errors.New("task execution failed")
 	ErrTaskTimeout    = errors.New("task execution timeout")
+	ErrContextTimeout = errors.New("task context timeout")
 	ErrTaskCancelled  = errors.New("task cancelled")
+	ErrRateLimited    = errors.New("rate limited")
+	ErrTransient      = errors.New("transient failure")
 
 	// Run errors
 	ErrRunNotFound    = errors.New("run not found")
@@ -28,6 +34,7 @@ var (
 	// Context errors
 	ErrContextTooLarge = errors.New("context exceeds maximum token limit")
 	ErrContextEmpty    = errors.New("context bundle is empty")
+	ErrOutputCollision = errors.New("named output routed by more than one dependency")
 
 	// Estimation errors
 	ErrEstimationFailed = errors.New("token estimation failed")
@@ -37,5 +44,7 @@ var (
 	ErrInvalidInput = errors.New("invalid input: nil or malformed")
 
 	// Orchestration errors
-	ErrDeadlock = errors.New("no progress possible: deadlock detected")
+	ErrDeadlock                 = errors.New("no progress possible: deadlock detected")
+	ErrBatchLimitExceeded       = errors.New("batch limit exceeded: run is not making progress")
+	ErrFailureThresholdExceeded = errors.New("failure threshold exceeded")
 )
@@ -0,0 +1,20 @@
+package contracts
+
+import "context"
+
+type providerHeadersKey struct{}
+
+// WithProviderHeaders returns a copy of ctx carrying extra HTTP headers,
+// keyed by header name, for the provider requests of a task (e.g. org IDs
+// or routing tags required by a proxy).
+func WithProviderHeaders(ctx context.Context, headers map[string]string) context.Context {
+	return context.WithValue(ctx, providerHeadersKey{}, headers)
+}
+
+// ProviderHeadersFromContext returns the headers attached by
+// WithProviderHeaders, or nil. Executors that call a provider add them to
+// every request. The map must not be modified.
+func ProviderHeadersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(providerHeadersKey{}).(map[string]string)
+	return headers
+}
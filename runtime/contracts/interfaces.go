@@ -8,7 +8,8 @@ import "context"
 
 // Scheduler determines which tasks are ready to execute and tracks completion.
 type Scheduler interface {
-	// NextReady returns task IDs that are ready to execute (all deps satisfied).
+	// NextReady returns task IDs that are ready to execute (all deps satisfied),
+	// highest Task.Priority first and then by TaskID.
 	NextReady(run *Run) ([]TaskID, error)
 
 	// MarkComplete marks a task as completed and updates the run state.
@@ -22,6 +23,14 @@ type DependencyResolver interface {
 
 	// Validate checks the DAG for cycles and missing dependencies.
 	Validate(dag *DAG) error
+
+	// Plan returns the DAG's execution batches: each holds the tasks whose
+	// dependencies are all in earlier batches, sorted by TaskID.
+	Plan(dag *DAG) ([][]TaskID, error)
+
+	// CriticalPath returns the DAG's longest dependency chain, from a root to
+	// a sink, and its length in tasks. Ties break by TaskID.
+	CriticalPath(dag *DAG) ([]TaskID, int)
 }
 
 // ParallelExecutor executes tasks with bounded concurrency.
@@ -59,6 +68,21 @@ type CostCalculator interface {
 	Estimate(tokens TokenCount, model ModelID) (Cost, error)
 }
 
+// SplitTokenEstimator is implemented by TokenEstimators that also estimate
+// a task's output. Budget pre-checks use it instead of Estimate when available.
+type SplitTokenEstimator interface {
+	// EstimateSplit returns the estimated input (prompt and context) and
+	// output tokens for a task.
+	EstimateSplit(input *TaskInput, ctx *ContextBundle) (in, out TokenCount, err error)
+}
+
+// SplitCostCalculator is implemented by CostCalculators that price input
+// and output tokens at separate rates.
+type SplitCostCalculator interface {
+	// EstimateSplit returns the estimated cost of in input and out output tokens.
+	EstimateSplit(in, out TokenCount, model ModelID) (Cost, error)
+}
+
 // BudgetEnforcer enforces budget limits for runs.
 type BudgetEnforcer interface {
 	// Allow checks if the estimated cost is within budget. Returns error if not.
@@ -68,6 +92,73 @@ type BudgetEnforcer interface {
 	Record(run *Run, actual Cost) error
 }
 
+// ModelBudgetEnforcer is an optional extension of BudgetEnforcer that enforces
+// per-model spend caps from RunPolicy.ModelBudgets.
+type ModelBudgetEnforcer interface {
+	// AllowModel checks if the estimated cost is within the model's budget. Returns error if not.
+	AllowModel(run *Run, model ModelID, estimate Cost) error
+
+	// RecordModel records actual usage for the model and updates run.ModelUsage.
+	RecordModel(run *Run, model ModelID, actual Usage) error
+}
+
+// CurrencyConvertingEnforcer is an optional extension of BudgetEnforcer
+// for enforcers that convert costs in other currencies into the budget
+// currency. The orchestrator fails results priced in another currency
+// unless the enforcer converts.
+type CurrencyConvertingEnforcer interface {
+	// ConvertsCurrency reports whether Record accepts costs in other currencies.
+	ConvertsCurrency() bool
+}
+
+// ReservingBudgetEnforcer is an optional extension of BudgetEnforcer for
+// enforcers that hold the estimates passed to Allow against shared budgets
+// until the costs are recorded. The orchestrator calls Release before each
+// batch's pre-check and when the run ends.
+type ReservingBudgetEnforcer interface {
+	// Release drops the run's outstanding reservations.
+	Release(run *Run)
+}
+
+// CurrencyConverter converts amounts between currencies, e.g. from a model's
+// pricing currency to the currency of a run budget.
+type CurrencyConverter interface {
+	// Convert returns amount, given in currency from, in currency to.
+	Convert(amount float64, from, to string) (float64, error)
+}
+
+// BudgetPool tracks spend shared by all runs with the same pool label.
+// Implementations must be safe for concurrent use by multiple runs.
+type BudgetPool interface {
+	// Allow checks if the estimated cost fits in the pool. Returns error if not.
+	Allow(label string, estimate Cost) error
+
+	// Record adds actual cost to the pool's usage.
+	Record(label string, actual Cost) error
+
+	// Usage returns the cost recorded against the pool so far.
+	Usage(label string) Cost
+}
+
+// ReservingBudgetPool is an optional extension of BudgetPool that holds
+// estimates until their actual cost is recorded, so concurrent runs cannot
+// both pass a pre-check against the same usage.
+type ReservingBudgetPool interface {
+	BudgetPool
+
+	// Reserve checks, atomically, that usage plus every other holder's
+	// reservation plus estimate fits in the pool, then sets holder's
+	// reservation to estimate. On error the reservation is unchanged.
+	Reserve(label, holder string, estimate Cost) error
+
+	// Commit records actual cost like Record and reduces holder's
+	// reservation by it.
+	Commit(label, holder string, actual Cost) error
+
+	// Release drops holder's reservation.
+	Release(label, holder string)
+}
+
 // UsageTracker tracks token and cost usage for a run.
 type UsageTracker interface {
 	// Add adds usage to the run's total.
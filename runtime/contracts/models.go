@@ -1,16 +1,76 @@
 package contracts
 
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
 // Run represents a single execution run containing multiple tasks.
 type Run struct {
-	ID        RunID
-	State     RunState
-	Policy    RunPolicy
-	DAG       *DAG
-	Tasks     map[TaskID]*Task
-	Usage     Usage
-	Memory    map[string]string // short-term memory for the run
-	CreatedAt Timestamp
-	UpdatedAt Timestamp
+	ID         RunID
+	State      RunState
+	Policy     RunPolicy
+	DAG        *DAG
+	Tasks      map[TaskID]*Task
+	Usage      Usage
+	ModelUsage map[ModelID]Usage // per-model usage, tracked when ModelBudgets is set
+	Memory     map[string]string // short-term memory for the run
+	ConfigHash string            // SHA-256 of the originating workflow config (optional)
+	TraceID    string            // correlation ID for audit lines and executor requests
+	StartAfter Timestamp         // run is held in RunScheduled until then (0 = start immediately)
+	Labels     map[string]string // key/value tags, e.g. team=payments (optional)
+	Plan       *DryRunPlan       // set by a dry run (RunPolicy.DryRun)
+	CreatedAt  Timestamp
+	UpdatedAt  Timestamp
+	StartedAt  Timestamp // set by the orchestrator when the run becomes RunRunning (0 = never started)
+	FinishedAt Timestamp // set by the orchestrator when the run reaches a terminal state
+
+	// BudgetWarning is set once usage reaches RunPolicy.SoftLimit.
+	BudgetWarning bool
+}
+
+// DryRunPlan is the outcome of a dry run. Each task's estimate is stored in
+// its EstimatedUse.
+type DryRunPlan struct {
+	Batches        [][]TaskID // tasks in the batches they would run in, in order
+	ProjectedUsage Usage      // sum of the task estimates
+}
+
+// TaskCounts counts a run's tasks by outcome.
+//
+// Run.Usage only covers tasks whose executor was called: completed tasks,
+// and failed tasks whose cost was recorded. Skipped tasks (condition not
+// met, dependency failed, dry run) never add usage, so a report dividing
+// usage by task count should use Completed + Failed, not the task total.
+type TaskCounts struct {
+	Completed  int
+	Failed     int
+	Skipped    int
+	Unfinished int // pending, ready or running
+}
+
+// CountTasks counts tasks by state.
+func CountTasks(tasks map[TaskID]*Task) TaskCounts {
+	var counts TaskCounts
+	for _, task := range tasks {
+		counts.Add(task.State)
+	}
+	return counts
+}
+
+// Add counts one task in the given state.
+func (c *TaskCounts) Add(state TaskState) {
+	switch state {
+	case TaskCompleted:
+		c.Completed++
+	case TaskFailed:
+		c.Failed++
+	case TaskSkipped:
+		c.Skipped++
+	default:
+		c.Unfinished++
+	}
 }
 
 // Task represents a single unit of work within a run.
@@ -24,6 +84,89 @@ type Task struct {
 	Model        ModelID
 	EstimatedUse Usage
 	ActualUse    Usage
+
+	// RequireNonEmptyOutput fails the task with code empty_output
+	// if the executor returns an empty Output.
+	RequireNonEmptyOutput bool
+
+	// RetryOn lists the error categories (RetryOnTimeout, ...) that are
+	// retried; other failures fail the task immediately. Empty = no retries.
+	RetryOn []string
+
+	// ContinueOnFail keeps the run going when this task fails (execution
+	// error, invalid result or empty output): the failure is recorded and
+	// the task's dependents are skipped. Other failures still end the run.
+	ContinueOnFail bool
+
+	// Retry sets the attempt cap and backoff for this task. With Retry set and
+	// RetryOn empty, every failure except cancellation is retried.
+	// Nil = RetryOn failures get the executor's default attempts and backoff.
+	Retry *RetryPolicy
+
+	// ConcurrencyKey names a shared resource. Tasks with the same key run at
+	// most RunPolicy.ConcurrencyLimits[key] (default 1) at a time. Empty = no limit.
+	ConcurrencyKey string
+
+	// TimeoutMs overrides RunPolicy.TimeoutMs for this task when > 0.
+	// 0 = use the run-level timeout.
+	TimeoutMs int64
+
+	// MaxContextTokens caps the task's context bundle after compaction: if
+	// it is still larger, the task fails with code context_too_large before
+	// the executor is called, including in runs with budgets disabled.
+	// Applies on top of RunPolicy.ContextPolicy. 0 = no per-task cap.
+	MaxContextTokens TokenCount
+
+	// RunIf makes the task conditional: when the condition does not hold
+	// once the task is ready, the task is skipped with code condition_not_met
+	// and its dependents run with an empty output from it. Nil = always run.
+	RunIf *Condition
+
+	// Priority orders ready tasks: higher values come first, ties by ID.
+	// Earlier tasks are pre-checked against the budget and started first
+	// within a batch. 0 = default; negative values run after it.
+	Priority int
+}
+
+// Condition is a predicate evaluated against a ready task's routed inputs.
+// Key is looked up in the task's Inputs (a dependency ID or named output),
+// then in Run.Memory. A key found in neither never matches.
+type Condition struct {
+	Key    string
+	Equals string
+}
+
+// RetryPolicy configures how often a failing task is re-executed.
+type RetryPolicy struct {
+	MaxAttempts int   // total executor calls, including the first (>= 1)
+	BackoffMs   int64 // delay before the first retry; doubles per attempt
+}
+
+// Retry categories for Task.RetryOn, assigned by ClassifyError.
+const (
+	RetryOnTimeout   = "timeout"    // ErrTaskTimeout or context.DeadlineExceeded
+	RetryOnRateLimit = "rate_limit" // ErrRateLimited
+	RetryOnTransient = "transient"  // ErrTransient
+)
+
+// RetryCategories returns the valid Task.RetryOn values.
+func RetryCategories() []string {
+	return []string{RetryOnTimeout, RetryOnRateLimit, RetryOnTransient}
+}
+
+// ClassifyError maps an executor error to a retry category.
+// Returns "" for errors that are never retried.
+func ClassifyError(err error) string {
+	switch {
+	case errors.Is(err, ErrTaskTimeout), errors.Is(err, context.DeadlineExceeded):
+		return RetryOnTimeout
+	case errors.Is(err, ErrRateLimited):
+		return RetryOnRateLimit
+	case errors.Is(err, ErrTransient):
+		return RetryOnTransient
+	default:
+		return ""
+	}
 }
 
 // DAG represents the directed acyclic graph of task dependencies.
@@ -41,9 +184,13 @@ type DAGNode struct {
 }
 
 // Usage represents token and cost usage.
+// Tokens is always the total; InputTokens and OutputTokens split it when
+// the source reports them (both 0 = not split).
 type Usage struct {
-	Tokens TokenCount
-	Cost   Cost
+	Tokens       TokenCount
+	InputTokens  TokenCount
+	OutputTokens TokenCount
+	Cost         Cost
 }
 
 // Cost represents a monetary cost.
@@ -52,11 +199,47 @@ type Cost struct {
 	Currency Currency
 }
 
+// Add returns c + other. An empty currency on either side adopts the other's,
+// so a zero Cost can be used as an accumulator.
+// Returns ErrCurrencyMismatch if both currencies are set and differ.
+func (c Cost) Add(other Cost) (Cost, error) {
+	currency, err := c.commonCurrency(other)
+	if err != nil {
+		return c, err
+	}
+	return Cost{Amount: c.Amount + other.Amount, Currency: currency}, nil
+}
+
+// Sub returns c - other, with the same currency rules as Add.
+func (c Cost) Sub(other Cost) (Cost, error) {
+	currency, err := c.commonCurrency(other)
+	if err != nil {
+		return c, err
+	}
+	return Cost{Amount: c.Amount - other.Amount, Currency: currency}, nil
+}
+
+// commonCurrency returns the currency of the result of combining c and other.
+func (c Cost) commonCurrency(other Cost) (Currency, error) {
+	switch {
+	case c.Currency == "":
+		return other.Currency, nil
+	case other.Currency == "" || other.Currency == c.Currency:
+		return c.Currency, nil
+	default:
+		return c.Currency, fmt.Errorf("%s and %s: %w", c.Currency, other.Currency, ErrCurrencyMismatch)
+	}
+}
+
 // TaskInput represents the input to a task.
 type TaskInput struct {
 	Prompt   string
 	Inputs   map[string]string
 	Metadata map[string]string
+
+	// FromMemory lists Run.Memory keys copied into Inputs as "memory:<key>"
+	// when the task's context is built. Keys not in memory are left out.
+	FromMemory []string
 }
 
 // TaskResult represents the output of a completed task.
@@ -86,12 +269,98 @@ type ContextPolicy struct {
 	Strategy  string
 	KeepLastN int
 	// TruncateTo removed - out of scope V1
+
+	// MaxRoutedValueBytes caps each output routed into a dependent's inputs.
+	// Longer values are truncated with RoutedValueTruncatedMarker appended. 0 = unlimited.
+	MaxRoutedValueBytes int
+
+	// ForceCompactRatio forces compaction when a task's assembled context
+	// (prompt, routed inputs, messages and memory) exceeds this fraction of the
+	// model's context window, even if Strategy is unset. 0 = disabled.
+	ForceCompactRatio float64
+
+	// OutputCollision decides what happens when two dependencies route a named
+	// output with the same key into one task: OutputCollisionError (default),
+	// OutputCollisionOverwrite or OutputCollisionSuffix.
+	OutputCollision string
 }
 
+// RoutedValueTruncatedMarker is appended to routed values cut at MaxRoutedValueBytes.
+const RoutedValueTruncatedMarker = "...[truncated]"
+
+// Named output collision policies (ContextPolicy.OutputCollision).
+const (
+	// OutputCollisionError fails routing with ErrOutputCollision.
+	OutputCollisionError = "error"
+	// OutputCollisionOverwrite keeps the value routed last.
+	OutputCollisionOverwrite = "overwrite"
+	// OutputCollisionSuffix stores every colliding value as "key.sourceTaskID".
+	OutputCollisionSuffix = "suffix"
+)
+
 // RunPolicy defines execution constraints for a run.
 type RunPolicy struct {
 	TimeoutMs      int64
 	MaxParallelism int
 	BudgetLimit    Cost
+	ModelBudgets   map[ModelID]Cost // optional per-model spend caps
+	BudgetPool     string           // optional label of a budget pool shared across runs
+	BudgetDisabled bool             // trusted/dev mode: skip budget checks, still track usage
 	ContextPolicy  ContextPolicy
+
+	// AbortGraceMs lets in-flight tasks finish for up to this long after an
+	// abort before their contexts are cancelled. 0 = cancel immediately.
+	AbortGraceMs int64
+
+	// MaxContextBuilds bounds how many ready tasks have their context built
+	// and estimated concurrently during the budget pre-check. Each bundle is
+	// a copy of run memory and dependency outputs, so this caps peak memory
+	// for wide ready sets. 0 or 1 = sequential.
+	MaxContextBuilds int
+
+	// RetryJitter randomizes retry backoff (equal jitter) so tasks failing
+	// together don't retry in lockstep. Delays are drawn per task from
+	// JitterSeed and the task ID, so a given seed reproduces them.
+	RetryJitter bool
+	JitterSeed  int64 // 0 = derived from the run ID
+
+	// ConcurrencyLimits caps concurrent tasks per Task.ConcurrencyKey.
+	// Keys without an entry run one task at a time.
+	ConcurrencyLimits map[string]int
+
+	// DedupIdenticalTasks executes tasks with the same input hash once; the
+	// others complete with a copy of its result and no usage.
+	DedupIdenticalTasks bool
+
+	// DryRun validates the DAG and estimates every task without executing
+	// any. The run completes with Run.Plan set and every task skipped.
+	DryRun bool
+
+	// MaxFailures fails the run once more tasks than this have failed, even
+	// if each one has ContinueOnFail. A value >= 1 is a task count; a value
+	// below 1 is a fraction of the run's tasks. 0 = no limit.
+	MaxFailures float64
+
+	// ContextTimeoutMs bounds compacting a task's context (e.g. a slow
+	// summarizer) during the budget pre-check; the task then fails with
+	// ErrContextTimeout. TimeoutMs only covers the executor call. 0 = no limit.
+	ContextTimeoutMs int64
+
+	// SoftLimit sets Run.BudgetWarning once the run's cost reaches it. The
+	// run keeps executing until BudgetLimit. Zero amount = no warning.
+	SoftLimit Cost
+
+	// SchedulingStrategy orders the ready tasks of each batch:
+	// SchedulingDefault or SchedulingCost.
+	SchedulingStrategy string
 }
+
+// Ready task orderings (RunPolicy.SchedulingStrategy).
+const (
+	// SchedulingDefault keeps the scheduler's order: Task.Priority, then ID.
+	SchedulingDefault = ""
+	// SchedulingCost orders ready tasks by descending estimated cost, so the
+	// most expensive branches start first when MaxParallelism is the limit.
+	// Ties keep the default order.
+	SchedulingCost = "cost"
+)
@@ -0,0 +1,73 @@
+package contracts
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCost_AddSub(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Cost
+		wantAdd Cost
+		wantSub Cost
+		wantErr error
+	}{
+		{
+			name:    "matching currencies",
+			a:       Cost{Amount: 1.5, Currency: "USD"},
+			b:       Cost{Amount: 0.5, Currency: "USD"},
+			wantAdd: Cost{Amount: 2.0, Currency: "USD"},
+			wantSub: Cost{Amount: 1.0, Currency: "USD"},
+		},
+		{
+			name:    "empty receiver adopts other",
+			a:       Cost{},
+			b:       Cost{Amount: 0.5, Currency: "EUR"},
+			wantAdd: Cost{Amount: 0.5, Currency: "EUR"},
+			wantSub: Cost{Amount: -0.5, Currency: "EUR"},
+		},
+		{
+			name:    "empty other keeps receiver",
+			a:       Cost{Amount: 2, Currency: "USD"},
+			b:       Cost{Amount: 1},
+			wantAdd: Cost{Amount: 3, Currency: "USD"},
+			wantSub: Cost{Amount: 1, Currency: "USD"},
+		},
+		{
+			name:    "both empty",
+			a:       Cost{Amount: 2},
+			b:       Cost{Amount: 1},
+			wantAdd: Cost{Amount: 3},
+			wantSub: Cost{Amount: 1},
+		},
+		{
+			name:    "mismatched currencies",
+			a:       Cost{Amount: 2, Currency: "USD"},
+			b:       Cost{Amount: 1, Currency: "EUR"},
+			wantAdd: Cost{Amount: 2, Currency: "USD"},
+			wantSub: Cost{Amount: 2, Currency: "USD"},
+			wantErr: ErrCurrencyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Add(tt.b)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Add() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.wantAdd {
+				t.Errorf("Add() = %+v, want %+v", got, tt.wantAdd)
+			}
+
+			got, err = tt.a.Sub(tt.b)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Sub() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.wantSub {
+				t.Errorf("Sub() = %+v, want %+v", got, tt.wantSub)
+			}
+		})
+	}
+}
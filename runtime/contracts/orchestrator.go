@@ -13,6 +13,7 @@ type Orchestrator interface {
 	// - ErrDAGInvalid: DAG validation failed
 	// - ErrBudgetExceeded: budget limit reached
 	// - ErrDeadlock: no progress possible
+	// - ErrBatchLimitExceeded: batch loop exceeded its safety limit
 	// - context.Canceled/DeadlineExceeded: context cancelled or timed out
 	// - other errors from task execution
 	//
@@ -9,6 +9,7 @@ const (
 	RunCompleted
 	RunFailed
 	RunAborted
+	RunScheduled // waiting for Run.StartAfter before it starts
 )
 
 func (s RunState) String() string {
@@ -23,6 +24,8 @@ func (s RunState) String() string {
 		return "failed"
 	case RunAborted:
 		return "aborted"
+	case RunScheduled:
+		return "scheduled"
 	default:
 		return "unknown"
 	}
@@ -0,0 +1,28 @@
+package contracts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// NewTraceID returns a random 32-character hex trace ID (W3C trace-id format).
+func NewTraceID() string {
+	var b [16]byte
+	rand.Read(b[:]) // never returns an error
+	return hex.EncodeToString(b[:])
+}
+
+type traceIDKey struct{}
+
+// WithTraceID returns a copy of ctx carrying the run's trace ID.
+// Executors read it with TraceIDFromContext, e.g. to tag provider requests.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext returns the trace ID attached by WithTraceID, or "".
+func TraceIDFromContext(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
@@ -1,3 +1,5 @@
 module github.com/anthropics/claude-workflow/runtime
 
 go 1.24.4
+
+require gopkg.in/yaml.v3 v3.0.1
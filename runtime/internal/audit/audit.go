@@ -1,10 +1,99 @@
 // Package audit provides structured logging for execution audit.
 package audit
 
-import "log"
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	mu     sync.Mutex
+	logger *log.Logger // nil: the standard logger
+)
+
+// SetOutput directs audit events to w instead of the standard logger, e.g.
+// to capture them in tests or keep them in a file of their own. Lines keep
+// the standard logger's flags at the time of the call. A nil w restores the
+// standard logger. It is safe to call while events are logged; each event
+// is written with a single Write.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	if w == nil {
+		logger = nil
+		return
+	}
+	logger = log.New(w, "", log.Flags())
+}
+
+// output writes one audit line to the configured destination.
+func output(line string) {
+	mu.Lock()
+	l := logger
+	mu.Unlock()
+	if l == nil {
+		log.Print(line)
+		return
+	}
+	l.Print(line)
+}
 
 // Log writes an audit event with [AUDIT] prefix.
 // Format should use key=value pairs for structured logging.
+// New call sites should prefer LogEvent, which log aggregators can parse.
 func Log(format string, args ...interface{}) {
-	log.Printf("[AUDIT] "+format, args...)
+	output(fmt.Sprintf("[AUDIT] "+format, args...))
+}
+
+// Fields holds the data of an audit event, keyed by field name.
+type Fields map[string]any
+
+// LogEvent writes an audit event as one JSON object on a single line with
+// [AUDIT] prefix, e.g. [AUDIT] {"event":"task_completed","run_id":"r1","tokens":120}.
+// The event name comes first, then the fields sorted by name. Values keep
+// their JSON types, so numbers stay numbers; errors are written as their
+// message and values JSON can't encode as their %v text. A field named
+// "event" is ignored.
+func LogEvent(event string, fields Fields) {
+	output("[AUDIT] " + encodeEvent(event, fields))
+}
+
+// encodeEvent returns the JSON object LogEvent writes.
+func encodeEvent(event string, fields Fields) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if k != "event" {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	var b strings.Builder
+	b.WriteString(`{"event":`)
+	b.Write(encodeValue(event))
+	for _, k := range keys {
+		b.WriteByte(',')
+		b.Write(encodeValue(k))
+		b.WriteByte(':')
+		b.Write(encodeValue(fields[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// encodeValue marshals v, falling back to its %v text.
+func encodeValue(v any) []byte {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprintf("%v", v))
+	}
+	return data
 }
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+type taskID string
+
+func TestLogEvent_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	LogEvent("task_completed", Fields{
+		"task_id":     taskID("A"),
+		"tokens":      int64(120),
+		"cost":        0.0042,
+		"duration_ms": int64(812),
+		"tasks":       []taskID{"A", "B"},
+		"error_msg":   errors.New("boom"),
+		"callback":    func() {},
+		"event":       "ignored",
+	})
+
+	line := strings.TrimSuffix(buf.String(), "\n")
+	_, data, ok := strings.Cut(line, "[AUDIT] ")
+	if !ok || strings.Contains(data, "\n") {
+		t.Fatalf("expected one [AUDIT] line, got %q", buf.String())
+	}
+	want := `{"event":"task_completed","callback":`
+	if !strings.HasPrefix(data, want) {
+		t.Errorf("event = %s, want prefix %s (event first, then sorted fields)", data, want)
+	}
+
+	var event map[string]any
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatalf("invalid JSON %q: %v", data, err)
+	}
+	for key, want := range map[string]any{
+		"event":       "task_completed",
+		"task_id":     "A",
+		"tokens":      120.0,
+		"cost":        0.0042,
+		"duration_ms": 812.0,
+		"error_msg":   "boom",
+	} {
+		if event[key] != want {
+			t.Errorf("%s = %#v, want %#v", key, event[key], want)
+		}
+	}
+	if tasks, ok := event["tasks"].([]any); !ok || len(tasks) != 2 || tasks[0] != "A" {
+		t.Errorf("tasks = %#v, want [A B]", event["tasks"])
+	}
+	if _, ok := event["callback"].(string); !ok {
+		t.Errorf("callback = %#v, want its text", event["callback"])
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	var std, buf bytes.Buffer
+	log.SetOutput(&std)
+	defer log.SetOutput(os.Stderr)
+
+	SetOutput(&buf)
+	LogEvent("run_started", Fields{"run_id": "r1"})
+	Log("event=budget_defaulted run_id=%s", "r1")
+	SetOutput(nil)
+	LogEvent("run_completed", Fields{"run_id": "r1"})
+
+	if got := buf.String(); !strings.Contains(got, `[AUDIT] {"event":"run_started","run_id":"r1"}`) ||
+		!strings.Contains(got, "[AUDIT] event=budget_defaulted run_id=r1") || strings.Contains(got, "run_completed") {
+		t.Errorf("SetOutput writer got %q", got)
+	}
+	if got := std.String(); !strings.Contains(got, "run_completed") || strings.Contains(got, "run_started") {
+		t.Errorf("standard logger got %q", got)
+	}
+}
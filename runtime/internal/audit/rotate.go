@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile is an io.Writer appending to a log file that is rotated by
+// size: when a write would grow the file past maxBytes, the file is renamed
+// to path.1 (path.1 to path.2, and so on) and a new file is started. At most
+// maxFiles rotated files are kept; older ones are removed.
+// It is safe for concurrent use.
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	maxFiles int
+	file     *os.File
+	size     int64
+	closed   bool
+}
+
+// NewRotatingFile opens path for appending, creating it if needed.
+// maxBytes <= 0 disables rotation; maxFiles < 0 is treated as 0 (rotated
+// files are removed right away).
+func NewRotatingFile(path string, maxBytes int64, maxFiles int) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, maxBytes: maxBytes, maxFiles: max(maxFiles, 0)}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write appends p to the file, rotating first if p would not fit. A single
+// write larger than maxBytes goes to a new file of its own.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.file == nil {
+		// A failed rotation left no file open; retry before giving up
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.maxBytes > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxBytes {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens the current file and records its size.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat audit log: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate shifts the rotated files up by one, dropping the oldest, moves the
+// current file to path.1 and opens a new one. The caller holds f.mu.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("close audit log: %w", err)
+	}
+	f.file = nil
+
+	if f.maxFiles == 0 {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove audit log: %w", err)
+		}
+		return f.open()
+	}
+
+	if err := os.Remove(f.rotatedPath(f.maxFiles)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove rotated audit log: %w", err)
+	}
+	for i := f.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(f.rotatedPath(i), f.rotatedPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("rotate audit log: %w", err)
+		}
+	}
+	if err := os.Rename(f.path, f.rotatedPath(1)); err != nil {
+		return fmt.Errorf("rotate audit log: %w", err)
+	}
+	return f.open()
+}
+
+// rotatedPath returns the path of the n-th most recent rotated file.
+func (f *RotatingFile) rotatedPath(n int) string {
+	return fmt.Sprintf("%s.%d", f.path, n)
+}
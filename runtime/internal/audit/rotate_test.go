@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestRotatingFile_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := NewRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"line-1\n", "line-2\n", "line-3\n", "line-4\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	// Each 7-byte line fills a 10-byte file, so every write starts a new one
+	if got := readFile(t, path); got != "line-4\n" {
+		t.Errorf("current = %q, want line-4", got)
+	}
+	if got := readFile(t, path+".1"); got != "line-3\n" {
+		t.Errorf(".1 = %q, want line-3", got)
+	}
+	if got := readFile(t, path+".2"); got != "line-2\n" {
+		t.Errorf(".2 = %q, want line-2", got)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected .3 to be pruned, stat err = %v", err)
+	}
+}
+
+func TestRotatingFile_NoRetainedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := NewRotatingFile(path, 10, 0)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("first\n"))
+	f.Write([]byte("second\n"))
+
+	if got := readFile(t, path); got != "second\n" {
+		t.Errorf("current = %q, want second", got)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Errorf("expected no rotated file, stat err = %v", err)
+	}
+}
+
+func TestRotatingFile_AppendsToExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := os.WriteFile(path, []byte("old-1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewRotatingFile(path, 10, 1)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	defer f.Close()
+
+	// The existing 6 bytes count towards the limit
+	f.Write([]byte("new-1\n"))
+	if got := readFile(t, path+".1"); got != "old-1\n" {
+		t.Errorf(".1 = %q, want old-1", got)
+	}
+	if got := readFile(t, path); got != "new-1\n" {
+		t.Errorf("current = %q, want new-1", got)
+	}
+}
+
+func TestRotatingFile_Disabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := NewRotatingFile(path, 0, 3)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	defer f.Close()
+
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(f, "line-%d\n", i)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Errorf("expected no rotation with maxBytes 0, stat err = %v", err)
+	}
+}
+
+func TestRotatingFile_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	const maxFiles = 3
+	f, err := NewRotatingFile(path, 100, maxFiles)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+
+	line := []byte("0123456789\n")
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if _, err := f.Write(line); err != nil {
+					t.Errorf("Write: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	f.Close()
+
+	// Every kept file holds whole lines and stays within the limit
+	for _, p := range []string{path, path + ".1", path + ".2", path + ".3"} {
+		data := readFile(t, p)
+		if len(data) > 100 {
+			t.Errorf("%s has %d bytes, want <= 100", p, len(data))
+		}
+		if len(data)%len(line) != 0 || bytes.Count([]byte(data), line)*len(line) != len(data) {
+			t.Errorf("%s holds interleaved writes: %q", p, data)
+		}
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s.%d", path, maxFiles+1)); !os.IsNotExist(err) {
+		t.Errorf("expected files beyond %d to be pruned, stat err = %v", maxFiles, err)
+	}
+}
+
+func TestRotatingFile_WriteAfterClose(t *testing.T) {
+	f, err := NewRotatingFile(filepath.Join(t.TempDir(), "audit.log"), 10, 1)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	f.Close()
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Error("expected error writing to closed file")
+	}
+}
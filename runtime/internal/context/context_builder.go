@@ -1,7 +1,10 @@
 package context
 
 import (
+	"slices"
+
 	"github.com/anthropics/claude-workflow/runtime/contracts"
+	"github.com/anthropics/claude-workflow/runtime/internal/audit"
 )
 
 // contextBuilder implements contracts.ContextBuilder for constructing context bundles for tasks.
@@ -12,12 +15,22 @@ func NewContextBuilder() contracts.ContextBuilder {
 	return &contextBuilder{}
 }
 
+// MemoryInputPrefix prefixes the Inputs keys of memory values a task
+// requests with TaskInput.FromMemory.
+const MemoryInputPrefix = "memory:"
+
 // Build constructs the context bundle for a task within a run.
 // It includes:
 // - Messages from outputs of all completed dependencies
 // - Memory copied from run.Memory
 // - Tools as an empty map (placeholder for future extensibility)
 //
+// It also resolves the task's FromMemory keys into its inputs
+// (see ResolveMemoryInputs).
+//
+// task.Deps is authoritative. If it disagrees with the task's DAG node deps,
+// a deps_mismatch audit event is logged and the build proceeds.
+//
 // Returns an error if:
 // - run is nil
 // - task is not found in run.Tasks
@@ -34,6 +47,14 @@ func (cb *contextBuilder) Build(run *contracts.Run, taskID contracts.TaskID) (*c
 		return nil, contracts.ErrTaskNotFound
 	}
 
+	// Consistency assertion: task.Deps should match the DAG node (logged, not fatal)
+	if run.DAG != nil {
+		if node, ok := run.DAG.Nodes[taskID]; ok && !sameDeps(task.Deps, node.Deps) {
+			audit.Log("event=deps_mismatch run_id=%s trace_id=%s task_id=%s task_deps=%v dag_deps=%v",
+				run.ID, run.TraceID, taskID, task.Deps, node.Deps)
+		}
+	}
+
 	// Build the context bundle
 	bundle := &contracts.ContextBundle{
 		Messages: []string{},
@@ -68,5 +89,41 @@ func (cb *contextBuilder) Build(run *contracts.Run, taskID contracts.TaskID) (*c
 
 	// Tools is empty map (placeholder for future)
 
+	ResolveMemoryInputs(run, task)
+
 	return bundle, nil
 }
+
+// ResolveMemoryInputs copies each run.Memory key listed in
+// task.Inputs.FromMemory into task.Inputs.Inputs as MemoryInputPrefix+key.
+// Keys missing from memory are skipped and logged as memory_input_missing.
+// Resolving again picks up values written to memory since.
+func ResolveMemoryInputs(run *contracts.Run, task *contracts.Task) {
+	if task.Inputs == nil || len(task.Inputs.FromMemory) == 0 {
+		return
+	}
+	for _, key := range task.Inputs.FromMemory {
+		value, ok := run.Memory[key]
+		if !ok {
+			audit.Log("event=memory_input_missing run_id=%s trace_id=%s task_id=%s key=%s",
+				run.ID, run.TraceID, task.ID, key)
+			continue
+		}
+		if task.Inputs.Inputs == nil {
+			task.Inputs.Inputs = make(map[string]string)
+		}
+		task.Inputs.Inputs[MemoryInputPrefix+key] = value
+	}
+}
+
+// sameDeps reports whether a and b contain the same task IDs, ignoring order.
+func sameDeps(a, b []contracts.TaskID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := slices.Clone(a)
+	sortedB := slices.Clone(b)
+	slices.Sort(sortedA)
+	slices.Sort(sortedB)
+	return slices.Equal(sortedA, sortedB)
+}
@@ -1,6 +1,10 @@
 package context
 
 import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/anthropics/claude-workflow/runtime/contracts"
@@ -651,3 +655,108 @@ func BenchmarkBuild(b *testing.B) {
 		_, _ = cb.Build(run, mainTaskID)
 	}
 }
+
+func TestBuild_DepsMismatchUsesTaskDeps(t *testing.T) {
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	defer log.SetOutput(os.Stderr)
+
+	cb := NewContextBuilder()
+	run := &contracts.Run{
+		ID: "run1",
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"a": {ID: "a", State: contracts.TaskCompleted, Outputs: &contracts.TaskResult{Output: "a output"}},
+			"b": {ID: "b", State: contracts.TaskCompleted, Outputs: &contracts.TaskResult{Output: "b output"}},
+			"c": {ID: "c", Deps: []contracts.TaskID{"a"}},
+		},
+		DAG: &contracts.DAG{
+			Nodes: map[contracts.TaskID]*contracts.DAGNode{
+				"c": {ID: "c", Deps: []contracts.TaskID{"b"}},
+			},
+		},
+	}
+
+	bundle, err := cb.Build(run, "c")
+	if err != nil {
+		t.Fatalf("Build() error = %v, want nil", err)
+	}
+	if len(bundle.Messages) != 1 || bundle.Messages[0] != "a output" {
+		t.Fatalf("Messages = %v, want [a output] from task.Deps", bundle.Messages)
+	}
+	if !strings.Contains(logBuf.String(), "event=deps_mismatch run_id=run1 trace_id= task_id=c") {
+		t.Errorf("expected deps_mismatch warning, got log: %q", logBuf.String())
+	}
+}
+
+func TestBuild_DepsMatchNoWarning(t *testing.T) {
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	defer log.SetOutput(os.Stderr)
+
+	cb := NewContextBuilder()
+	run := &contracts.Run{
+		ID: "run1",
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"c": {ID: "c", Deps: []contracts.TaskID{"a", "b"}},
+		},
+		DAG: &contracts.DAG{
+			Nodes: map[contracts.TaskID]*contracts.DAGNode{
+				"c": {ID: "c", Deps: []contracts.TaskID{"b", "a"}},
+			},
+		},
+	}
+
+	if _, err := cb.Build(run, "c"); err != nil {
+		t.Fatalf("Build() error = %v, want nil", err)
+	}
+	if strings.Contains(logBuf.String(), "deps_mismatch") {
+		t.Errorf("unexpected deps_mismatch warning: %q", logBuf.String())
+	}
+}
+
+func TestBuild_FromMemory(t *testing.T) {
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	defer log.SetOutput(os.Stderr)
+
+	cb := NewContextBuilder()
+	run := &contracts.Run{
+		ID: "run1",
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"a": {ID: "a", Inputs: &contracts.TaskInput{
+				Inputs:     map[string]string{"dep": "x"},
+				FromMemory: []string{"topic", "style", "absent"},
+			}},
+		},
+		Memory: map[string]string{"topic": "go", "style": "terse", "other": "unused"},
+	}
+
+	if _, err := cb.Build(run, "a"); err != nil {
+		t.Fatalf("Build() error = %v, want nil", err)
+	}
+
+	want := map[string]string{"dep": "x", "memory:topic": "go", "memory:style": "terse"}
+	got := run.Tasks["a"].Inputs.Inputs
+	if len(got) != len(want) {
+		t.Fatalf("Inputs = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Inputs[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+	if !strings.Contains(logBuf.String(), "event=memory_input_missing run_id=run1 trace_id= task_id=a key=absent") {
+		t.Errorf("expected memory_input_missing warning, got log: %q", logBuf.String())
+	}
+}
+
+func TestResolveMemoryInputs_NilInputsMap(t *testing.T) {
+	run := &contracts.Run{ID: "run1", Memory: map[string]string{"k": "v"}}
+	task := &contracts.Task{ID: "a", Inputs: &contracts.TaskInput{FromMemory: []string{"k"}}}
+
+	ResolveMemoryInputs(run, task)
+
+	if got := task.Inputs.Inputs["memory:k"]; got != "v" {
+		t.Errorf("Inputs[memory:k] = %q, want v", got)
+	}
+}
@@ -2,6 +2,7 @@ package context
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/anthropics/claude-workflow/runtime/contracts"
 )
@@ -11,28 +12,68 @@ const (
 	StrategyTruncate = "truncate"
 	// StrategyKeepLastN keeps only the last N messages.
 	StrategyKeepLastN = "keep_last_n"
+	// StrategySummarize collapses the oldest messages into one summary message.
+	StrategySummarize = "summarize"
 	// StrategyNone does no compaction (default).
 	StrategyNone = "none"
 
 	// defaultCharsPerToken for token estimation.
 	defaultCharsPerToken = 4
+
+	// defaultSummaryMessageChars caps each message in the default summary.
+	defaultSummaryMessageChars = 80
 )
 
+// Summarizer collapses messages (oldest first) into a single summary message.
+type Summarizer func(messages []string) string
+
+// DefaultSummarizer concatenates messages, eliding each one past
+// defaultSummaryMessageChars characters.
+func DefaultSummarizer(messages []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[summary of %d messages]", len(messages))
+	for _, msg := range messages {
+		b.WriteString("\n")
+		if len(msg) > defaultSummaryMessageChars {
+			b.WriteString(msg[:defaultSummaryMessageChars])
+			b.WriteString("...")
+			continue
+		}
+		b.WriteString(msg)
+	}
+	return b.String()
+}
+
 // contextCompactor implements contracts.ContextCompactor.
 // CRITICAL: This component reduces context size. Errors mean information loss.
 //
 // Strategies:
 // - "truncate": Remove oldest messages until tokens <= MaxTokens
 // - "keep_last_n": Keep only last N messages (from policy.KeepLastN)
+// - "summarize": Collapse oldest messages into one summary until tokens <= MaxTokens
 // - "none": No compaction (may error if context too large)
 type contextCompactor struct {
 	charsPerToken int
+	summarizer    Summarizer
 }
 
 // NewContextCompactor creates a new ContextCompactor.
 func NewContextCompactor() contracts.ContextCompactor {
 	return &contextCompactor{
 		charsPerToken: defaultCharsPerToken,
+		summarizer:    DefaultSummarizer,
+	}
+}
+
+// NewContextCompactorWithSummarizer creates a ContextCompactor whose
+// "summarize" strategy uses the given summarizer (nil = DefaultSummarizer).
+func NewContextCompactorWithSummarizer(summarizer Summarizer) contracts.ContextCompactor {
+	if summarizer == nil {
+		summarizer = DefaultSummarizer
+	}
+	return &contextCompactor{
+		charsPerToken: defaultCharsPerToken,
+		summarizer:    summarizer,
 	}
 }
 
@@ -43,6 +84,7 @@ func NewContextCompactorWithRatio(charsPerToken int) contracts.ContextCompactor
 	}
 	return &contextCompactor{
 		charsPerToken: charsPerToken,
+		summarizer:    DefaultSummarizer,
 	}
 }
 
@@ -68,6 +110,9 @@ func (c *contextCompactor) Compact(bundle *contracts.ContextBundle, policy contr
 	case StrategyTruncate:
 		result = c.applyTruncate(result, policy.MaxTokens)
 
+	case StrategySummarize:
+		result = c.applySummarize(result, policy.MaxTokens)
+
 	case StrategyNone, "":
 		// No compaction
 
@@ -133,6 +178,29 @@ func (c *contextCompactor) applyTruncate(bundle *contracts.ContextBundle, maxTok
 	return bundle
 }
 
+// applySummarize replaces the fewest oldest messages with one summary message
+// that brings the bundle within the token limit. The summary counts toward the
+// limit; if even summarizing every message does not fit, the fully summarized
+// bundle is returned and the final size check in Compact rejects it.
+func (c *contextCompactor) applySummarize(bundle *contracts.ContextBundle, maxTokens contracts.TokenCount) *contracts.ContextBundle {
+	if maxTokens <= 0 || len(bundle.Messages) == 0 || c.estimateTokens(bundle) <= maxTokens {
+		return bundle
+	}
+
+	messages := bundle.Messages
+	for n := 1; n <= len(messages); n++ {
+		summarized := make([]string, 0, len(messages)-n+1)
+		summarized = append(summarized, c.summarizer(messages[:n]))
+		summarized = append(summarized, messages[n:]...)
+		bundle.Messages = summarized
+		if c.estimateTokens(bundle) <= maxTokens {
+			break
+		}
+	}
+
+	return bundle
+}
+
 // estimateTokens estimates the token count for a bundle.
 func (c *contextCompactor) estimateTokens(bundle *contracts.ContextBundle) contracts.TokenCount {
 	var totalChars int
@@ -2,6 +2,7 @@ package context
 
 import (
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/anthropics/claude-workflow/runtime/contracts"
@@ -331,3 +332,124 @@ func TestContextCompactor_EmptyNilMaps(t *testing.T) {
 		t.Error("result.Tools is nil, expected empty map")
 	}
 }
+
+func TestContextCompactor_Summarize(t *testing.T) {
+	compactor := NewContextCompactor()
+
+	// 5 messages x 200 chars = 250 tokens
+	msg := strings.Repeat("a", 200)
+	bundle := &contracts.ContextBundle{
+		Messages: []string{msg, msg, msg, msg, msg},
+	}
+
+	result, err := compactor.Compact(bundle, contracts.ContextPolicy{
+		Strategy:  StrategySummarize,
+		MaxTokens: 150,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Messages) >= len(bundle.Messages) {
+		t.Errorf("message count = %d, want fewer than %d", len(result.Messages), len(bundle.Messages))
+	}
+	if !strings.HasPrefix(result.Messages[0], "[summary of ") {
+		t.Errorf("first message = %q, want summary", result.Messages[0])
+	}
+	if last := result.Messages[len(result.Messages)-1]; last != msg {
+		t.Errorf("last message = %q, want most recent message kept", last)
+	}
+
+	var chars int
+	for _, m := range result.Messages {
+		chars += len(m)
+	}
+	if tokens := chars / defaultCharsPerToken; tokens > 150 {
+		t.Errorf("tokens = %d, exceeds limit 150", tokens)
+	}
+	if len(bundle.Messages) != 5 {
+		t.Error("original bundle was mutated")
+	}
+}
+
+func TestContextCompactor_SummarizeWithinLimit(t *testing.T) {
+	compactor := NewContextCompactor()
+
+	bundle := &contracts.ContextBundle{
+		Messages: []string{"one", "two"},
+	}
+
+	result, err := compactor.Compact(bundle, contracts.ContextPolicy{
+		Strategy:  StrategySummarize,
+		MaxTokens: 100,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Messages) != 2 || result.Messages[0] != "one" {
+		t.Errorf("messages = %v, want unchanged", result.Messages)
+	}
+}
+
+func TestContextCompactor_CustomSummarizer(t *testing.T) {
+	var got []string
+	compactor := NewContextCompactorWithSummarizer(func(messages []string) string {
+		got = append([]string(nil), messages...)
+		return "sum"
+	})
+
+	msg := strings.Repeat("b", 40) // 10 tokens
+	bundle := &contracts.ContextBundle{
+		Messages: []string{"first" + msg, "second" + msg, msg},
+	}
+
+	result, err := compactor.Compact(bundle, contracts.ContextPolicy{
+		Strategy:  StrategySummarize,
+		MaxTokens: 12,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"sum", msg}
+	if len(result.Messages) != len(want) || result.Messages[0] != want[0] || result.Messages[1] != want[1] {
+		t.Errorf("messages = %v, want %v", result.Messages, want)
+	}
+	if len(got) != 2 || !strings.HasPrefix(got[0], "first") {
+		t.Errorf("summarizer got %v, want the two oldest messages", got)
+	}
+}
+
+func TestContextCompactor_SummarizeTooLarge(t *testing.T) {
+	// Summary alone exceeds the limit.
+	compactor := NewContextCompactorWithSummarizer(func(messages []string) string {
+		return strings.Repeat("s", 100)
+	})
+
+	bundle := &contracts.ContextBundle{
+		Messages: []string{strings.Repeat("m", 80)},
+	}
+
+	_, err := compactor.Compact(bundle, contracts.ContextPolicy{
+		Strategy:  StrategySummarize,
+		MaxTokens: 10,
+	})
+	if !errors.Is(err, contracts.ErrContextTooLarge) {
+		t.Errorf("expected ErrContextTooLarge, got %v", err)
+	}
+}
+
+func TestDefaultSummarizer_Elides(t *testing.T) {
+	long := strings.Repeat("x", 200)
+	summary := DefaultSummarizer([]string{"short", long})
+
+	if !strings.HasPrefix(summary, "[summary of 2 messages]") {
+		t.Errorf("summary = %q, want header", summary)
+	}
+	if !strings.Contains(summary, "short") {
+		t.Error("summary dropped short message")
+	}
+	if strings.Contains(summary, long) || !strings.Contains(summary, "...") {
+		t.Error("long message was not elided")
+	}
+}
@@ -1,6 +1,11 @@
 package context
 
 import (
+	"fmt"
+	"maps"
+	"slices"
+	"unicode/utf8"
+
 	"github.com/anthropics/claude-workflow/runtime/contracts"
 )
 
@@ -14,7 +19,10 @@ func NewContextRouter() contracts.ContextRouter {
 
 // Route passes output from one task to another by storing the source task's output
 // in the target task's Inputs.Inputs map, keyed by the source task ID.
+// Named outputs (TaskResult.Outputs) are stored under their own keys; see
+// routeNamedOutputs for how keys produced by several dependencies are handled.
 // It validates that both tasks exist in the run and handles nil maps gracefully.
+// Values longer than run.Policy.ContextPolicy.MaxRoutedValueBytes are truncated.
 func (cr *contextRouter) Route(run *contracts.Run, from contracts.TaskID, to contracts.TaskID, output *contracts.TaskResult) error {
 	// Validate inputs
 	if run == nil {
@@ -46,10 +54,82 @@ func (cr *contextRouter) Route(run *contracts.Run, from contracts.TaskID, to con
 	// Store the output in the target task's Inputs map, keyed by source task ID
 	var outputValue string
 	if output != nil {
-		outputValue = output.Output
+		outputValue = truncateRoutedValue(output.Output, run.Policy.ContextPolicy.MaxRoutedValueBytes)
 	}
 
 	toTask.Inputs.Inputs[string(from)] = outputValue
 
+	if output != nil {
+		return routeNamedOutputs(run, from, toTask, output.Outputs)
+	}
+	return nil
+}
+
+// routeNamedOutputs stores each named output in the target's inputs under its
+// own key. A key also produced by another completed dependency of the target
+// is a collision, resolved by run.Policy.ContextPolicy.OutputCollision.
+// With the suffix policy every colliding value is stored as "key.sourceTaskID"
+// and the bare key is removed, so the result does not depend on routing order.
+func routeNamedOutputs(run *contracts.Run, from contracts.TaskID, toTask *contracts.Task, outputs map[string]string) error {
+	policy := run.Policy.ContextPolicy
+	inputs := toTask.Inputs.Inputs
+
+	for _, key := range slices.Sorted(maps.Keys(outputs)) {
+		value := truncateRoutedValue(outputs[key], policy.MaxRoutedValueBytes)
+		others := namedOutputSources(run, toTask, from, key)
+		if len(others) == 0 {
+			inputs[key] = value
+			continue
+		}
+
+		switch policy.OutputCollision {
+		case contracts.OutputCollisionOverwrite:
+			inputs[key] = value
+		case contracts.OutputCollisionSuffix:
+			delete(inputs, key)
+			for _, other := range others {
+				otherValue := run.Tasks[other].Outputs.Outputs[key]
+				inputs[key+"."+string(other)] = truncateRoutedValue(otherValue, policy.MaxRoutedValueBytes)
+			}
+			inputs[key+"."+string(from)] = value
+		default:
+			return fmt.Errorf("output %q of %s also produced by %s for %s: %w",
+				key, from, others[0], toTask.ID, contracts.ErrOutputCollision)
+		}
+	}
 	return nil
 }
+
+// namedOutputSources returns the completed dependencies of task, other than
+// from, that produced the named output key. The result is sorted by task ID.
+func namedOutputSources(run *contracts.Run, task *contracts.Task, from contracts.TaskID, key string) []contracts.TaskID {
+	var sources []contracts.TaskID
+	for _, depID := range task.Deps {
+		if depID == from {
+			continue
+		}
+		dep, ok := run.Tasks[depID]
+		if !ok || dep.State != contracts.TaskCompleted || dep.Outputs == nil {
+			continue
+		}
+		if _, ok := dep.Outputs.Outputs[key]; ok {
+			sources = append(sources, depID)
+		}
+	}
+	slices.Sort(sources)
+	return sources
+}
+
+// truncateRoutedValue cuts value to at most maxBytes bytes (backing off to a
+// UTF-8 rune boundary) and appends RoutedValueTruncatedMarker.
+// maxBytes <= 0 means unlimited.
+func truncateRoutedValue(value string, maxBytes int) string {
+	if maxBytes <= 0 || len(value) <= maxBytes {
+		return value
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut] + contracts.RoutedValueTruncatedMarker
+}
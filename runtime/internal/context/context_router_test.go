@@ -450,3 +450,166 @@ func TestNewContextRouter(t *testing.T) {
 	// Verify it implements the interface
 	var _ contracts.ContextRouter = router
 }
+
+func TestContextRouter_Route_TruncatesOversizedValue(t *testing.T) {
+	router := NewContextRouter()
+	run := &contracts.Run{
+		Policy: contracts.RunPolicy{
+			ContextPolicy: contracts.ContextPolicy{MaxRoutedValueBytes: 10},
+		},
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"from": {ID: "from"},
+			"to":   {ID: "to"},
+		},
+	}
+
+	output := &contracts.TaskResult{Output: "0123456789abcdefghij"}
+	if err := router.Route(run, "from", "to", output); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := "0123456789" + contracts.RoutedValueTruncatedMarker
+	if got := run.Tasks["to"].Inputs.Inputs["from"]; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if output.Output != "0123456789abcdefghij" {
+		t.Errorf("source output must not be modified, got %q", output.Output)
+	}
+}
+
+func TestContextRouter_Route_TruncationRespectsRuneBoundary(t *testing.T) {
+	router := NewContextRouter()
+	run := &contracts.Run{
+		Policy: contracts.RunPolicy{
+			ContextPolicy: contracts.ContextPolicy{MaxRoutedValueBytes: 4},
+		},
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"from": {ID: "from"},
+			"to":   {ID: "to"},
+		},
+	}
+
+	// "aéé" = 1 + 2 + 2 bytes; a 4-byte cut would split the second "é"
+	if err := router.Route(run, "from", "to", &contracts.TaskResult{Output: "aééz"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := "aé" + contracts.RoutedValueTruncatedMarker
+	if got := run.Tasks["to"].Inputs.Inputs["from"]; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestContextRouter_Route_WithinLimitUnchanged(t *testing.T) {
+	router := NewContextRouter()
+	run := &contracts.Run{
+		Policy: contracts.RunPolicy{
+			ContextPolicy: contracts.ContextPolicy{MaxRoutedValueBytes: 10},
+		},
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"from": {ID: "from"},
+			"to":   {ID: "to"},
+		},
+	}
+
+	if err := router.Route(run, "from", "to", &contracts.TaskResult{Output: "0123456789"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := run.Tasks["to"].Inputs.Inputs["from"]; got != "0123456789" {
+		t.Errorf("expected value unchanged, got %q", got)
+	}
+}
+
+// newCollisionRun builds a run where A and B both produce the named output
+// "report" for C. Both are completed, as they are when the orchestrator routes.
+func newCollisionRun(policy string) *contracts.Run {
+	done := func(id contracts.TaskID, report string) *contracts.Task {
+		return &contracts.Task{
+			ID:      id,
+			State:   contracts.TaskCompleted,
+			Outputs: &contracts.TaskResult{Output: "out " + string(id), Outputs: map[string]string{"report": report}},
+		}
+	}
+	return &contracts.Run{
+		Policy: contracts.RunPolicy{
+			ContextPolicy: contracts.ContextPolicy{OutputCollision: policy},
+		},
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"A": done("A", "report from A"),
+			"B": done("B", "report from B"),
+			"C": {ID: "C", Deps: []contracts.TaskID{"A", "B"}},
+		},
+	}
+}
+
+func routeBoth(router contracts.ContextRouter, run *contracts.Run) error {
+	for _, from := range []contracts.TaskID{"A", "B"} {
+		if err := router.Route(run, from, "C", run.Tasks[from].Outputs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestContextRouter_Route_NamedOutput(t *testing.T) {
+	router := NewContextRouter()
+	run := newCollisionRun("")
+	run.Tasks["B"].Outputs.Outputs = map[string]string{"summary": "summary from B"}
+
+	if err := routeBoth(router, run); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	inputs := run.Tasks["C"].Inputs.Inputs
+	if inputs["report"] != "report from A" || inputs["summary"] != "summary from B" {
+		t.Errorf("named outputs not routed by key: %v", inputs)
+	}
+}
+
+func TestContextRouter_Route_OutputCollisionError(t *testing.T) {
+	for _, policy := range []string{"", contracts.OutputCollisionError} {
+		router := NewContextRouter()
+		run := newCollisionRun(policy)
+
+		err := routeBoth(router, run)
+		if !errors.Is(err, contracts.ErrOutputCollision) {
+			t.Errorf("policy %q: expected ErrOutputCollision, got %v", policy, err)
+		}
+	}
+}
+
+func TestContextRouter_Route_OutputCollisionOverwrite(t *testing.T) {
+	router := NewContextRouter()
+	run := newCollisionRun(contracts.OutputCollisionOverwrite)
+
+	if err := routeBoth(router, run); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := run.Tasks["C"].Inputs.Inputs["report"]; got != "report from B" {
+		t.Errorf("expected last routed value, got %q", got)
+	}
+}
+
+func TestContextRouter_Route_OutputCollisionSuffix(t *testing.T) {
+	// Routing order must not change the result
+	for _, order := range [][]contracts.TaskID{{"A", "B"}, {"B", "A"}} {
+		router := NewContextRouter()
+		run := newCollisionRun(contracts.OutputCollisionSuffix)
+
+		for _, from := range order {
+			if err := router.Route(run, from, "C", run.Tasks[from].Outputs); err != nil {
+				t.Fatalf("order %v: expected no error, got %v", order, err)
+			}
+		}
+
+		inputs := run.Tasks["C"].Inputs.Inputs
+		if _, ok := inputs["report"]; ok {
+			t.Errorf("order %v: bare key should be removed, got %v", order, inputs)
+		}
+		if inputs["report.A"] != "report from A" || inputs["report.B"] != "report from B" {
+			t.Errorf("order %v: expected suffixed keys, got %v", order, inputs)
+		}
+		if inputs["A"] != "out A" || inputs["B"] != "out B" {
+			t.Errorf("order %v: primary outputs missing, got %v", order, inputs)
+		}
+	}
+}
@@ -13,20 +13,54 @@ import (
 // Thread-safety: Uses mutex for concurrent access to run state.
 // The enforcer tracks usage per run to prevent budget overruns.
 type budgetEnforcer struct {
-	mu sync.Mutex
+	mu        sync.Mutex
+	pool      contracts.BudgetPool        // optional shared pool for runs with Policy.BudgetPool
+	converter contracts.CurrencyConverter // optional; nil = budget and costs must share a currency
 }
 
 // NewBudgetEnforcer creates a new BudgetEnforcer.
+// The returned enforcer also implements contracts.ModelBudgetEnforcer,
+// contracts.CurrencyConvertingEnforcer and contracts.ReservingBudgetEnforcer.
 func NewBudgetEnforcer() contracts.BudgetEnforcer {
 	return &budgetEnforcer{}
 }
 
+// NewBudgetEnforcerWithPool creates a BudgetEnforcer that also charges runs
+// labeled with run.Policy.BudgetPool against the shared pool.
+// If pool is nil, behaves like NewBudgetEnforcer.
+func NewBudgetEnforcerWithPool(pool contracts.BudgetPool) contracts.BudgetEnforcer {
+	return &budgetEnforcer{pool: pool}
+}
+
+// NewBudgetEnforcerWithConverter creates a BudgetEnforcer that accepts costs
+// in a currency other than the run budget's: estimates are converted into the
+// budget currency before they are checked, and recorded costs before they are
+// added to run.Usage, which stays in the budget currency.
+// Model budgets still require matching currencies.
+// If converter is nil, behaves like NewBudgetEnforcer.
+func NewBudgetEnforcerWithConverter(converter contracts.CurrencyConverter) contracts.BudgetEnforcer {
+	return &budgetEnforcer{converter: converter}
+}
+
+var (
+	_ contracts.ModelBudgetEnforcer        = (*budgetEnforcer)(nil)
+	_ contracts.CurrencyConvertingEnforcer = (*budgetEnforcer)(nil)
+	_ contracts.ReservingBudgetEnforcer    = (*budgetEnforcer)(nil)
+)
+
+// ConvertsCurrency reports whether the enforcer has a converter.
+func (b *budgetEnforcer) ConvertsCurrency() bool {
+	return b.converter != nil
+}
+
 // Allow checks if the estimated cost is within budget.
 // Returns error if:
 // - run is nil (ErrInvalidInput)
 // - budget not set (ErrBudgetNotSet)
 // - estimate would exceed budget (ErrBudgetExceeded)
-// - currency mismatch between estimate and budget
+// - estimate would exceed the run's budget pool (ErrBudgetPoolExhausted)
+// - currency mismatch between estimate and budget (no converter)
+// - the estimate cannot be converted (ErrCurrencyMismatch)
 func (b *budgetEnforcer) Allow(run *contracts.Run, estimate contracts.Cost) error {
 	if run == nil {
 		return contracts.ErrInvalidInput
@@ -41,20 +75,38 @@ func (b *budgetEnforcer) Allow(run *contracts.Run, estimate contracts.Cost) erro
 		return contracts.ErrBudgetNotSet
 	}
 
-	// Validate currency matches
+	// Validate currency matches, converting the estimate if possible
+	converted := estimate
 	if estimate.Currency != "" && budget.Currency != "" && estimate.Currency != budget.Currency {
-		return fmt.Errorf("currency mismatch: estimate %s, budget %s: %w",
-			estimate.Currency, budget.Currency, contracts.ErrInvalidInput)
+		if b.converter == nil {
+			return fmt.Errorf("currency mismatch: estimate %s, budget %s: %w",
+				estimate.Currency, budget.Currency, contracts.ErrInvalidInput)
+		}
+		var err error
+		if converted, err = b.convert(estimate, budget.Currency); err != nil {
+			return err
+		}
 	}
 
 	// Calculate projected total: current usage + estimate
 	currentUsage := run.Usage.Cost.Amount
-	projectedTotal := currentUsage + estimate.Amount
+	projectedTotal := currentUsage + converted.Amount
 
 	// Check if projected total exceeds budget
 	if projectedTotal > budget.Amount {
 		return fmt.Errorf("projected cost %.4f exceeds budget %.4f (current: %.4f, estimate: %.4f): %w",
-			projectedTotal, budget.Amount, currentUsage, estimate.Amount, contracts.ErrBudgetExceeded)
+			projectedTotal, budget.Amount, currentUsage, converted.Amount, contracts.ErrBudgetExceeded)
+	}
+
+	// Check shared pool even if the run budget has room; the pool checks
+	// the estimate in its own currency and, if it can, holds it for the run
+	if b.pool != nil && run.Policy.BudgetPool != "" {
+		if reserving, ok := b.pool.(contracts.ReservingBudgetPool); ok {
+			return reserving.Reserve(run.Policy.BudgetPool, string(run.ID), estimate)
+		}
+		if err := b.pool.Allow(run.Policy.BudgetPool, estimate); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -64,8 +116,11 @@ func (b *budgetEnforcer) Allow(run *contracts.Run, estimate contracts.Cost) erro
 // Returns error if:
 // - run is nil (ErrInvalidInput)
 // - recording would exceed budget (ErrBudgetExceeded) - safety check
+// - recording exceeded the run's budget pool (ErrBudgetPoolExhausted); run usage is still updated
+// - the cost cannot be converted (ErrCurrencyMismatch)
 //
-// Note: Record updates run.Usage.Cost in place.
+// Note: Record updates run.Usage.Cost in place. With a converter, the cost
+// is added in the budget currency.
 func (b *budgetEnforcer) Record(run *contracts.Run, actual contracts.Cost) error {
 	if run == nil {
 		return contracts.ErrInvalidInput
@@ -74,22 +129,131 @@ func (b *budgetEnforcer) Record(run *contracts.Run, actual contracts.Cost) error
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	// Safety check: don't allow recording if it would exceed budget
-	// This catches cases where Allow was bypassed or estimate was wrong
 	budget := run.Policy.BudgetLimit
-	if budget.Amount > 0 {
-		projectedTotal := run.Usage.Cost.Amount + actual.Amount
-		if projectedTotal > budget.Amount {
-			return fmt.Errorf("recording cost %.4f would exceed budget %.4f (current: %.4f): %w",
-				actual.Amount, budget.Amount, run.Usage.Cost.Amount, contracts.ErrBudgetExceeded)
+	converted := actual
+	if b.converter != nil && actual.Currency != "" && budget.Currency != "" && actual.Currency != budget.Currency {
+		var err error
+		if converted, err = b.convert(actual, budget.Currency); err != nil {
+			return fmt.Errorf("recording cost for run %s: %w", run.ID, err)
 		}
 	}
 
+	projected, err := run.Usage.Cost.Add(converted)
+	if err != nil {
+		return fmt.Errorf("recording cost for run %s: %w", run.ID, err)
+	}
+
+	// Safety check: don't allow recording if it would exceed budget
+	// This catches cases where Allow was bypassed or estimate was wrong
+	if budget.Amount > 0 && projected.Amount > budget.Amount {
+		return fmt.Errorf("recording cost %.4f would exceed budget %.4f (current: %.4f): %w",
+			converted.Amount, budget.Amount, run.Usage.Cost.Amount, contracts.ErrBudgetExceeded)
+	}
+
 	// Update usage
-	run.Usage.Cost.Amount += actual.Amount
-	if run.Usage.Cost.Currency == "" && actual.Currency != "" {
-		run.Usage.Cost.Currency = actual.Currency
+	run.Usage.Cost = projected
+
+	// Charge shared pool
+	if b.pool != nil && run.Policy.BudgetPool != "" {
+		if reserving, ok := b.pool.(contracts.ReservingBudgetPool); ok {
+			return reserving.Commit(run.Policy.BudgetPool, string(run.ID), actual)
+		}
+		return b.pool.Record(run.Policy.BudgetPool, actual)
+	}
+
+	return nil
+}
+
+// Release drops the run's reservation in its budget pool, if any.
+func (b *budgetEnforcer) Release(run *contracts.Run) {
+	if run == nil || run.Policy.BudgetPool == "" {
+		return
+	}
+	if reserving, ok := b.pool.(contracts.ReservingBudgetPool); ok {
+		reserving.Release(run.Policy.BudgetPool, string(run.ID))
+	}
+}
+
+// convert returns c in currency to. Conversion failures wrap
+// ErrCurrencyMismatch and the converter's error.
+func (b *budgetEnforcer) convert(c contracts.Cost, to contracts.Currency) (contracts.Cost, error) {
+	amount, err := b.converter.Convert(c.Amount, string(c.Currency), string(to))
+	if err != nil {
+		return c, fmt.Errorf("converting %.4f %s to %s: %w: %w", c.Amount, c.Currency, to, contracts.ErrCurrencyMismatch, err)
+	}
+	return contracts.Cost{Amount: amount, Currency: to}, nil
+}
+
+// AllowModel checks if the estimated cost is within the model's budget.
+// Models without an entry in run.Policy.ModelBudgets are not capped.
+// Returns error if:
+// - run is nil (ErrInvalidInput)
+// - estimate would exceed the model budget (ErrModelBudgetExceeded)
+// - currency mismatch between estimate and model budget
+func (b *budgetEnforcer) AllowModel(run *contracts.Run, model contracts.ModelID, estimate contracts.Cost) error {
+	if run == nil {
+		return contracts.ErrInvalidInput
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	budget, ok := run.Policy.ModelBudgets[model]
+	if !ok || budget.Amount <= 0 {
+		return nil
+	}
+
+	// Validate currency matches
+	if estimate.Currency != "" && budget.Currency != "" && estimate.Currency != budget.Currency {
+		return fmt.Errorf("currency mismatch for model %s: estimate %s, budget %s: %w",
+			model, estimate.Currency, budget.Currency, contracts.ErrInvalidInput)
+	}
+
+	currentUsage := run.ModelUsage[model].Cost.Amount
+	projectedTotal := currentUsage + estimate.Amount
+
+	if projectedTotal > budget.Amount {
+		return fmt.Errorf("model %s projected cost %.4f exceeds model budget %.4f (current: %.4f, estimate: %.4f): %w",
+			model, projectedTotal, budget.Amount, currentUsage, estimate.Amount, contracts.ErrModelBudgetExceeded)
+	}
+
+	return nil
+}
+
+// RecordModel records actual usage for a model and updates run.ModelUsage.
+// Returns error if:
+// - run is nil (ErrInvalidInput)
+// - recording would exceed the model budget (ErrModelBudgetExceeded) - safety check
+//
+// Note: RecordModel does not update run.Usage; that is done by Record and UsageTracker.
+func (b *budgetEnforcer) RecordModel(run *contracts.Run, model contracts.ModelID, actual contracts.Usage) error {
+	if run == nil {
+		return contracts.ErrInvalidInput
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current := run.ModelUsage[model]
+	projected, err := current.Cost.Add(actual.Cost)
+	if err != nil {
+		return fmt.Errorf("recording cost for model %s: %w", model, err)
+	}
+
+	// Safety check: don't allow recording if it would exceed the model budget
+	if budget, ok := run.Policy.ModelBudgets[model]; ok && budget.Amount > 0 && projected.Amount > budget.Amount {
+		return fmt.Errorf("recording cost %.4f for model %s would exceed model budget %.4f (current: %.4f): %w",
+			actual.Cost.Amount, model, budget.Amount, current.Cost.Amount, contracts.ErrModelBudgetExceeded)
+	}
+
+	if run.ModelUsage == nil {
+		run.ModelUsage = make(map[contracts.ModelID]contracts.Usage)
 	}
+	current.Tokens += actual.Tokens
+	current.InputTokens += actual.InputTokens
+	current.OutputTokens += actual.OutputTokens
+	current.Cost = projected
+	run.ModelUsage[model] = current
 
 	return nil
 }
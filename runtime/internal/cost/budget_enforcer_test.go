@@ -2,6 +2,7 @@ package cost
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 
@@ -238,15 +239,101 @@ func TestBudgetEnforcer_CurrencyPreservation(t *testing.T) {
 		Usage: contracts.Usage{Cost: contracts.Cost{Amount: 10, Currency: "USD"}},
 	}
 
-	// Record with different currency should preserve original
+	// Record with different currency is rejected and leaves usage untouched
 	err := enforcer.Record(run, contracts.Cost{Amount: 5, Currency: "EUR"})
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if !errors.Is(err, contracts.ErrCurrencyMismatch) {
+		t.Fatalf("expected ErrCurrencyMismatch, got %v", err)
 	}
 
 	if run.Usage.Cost.Currency != "USD" {
 		t.Errorf("currency changed from USD to %v", run.Usage.Cost.Currency)
 	}
+	if run.Usage.Cost.Amount != 10 {
+		t.Errorf("usage amount changed to %v", run.Usage.Cost.Amount)
+	}
+
+	// Empty currency inherits the run's
+	if err := enforcer.Record(run, contracts.Cost{Amount: 5}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if run.Usage.Cost != (contracts.Cost{Amount: 15, Currency: "USD"}) {
+		t.Errorf("unexpected usage %+v", run.Usage.Cost)
+	}
+}
+
+// stubConverter converts at fixed rates keyed "FROM->TO".
+type stubConverter map[string]float64
+
+func (s stubConverter) Convert(amount float64, from, to string) (float64, error) {
+	rate, ok := s[from+"->"+to]
+	if !ok {
+		return 0, fmt.Errorf("no rate for %s to %s", from, to)
+	}
+	return amount * rate, nil
+}
+
+func TestBudgetEnforcer_Converter(t *testing.T) {
+	enforcer := NewBudgetEnforcerWithConverter(stubConverter{"USD->EUR": 0.5})
+	if !enforcer.(contracts.CurrencyConvertingEnforcer).ConvertsCurrency() {
+		t.Error("expected enforcer with converter to convert currency")
+	}
+	run := &contracts.Run{
+		ID:     "run-1",
+		Policy: contracts.RunPolicy{BudgetLimit: contracts.Cost{Amount: 1.0, Currency: "EUR"}},
+	}
+
+	// 1.5 USD is 0.75 EUR: within the EUR budget
+	if err := enforcer.Allow(run, contracts.Cost{Amount: 1.5, Currency: "USD"}); err != nil {
+		t.Fatalf("expected USD estimate within EUR budget, got %v", err)
+	}
+	// 2.5 USD is 1.25 EUR: over it
+	if err := enforcer.Allow(run, contracts.Cost{Amount: 2.5, Currency: "USD"}); !errors.Is(err, contracts.ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+
+	// Recorded costs are stored in the budget currency
+	if err := enforcer.Record(run, contracts.Cost{Amount: 1.0, Currency: "USD"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if run.Usage.Cost != (contracts.Cost{Amount: 0.5, Currency: "EUR"}) {
+		t.Errorf("usage = %+v, want 0.5 EUR", run.Usage.Cost)
+	}
+	// 1.2 USD is 0.6 EUR: on top of 0.5 EUR it exceeds the budget
+	if err := enforcer.Allow(run, contracts.Cost{Amount: 1.2, Currency: "USD"}); !errors.Is(err, contracts.ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded after recording, got %v", err)
+	}
+
+	// A currency the converter cannot price is a mismatch, and usage is untouched
+	err := enforcer.Allow(run, contracts.Cost{Amount: 0.1, Currency: "GBP"})
+	if !errors.Is(err, contracts.ErrCurrencyMismatch) {
+		t.Fatalf("expected ErrCurrencyMismatch from Allow, got %v", err)
+	}
+	err = enforcer.Record(run, contracts.Cost{Amount: 0.1, Currency: "GBP"})
+	if !errors.Is(err, contracts.ErrCurrencyMismatch) {
+		t.Fatalf("expected ErrCurrencyMismatch from Record, got %v", err)
+	}
+	if run.Usage.Cost != (contracts.Cost{Amount: 0.5, Currency: "EUR"}) {
+		t.Errorf("usage changed to %+v", run.Usage.Cost)
+	}
+}
+
+func TestBudgetEnforcer_StrictWithoutConverter(t *testing.T) {
+	run := &contracts.Run{
+		ID:     "run-1",
+		Policy: contracts.RunPolicy{BudgetLimit: contracts.Cost{Amount: 1.0, Currency: "EUR"}},
+	}
+	for name, enforcer := range map[string]contracts.BudgetEnforcer{
+		"default":       NewBudgetEnforcer(),
+		"nil converter": NewBudgetEnforcerWithConverter(nil),
+	} {
+		if enforcer.(contracts.CurrencyConvertingEnforcer).ConvertsCurrency() {
+			t.Errorf("%s: expected no currency conversion", name)
+		}
+		err := enforcer.Allow(run, contracts.Cost{Amount: 0.1, Currency: "USD"})
+		if !errors.Is(err, contracts.ErrInvalidInput) {
+			t.Errorf("%s: expected ErrInvalidInput, got %v", name, err)
+		}
+	}
 }
 
 func TestBudgetEnforcer_Concurrent(t *testing.T) {
@@ -369,3 +456,91 @@ func TestBudgetEnforcer_PrecisionEdgeCases(t *testing.T) {
 		t.Logf("Note: floating point precision issue: %v", err)
 	}
 }
+
+func TestBudgetEnforcer_AllowModel(t *testing.T) {
+	enforcer := NewBudgetEnforcer().(contracts.ModelBudgetEnforcer)
+
+	run := &contracts.Run{
+		ID: "run-1",
+		Policy: contracts.RunPolicy{
+			BudgetLimit: contracts.Cost{Amount: 100, Currency: "USD"},
+			ModelBudgets: map[contracts.ModelID]contracts.Cost{
+				"expensive": {Amount: 10, Currency: "USD"},
+			},
+		},
+		ModelUsage: map[contracts.ModelID]contracts.Usage{
+			"expensive": {Cost: contracts.Cost{Amount: 8, Currency: "USD"}},
+		},
+	}
+
+	// Within model budget
+	if err := enforcer.AllowModel(run, "expensive", contracts.Cost{Amount: 2, Currency: "USD"}); err != nil {
+		t.Fatalf("AllowModel(2) unexpected error: %v", err)
+	}
+
+	// Over model budget while run budget remains
+	err := enforcer.AllowModel(run, "expensive", contracts.Cost{Amount: 3, Currency: "USD"})
+	if !errors.Is(err, contracts.ErrModelBudgetExceeded) {
+		t.Fatalf("AllowModel(3) expected ErrModelBudgetExceeded, got %v", err)
+	}
+
+	// Uncapped model is always allowed
+	if err := enforcer.AllowModel(run, "cheap", contracts.Cost{Amount: 50, Currency: "USD"}); err != nil {
+		t.Fatalf("AllowModel(uncapped) unexpected error: %v", err)
+	}
+
+	// Currency mismatch
+	err = enforcer.AllowModel(run, "expensive", contracts.Cost{Amount: 1, Currency: "EUR"})
+	if !errors.Is(err, contracts.ErrInvalidInput) {
+		t.Fatalf("AllowModel(EUR) expected ErrInvalidInput, got %v", err)
+	}
+
+	// Nil run
+	if err := enforcer.AllowModel(nil, "expensive", contracts.Cost{}); !errors.Is(err, contracts.ErrInvalidInput) {
+		t.Fatalf("AllowModel(nil) expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestBudgetEnforcer_RecordModel(t *testing.T) {
+	enforcer := NewBudgetEnforcer().(contracts.ModelBudgetEnforcer)
+
+	run := &contracts.Run{
+		ID: "run-1",
+		Policy: contracts.RunPolicy{
+			BudgetLimit: contracts.Cost{Amount: 100, Currency: "USD"},
+			ModelBudgets: map[contracts.ModelID]contracts.Cost{
+				"expensive": {Amount: 10, Currency: "USD"},
+			},
+		},
+	}
+
+	usage := contracts.Usage{Tokens: 1000, Cost: contracts.Cost{Amount: 6, Currency: "USD"}}
+	if err := enforcer.RecordModel(run, "expensive", usage); err != nil {
+		t.Fatalf("RecordModel unexpected error: %v", err)
+	}
+	if err := enforcer.RecordModel(run, "cheap", usage); err != nil {
+		t.Fatalf("RecordModel(uncapped) unexpected error: %v", err)
+	}
+
+	got := run.ModelUsage["expensive"]
+	if got.Tokens != 1000 || got.Cost.Amount != 6 || got.Cost.Currency != "USD" {
+		t.Errorf("unexpected model usage: %+v", got)
+	}
+	if run.ModelUsage["cheap"].Cost.Amount != 6 {
+		t.Errorf("expected cheap usage 6, got %v", run.ModelUsage["cheap"].Cost.Amount)
+	}
+
+	// Second record would exceed the model cap
+	err := enforcer.RecordModel(run, "expensive", usage)
+	if !errors.Is(err, contracts.ErrModelBudgetExceeded) {
+		t.Fatalf("expected ErrModelBudgetExceeded, got %v", err)
+	}
+	if run.ModelUsage["expensive"].Cost.Amount != 6 {
+		t.Errorf("usage should be unchanged after failed record, got %v", run.ModelUsage["expensive"].Cost.Amount)
+	}
+
+	// Run-level usage is not touched
+	if run.Usage.Cost.Amount != 0 {
+		t.Errorf("expected run usage unchanged, got %v", run.Usage.Cost.Amount)
+	}
+}
@@ -0,0 +1,262 @@
+package cost
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+	"github.com/anthropics/claude-workflow/runtime/internal/audit"
+)
+
+// PoolUsageStore persists budget pool usage so it survives restarts.
+// See NewBudgetPoolWithStore.
+type PoolUsageStore interface {
+	// Load returns the stored usage keyed by pool label (empty if none).
+	Load() (map[string]contracts.Cost, error)
+	// Save replaces the stored usage.
+	Save(usage map[string]contracts.Cost) error
+}
+
+// budgetPool implements contracts.ReservingBudgetPool. A single pool
+// instance is shared by all runs, so usage accumulates across runs; with a
+// PoolUsageStore it also carries over across restarts.
+//
+// Thread-safety: Uses mutex; safe for concurrent runs.
+type budgetPool struct {
+	mu       sync.Mutex
+	limits   map[string]contracts.Cost
+	usage    map[string]contracts.Cost
+	reserved map[string]map[string]float64 // by label, then holder
+	store    PoolUsageStore                // nil = usage is not persisted
+}
+
+var _ contracts.ReservingBudgetPool = (*budgetPool)(nil)
+
+// NewBudgetPool creates a BudgetPool with spend limits keyed by label.
+// Labels without a limit (or with a non-positive amount) are tracked but not capped.
+// The returned pool also implements contracts.ReservingBudgetPool.
+func NewBudgetPool(limits map[string]contracts.Cost) contracts.BudgetPool {
+	return newBudgetPool(limits)
+}
+
+// NewBudgetPoolWithStore creates a BudgetPool like NewBudgetPool whose usage
+// starts from store and is saved to it after every recorded cost. Save
+// errors are logged (event=budget_pool_persist_failed), not returned.
+func NewBudgetPoolWithStore(limits map[string]contracts.Cost, store PoolUsageStore) (contracts.BudgetPool, error) {
+	usage, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load budget pool usage: %w", err)
+	}
+	p := newBudgetPool(limits)
+	for label, cost := range usage {
+		p.usage[label] = cost
+	}
+	p.store = store
+	return p, nil
+}
+
+func newBudgetPool(limits map[string]contracts.Cost) *budgetPool {
+	copied := make(map[string]contracts.Cost, len(limits))
+	for label, limit := range limits {
+		copied[label] = limit
+	}
+	return &budgetPool{
+		limits:   copied,
+		usage:    make(map[string]contracts.Cost),
+		reserved: make(map[string]map[string]float64),
+	}
+}
+
+// Allow checks if the estimated cost fits in the pool, counting every
+// outstanding reservation.
+// Returns error if:
+// - currency mismatch between estimate and pool limit (ErrInvalidInput)
+// - pool usage plus reservations plus estimate would exceed the limit (ErrBudgetPoolExhausted)
+func (p *budgetPool) Allow(label string, estimate contracts.Cost) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.checkLocked(label, "", estimate)
+}
+
+// Reserve checks the estimate like Allow, leaving out holder's own
+// reservation, and on success sets holder's reservation to it.
+func (p *budgetPool) Reserve(label, holder string, estimate contracts.Cost) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.checkLocked(label, holder, estimate); err != nil {
+		return err
+	}
+	if limit, ok := p.limits[label]; !ok || limit.Amount <= 0 {
+		return nil // uncapped: nothing to hold
+	}
+	if p.reserved[label] == nil {
+		p.reserved[label] = make(map[string]float64)
+	}
+	p.reserved[label][holder] = estimate.Amount
+	return nil
+}
+
+// Commit reduces holder's reservation by the actual cost, then records it.
+func (p *budgetPool) Commit(label, holder string, actual contracts.Cost) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if held, ok := p.reserved[label][holder]; ok {
+		if held -= actual.Amount; held > 0 {
+			p.reserved[label][holder] = held
+		} else {
+			delete(p.reserved[label], holder)
+		}
+	}
+	return p.recordLocked(label, actual)
+}
+
+// Release drops holder's reservation.
+func (p *budgetPool) Release(label, holder string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.reserved[label], holder)
+}
+
+// checkLocked returns an error if estimate does not fit in the pool next to
+// its usage and the reservations of holders other than holder.
+func (p *budgetPool) checkLocked(label, holder string, estimate contracts.Cost) error {
+	limit, ok := p.limits[label]
+	if !ok || limit.Amount <= 0 {
+		return nil
+	}
+
+	// Validate currency matches
+	if estimate.Currency != "" && limit.Currency != "" && estimate.Currency != limit.Currency {
+		return fmt.Errorf("currency mismatch for pool %s: estimate %s, limit %s: %w",
+			label, estimate.Currency, limit.Currency, contracts.ErrInvalidInput)
+	}
+
+	current := p.usage[label].Amount
+	var reserved float64
+	for h, amount := range p.reserved[label] {
+		if h != holder {
+			reserved += amount
+		}
+	}
+	projectedTotal := current + reserved + estimate.Amount
+	if projectedTotal > limit.Amount {
+		return fmt.Errorf("pool %s projected cost %.4f exceeds limit %.4f (current: %.4f, reserved: %.4f, estimate: %.4f): %w",
+			label, projectedTotal, limit.Amount, current, reserved, estimate.Amount, contracts.ErrBudgetPoolExhausted)
+	}
+
+	return nil
+}
+
+// Record adds actual cost to the pool's usage.
+// Usage is recorded even if it exceeds the limit (the cost was already incurred),
+// in which case ErrBudgetPoolExhausted is returned.
+func (p *budgetPool) Record(label string, actual contracts.Cost) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.recordLocked(label, actual)
+}
+
+func (p *budgetPool) recordLocked(label string, actual contracts.Cost) error {
+	current, err := p.usage[label].Add(actual)
+	if err != nil {
+		return fmt.Errorf("pool %s: %w", label, err)
+	}
+	p.usage[label] = current
+
+	if p.store != nil {
+		if err := p.store.Save(p.usage); err != nil {
+			audit.Log("event=budget_pool_persist_failed pool=%s error_msg=%s", label, err.Error())
+		}
+	}
+
+	if limit, ok := p.limits[label]; ok && limit.Amount > 0 && current.Amount > limit.Amount {
+		return fmt.Errorf("pool %s usage %.4f exceeds limit %.4f: %w",
+			label, current.Amount, limit.Amount, contracts.ErrBudgetPoolExhausted)
+	}
+
+	return nil
+}
+
+// Usage returns the cost recorded against the pool so far.
+func (p *budgetPool) Usage(label string) contracts.Cost {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.usage[label]
+}
+
+// FilePoolUsageStore stores pool usage as one JSON object, keyed by pool
+// label, in a single file.
+type FilePoolUsageStore struct {
+	path string
+}
+
+// poolUsageEntry is the file format of one pool's usage.
+type poolUsageEntry struct {
+	Amount   float64            `json:"amount"`
+	Currency contracts.Currency `json:"currency,omitempty"`
+}
+
+var _ PoolUsageStore = (*FilePoolUsageStore)(nil)
+
+// NewFilePoolUsageStore creates a FilePoolUsageStore writing to path.
+// Its directory is created on the first Save.
+func NewFilePoolUsageStore(path string) *FilePoolUsageStore {
+	return &FilePoolUsageStore{path: path}
+}
+
+// Load reads the usage file. A missing file holds no usage.
+func (s *FilePoolUsageStore) Load() (map[string]contracts.Cost, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]contracts.Cost{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries map[string]poolUsageEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	usage := make(map[string]contracts.Cost, len(entries))
+	for label, e := range entries {
+		usage[label] = contracts.Cost{Amount: e.Amount, Currency: e.Currency}
+	}
+	return usage, nil
+}
+
+// Save writes the usage file. The file is replaced atomically, so a crash
+// mid-write leaves the previous usage in place.
+func (s *FilePoolUsageStore) Save(usage map[string]contracts.Cost) error {
+	entries := make(map[string]poolUsageEntry, len(usage))
+	for label, c := range usage {
+		entries[label] = poolUsageEntry{Amount: c.Amount, Currency: c.Currency}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create dir %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".budget-pools-*.json.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", s.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", s.path, err)
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,171 @@
+package cost
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+func TestBudgetPool_SharedAcrossRuns(t *testing.T) {
+	pool := NewBudgetPool(map[string]contracts.Cost{
+		"team-a": {Amount: 10, Currency: "USD"},
+	})
+	enforcer := NewBudgetEnforcerWithPool(pool)
+
+	newRun := func(id contracts.RunID) *contracts.Run {
+		return &contracts.Run{
+			ID: id,
+			Policy: contracts.RunPolicy{
+				BudgetLimit: contracts.Cost{Amount: 100, Currency: "USD"},
+				BudgetPool:  "team-a",
+			},
+		}
+	}
+	run1 := newRun("run-1")
+	run2 := newRun("run-2")
+
+	// Run 1 spends 6 of the pool's 10
+	if err := enforcer.Allow(run1, contracts.Cost{Amount: 6, Currency: "USD"}); err != nil {
+		t.Fatalf("run-1 Allow: unexpected error: %v", err)
+	}
+	if err := enforcer.Record(run1, contracts.Cost{Amount: 6, Currency: "USD"}); err != nil {
+		t.Fatalf("run-1 Record: unexpected error: %v", err)
+	}
+
+	// Run 2 has plenty of run budget, but only 4 left in the pool
+	if err := enforcer.Allow(run2, contracts.Cost{Amount: 5, Currency: "USD"}); !errors.Is(err, contracts.ErrBudgetPoolExhausted) {
+		t.Fatalf("run-2 Allow: expected ErrBudgetPoolExhausted, got %v", err)
+	}
+	if err := enforcer.Allow(run2, contracts.Cost{Amount: 4, Currency: "USD"}); err != nil {
+		t.Fatalf("run-2 Allow within pool: unexpected error: %v", err)
+	}
+	if err := enforcer.Record(run2, contracts.Cost{Amount: 4, Currency: "USD"}); err != nil {
+		t.Fatalf("run-2 Record: unexpected error: %v", err)
+	}
+
+	// Pool is now exhausted for both runs
+	for _, run := range []*contracts.Run{run1, run2} {
+		if err := enforcer.Allow(run, contracts.Cost{Amount: 0.01, Currency: "USD"}); !errors.Is(err, contracts.ErrBudgetPoolExhausted) {
+			t.Errorf("%s: expected ErrBudgetPoolExhausted, got %v", run.ID, err)
+		}
+	}
+	if got := pool.Usage("team-a").Amount; got != 10 {
+		t.Errorf("expected pool usage 10, got %v", got)
+	}
+}
+
+func TestBudgetPool_UnlabeledRunNotCharged(t *testing.T) {
+	pool := NewBudgetPool(map[string]contracts.Cost{"team-a": {Amount: 1, Currency: "USD"}})
+	enforcer := NewBudgetEnforcerWithPool(pool)
+	run := &contracts.Run{
+		ID:     "run-1",
+		Policy: contracts.RunPolicy{BudgetLimit: contracts.Cost{Amount: 100, Currency: "USD"}},
+	}
+
+	if err := enforcer.Allow(run, contracts.Cost{Amount: 5, Currency: "USD"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := enforcer.Record(run, contracts.Cost{Amount: 5, Currency: "USD"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := pool.Usage("team-a").Amount; got != 0 {
+		t.Errorf("expected pool usage 0, got %v", got)
+	}
+}
+
+func TestBudgetPool_RecordOverLimit(t *testing.T) {
+	pool := NewBudgetPool(map[string]contracts.Cost{"team-a": {Amount: 1, Currency: "USD"}})
+
+	err := pool.Record("team-a", contracts.Cost{Amount: 1.5, Currency: "USD"})
+	if !errors.Is(err, contracts.ErrBudgetPoolExhausted) {
+		t.Fatalf("expected ErrBudgetPoolExhausted, got %v", err)
+	}
+	// Incurred cost is still recorded
+	if got := pool.Usage("team-a").Amount; got != 1.5 {
+		t.Errorf("expected pool usage 1.5, got %v", got)
+	}
+}
+
+func TestBudgetPool_ReservationsAcrossRuns(t *testing.T) {
+	pool := NewBudgetPool(map[string]contracts.Cost{"team-a": {Amount: 10, Currency: "USD"}})
+	enforcer := NewBudgetEnforcerWithPool(pool)
+	newRun := func(id contracts.RunID) *contracts.Run {
+		return &contracts.Run{
+			ID: id,
+			Policy: contracts.RunPolicy{
+				BudgetLimit: contracts.Cost{Amount: 100, Currency: "USD"},
+				BudgetPool:  "team-a",
+			},
+		}
+	}
+	run1, run2 := newRun("run-1"), newRun("run-2")
+	usd := func(amount float64) contracts.Cost { return contracts.Cost{Amount: amount, Currency: "USD"} }
+
+	// Both runs pre-check before either records: the second must see the
+	// first one's reservation
+	if err := enforcer.Allow(run1, usd(6)); err != nil {
+		t.Fatalf("run-1 Allow: unexpected error: %v", err)
+	}
+	if err := enforcer.Allow(run2, usd(6)); !errors.Is(err, contracts.ErrBudgetPoolExhausted) {
+		t.Fatalf("run-2 Allow: expected ErrBudgetPoolExhausted, got %v", err)
+	}
+	if err := enforcer.Allow(run2, usd(4)); err != nil {
+		t.Fatalf("run-2 Allow within the rest: unexpected error: %v", err)
+	}
+
+	// A run's later estimate in the same batch replaces its reservation
+	if err := enforcer.Allow(run1, usd(6.5)); !errors.Is(err, contracts.ErrBudgetPoolExhausted) {
+		t.Fatalf("run-1 growing reservation: expected ErrBudgetPoolExhausted, got %v", err)
+	}
+
+	// Run 1 spends less than it reserved and releases the rest
+	if err := enforcer.Record(run1, usd(3)); err != nil {
+		t.Fatalf("run-1 Record: unexpected error: %v", err)
+	}
+	reserving := enforcer.(contracts.ReservingBudgetEnforcer)
+	if err := pool.Allow("team-a", usd(3.5)); !errors.Is(err, contracts.ErrBudgetPoolExhausted) {
+		t.Errorf("with run-1's remaining reservation: expected ErrBudgetPoolExhausted, got %v", err)
+	}
+	reserving.Release(run1)
+	if err := pool.Allow("team-a", usd(3)); err != nil {
+		t.Errorf("after release: unexpected error: %v", err)
+	}
+	if got := pool.Usage("team-a").Amount; got != 3 {
+		t.Errorf("expected pool usage 3, got %v", got)
+	}
+}
+
+func TestBudgetPool_PersistedUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "budget-pools.json")
+	limits := map[string]contracts.Cost{"team-a": {Amount: 10, Currency: "USD"}}
+
+	pool, err := NewBudgetPoolWithStore(limits, NewFilePoolUsageStore(path))
+	if err != nil {
+		t.Fatalf("NewBudgetPoolWithStore: %v", err)
+	}
+	if err := pool.Record("team-a", contracts.Cost{Amount: 7, Currency: "USD"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	// A new process starts from the stored usage
+	restarted, err := NewBudgetPoolWithStore(limits, NewFilePoolUsageStore(path))
+	if err != nil {
+		t.Fatalf("NewBudgetPoolWithStore after restart: %v", err)
+	}
+	if got := restarted.Usage("team-a"); got.Amount != 7 || got.Currency != "USD" {
+		t.Errorf("restored usage = %+v, want 7 USD", got)
+	}
+	if err := restarted.Allow("team-a", contracts.Cost{Amount: 4, Currency: "USD"}); !errors.Is(err, contracts.ErrBudgetPoolExhausted) {
+		t.Errorf("expected ErrBudgetPoolExhausted after restart, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewBudgetPoolWithStore(limits, NewFilePoolUsageStore(path)); err == nil {
+		t.Error("expected an error for a corrupt usage file")
+	}
+}
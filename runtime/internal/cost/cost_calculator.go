@@ -6,7 +6,8 @@ import (
 
 const defaultCurrency = contracts.Currency("USD")
 
-// costCalculator implements contracts.CostCalculator using ModelCatalog.
+// costCalculator implements contracts.CostCalculator and
+// contracts.SplitCostCalculator using ModelCatalog.
 type costCalculator struct {
 	catalog  contracts.ModelCatalog
 	currency contracts.Currency
@@ -20,6 +21,39 @@ func NewCostCalculator() contracts.CostCalculator {
 	}
 }
 
+// ModelPricing is a model's price in USD per 1M tokens.
+type ModelPricing struct {
+	InputPer1M  float64
+	OutputPer1M float64
+}
+
+// DefaultPricing returns the rates of DefaultModels keyed by model ID.
+func DefaultPricing() map[contracts.ModelID]ModelPricing {
+	pricing := make(map[contracts.ModelID]ModelPricing, len(DefaultModels))
+	for _, m := range DefaultModels {
+		pricing[m.ID] = ModelPricing{InputPer1M: m.InputCostPer1M, OutputPer1M: m.OutputCostPer1M}
+	}
+	return pricing
+}
+
+// NewCostCalculatorWithPricing creates a CostCalculator with custom rates (USD).
+// Models missing from pricing return ErrModelUnknown; roles map to models
+// as in DefaultRoleMappings.
+func NewCostCalculatorWithPricing(pricing map[contracts.ModelID]ModelPricing) contracts.CostCalculator {
+	models := make([]contracts.ModelInfo, 0, len(pricing))
+	for id, p := range pricing {
+		models = append(models, contracts.ModelInfo{
+			ID:              id,
+			InputCostPer1M:  p.InputPer1M,
+			OutputCostPer1M: p.OutputPer1M,
+		})
+	}
+	return &costCalculator{
+		catalog:  NewModelCatalogWithModels(models, DefaultRoleMappings),
+		currency: defaultCurrency,
+	}
+}
+
 // NewCostCalculatorWithCatalog creates a CostCalculator with custom catalog.
 func NewCostCalculatorWithCatalog(catalog contracts.ModelCatalog, currency contracts.Currency) contracts.CostCalculator {
 	if catalog == nil {
@@ -51,6 +85,22 @@ func (c *costCalculator) Estimate(tokens contracts.TokenCount, model contracts.M
 	}, nil
 }
 
+// EstimateSplit returns the estimated cost of in input and out output tokens,
+// each at the model's own rate.
+func (c *costCalculator) EstimateSplit(in, out contracts.TokenCount, model contracts.ModelID) (contracts.Cost, error) {
+	info, ok := c.catalog.Get(model)
+	if !ok {
+		return contracts.Cost{}, contracts.ErrModelUnknown
+	}
+
+	amount := (float64(in)*info.InputCostPer1M + float64(out)*info.OutputCostPer1M) / 1_000_000
+
+	return contracts.Cost{
+		Amount:   amount,
+		Currency: c.currency,
+	}, nil
+}
+
 // EstimateByRole estimates cost using the model assigned to a role.
 func (c *costCalculator) EstimateByRole(tokens contracts.TokenCount, role contracts.ModelRole) (contracts.Cost, error) {
 	info, ok := c.catalog.GetByRole(role)
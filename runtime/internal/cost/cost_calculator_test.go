@@ -2,6 +2,7 @@ package cost
 
 import (
 	"errors"
+	"math"
 	"testing"
 
 	"github.com/anthropics/claude-workflow/runtime/contracts"
@@ -29,6 +30,12 @@ func TestCostCalculator_Estimate(t *testing.T) {
 			model:    "claude-3-haiku-20240307",
 			wantCost: 0.75, // (0.25 + 1.25) / 2 = 0.75
 		},
+		{
+			name:     "haiku 3.5 1M tokens",
+			tokens:   1_000_000,
+			model:    "claude-3-5-haiku-20241022",
+			wantCost: 2.4, // (0.8 + 4) / 2 = 2.4
+		},
 		{
 			name:     "sonnet 4.5 1M tokens",
 			tokens:   1_000_000,
@@ -183,6 +190,60 @@ func TestCostCalculator_CustomCatalog(t *testing.T) {
 	}
 }
 
+func TestCostCalculator_WithPricing(t *testing.T) {
+	calc := NewCostCalculatorWithPricing(map[contracts.ModelID]ModelPricing{
+		"claude-3-haiku-20240307": {InputPer1M: 1.0, OutputPer1M: 3.0},
+		"custom-model":            {InputPer1M: 10.0, OutputPer1M: 20.0},
+	}).(*costCalculator)
+
+	tests := []struct {
+		model    contracts.ModelID
+		wantCost float64
+	}{
+		{"claude-3-haiku-20240307", 2.0}, // custom rate replaces the default (0.75)
+		{"custom-model", 15.0},
+	}
+	for _, tt := range tests {
+		got, err := calc.Estimate(1_000_000, tt.model)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.model, err)
+		}
+		if got.Amount != tt.wantCost || got.Currency != "USD" {
+			t.Errorf("%s: got %v %s, want %v USD", tt.model, got.Amount, got.Currency, tt.wantCost)
+		}
+	}
+
+	// Models without a rate stay unknown, even default ones
+	if _, err := calc.Estimate(1000, "claude-sonnet-4-20250514"); !errors.Is(err, contracts.ErrModelUnknown) {
+		t.Errorf("expected ErrModelUnknown for unpriced model, got %v", err)
+	}
+
+	// Roles resolve through the default mappings
+	got, err := calc.EstimateByRole(1_000_000, contracts.RoleFast)
+	if err != nil || got.Amount != 2.0 {
+		t.Errorf("EstimateByRole(fast) = %v, %v; want 2.0", got.Amount, err)
+	}
+	if _, err := calc.EstimateByRole(1000, contracts.RoleFlagship); !errors.Is(err, contracts.ErrModelUnknown) {
+		t.Errorf("expected ErrModelUnknown for role with unpriced model, got %v", err)
+	}
+}
+
+func TestCostCalculator_DefaultPricing(t *testing.T) {
+	defaults := NewCostCalculator()
+	priced := NewCostCalculatorWithPricing(DefaultPricing())
+
+	for _, m := range DefaultModels {
+		want, err := defaults.Estimate(123_456, m.ID)
+		if err != nil {
+			t.Fatalf("%s: %v", m.ID, err)
+		}
+		got, err := priced.Estimate(123_456, m.ID)
+		if err != nil || got != want {
+			t.Errorf("%s: got %v, %v; want %v", m.ID, got, err, want)
+		}
+	}
+}
+
 func TestCostCalculator_DefaultsOnNil(t *testing.T) {
 	calc := NewCostCalculatorWithCatalog(nil, "")
 
@@ -201,3 +262,27 @@ func TestCostCalculator_DefaultsOnNil(t *testing.T) {
 		t.Errorf("currency = %v, want USD", got.Currency)
 	}
 }
+
+func TestCostCalculator_EstimateSplit(t *testing.T) {
+	calc := NewCostCalculator().(contracts.SplitCostCalculator)
+
+	// Haiku: 1M input at 0.25 + 2M output at 1.25
+	got, err := calc.EstimateSplit(1_000_000, 2_000_000, "claude-3-haiku-20240307")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Amount != 2.75 || got.Currency != "USD" {
+		t.Errorf("EstimateSplit() = %v %s, want 2.75 USD", got.Amount, got.Currency)
+	}
+
+	// At the default output ratio a split estimate matches the average rate
+	avg, _ := NewCostCalculator().Estimate(1_000_000, "claude-sonnet-4-20250514")
+	split, _ := calc.EstimateSplit(1_000_000, 400_000, "claude-sonnet-4-20250514")
+	if math.Abs(split.Amount-avg.Amount) > 1e-9 {
+		t.Errorf("split estimate %v differs from average-rate estimate %v", split.Amount, avg.Amount)
+	}
+
+	if _, err := calc.EstimateSplit(1, 1, "unknown-model"); !errors.Is(err, contracts.ErrModelUnknown) {
+		t.Errorf("expected ErrModelUnknown, got %v", err)
+	}
+}
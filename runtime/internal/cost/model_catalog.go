@@ -52,6 +52,15 @@ var DefaultModels = []contracts.ModelInfo{
 	},
 
 	// Claude 3.5 models
+	{
+		ID:              "claude-3-5-sonnet-20241022",
+		Provider:        "anthropic",
+		MaxContext:      200000,
+		InputCostPer1M:  3.0,
+		OutputCostPer1M: 15.0,
+		DefaultRole:     contracts.RoleBalanced,
+		SupportsTools:   true,
+	},
 	{
 		ID:              "claude-3-5-sonnet-20240620",
 		Provider:        "anthropic",
@@ -61,6 +70,15 @@ var DefaultModels = []contracts.ModelInfo{
 		DefaultRole:     contracts.RoleBalanced,
 		SupportsTools:   true,
 	},
+	{
+		ID:              "claude-3-5-haiku-20241022",
+		Provider:        "anthropic",
+		MaxContext:      200000,
+		InputCostPer1M:  0.8,
+		OutputCostPer1M: 4.0,
+		DefaultRole:     contracts.RoleFast,
+		SupportsTools:   true,
+	},
 
 	// Claude 3 models (fast/cheap)
 	{
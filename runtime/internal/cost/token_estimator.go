@@ -1,30 +1,89 @@
 package cost
 
 import (
+	"math"
+
 	"github.com/anthropics/claude-workflow/runtime/contracts"
 )
 
 const defaultCharsPerToken = 4
 
-// tokenEstimator implements contracts.TokenEstimator using character-based heuristic.
+// DefaultOutputRatio is the default number of expected output tokens per
+// input token. Claude output tokens cost 5x input tokens, so at this ratio a
+// split estimate costs the same as pricing the input at the average rate.
+const DefaultOutputRatio = 0.4
+
+// TokenEstimatorOptions configures a TokenEstimator.
+type TokenEstimatorOptions struct {
+	// CharsPerToken converts characters to tokens. If zero, defaults to 4.
+	CharsPerToken int
+
+	// OutputRatio is the expected output tokens per input token.
+	// If zero, defaults to DefaultOutputRatio; negative disables output estimates.
+	OutputRatio float64
+
+	// RoleOutputRatios override OutputRatio for tasks whose metadata "role"
+	// matches, e.g. a higher ratio for roles that write long documents.
+	RoleOutputRatios map[string]float64
+}
+
+// tokenEstimator implements contracts.TokenEstimator and
+// contracts.SplitTokenEstimator using character-based heuristic.
 type tokenEstimator struct {
-	charsPerToken int
+	charsPerToken    int
+	outputRatio      float64
+	roleOutputRatios map[string]float64
 }
 
+var _ contracts.SplitTokenEstimator = (*tokenEstimator)(nil)
+
 // NewTokenEstimator creates a new TokenEstimator with default settings.
 func NewTokenEstimator() contracts.TokenEstimator {
-	return &tokenEstimator{charsPerToken: defaultCharsPerToken}
+	return NewTokenEstimatorWithOptions(TokenEstimatorOptions{})
 }
 
 // NewTokenEstimatorWithRatio creates a TokenEstimator with custom chars-per-token ratio.
 func NewTokenEstimatorWithRatio(charsPerToken int) contracts.TokenEstimator {
+	return NewTokenEstimatorWithOptions(TokenEstimatorOptions{CharsPerToken: charsPerToken})
+}
+
+// NewTokenEstimatorWithOptions creates a TokenEstimator with custom options.
+func NewTokenEstimatorWithOptions(opts TokenEstimatorOptions) contracts.TokenEstimator {
+	charsPerToken := opts.CharsPerToken
 	if charsPerToken <= 0 {
 		charsPerToken = defaultCharsPerToken
 	}
-	return &tokenEstimator{charsPerToken: charsPerToken}
+	ratio := opts.OutputRatio
+	if ratio == 0 {
+		ratio = DefaultOutputRatio
+	}
+	return &tokenEstimator{
+		charsPerToken:    charsPerToken,
+		outputRatio:      max(ratio, 0),
+		roleOutputRatios: opts.RoleOutputRatios,
+	}
+}
+
+// EstimateSplit returns the estimated input tokens (as Estimate) and the
+// expected output tokens: the input scaled by the task role's output ratio
+// (at least 1 when the ratio and input are non-zero).
+func (e *tokenEstimator) EstimateSplit(input *contracts.TaskInput, ctx *contracts.ContextBundle) (in, out contracts.TokenCount, err error) {
+	in, err = e.Estimate(input, ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	ratio := e.outputRatio
+	if r, ok := e.roleOutputRatios[input.Metadata["role"]]; ok && r >= 0 {
+		ratio = r
+	}
+	out = contracts.TokenCount(math.Round(float64(in) * ratio))
+	if out == 0 && in > 0 && ratio > 0 {
+		out = 1
+	}
+	return in, out, nil
 }
 
-// Estimate returns the estimated token count for a task.
+// Estimate returns the estimated input token count for a task.
 func (e *tokenEstimator) Estimate(input *contracts.TaskInput, ctx *contracts.ContextBundle) (contracts.TokenCount, error) {
 	if input == nil {
 		return 0, contracts.ErrInvalidInput
@@ -1,6 +1,8 @@
 package cost
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/anthropics/claude-workflow/runtime/contracts"
@@ -158,3 +160,49 @@ func TestTokenEstimator_InvalidRatio(t *testing.T) {
 		t.Errorf("Estimate() = %v, want 2 (default ratio should be 4)", got)
 	}
 }
+
+func TestTokenEstimator_EstimateSplit(t *testing.T) {
+	input := func(role string) *contracts.TaskInput {
+		return &contracts.TaskInput{
+			Prompt:   strings.Repeat("x", 400), // 100 input tokens
+			Metadata: map[string]string{"role": role},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		opts    TokenEstimatorOptions
+		role    string
+		wantIn  contracts.TokenCount
+		wantOut contracts.TokenCount
+	}{
+		// 4 role chars count towards the input: 404 chars → 101 tokens
+		{name: "default ratio", role: "docs", wantIn: 101, wantOut: 40},
+		{name: "custom ratio", opts: TokenEstimatorOptions{OutputRatio: 2}, role: "docs", wantIn: 101, wantOut: 202},
+		{name: "role hint", opts: TokenEstimatorOptions{RoleOutputRatios: map[string]float64{"docs": 3}}, role: "docs", wantIn: 101, wantOut: 303},
+		{name: "other role uses ratio", opts: TokenEstimatorOptions{RoleOutputRatios: map[string]float64{"docs": 3}}, role: "test", wantIn: 101, wantOut: 40},
+		{name: "negative ratio disables output", opts: TokenEstimatorOptions{OutputRatio: -1}, role: "docs", wantIn: 101, wantOut: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimator := NewTokenEstimatorWithOptions(tt.opts).(contracts.SplitTokenEstimator)
+			in, out, err := estimator.EstimateSplit(input(tt.role), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if in != tt.wantIn || out != tt.wantOut {
+				t.Errorf("EstimateSplit() = %d, %d; want %d, %d", in, out, tt.wantIn, tt.wantOut)
+			}
+		})
+	}
+
+	estimator := NewTokenEstimator().(contracts.SplitTokenEstimator)
+
+	// Tiny inputs still expect some output
+	if _, out, _ := estimator.EstimateSplit(&contracts.TaskInput{Prompt: "hi"}, nil); out != 1 {
+		t.Errorf("expected at least 1 output token, got %d", out)
+	}
+	if _, _, err := estimator.EstimateSplit(nil, nil); !errors.Is(err, contracts.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput for nil input, got %v", err)
+	}
+}
@@ -19,7 +19,7 @@ func NewUsageTracker() contracts.UsageTracker {
 }
 
 // Add adds usage tokens to the run's total.
-// Only updates Tokens - Cost is updated by BudgetEnforcer.Record() to avoid double-counting.
+// Only updates token counts - Cost is updated by BudgetEnforcer.Record() to avoid double-counting.
 // If run is nil, it gracefully returns without panicking.
 func (ut *usageTracker) Add(run *contracts.Run, usage contracts.Usage) {
 	if run == nil {
@@ -31,6 +31,8 @@ func (ut *usageTracker) Add(run *contracts.Run, usage contracts.Usage) {
 
 	// Only update Tokens - Cost is updated by BudgetEnforcer.Record()
 	run.Usage.Tokens += usage.Tokens
+	run.Usage.InputTokens += usage.InputTokens
+	run.Usage.OutputTokens += usage.OutputTokens
 }
 
 // Snapshot returns the current usage for the run.
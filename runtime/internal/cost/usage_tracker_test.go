@@ -209,3 +209,16 @@ func TestUsageTracker_DoesNotUpdateCost(t *testing.T) {
 		t.Errorf("run.Usage.Cost.Currency = %s, want USD (unchanged)", run.Usage.Cost.Currency)
 	}
 }
+
+func TestUsageTracker_Add_SplitTokens(t *testing.T) {
+	ut := NewUsageTracker()
+	run := &contracts.Run{ID: "run-1"}
+
+	ut.Add(run, contracts.Usage{Tokens: 150, InputTokens: 100, OutputTokens: 50})
+	ut.Add(run, contracts.Usage{Tokens: 30}) // executor without a split
+
+	want := contracts.Usage{Tokens: 180, InputTokens: 100, OutputTokens: 50}
+	if run.Usage != want {
+		t.Errorf("run.Usage = %+v, want %+v", run.Usage, want)
+	}
+}
@@ -0,0 +1,41 @@
+package orchestration
+
+import (
+	"sync"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+// BudgetOverride delivers a new budget limit to a running run.
+// The orchestrator applies it to run.Policy.BudgetLimit at the next batch
+// boundary, so tasks already past the budget pre-check are unaffected.
+//
+// Thread-safety: safe for concurrent use by API handlers and the orchestrator.
+type BudgetOverride struct {
+	mu    sync.Mutex
+	limit *contracts.Cost
+}
+
+// NewBudgetOverride creates a BudgetOverride with no pending limit.
+func NewBudgetOverride() *BudgetOverride {
+	return &BudgetOverride{}
+}
+
+// Set requests a new budget limit, replacing any limit not yet applied.
+func (b *BudgetOverride) Set(limit contracts.Cost) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limit = &limit
+}
+
+// take removes and returns the pending limit.
+func (b *BudgetOverride) take() (contracts.Cost, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limit == nil {
+		return contracts.Cost{}, false
+	}
+	limit := *b.limit
+	b.limit = nil
+	return limit, true
+}
@@ -0,0 +1,50 @@
+package orchestration
+
+import (
+	"context"
+	"time"
+)
+
+// Clock supplies the current time and timers to the orchestrator and the
+// parallel executor, so tests can control durations and timeouts.
+type Clock interface {
+	Now() time.Time
+	// After returns a channel that receives the current time once d has
+	// elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the Clock backed by package time. It is used when no
+// clock is set.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// clockOrSystem returns c, or SystemClock if c is nil.
+func clockOrSystem(c Clock) Clock {
+	if c == nil {
+		return SystemClock
+	}
+	return c
+}
+
+// withClockTimeout is context.WithTimeout on c. On a clock other than
+// SystemClock the returned context's Err is context.Canceled once d has
+// elapsed; its cause (context.Cause) is context.DeadlineExceeded either way.
+func withClockTimeout(ctx context.Context, c Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := c.(systemClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+	timeoutCtx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-c.After(d):
+			cancel(context.DeadlineExceeded)
+		case <-timeoutCtx.Done():
+		}
+	}()
+	return timeoutCtx, func() { cancel(context.Canceled) }
+}
@@ -0,0 +1,219 @@
+package orchestration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+	"github.com/anthropics/claude-workflow/runtime/internal/audit"
+)
+
+// fakeClock is a Clock that only moves when advanced.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []fakeTimer
+	changed chan struct{} // closed and replaced whenever a timer is added
+}
+
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, changed: make(chan struct{})}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), ch: ch})
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return ch
+}
+
+// Advance moves the clock forward by d, firing the timers that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.ch <- c.now
+	}
+	c.timers = pending
+}
+
+// waitForTimers blocks until n timers are pending.
+func (c *fakeClock) waitForTimers(t *testing.T, n int) {
+	t.Helper()
+	for {
+		c.mu.Lock()
+		pending, changed := len(c.timers), c.changed
+		c.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %d pending timers, have %d", n, pending)
+		}
+	}
+}
+
+var fakeEpoch = time.UnixMilli(1_700_000_000_000)
+
+// newClockRun returns a single-task run and a fake clock set to fakeEpoch.
+func newClockRun(t *testing.T, policy contracts.RunPolicy) (*contracts.Run, *fakeClock) {
+	t.Helper()
+	dag, err := NewDependencyResolver().BuildDAG([]contracts.Task{{ID: "A"}})
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	return createRun("run-clock", dag, createTasksFromDAG(dag, 40), policy), newFakeClock(fakeEpoch)
+}
+
+func TestIntegration_FakeClockDurations(t *testing.T) {
+	var buf bytes.Buffer
+	audit.SetOutput(&buf)
+	defer audit.SetOutput(nil)
+
+	run, clock := newClockRun(t, defaultPolicy())
+	execute := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		clock.Advance(1500 * time.Millisecond)
+		return &contracts.TaskResult{
+			Output: "ok",
+			Usage:  contracts.Usage{Tokens: 100, Cost: contracts.Cost{Amount: 0.01, Currency: "USD"}},
+		}, nil
+	}
+
+	orch := NewOrchestratorWithOptions(run.Policy, execute, FactoryOptions{Clock: clock})
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	assertRunCompleted(t, run)
+	if want := contracts.Timestamp(fakeEpoch.UnixMilli()); run.StartedAt != want {
+		t.Errorf("StartedAt = %d, want %d", run.StartedAt, want)
+	}
+	if got := run.FinishedAt - run.StartedAt; got != 1500 {
+		t.Errorf("FinishedAt - StartedAt = %d, want 1500", got)
+	}
+
+	durations := map[string]float64{}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		_, data, ok := strings.Cut(line, "[AUDIT] ")
+		if !ok {
+			continue
+		}
+		var event map[string]any
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		if d, ok := event["duration_ms"].(float64); ok {
+			durations[event["event"].(string)] = d
+		}
+	}
+	for _, event := range []string{"task_completed", "run_completed"} {
+		if durations[event] != 1500 {
+			t.Errorf("%s duration_ms = %v, want 1500", event, durations[event])
+		}
+	}
+}
+
+func TestIntegration_FakeClockTaskTimeout(t *testing.T) {
+	policy := defaultPolicy()
+	policy.TimeoutMs = 1000
+	run, clock := newClockRun(t, policy)
+	execute := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		orch := NewOrchestratorWithOptions(run.Policy, execute, FactoryOptions{Clock: clock})
+		done <- orch.Run(context.Background(), run)
+	}()
+
+	clock.waitForTimers(t, 1)
+	clock.Advance(999 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("run finished before the timeout: %v", err)
+	default:
+	}
+
+	clock.Advance(time.Millisecond)
+	err := <-done
+	if !errors.Is(err, contracts.ErrTaskTimeout) {
+		t.Fatalf("err = %v, want ErrTaskTimeout", err)
+	}
+	assertRunFailed(t, run)
+	if a := run.Tasks["A"]; a.State != contracts.TaskFailed || a.Error == nil || a.Error.Code != "task_timeout" {
+		t.Errorf("A = %v %+v, want failed with task_timeout", a.State, a.Error)
+	}
+	if got := run.FinishedAt - run.StartedAt; got != 1000 {
+		t.Errorf("FinishedAt - StartedAt = %d, want 1000", got)
+	}
+}
+
+func TestIntegration_FakeClockRetryDelay(t *testing.T) {
+	run, clock := newClockRun(t, defaultPolicy())
+	run.Tasks["A"].Retry = &contracts.RetryPolicy{MaxAttempts: 2, BackoffMs: 500}
+	var mu sync.Mutex
+	attempts := 0
+	execute := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if attempts++; attempts == 1 {
+			return nil, errors.New("transient")
+		}
+		return &contracts.TaskResult{
+			Output: "ok",
+			Usage:  contracts.Usage{Tokens: 100, Cost: contracts.Cost{Amount: 0.01, Currency: "USD"}},
+		}, nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		orch := NewOrchestratorWithOptions(run.Policy, execute, FactoryOptions{Clock: clock})
+		done <- orch.Run(context.Background(), run)
+	}()
+
+	clock.waitForTimers(t, 1)
+	clock.Advance(500 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	assertRunCompleted(t, run)
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+	if got := run.FinishedAt - run.StartedAt; got != 500 {
+		t.Errorf("FinishedAt - StartedAt = %d, want 500", got)
+	}
+}
@@ -2,6 +2,7 @@ package orchestration
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/anthropics/claude-workflow/runtime/contracts"
 )
@@ -24,7 +25,7 @@ func NewDependencyResolver() contracts.DependencyResolver {
 // BuildDAG constructs a DAG from a list of tasks.
 // Creates DAGNodes with Deps, Next, and Pending counts.
 // Returns a valid empty DAG for empty task lists.
-// Returns error if input is nil.
+// Returns error if input is nil or a task has an empty ID (ErrInvalidInput).
 func (dr *dependencyResolver) BuildDAG(tasks []contracts.Task) (*contracts.DAG, error) {
 	// Edge case: nil input
 	if tasks == nil {
@@ -47,6 +48,9 @@ func (dr *dependencyResolver) BuildDAG(tasks []contracts.Task) (*contracts.DAG,
 	// Create a quick lookup map for task IDs to validate dependencies exist
 	taskIDSet := make(map[contracts.TaskID]bool)
 	for i := range tasks {
+		if tasks[i].ID == "" {
+			return nil, fmt.Errorf("task at index %d has an empty ID: %w", i, contracts.ErrInvalidInput)
+		}
 		taskIDSet[tasks[i].ID] = true
 	}
 
@@ -137,6 +141,104 @@ func (dr *dependencyResolver) Validate(dag *contracts.DAG) error {
 	return nil
 }
 
+// Plan layers the DAG with Kahn's algorithm: the first batch holds the tasks
+// without dependencies, each later batch the tasks whose dependencies are all
+// in earlier ones. Batches are sorted by TaskID for determinism.
+// The counts start from each node's Deps, so a DAG whose Pending counts were
+// decremented by a run still yields its full plan; node.Pending is not touched.
+// Returns ErrDAGCycle if some tasks can never be layered.
+func (dr *dependencyResolver) Plan(dag *contracts.DAG) ([][]contracts.TaskID, error) {
+	if dag == nil {
+		return nil, contracts.ErrInvalidInput
+	}
+	if dag.Nodes == nil {
+		return nil, fmt.Errorf("DAG has nil Nodes: %w", contracts.ErrDAGInvalid)
+	}
+
+	pending := make(map[contracts.TaskID]int, len(dag.Nodes))
+	var batch []contracts.TaskID
+	for id, node := range dag.Nodes {
+		pending[id] = len(node.Deps)
+		if pending[id] == 0 {
+			batch = append(batch, id)
+		}
+	}
+
+	var batches [][]contracts.TaskID
+	planned := 0
+	for len(batch) > 0 {
+		slices.Sort(batch)
+		batches = append(batches, batch)
+		planned += len(batch)
+
+		var next []contracts.TaskID
+		for _, id := range batch {
+			for _, nextID := range dag.Nodes[id].Next {
+				if _, exists := pending[nextID]; !exists {
+					continue
+				}
+				pending[nextID]--
+				if pending[nextID] == 0 {
+					next = append(next, nextID)
+				}
+			}
+		}
+		batch = next
+	}
+
+	if planned != len(dag.Nodes) {
+		return nil, fmt.Errorf("%d of %d tasks cannot be planned: %w",
+			len(dag.Nodes)-planned, len(dag.Nodes), contracts.ErrDAGCycle)
+	}
+	return batches, nil
+}
+
+// CriticalPath returns the longest dependency chain of the DAG and its
+// length in tasks. Of several longest chains it returns the one that is
+// smallest by TaskID, comparing task by task from the root. Like Plan it
+// ignores Pending counts. Returns nil and 0 for an empty DAG or one Plan
+// rejects (nil or cyclic).
+func (dr *dependencyResolver) CriticalPath(dag *contracts.DAG) ([]contracts.TaskID, int) {
+	batches, err := dr.Plan(dag)
+	if err != nil || len(batches) == 0 {
+		return nil, 0
+	}
+
+	// height: tasks on the longest chain starting at the task. Later batches
+	// come first, so every dependent's height is known.
+	height := make(map[contracts.TaskID]int, len(dag.Nodes))
+	for i := len(batches) - 1; i >= 0; i-- {
+		for _, id := range batches[i] {
+			h := 0
+			for _, nextID := range dag.Nodes[id].Next {
+				h = max(h, height[nextID])
+			}
+			height[id] = h + 1
+		}
+	}
+
+	// Walk down from the lowest-ID tallest root
+	var path []contracts.TaskID
+	candidates := batches[0]
+	for {
+		var best contracts.TaskID
+		for _, id := range candidates {
+			if _, exists := height[id]; !exists {
+				continue
+			}
+			if best == "" || height[id] > height[best] || (height[id] == height[best] && id < best) {
+				best = id
+			}
+		}
+		if best == "" {
+			break
+		}
+		path = append(path, best)
+		candidates = dag.Nodes[best].Next
+	}
+	return path, len(path)
+}
+
 // hasCycle performs DFS to detect cycles.
 // Returns true if a cycle is found starting from the given node.
 // Uses color marking: white=0, gray=1, black=2.
@@ -2,6 +2,7 @@ package orchestration
 
 import (
 	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/anthropics/claude-workflow/runtime/contracts"
@@ -259,6 +260,36 @@ func TestBuildDAG_MissingDependency(t *testing.T) {
 	}
 }
 
+func TestBuildDAG_EmptyTaskID(t *testing.T) {
+	resolver := NewDependencyResolver()
+
+	tests := []struct {
+		name  string
+		tasks []contracts.Task
+	}{
+		{name: "single", tasks: []contracts.Task{{ID: ""}}},
+		{name: "among others", tasks: []contracts.Task{
+			{ID: "task1"},
+			{ID: "", Deps: []contracts.TaskID{"task1"}},
+		}},
+		{name: "depended on", tasks: []contracts.Task{
+			{ID: ""},
+			{ID: "task2", Deps: []contracts.TaskID{""}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dag, err := resolver.BuildDAG(tt.tasks)
+			if !errors.Is(err, contracts.ErrInvalidInput) {
+				t.Fatalf("expected ErrInvalidInput, got %v", err)
+			}
+			if dag != nil {
+				t.Fatal("expected nil DAG when error occurs")
+			}
+		})
+	}
+}
+
 // TestBuildDAG_SelfDependency tests self-dependency is allowed in BuildDAG (caught in Validate).
 func TestBuildDAG_SelfDependency(t *testing.T) {
 	resolver := NewDependencyResolver()
@@ -727,3 +758,191 @@ func TestBuildDAG_MultipleIndependentTasks(t *testing.T) {
 		}
 	}
 }
+
+// TestPlan_Layers tests batch layering of linear, diamond and fan-in DAGs
+func TestPlan_Layers(t *testing.T) {
+	resolver := NewDependencyResolver()
+
+	tests := []struct {
+		name  string
+		tasks []contracts.Task
+		want  [][]contracts.TaskID
+	}{
+		{
+			name: "linear",
+			tasks: []contracts.Task{
+				{ID: "task3", Deps: []contracts.TaskID{"task2"}},
+				{ID: "task1"},
+				{ID: "task2", Deps: []contracts.TaskID{"task1"}},
+			},
+			want: [][]contracts.TaskID{{"task1"}, {"task2"}, {"task3"}},
+		},
+		{
+			name: "diamond",
+			tasks: []contracts.Task{
+				{ID: "A"},
+				{ID: "C", Deps: []contracts.TaskID{"A"}},
+				{ID: "B", Deps: []contracts.TaskID{"A"}},
+				{ID: "D", Deps: []contracts.TaskID{"B", "C"}},
+			},
+			want: [][]contracts.TaskID{{"A"}, {"B", "C"}, {"D"}},
+		},
+		{
+			name: "fan-in",
+			tasks: []contracts.Task{
+				{ID: "B"},
+				{ID: "A"},
+				{ID: "E"},
+				{ID: "C", Deps: []contracts.TaskID{"A", "B"}},
+				{ID: "D", Deps: []contracts.TaskID{"C", "E"}},
+			},
+			want: [][]contracts.TaskID{{"A", "B", "E"}, {"C"}, {"D"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dag, err := resolver.BuildDAG(tt.tasks)
+			if err != nil {
+				t.Fatalf("BuildDAG failed: %v", err)
+			}
+			got, err := resolver.Plan(dag)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestPlan_IgnoresRunProgress tests that Pending counts changed by a run do not alter the plan
+func TestPlan_IgnoresRunProgress(t *testing.T) {
+	resolver := NewDependencyResolver()
+
+	dag, err := resolver.BuildDAG([]contracts.Task{
+		{ID: "task1"},
+		{ID: "task2", Deps: []contracts.TaskID{"task1"}},
+	})
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	dag.Nodes["task2"].Pending = 0 // task1 completed
+
+	got, err := resolver.Plan(dag)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := [][]contracts.TaskID{{"task1"}, {"task2"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if dag.Nodes["task2"].Pending != 0 {
+		t.Fatalf("expected Pending to be left unchanged, got %d", dag.Nodes["task2"].Pending)
+	}
+}
+
+// TestPlan_Cycle tests that a cyclic DAG cannot be planned
+func TestPlan_Cycle(t *testing.T) {
+	resolver := NewDependencyResolver()
+
+	dag, err := resolver.BuildDAG([]contracts.Task{
+		{ID: "task1"},
+		{ID: "task2", Deps: []contracts.TaskID{"task1", "task3"}},
+		{ID: "task3", Deps: []contracts.TaskID{"task2"}},
+	})
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+
+	if _, err := resolver.Plan(dag); !errors.Is(err, contracts.ErrDAGCycle) {
+		t.Fatalf("expected ErrDAGCycle, got %v", err)
+	}
+	if _, err := resolver.Plan(nil); !errors.Is(err, contracts.ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for nil DAG, got %v", err)
+	}
+}
+
+// TestCriticalPath tests the longest chain and its tie-breaking by TaskID
+func TestCriticalPath(t *testing.T) {
+	resolver := NewDependencyResolver()
+
+	tests := []struct {
+		name     string
+		tasks    []contracts.Task
+		wantPath []contracts.TaskID
+	}{
+		{
+			name: "diamond",
+			tasks: []contracts.Task{
+				{ID: "A"},
+				{ID: "C", Deps: []contracts.TaskID{"A"}},
+				{ID: "B", Deps: []contracts.TaskID{"A"}},
+				{ID: "D", Deps: []contracts.TaskID{"B", "C"}},
+			},
+			wantPath: []contracts.TaskID{"A", "B", "D"},
+		},
+		{
+			name: "diamond with a longer branch",
+			tasks: []contracts.Task{
+				{ID: "A"},
+				{ID: "B", Deps: []contracts.TaskID{"A"}},
+				{ID: "C", Deps: []contracts.TaskID{"A"}},
+				{ID: "C2", Deps: []contracts.TaskID{"C"}},
+				{ID: "D", Deps: []contracts.TaskID{"B", "C2"}},
+			},
+			wantPath: []contracts.TaskID{"A", "C", "C2", "D"},
+		},
+		{
+			name: "multi-root",
+			tasks: []contracts.Task{
+				{ID: "X"},
+				{ID: "Y", Deps: []contracts.TaskID{"X"}},
+				{ID: "Z", Deps: []contracts.TaskID{"Y"}},
+				{ID: "A"},
+				{ID: "B", Deps: []contracts.TaskID{"A"}},
+				{ID: "M"},
+				{ID: "N", Deps: []contracts.TaskID{"M"}},
+				{ID: "O", Deps: []contracts.TaskID{"N"}},
+			},
+			wantPath: []contracts.TaskID{"M", "N", "O"},
+		},
+		{
+			name:     "independent tasks",
+			tasks:    []contracts.Task{{ID: "b"}, {ID: "a"}},
+			wantPath: []contracts.TaskID{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dag, err := resolver.BuildDAG(tt.tasks)
+			if err != nil {
+				t.Fatalf("BuildDAG failed: %v", err)
+			}
+			path, length := resolver.CriticalPath(dag)
+			if !reflect.DeepEqual(path, tt.wantPath) || length != len(tt.wantPath) {
+				t.Fatalf("expected %v (%d), got %v (%d)", tt.wantPath, len(tt.wantPath), path, length)
+			}
+		})
+	}
+}
+
+// TestCriticalPath_Invalid tests that empty, nil and cyclic DAGs have no critical path
+func TestCriticalPath_Invalid(t *testing.T) {
+	resolver := NewDependencyResolver()
+
+	empty, _ := resolver.BuildDAG([]contracts.Task{})
+	cyclic, err := resolver.BuildDAG([]contracts.Task{
+		{ID: "task1", Deps: []contracts.TaskID{"task2"}},
+		{ID: "task2", Deps: []contracts.TaskID{"task1"}},
+	})
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	for name, dag := range map[string]*contracts.DAG{"empty": empty, "nil": nil, "cyclic": cyclic} {
+		if path, length := resolver.CriticalPath(dag); path != nil || length != 0 {
+			t.Errorf("%s: expected no critical path, got %v (%d)", name, path, length)
+		}
+	}
+}
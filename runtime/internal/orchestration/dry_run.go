@@ -0,0 +1,99 @@
+package orchestration
+
+import (
+	"fmt"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+	"github.com/anthropics/claude-workflow/runtime/internal/audit"
+)
+
+// dryRun plans a run without executing it. Batches are formed as in Run:
+// each holds every task whose dependencies are done, in dispatch order. Each task's context is
+// built and its cost estimated as in the budget pre-check, then the task is
+// skipped so its dependents become ready with an empty input from it.
+// The run completes with run.Plan set. A task that cannot be estimated fails
+// the run, as it would in a real run; budgets are not checked.
+func (o *orchestrator) dryRun(run *contracts.Run) error {
+	plan := &contracts.DryRunPlan{}
+	for {
+		ready, err := o.scheduler.NextReady(run)
+		if err != nil {
+			run.State = contracts.RunFailed
+			o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "scheduler_error", "dry_run": true, "error_msg": err.Error()})
+			return err
+		}
+		if len(ready) == 0 {
+			break
+		}
+		if run.Policy.SchedulingStrategy == contracts.SchedulingCost {
+			ready = o.orderByCost(run, ready)
+		}
+
+		for _, tid := range ready {
+			task, exists := run.Tasks[tid]
+			if !exists {
+				return o.failDryRun(run, deniedResult{
+					taskID:    tid,
+					errorCode: "task_not_found",
+					errorMsg:  fmt.Sprintf("task %s not found in run", tid),
+					err:       contracts.ErrTaskNotFound,
+				})
+			}
+			tokens, cost, dr := o.estimateCost(run, tid, task)
+			if dr != nil {
+				return o.failDryRun(run, *dr)
+			}
+			total, err := plan.ProjectedUsage.Cost.Add(cost)
+			if err != nil {
+				return o.failDryRun(run, deniedResult{
+					taskID:    tid,
+					errorCode: "currency_mismatch",
+					errorMsg:  fmt.Sprintf("projected cost: %v", err),
+					err:       err,
+				})
+			}
+			task.EstimatedUse = contracts.Usage{Tokens: tokens, Cost: cost}
+			plan.ProjectedUsage.Tokens += tokens
+			plan.ProjectedUsage.Cost = total
+		}
+
+		for _, tid := range ready {
+			run.Tasks[tid].State = contracts.TaskSkipped
+			if err := o.routeSkipped(run, tid); err != nil {
+				run.State = contracts.RunFailed
+				o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "routing_failed", "dry_run": true, "error_msg": err.Error()})
+				return err
+			}
+		}
+		plan.Batches = append(plan.Batches, ready)
+	}
+
+	if !o.allTerminal(run) {
+		run.State = contracts.RunFailed
+		o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "deadlock", "dry_run": true})
+		return contracts.ErrDeadlock
+	}
+
+	run.Plan = plan
+	run.State = contracts.RunCompleted
+	o.logRunEnd(run, "run_completed", audit.Fields{
+		"state":              "completed",
+		"dry_run":            true,
+		"batches":            len(plan.Batches),
+		"projected_tokens":   plan.ProjectedUsage.Tokens,
+		"projected_cost":     plan.ProjectedUsage.Cost.Amount,
+		"projected_currency": plan.ProjectedUsage.Cost.Currency,
+	})
+	return nil
+}
+
+// failDryRun fails the task that could not be planned and the run.
+func (o *orchestrator) failDryRun(run *contracts.Run, dr deniedResult) error {
+	if task, exists := run.Tasks[dr.taskID]; exists {
+		task.State = contracts.TaskFailed
+		task.Error = &contracts.TaskError{Code: dr.errorCode, Message: dr.errorMsg}
+	}
+	run.State = contracts.RunFailed
+	o.logRunEnd(run, "run_failed", audit.Fields{"error_code": dr.errorCode, "task_id": dr.taskID, "dry_run": true})
+	return fmt.Errorf("task %s: %s: %w", dr.taskID, dr.errorMsg, dr.err)
+}
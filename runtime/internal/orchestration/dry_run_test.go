@@ -0,0 +1,90 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+func newDryRun(t *testing.T) *contracts.Run {
+	t.Helper()
+	dag, err := buildDiamondDAG()
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	policy := defaultPolicy()
+	policy.DryRun = true
+	return createRun("run-dry", dag, createTasksFromDAG(dag, 400), policy)
+}
+
+func TestIntegration_DryRun(t *testing.T) {
+	run := newDryRun(t)
+	stub := newStubExecutor()
+
+	orch := NewOrchestrator(createRealDeps(run.Policy, stub.Execute))
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	assertRunCompleted(t, run)
+
+	if executed := stub.ExecutedTasks(); len(executed) != 0 {
+		t.Fatalf("executed = %v, want none", executed)
+	}
+	if run.Plan == nil {
+		t.Fatal("expected run.Plan to be set")
+	}
+	want := [][]contracts.TaskID{{"A"}, {"B", "C"}, {"D"}}
+	if !reflect.DeepEqual(run.Plan.Batches, want) {
+		t.Errorf("batches = %v, want %v", run.Plan.Batches, want)
+	}
+
+	var tokens contracts.TokenCount
+	var amount float64
+	for id, task := range run.Tasks {
+		if task.State != contracts.TaskSkipped {
+			t.Errorf("task %s state = %v, want skipped", id, task.State)
+		}
+		if task.EstimatedUse.Tokens <= 0 || task.EstimatedUse.Cost.Amount <= 0 {
+			t.Errorf("task %s has no estimate: %+v", id, task.EstimatedUse)
+		}
+		tokens += task.EstimatedUse.Tokens
+		amount += task.EstimatedUse.Cost.Amount
+	}
+	projected := run.Plan.ProjectedUsage
+	if projected.Tokens != tokens {
+		t.Errorf("projected tokens = %d, want %d", projected.Tokens, tokens)
+	}
+	if diff := projected.Cost.Amount - amount; diff > 1e-12 || diff < -1e-12 {
+		t.Errorf("projected cost = %v, want %v", projected.Cost.Amount, amount)
+	}
+	if projected.Cost.Currency != "USD" {
+		t.Errorf("projected currency = %q, want USD", projected.Cost.Currency)
+	}
+
+	// Nothing was charged
+	if run.Usage.Tokens != 0 || run.Usage.Cost.Amount != 0 {
+		t.Errorf("run usage = %+v, want zero", run.Usage)
+	}
+}
+
+func TestIntegration_DryRunUnknownModel(t *testing.T) {
+	run := newDryRun(t)
+	run.Tasks["C"].Model = "unknown-model"
+	stub := newStubExecutor()
+
+	orch := NewOrchestrator(createRealDeps(run.Policy, stub.Execute))
+	err := orch.Run(context.Background(), run)
+	if !errors.Is(err, contracts.ErrModelUnknown) {
+		t.Fatalf("err = %v, want ErrModelUnknown", err)
+	}
+	assertRunFailed(t, run)
+	if c := run.Tasks["C"]; c.State != contracts.TaskFailed || c.Error.Code != "model_unknown" {
+		t.Errorf("C = %v %+v, want failed with model_unknown", c.State, c.Error)
+	}
+	if run.Plan != nil {
+		t.Error("expected no plan for a failed dry run")
+	}
+}
@@ -0,0 +1,151 @@
+package orchestration
+
+import (
+	"hash/maphash"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+// EstimateCache caches pre-check cost estimates per task across loop iterations.
+// An entry is reused only while the task's estimate key is unchanged; the key
+// covers everything the estimate depends on (model, inputs, dependency outputs,
+// run memory, context policy), so routing new inputs invalidates it.
+//
+// Thread-safety: Safe for concurrent use; may be shared across runs.
+type EstimateCache struct {
+	mu      sync.Mutex
+	seed    maphash.Seed
+	entries map[estimateCacheKey]cachedEstimate
+	hits    int64
+	misses  int64
+}
+
+type estimateCacheKey struct {
+	runID  contracts.RunID
+	taskID contracts.TaskID
+}
+
+type cachedEstimate struct {
+	key      uint64 // Key at the time of estimation
+	estimate Estimate
+}
+
+// Estimate is a cached pre-check estimate.
+type Estimate struct {
+	Tokens contracts.TokenCount
+	Cost   contracts.Cost
+}
+
+// NewEstimateCache creates an empty EstimateCache.
+func NewEstimateCache() *EstimateCache {
+	return &EstimateCache{
+		seed:    maphash.MakeSeed(),
+		entries: make(map[estimateCacheKey]cachedEstimate),
+	}
+}
+
+// Get returns the cached estimate for a task if it was estimated under the same key.
+func (c *EstimateCache) Get(runID contracts.RunID, taskID contracts.TaskID, key uint64) (Estimate, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[estimateCacheKey{runID, taskID}]
+	if !ok || entry.key != key {
+		c.misses++
+		return Estimate{}, false
+	}
+	c.hits++
+	return entry.estimate, true
+}
+
+// Put stores the estimate for a task under key, replacing any stale entry.
+func (c *EstimateCache) Put(runID contracts.RunID, taskID contracts.TaskID, key uint64, estimate Estimate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[estimateCacheKey{runID, taskID}] = cachedEstimate{key: key, estimate: estimate}
+}
+
+// Forget drops all entries for a run. Call when a run finishes if the cache is shared.
+func (c *EstimateCache) Forget(runID contracts.RunID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.entries {
+		if k.runID == runID {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// Stats returns the number of cache hits and misses so far.
+func (c *EstimateCache) Stats() (hits, misses int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Key hashes the inputs of a task's cost estimate.
+// Must cover everything read by ContextBuilder, ContextCompactor and TokenEstimator.
+func (c *EstimateCache) Key(run *contracts.Run, task *contracts.Task) uint64 {
+	var h maphash.Hash
+	h.SetSeed(c.seed)
+	writeString(&h, string(task.Model))
+
+	if task.Inputs != nil {
+		writeString(&h, task.Inputs.Prompt)
+		writeMap(&h, task.Inputs.Inputs)
+		writeMap(&h, task.Inputs.Metadata)
+	}
+
+	// Completed dependency outputs become context messages
+	for _, depID := range task.Deps {
+		dep, ok := run.Tasks[depID]
+		if !ok || dep.State != contracts.TaskCompleted || dep.Outputs == nil {
+			writeString(&h, "")
+			continue
+		}
+		writeString(&h, dep.Outputs.Output)
+	}
+
+	writeMap(&h, run.Memory)
+
+	cp := run.Policy.ContextPolicy
+	writeString(&h, cp.Strategy)
+	writeInt(&h, int64(cp.MaxTokens))
+	writeInt(&h, int64(cp.KeepLastN))
+	writeInt(&h, int64(cp.MaxRoutedValueBytes))
+	writeInt(&h, int64(math.Float64bits(cp.ForceCompactRatio)))
+
+	return h.Sum64()
+}
+
+// writeString writes a length-prefixed string so adjacent fields can't collide.
+func writeString(h *maphash.Hash, s string) {
+	writeInt(h, int64(len(s)))
+	h.WriteString(s)
+}
+
+// writeInt writes v as 8 little-endian bytes.
+func writeInt(h *maphash.Hash, v int64) {
+	var buf [8]byte
+	for i := range buf {
+		buf[i] = byte(v >> (8 * i))
+	}
+	h.Write(buf[:])
+}
+
+// writeMap writes map entries in sorted key order.
+func writeMap(h *maphash.Hash, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	writeInt(h, int64(len(keys)))
+	for _, k := range keys {
+		writeString(h, k)
+		writeString(h, m[k])
+	}
+}
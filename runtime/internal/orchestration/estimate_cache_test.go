@@ -0,0 +1,144 @@
+package orchestration
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+// newEstimateRun creates a fan-in run (A, B -> C) with A and B completed,
+// so C's estimate depends on routed inputs and dependency outputs.
+func newEstimateRun(t testing.TB) *contracts.Run {
+	t.Helper()
+	dag, err := buildFanInDAG()
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	tasks := createTasksFromDAG(dag, 400)
+	for _, id := range []contracts.TaskID{"A", "B"} {
+		tasks[id].State = contracts.TaskCompleted
+		tasks[id].Outputs = &contracts.TaskResult{Output: strings.Repeat(string(id), 2000)}
+	}
+	run := createRun("run-estimate", dag, tasks, defaultPolicy())
+	run.State = contracts.RunRunning
+	return run
+}
+
+func TestEstimateCache_MatchesFresh(t *testing.T) {
+	run := newEstimateRun(t)
+	cache := NewEstimateCache()
+
+	deps := createRealDeps(run.Policy, nil)
+	fresh := NewOrchestrator(deps).(*orchestrator)
+	deps.EstimateCache = cache
+	cached := NewOrchestrator(deps).(*orchestrator)
+
+	assertSameEstimate := func(t *testing.T) {
+		t.Helper()
+		wantTokens, wantCost, dr := fresh.estimateCost(run, "C", run.Tasks["C"])
+		if dr != nil {
+			t.Fatalf("fresh estimate denied: %s", dr.errorMsg)
+		}
+		gotTokens, gotCost, dr := cached.estimateCost(run, "C", run.Tasks["C"])
+		if dr != nil {
+			t.Fatalf("cached estimate denied: %s", dr.errorMsg)
+		}
+		if gotTokens != wantTokens || gotCost != wantCost {
+			t.Errorf("cached estimate (%d, %+v) differs from fresh (%d, %+v)",
+				gotTokens, gotCost, wantTokens, wantCost)
+		}
+	}
+
+	// First call misses, second hits
+	assertSameEstimate(t)
+	assertSameEstimate(t)
+	if hits, misses := cache.Stats(); hits != 1 || misses != 1 {
+		t.Fatalf("expected 1 hit and 1 miss, got %d hits and %d misses", hits, misses)
+	}
+
+	// Routing new inputs invalidates the entry
+	run.Tasks["C"].Inputs.Inputs = map[string]string{"A": strings.Repeat("y", 4000)}
+	assertSameEstimate(t)
+	if _, misses := cache.Stats(); misses != 2 {
+		t.Errorf("expected routed inputs to invalidate the entry, got %d misses", misses)
+	}
+
+	// A changed dependency output invalidates the entry
+	run.Tasks["A"].Outputs.Output = "short"
+	assertSameEstimate(t)
+	if _, misses := cache.Stats(); misses != 3 {
+		t.Errorf("expected dependency output to invalidate the entry, got %d misses", misses)
+	}
+
+	// Run memory is part of the context
+	run.Memory["notes"] = strings.Repeat("z", 4000)
+	assertSameEstimate(t)
+	if _, misses := cache.Stats(); misses != 4 {
+		t.Errorf("expected run memory to invalidate the entry, got %d misses", misses)
+	}
+}
+
+func TestEstimateCache_PreCheckUsesCache(t *testing.T) {
+	run := newEstimateRun(t)
+	cache := NewEstimateCache()
+	deps := createRealDeps(run.Policy, nil)
+	deps.EstimateCache = cache
+	orch := NewOrchestrator(deps).(*orchestrator)
+
+	for i := 0; i < 3; i++ {
+		allowed, denied := orch.preCheckBudget(run, []contracts.TaskID{"C"})
+		if len(denied) != 0 || len(allowed) != 1 {
+			t.Fatalf("iteration %d: expected C allowed, got allowed=%v denied=%d", i, allowed, len(denied))
+		}
+	}
+	if hits, misses := cache.Stats(); hits != 2 || misses != 1 {
+		t.Errorf("expected 2 hits and 1 miss, got %d hits and %d misses", hits, misses)
+	}
+
+	cache.Forget(run.ID)
+	orch.preCheckBudget(run, []contracts.TaskID{"C"})
+	if _, misses := cache.Stats(); misses != 2 {
+		t.Errorf("expected a miss after Forget, got %d misses", misses)
+	}
+}
+
+func TestEstimateCache_FailuresNotCached(t *testing.T) {
+	run := newEstimateRun(t)
+	run.Tasks["C"].Model = "unknown-model"
+	cache := NewEstimateCache()
+	deps := createRealDeps(run.Policy, nil)
+	deps.EstimateCache = cache
+	orch := NewOrchestrator(deps).(*orchestrator)
+
+	for i := 0; i < 2; i++ {
+		if _, _, dr := orch.estimateCost(run, "C", run.Tasks["C"]); dr == nil || dr.errorCode != "model_unknown" {
+			t.Fatalf("iteration %d: expected model_unknown denial, got %+v", i, dr)
+		}
+	}
+	if hits, _ := cache.Stats(); hits != 0 {
+		t.Errorf("expected failed estimates not to be cached, got %d hits", hits)
+	}
+}
+
+// BenchmarkPreCheckBudget measures repeated pre-checks of an unchanged ready set.
+func BenchmarkPreCheckBudget(b *testing.B) {
+	for _, withCache := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cache=%v", withCache), func(b *testing.B) {
+			run := newEstimateRun(b)
+			run.Policy.ContextPolicy = contracts.ContextPolicy{MaxTokens: 200, Strategy: "truncate"}
+			deps := createRealDeps(run.Policy, nil)
+			if withCache {
+				deps.EstimateCache = NewEstimateCache()
+			}
+			orch := NewOrchestrator(deps).(*orchestrator)
+			ready := []contracts.TaskID{"C"}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				orch.preCheckBudget(run, ready)
+			}
+		})
+	}
+}
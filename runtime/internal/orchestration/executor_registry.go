@@ -0,0 +1,79 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+// Task metadata keys used to select an executor.
+const (
+	// MetadataExecutor names the executor explicitly. Unknown names fail the task.
+	MetadataExecutor = "executor"
+	// MetadataRole is the workflow role; used when no executor is named.
+	MetadataRole = "role"
+)
+
+// ExecutorRegistry routes tasks to TaskExecutorFuncs by task metadata.
+// Lookup order: metadata["executor"], then metadata["role"], then the fallback.
+//
+// Thread-safety: Register and Execute may be called concurrently.
+type ExecutorRegistry struct {
+	mu        sync.RWMutex
+	executors map[string]TaskExecutorFunc
+	fallback  TaskExecutorFunc
+}
+
+// NewExecutorRegistry creates a registry that uses fallback for tasks
+// without a matching executor. If fallback is nil, uses a no-op executor.
+func NewExecutorRegistry(fallback TaskExecutorFunc) *ExecutorRegistry {
+	if fallback == nil {
+		fallback = defaultExecutor
+	}
+	return &ExecutorRegistry{
+		executors: make(map[string]TaskExecutorFunc),
+		fallback:  fallback,
+	}
+}
+
+// Register adds or replaces the executor for a name (executor or role key).
+func (r *ExecutorRegistry) Register(name string, executor TaskExecutorFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.executors[name] = executor
+}
+
+// Execute dispatches task to its executor. Satisfies TaskExecutorFunc,
+// so it can be passed to NewParallelExecutor.
+func (r *ExecutorRegistry) Execute(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+	executor, err := r.resolve(task)
+	if err != nil {
+		return nil, err
+	}
+	return executor(ctx, task)
+}
+
+// resolve selects the executor for a task.
+func (r *ExecutorRegistry) resolve(task *contracts.Task) (TaskExecutorFunc, error) {
+	var metadata map[string]string
+	if task.Inputs != nil {
+		metadata = task.Inputs.Metadata
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if name := metadata[MetadataExecutor]; name != "" {
+		executor, ok := r.executors[name]
+		if !ok {
+			return nil, fmt.Errorf("task %s: unknown executor %q", task.ID, name)
+		}
+		return executor, nil
+	}
+	if executor, ok := r.executors[metadata[MetadataRole]]; ok {
+		return executor, nil
+	}
+	return r.fallback, nil
+}
@@ -0,0 +1,59 @@
+package orchestration
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+func TestExecutorRegistry_RoutesByMetadata(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string][]contracts.TaskID)
+	record := func(name string) TaskExecutorFunc {
+		return func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+			mu.Lock()
+			calls[name] = append(calls[name], task.ID)
+			mu.Unlock()
+			return &contracts.TaskResult{Output: name}, nil
+		}
+	}
+
+	reg := NewExecutorRegistry(record("fallback"))
+	reg.Register("tool", record("tool"))
+	reg.Register("spec-analyst", record("model"))
+
+	tasks := []*contracts.Task{
+		{ID: "A", Inputs: &contracts.TaskInput{Metadata: map[string]string{"executor": "tool"}}},
+		{ID: "B", Inputs: &contracts.TaskInput{Metadata: map[string]string{"role": "spec-analyst"}}},
+		{ID: "C", Inputs: &contracts.TaskInput{Metadata: map[string]string{"role": "spec-developer"}}},
+		{ID: "D"},
+	}
+	want := map[contracts.TaskID]string{"A": "tool", "B": "model", "C": "fallback", "D": "fallback"}
+
+	for _, task := range tasks {
+		result, err := reg.Execute(context.Background(), task)
+		if err != nil {
+			t.Fatalf("task %s: unexpected error: %v", task.ID, err)
+		}
+		if result.Output != want[task.ID] {
+			t.Errorf("task %s: expected executor %s, got %s", task.ID, want[task.ID], result.Output)
+		}
+	}
+
+	if len(calls["tool"]) != 1 || len(calls["model"]) != 1 || len(calls["fallback"]) != 2 {
+		t.Errorf("unexpected calls: %v", calls)
+	}
+}
+
+func TestExecutorRegistry_UnknownExecutor(t *testing.T) {
+	reg := NewExecutorRegistry(nil)
+	task := &contracts.Task{ID: "A", Inputs: &contracts.TaskInput{Metadata: map[string]string{"executor": "missing"}}}
+
+	_, err := reg.Execute(context.Background(), task)
+	if err == nil || !strings.Contains(err.Error(), `unknown executor "missing"`) {
+		t.Errorf("expected unknown executor error, got %v", err)
+	}
+}
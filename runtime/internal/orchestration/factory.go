@@ -15,6 +15,26 @@ type FactoryOptions struct {
 	// Currency overrides the default currency (USD) for cost calculation.
 	// If empty, defaults to USD.
 	Currency contracts.Currency
+
+	// BudgetPool is charged for runs with Policy.BudgetPool set.
+	// Share one pool across orchestrators to enforce a budget across runs.
+	BudgetPool contracts.BudgetPool
+
+	// EstimateCache reuses pre-check estimates for unchanged tasks.
+	// If nil, every pre-check re-estimates.
+	EstimateCache *EstimateCache
+
+	// BudgetAwareSelection defers ready tasks the remaining budget can't cover
+	// to a later batch instead of estimating them all up front.
+	BudgetAwareSelection bool
+
+	// MaxBatches caps the number of batches per run.
+	// If zero, the limit scales with the run's task count.
+	MaxBatches int
+
+	// Clock times the run, its tasks and their timeouts.
+	// If nil, uses SystemClock.
+	Clock Clock
 }
 
 // NewOrchestratorWithDefaults creates an orchestrator with all default components.
@@ -56,18 +76,29 @@ func NewOrchestratorWithOptions(
 		costCalc = cost.NewCostCalculator()
 	}
 
+	catalog := opts.ModelCatalog
+	if catalog == nil {
+		catalog = cost.NewModelCatalog()
+	}
+
 	deps := OrchestratorDeps{
 		Scheduler:      NewScheduler(),
 		DepResolver:    NewDependencyResolver(),
 		Queue:          NewQueueManager(),
-		Executor:       NewParallelExecutorFromPolicy(policy, executor),
+		Executor:       NewParallelExecutorWithClock(policy.MaxParallelism, executor, opts.Clock),
 		ContextBuilder: ctxpkg.NewContextBuilder(),
 		Compactor:      ctxpkg.NewContextCompactor(),
 		TokenEstimator: cost.NewTokenEstimator(),
 		CostCalc:       costCalc,
-		BudgetEnforcer: cost.NewBudgetEnforcer(),
+		BudgetEnforcer: cost.NewBudgetEnforcerWithPool(opts.BudgetPool),
 		UsageTracker:   cost.NewUsageTracker(),
 		Router:         ctxpkg.NewContextRouter(),
+		EstimateCache:  opts.EstimateCache,
+		ModelCatalog:   catalog,
+		MaxBatches:     opts.MaxBatches,
+		Clock:          opts.Clock,
+
+		BudgetAwareSelection: opts.BudgetAwareSelection,
 	}
 
 	return NewOrchestrator(deps)
@@ -0,0 +1,80 @@
+package orchestration
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"maps"
+	"slices"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+	ctxpkg "github.com/anthropics/claude-workflow/runtime/internal/context"
+)
+
+// MetadataInputHash is the task metadata key holding the task's input hash.
+// It is set just before the task is executed.
+const MetadataInputHash = "input_hash"
+
+// InputHash returns a stable hex-encoded SHA-256 of a task's effective input,
+// for executors that cache responses. It covers, in order: the model, the
+// prompt, inputs (including routed dependency outputs) and metadata (except
+// MetadataInputHash) in sorted key order, completed dependency outputs in
+// Deps order, and the run memory in sorted key order. Strings are
+// length-prefixed, so the hash is unambiguous and the same across processes.
+func InputHash(run *contracts.Run, task *contracts.Task) string {
+	input := task.Inputs
+	if input == nil {
+		input = &contracts.TaskInput{}
+	}
+
+	h := sha256.New()
+	writeInputString(h, string(task.Model))
+	writeInputString(h, input.Prompt)
+	writeInputMap(h, input.Inputs, "")
+	writeInputMap(h, input.Metadata, MetadataInputHash)
+
+	for _, depID := range task.Deps {
+		dep, ok := run.Tasks[depID]
+		if !ok || dep.State != contracts.TaskCompleted || dep.Outputs == nil {
+			writeInputString(h, "")
+			continue
+		}
+		writeInputString(h, dep.Outputs.Output)
+	}
+
+	writeInputMap(h, run.Memory, "")
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// setInputHash resolves the task's memory inputs, then records its input
+// hash in its metadata. Dedup and execution both hash through it, so their
+// hashes agree.
+func setInputHash(run *contracts.Run, task *contracts.Task) {
+	ctxpkg.ResolveMemoryInputs(run, task)
+	sum := InputHash(run, task)
+	if task.Inputs == nil {
+		task.Inputs = &contracts.TaskInput{}
+	}
+	if task.Inputs.Metadata == nil {
+		task.Inputs.Metadata = make(map[string]string, 1)
+	}
+	task.Inputs.Metadata[MetadataInputHash] = sum
+}
+
+// writeInputString writes a length-prefixed string.
+func writeInputString(h hash.Hash, s string) {
+	binary.Write(h, binary.LittleEndian, uint64(len(s)))
+	h.Write([]byte(s))
+}
+
+// writeInputMap writes map entries in sorted key order, leaving out skip.
+func writeInputMap(h hash.Hash, m map[string]string, skip string) {
+	keys := slices.Sorted(maps.Keys(m))
+	keys = slices.DeleteFunc(keys, func(k string) bool { return skip != "" && k == skip })
+	binary.Write(h, binary.LittleEndian, uint64(len(keys)))
+	for _, k := range keys {
+		writeInputString(h, k)
+		writeInputString(h, m[k])
+	}
+}
@@ -0,0 +1,79 @@
+package orchestration
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+func TestInputHash(t *testing.T) {
+	base := InputHash(newEstimateRun(t), newEstimateRun(t).Tasks["C"])
+	if len(base) != 64 {
+		t.Fatalf("expected 64 hex characters, got %q", base)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(run *contracts.Run)
+		same   bool
+	}{
+		{"identical inputs", func(run *contracts.Run) {}, true},
+		{"input hash metadata ignored", func(run *contracts.Run) {
+			setInputHash(run, run.Tasks["C"])
+		}, true},
+		{"routed input", func(run *contracts.Run) {
+			run.Tasks["C"].Inputs.Inputs = map[string]string{"A": "changed"}
+		}, false},
+		{"prompt", func(run *contracts.Run) { run.Tasks["C"].Inputs.Prompt += "!" }, false},
+		{"model", func(run *contracts.Run) { run.Tasks["C"].Model = "claude-3-opus-20240229" }, false},
+		{"dependency output", func(run *contracts.Run) {
+			run.Tasks["A"].Outputs = &contracts.TaskResult{Output: "other"}
+		}, false},
+		{"run memory", func(run *contracts.Run) { run.Memory = map[string]string{"k": "v"} }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := newEstimateRun(t)
+			tt.mutate(run)
+			got := InputHash(run, run.Tasks["C"])
+			if (got == base) != tt.same {
+				t.Errorf("hash %s, base %s, want same=%v", got, base, tt.same)
+			}
+		})
+	}
+}
+
+func TestIntegration_InputHashMetadata(t *testing.T) {
+	run := newEstimateRun(t)
+	run.Tasks["A"].State = contracts.TaskPending
+	run.Tasks["B"].State = contracts.TaskPending
+
+	var mu sync.Mutex
+	seen := make(map[contracts.TaskID]string)
+	executor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		mu.Lock()
+		seen[task.ID] = task.Inputs.Metadata[MetadataInputHash]
+		mu.Unlock()
+		return &contracts.TaskResult{
+			Output: "out-" + string(task.ID),
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.001, Currency: "USD"}},
+		}, nil
+	}
+
+	orch := NewOrchestrator(createRealDeps(run.Policy, executor))
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	for id, task := range run.Tasks {
+		if seen[id] == "" {
+			t.Errorf("task %s executed without %s metadata", id, MetadataInputHash)
+		}
+		if want := InputHash(run, task); seen[id] != want {
+			t.Errorf("task %s: executor saw %s, recomputed %s", id, seen[id], want)
+		}
+	}
+}
@@ -2,14 +2,15 @@ package orchestration
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/anthropics/claude-workflow/runtime/contracts"
 	"github.com/anthropics/claude-workflow/runtime/internal/audit"
+	ctxpkg "github.com/anthropics/claude-workflow/runtime/internal/context"
 )
 
 // orchestrator implements contracts.Orchestrator with batched execution loop.
@@ -26,12 +27,34 @@ type orchestrator struct {
 	budgetEnforcer contracts.BudgetEnforcer
 	usageTracker   contracts.UsageTracker
 	router         contracts.ContextRouter
+	estimateCache  *EstimateCache         // optional, nil = estimate on every pre-check
+	modelCatalog   contracts.ModelCatalog // optional, nil = no forced compaction
+	canceller      *TaskCanceller         // optional, nil = tasks can't be cancelled individually
+	budgetOverride *BudgetOverride        // optional, nil = budget fixed at start
+
+	// budgetAwareSelection defers ready tasks the remaining budget can't cover.
+	budgetAwareSelection bool
 
 	// onProgress is called after each successful batch merge (optional).
 	onProgress func(*contracts.Run)
 
+	// onBatchStart is called with the batch number as a batch starts (optional).
+	onBatchStart func(batch int)
+
+	// progressStale is set when the last onProgress call panicked. The call is
+	// retried at batch boundaries, at most once per reconcileInterval.
+	progressStale     bool
+	lastProgress      time.Time
+	reconcileInterval time.Duration
+
+	// maxBatches is the batch limit per run (0 = scaled by task count).
+	maxBatches int
+
 	// runStart tracks when the run started for duration calculation.
 	runStart time.Time
+
+	// clock times the run, its tasks and its context and abort timeouts.
+	clock Clock
 }
 
 // OrchestratorDeps contains all dependencies needed by the orchestrator.
@@ -47,10 +70,58 @@ type OrchestratorDeps struct {
 	BudgetEnforcer contracts.BudgetEnforcer
 	UsageTracker   contracts.UsageTracker
 	Router         contracts.ContextRouter
+
+	// EstimateCache reuses pre-check estimates for tasks whose inputs and
+	// context are unchanged (optional, nil = disabled).
+	EstimateCache *EstimateCache
+
+	// ModelCatalog supplies model context windows for
+	// ContextPolicy.ForceCompactRatio (optional, nil = never force compaction).
+	ModelCatalog contracts.ModelCatalog
+
+	// Canceller delivers per-task cancel requests (optional).
+	Canceller *TaskCanceller
+
+	// BudgetOverride delivers budget limit changes for a running run (optional).
+	BudgetOverride *BudgetOverride
+
+	// BudgetAwareSelection admits only as many ready tasks per batch as the
+	// remaining run budget covers by their estimates; the rest wait for a
+	// later batch without being estimated. The pre-check still runs on every
+	// admitted task. An EstimateCache is created if none is set, so admitted
+	// tasks are not estimated twice.
+	BudgetAwareSelection bool
+
+	// ReconcileInterval is the minimum time between progress callback retries
+	// after a callback panicked (0 = retry at every batch boundary).
+	ReconcileInterval time.Duration
+
+	// MaxBatches fails the run with ErrBatchLimitExceeded once the batch loop
+	// runs more than this many batches, as a backstop against a scheduler
+	// that never makes progress (0 = defaultBatchesPerTask per task).
+	MaxBatches int
+
+	// OnBatchStart is called on the orchestrator goroutine with the batch
+	// number before each batch executes, e.g. to expose it in metrics.
+	// It must not block (optional).
+	OnBatchStart func(batch int)
+
+	// Clock times the run and its tasks, context compaction and the abort
+	// grace window (optional, nil = SystemClock). The executor's task
+	// timeouts use its own clock; see NewParallelExecutorWithClock.
+	Clock Clock
 }
 
+// defaultBatchesPerTask sets the default batch limit. A well-behaved run
+// completes at least one task per batch, so this leaves ample headroom.
+const defaultBatchesPerTask = 10
+
 // NewOrchestrator creates a new Orchestrator with the given dependencies.
 func NewOrchestrator(deps OrchestratorDeps) contracts.Orchestrator {
+	estimateCache := deps.EstimateCache
+	if deps.BudgetAwareSelection && estimateCache == nil {
+		estimateCache = NewEstimateCache()
+	}
 	return &orchestrator{
 		scheduler:      deps.Scheduler,
 		depResolver:    deps.DepResolver,
@@ -63,11 +134,22 @@ func NewOrchestrator(deps OrchestratorDeps) contracts.Orchestrator {
 		budgetEnforcer: deps.BudgetEnforcer,
 		usageTracker:   deps.UsageTracker,
 		router:         deps.Router,
+		estimateCache:  estimateCache,
+		modelCatalog:   deps.ModelCatalog,
+		canceller:      deps.Canceller,
+		budgetOverride: deps.BudgetOverride,
+
+		budgetAwareSelection: deps.BudgetAwareSelection,
+		reconcileInterval:    deps.ReconcileInterval,
+		maxBatches:           deps.MaxBatches,
+		onBatchStart:         deps.OnBatchStart,
+		clock:                clockOrSystem(deps.Clock),
 	}
 }
 
 // NewOrchestratorWithCallback creates an Orchestrator with progress callback.
-// The callback is called after each successful batch merge.
+// The callback is called after each successful batch merge. A panicking
+// callback does not fail the run; it is retried at later batch boundaries.
 func NewOrchestratorWithCallback(deps OrchestratorDeps, onProgress func(*contracts.Run)) contracts.Orchestrator {
 	o := NewOrchestrator(deps).(*orchestrator)
 	o.onProgress = onProgress
@@ -93,59 +175,149 @@ type batchResult struct {
 // Run executes all tasks in the run according to the dependency graph.
 // Uses batched execution: parallel executor I/O, sequential deterministic merge.
 // Fail-fast: any task failure terminates the run immediately.
+// If ctx is cancelled after all tasks completed successfully, the run is
+// RunCompleted rather than RunAborted.
 func (o *orchestrator) Run(ctx context.Context, run *contracts.Run) error {
-	o.runStart = time.Now()
+	o.runStart = o.clock.Now()
 	batchNum := 0
 
 	// Init
 	if err := o.init(run); err != nil {
 		return err
 	}
+	if run.Policy.DryRun {
+		return o.dryRun(run)
+	}
+	defer o.releaseReservations(run)
+
+	// With an abort grace window, executor calls outlive ctx by AbortGraceMs
+	// so in-flight tasks can finish and be recorded; no new tasks start.
+	execCtx := ctx
+	if run.Policy.AbortGraceMs > 0 {
+		var cancel context.CancelFunc
+		execCtx, cancel = withAbortGrace(ctx, o.clock, time.Duration(run.Policy.AbortGraceMs)*time.Millisecond)
+		defer cancel()
+	}
+
+	maxBatches := o.maxBatches
+	if maxBatches <= 0 {
+		maxBatches = defaultBatchesPerTask * max(len(run.Tasks), 1)
+	}
 
 	// Main batched execution loop
 	for {
 		batchNum++
 		select {
 		case <-ctx.Done():
+			// Completion wins over a cancel observed after every task already
+			// succeeded; fall through to the termination check below.
+			if o.allTerminal(run) && !o.hasFailures(run) {
+				break
+			}
 			run.State = contracts.RunAborted
-			audit.Log("event=run_aborted run_id=%s duration_ms=%d reason=context_cancelled",
-				run.ID, time.Since(o.runStart).Milliseconds())
+			o.logRunEnd(run, "run_aborted", audit.Fields{"reason": "context_cancelled"})
 			return ctx.Err()
 		default:
 		}
 
-		// 1. Get ready tasks (sorted by TaskID for determinism)
+		// Resync progress missed by a panicking callback (bounded by reconcileInterval)
+		if o.progressStale && o.since(o.lastProgress) >= o.reconcileInterval {
+			o.notifyProgress(run, batchNum)
+		}
+
+		// Apply cancel requests for tasks that have not started
+		if err := o.applyCancels(run); err != nil {
+			run.State = contracts.RunFailed
+			o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "task_cancelled", "error_msg": err.Error()})
+			return err
+		}
+
+		// Apply a budget limit raised since the last batch
+		o.applyBudgetOverride(run)
+
+		// 1. Get ready tasks (by priority, then TaskID for determinism)
 		ready, err := o.scheduler.NextReady(run)
 		if err != nil {
 			run.State = contracts.RunFailed
-			audit.Log("event=run_failed run_id=%s duration_ms=%d error_code=scheduler_error error_msg=%s",
-				run.ID, time.Since(o.runStart).Milliseconds(), err.Error())
+			o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "scheduler_error", "error_msg": err.Error()})
 			return err
 		}
 
+		// Guard against a misbehaving scheduler: never dispatch a task twice per batch
+		if deduped := dedupTaskIDs(ready); len(deduped) != len(ready) {
+			logRunEvent(run, "ready_duplicates_dropped", audit.Fields{"batch": batchNum, "count": len(ready) - len(deduped)})
+			ready = deduped
+		}
+
+		// Never dispatch a task the scheduler returned in a non-ready state
+		// (e.g. still running); the executor would reject it mid-batch
+		ready = o.dropNotReady(run, ready, batchNum)
+
+		// Skip conditional tasks whose RunIf does not hold
+		ready, skipped, err := o.skipUnmetConditions(run, ready)
+		if err != nil {
+			run.State = contracts.RunFailed
+			o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "routing_failed", "error_msg": err.Error()})
+			return err
+		}
+		if len(ready) == 0 && skipped > 0 {
+			// Their dependents may be ready now
+			o.notifyProgress(run, batchNum)
+			continue
+		}
+
+		// Execute tasks with identical input once; duplicates share the result
+		var dups map[contracts.TaskID]contracts.TaskID
+		if run.Policy.DedupIdenticalTasks {
+			ready, dups = o.dedupIdentical(run, ready)
+			if len(ready) == 0 && len(dups) > 0 {
+				// Every ready task duplicates a completed one: nothing to execute
+				if err := o.completeDuplicates(run, dups); err != nil {
+					run.State = contracts.RunFailed
+					o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "merge_failed", "error_msg": err.Error()})
+					return err
+				}
+				o.notifyProgress(run, batchNum)
+				continue
+			}
+		}
+
 		// 2. Check termination (all tasks terminal)
 		if len(ready) == 0 {
 			if o.allTerminal(run) {
 				// Check if any task failed - if so, run is failed
 				if o.hasFailures(run) {
 					run.State = contracts.RunFailed
-					audit.Log("event=run_failed run_id=%s duration_ms=%d error_code=task_failed",
-						run.ID, time.Since(o.runStart).Milliseconds())
+					o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "task_failed"})
 				} else {
 					run.State = contracts.RunCompleted
-					audit.Log("event=run_completed run_id=%s duration_ms=%d total_tokens=%d total_cost=%.4f%s state=completed",
-						run.ID, time.Since(o.runStart).Milliseconds(), run.Usage.Tokens,
-						run.Usage.Cost.Amount, run.Usage.Cost.Currency)
+					o.logRunEnd(run, "run_completed", audit.Fields{"state": "completed"})
 				}
 				return nil
 			}
 			// Unreachable if fail-fast works correctly
 			run.State = contracts.RunFailed
-			audit.Log("event=run_failed run_id=%s duration_ms=%d error_code=deadlock",
-				run.ID, time.Since(o.runStart).Milliseconds())
+			o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "deadlock"})
 			return contracts.ErrDeadlock
 		}
 
+		// Backstop against a scheduler that keeps returning work without progress
+		if batchNum > maxBatches {
+			run.State = contracts.RunFailed
+			o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "batch_limit_exceeded", "max_batches": maxBatches})
+			return fmt.Errorf("%d batches: %w", maxBatches, contracts.ErrBatchLimitExceeded)
+		}
+
+		// Start the most expensive tasks first
+		if run.Policy.SchedulingStrategy == contracts.SchedulingCost {
+			ready = o.orderByCost(run, ready)
+		}
+
+		// Leave tasks the remaining budget can't cover for a later batch
+		if o.budgetAwareSelection {
+			ready = o.selectAffordable(run, ready, batchNum)
+		}
+
 		// 3. Pre-check budget SEQUENTIALLY (deterministic)
 		allowed, deniedResults := o.preCheckBudget(run, ready)
 
@@ -165,60 +337,135 @@ func (o *orchestrator) Run(ctx context.Context, run *contracts.Run) error {
 			// Return error for first denied task (with sentinel wrapped)
 			dr := deniedResults[0]
 			run.State = contracts.RunFailed
-			audit.Log("event=run_failed run_id=%s duration_ms=%d error_code=%s task_id=%s",
-				run.ID, time.Since(o.runStart).Milliseconds(), dr.errorCode, dr.taskID)
+			o.logRunEnd(run, "run_failed", audit.Fields{"error_code": dr.errorCode, "task_id": dr.taskID})
 			return fmt.Errorf("task %s: %s: %w", dr.taskID, dr.errorMsg, dr.err)
 		}
 
 		// 5. Log batch started
-		taskIDStrs := make([]string, len(allowed))
-		for i, tid := range allowed {
-			taskIDStrs[i] = string(tid)
+		logRunEvent(run, "batch_started", audit.Fields{"batch": batchNum, "task_count": len(allowed), "tasks": allowed})
+		batchStart := o.clock.Now()
+		if o.onBatchStart != nil {
+			o.onBatchStart(batchNum)
 		}
-		audit.Log("event=batch_started run_id=%s batch=%d task_count=%d tasks=%s",
-			run.ID, batchNum, len(allowed), strings.Join(taskIDStrs, ","))
-		batchStart := time.Now()
 
 		// 6. Execute allowed batch (parallel executor calls, NO mutations except TaskRunning)
-		results := o.executeBatch(ctx, run, allowed)
+		results := o.executeBatch(execCtx, run, allowed)
 
 		// 7. Deterministic merge (sequential, sorted by TaskID)
 		// Returns error on first failure (fail-fast)
-		if err := o.mergeBatchResults(run, results); err != nil {
+		graceAbort := execCtx != ctx && ctx.Err() != nil
+		if err := o.mergeBatchResults(run, results, graceAbort); err != nil {
+			run.State = contracts.RunFailed
+			o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "merge_failed", "error_msg": err.Error()})
+			return err
+		}
+		if err := o.completeDuplicates(run, dups); err != nil {
 			run.State = contracts.RunFailed
-			audit.Log("event=run_failed run_id=%s duration_ms=%d error_code=merge_failed error_msg=%s",
-				run.ID, time.Since(o.runStart).Milliseconds(), err.Error())
+			o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "merge_failed", "error_msg": err.Error()})
 			return err
 		}
 
 		// 8. Log batch completed
-		audit.Log("event=batch_completed run_id=%s batch=%d duration_ms=%d tasks_completed=%d",
-			run.ID, batchNum, time.Since(batchStart).Milliseconds(), len(allowed))
+		logRunEvent(run, "batch_completed", audit.Fields{
+			"batch":           batchNum,
+			"duration_ms":     o.since(batchStart).Milliseconds(),
+			"tasks_completed": len(allowed),
+		})
 
-		// 9. Call progress callback if set
-		if o.onProgress != nil {
-			o.onProgress(run)
+		// Stop dispatching once too many tolerated failures piled up
+		if failed, limit, exceeded := failureThresholdExceeded(run); exceeded {
+			run.State = contracts.RunFailed
+			o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "failure_threshold_exceeded", "failed": failed, "max_failures": limit})
+			return fmt.Errorf("%d of %d tasks failed, limit %g: %w",
+				failed, len(run.Tasks), limit, contracts.ErrFailureThresholdExceeded)
 		}
+
+		// 9. Call progress callback if set
+		o.notifyProgress(run, batchNum)
 	}
 }
 
+// notifyProgress calls the progress callback, recovering from a panic so a
+// faulty callback cannot crash the run. Progress stays stale until a later
+// call succeeds.
+func (o *orchestrator) notifyProgress(run *contracts.Run, batchNum int) {
+	if o.onProgress == nil {
+		return
+	}
+	o.lastProgress = o.clock.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			o.progressStale = true
+			logRunEvent(run, "progress_callback_panic", audit.Fields{"batch": batchNum, "panic": fmt.Sprint(r)})
+		}
+	}()
+	o.onProgress(run)
+	o.progressStale = false
+}
+
 // init validates the run and marks it as running.
 func (o *orchestrator) init(run *contracts.Run) error {
 	if run == nil || run.DAG == nil {
-		audit.Log("event=run_failed run_id=unknown duration_ms=%d error_code=invalid_input",
-			time.Since(o.runStart).Milliseconds())
+		audit.LogEvent("run_failed", audit.Fields{
+			"run_id":      "unknown",
+			"duration_ms": o.since(o.runStart).Milliseconds(),
+			"error_code":  "invalid_input",
+		})
 		return contracts.ErrInvalidInput
 	}
 	if err := o.depResolver.Validate(run.DAG); err != nil {
 		run.State = contracts.RunFailed
-		audit.Log("event=run_failed run_id=%s duration_ms=%d error_code=dag_validation error_msg=%s",
-			run.ID, time.Since(o.runStart).Milliseconds(), err.Error())
+		o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "dag_validation", "error_msg": err.Error()})
 		return err
 	}
 	run.State = contracts.RunRunning
-	audit.Log("event=run_started run_id=%s policy_timeout_ms=%d policy_parallelism=%d policy_budget=%.2f%s",
-		run.ID, run.Policy.TimeoutMs, run.Policy.MaxParallelism,
-		run.Policy.BudgetLimit.Amount, run.Policy.BudgetLimit.Currency)
+	run.StartedAt = contracts.Timestamp(o.runStart.UnixMilli())
+	if run.Policy.SchedulingStrategy == contracts.SchedulingCost && o.estimateCache == nil {
+		// Ordering estimates every ready task; the pre-check reuses them
+		o.estimateCache = NewEstimateCache()
+	}
+	if run.TraceID == "" {
+		run.TraceID = contracts.NewTraceID()
+	}
+	if run.Policy.BudgetDisabled {
+		logRunEvent(run, "budget_disabled", audit.Fields{"warning": "budget_checks_bypassed"})
+	}
+	logRunEvent(run, "run_started", audit.Fields{
+		"policy_timeout_ms":  run.Policy.TimeoutMs,
+		"policy_parallelism": run.Policy.MaxParallelism,
+		"policy_budget":      run.Policy.BudgetLimit.Amount,
+		"currency":           run.Policy.BudgetLimit.Currency,
+	})
+	if err := o.routeSubmittedSkipped(run); err != nil {
+		run.State = contracts.RunFailed
+		o.logRunEnd(run, "run_failed", audit.Fields{"error_code": "routing_failed", "error_msg": err.Error()})
+		return err
+	}
+	return nil
+}
+
+// routeSubmittedSkipped releases the dependents of tasks submitted already
+// skipped (e.g. disabled optional steps), in task ID order, so they run
+// without their output as after a soft cancel. Tasks the orchestrator skips
+// always carry an error, so only submitted ones have none.
+func (o *orchestrator) routeSubmittedSkipped(run *contracts.Run) error {
+	var skipped []contracts.TaskID
+	for tid, task := range run.Tasks {
+		if task.State == contracts.TaskSkipped && task.Error == nil {
+			skipped = append(skipped, tid)
+		}
+	}
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i] < skipped[j] })
+	for _, tid := range skipped {
+		run.Tasks[tid].Error = &contracts.TaskError{
+			Code:    "submitted_skipped",
+			Message: "task submitted as skipped",
+		}
+		logRunEvent(run, "task_skipped", audit.Fields{"task_id": tid, "reason": "submitted_skipped"})
+		if err := o.routeSkipped(run, tid); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -229,10 +476,49 @@ func (o *orchestrator) preCheckBudget(
 	run *contracts.Run,
 	taskIDs []contracts.TaskID,
 ) (allowed []contracts.TaskID, denied []deniedResult) {
+	// Trusted mode: no estimation or budget checks, but per-task context
+	// caps still hold
+	if run.Policy.BudgetDisabled {
+		for _, tid := range taskIDs {
+			task, exists := run.Tasks[tid]
+			if !exists {
+				denied = append(denied, deniedResult{
+					taskID:    tid,
+					errorCode: "task_not_found",
+					errorMsg:  fmt.Sprintf("task %s not found in run", tid),
+					err:       contracts.ErrTaskNotFound,
+				})
+				continue
+			}
+			if task.MaxContextTokens > 0 {
+				compacted, dr := o.compactedContext(run, tid, task)
+				if dr == nil {
+					dr = o.checkContextCap(tid, task, compacted)
+				}
+				if dr != nil {
+					denied = append(denied, *dr)
+					continue
+				}
+			}
+			allowed = append(allowed, tid)
+		}
+		return allowed, denied
+	}
+
+	// The previous batch has recorded its costs; drop what it still holds
+	// in shared pools, as this batch reserves afresh
+	o.releaseReservations(run)
+
+	// Estimate all tasks first (possibly concurrently), then check budgets
+	// sequentially in ready order so reservations stay deterministic
+	estimates := o.estimateBatch(run, taskIDs)
+
 	// Track reserved cost for this batch to prevent over-commitment
 	var reservedCost contracts.Cost
+	reservedByModel := make(map[contracts.ModelID]contracts.Cost)
+	modelEnforcer, hasModelEnforcer := o.budgetEnforcer.(contracts.ModelBudgetEnforcer)
 
-	for _, tid := range taskIDs {
+	for i, tid := range taskIDs {
 		// Guard: validate task exists
 		task, exists := run.Tasks[tid]
 		if !exists {
@@ -245,63 +531,60 @@ func (o *orchestrator) preCheckBudget(
 			continue
 		}
 
-		// Build context for estimation
-		bundle, err := o.contextBuilder.Build(run, tid)
-		if err != nil {
-			denied = append(denied, deniedResult{
-				taskID:    tid,
-				errorCode: "context_build_failed",
-				errorMsg:  fmt.Sprintf("failed to build context: %v", err),
-				err:       err,
-			})
-			continue
-		}
-
-		// Compact context
-		compacted, err := o.compactor.Compact(bundle, run.Policy.ContextPolicy)
-		if err != nil {
-			denied = append(denied, deniedResult{
-				taskID:    tid,
-				errorCode: "context_compact_failed",
-				errorMsg:  fmt.Sprintf("failed to compact context: %v", err),
-				err:       err,
-			})
+		tokens, cost := estimates[i].tokens, estimates[i].cost
+		if dr := estimates[i].denied; dr != nil {
+			denied = append(denied, *dr)
 			continue
 		}
 
-		// Estimate tokens
-		tokens, err := o.tokenEstimator.Estimate(task.Inputs, compacted)
-		if err != nil {
-			denied = append(denied, deniedResult{
-				taskID:    tid,
-				errorCode: "token_estimation_failed",
-				errorMsg:  fmt.Sprintf("failed to estimate tokens: %v", err),
-				err:       err,
-			})
-			continue
+		// Pre-check budget INCLUDING already reserved cost for this batch
+		// This prevents over-commitment when multiple tasks pass Allow() individually
+		totalEstimate, err := cost.Add(reservedCost)
+		if err == nil {
+			_, err = cost.Add(reservedByModel[task.Model])
 		}
-
-		// Estimate cost
-		cost, err := o.costCalc.Estimate(tokens, task.Model)
 		if err != nil {
 			denied = append(denied, deniedResult{
 				taskID:    tid,
-				errorCode: "model_unknown",
-				errorMsg:  fmt.Sprintf("failed to estimate cost for model %s: %v", task.Model, err),
+				errorCode: "currency_mismatch",
+				errorMsg:  fmt.Sprintf("budget pre-check failed: %v", err),
 				err:       err,
 			})
 			continue
 		}
-
-		// Pre-check budget INCLUDING already reserved cost for this batch
-		// This prevents over-commitment when multiple tasks pass Allow() individually
-		totalEstimate := contracts.Cost{
-			Amount:   cost.Amount + reservedCost.Amount,
-			Currency: cost.Currency,
-		}
 		if err := o.budgetEnforcer.Allow(run, totalEstimate); err != nil {
-			audit.Log("event=budget_precheck_failed run_id=%s task_id=%s estimated_cost=%.4f%s reason=budget_exceeded",
-				run.ID, tid, cost.Amount, cost.Currency)
+			if errors.Is(err, contracts.ErrBudgetPoolExhausted) {
+				logRunEvent(run, "budget_precheck_failed", audit.Fields{
+					"task_id":        tid,
+					"pool":           run.Policy.BudgetPool,
+					"estimated_cost": cost.Amount,
+					"currency":       cost.Currency,
+					"reason":         "budget_pool_exhausted",
+				})
+				denied = append(denied, deniedResult{
+					taskID:    tid,
+					errorCode: "budget_pool_exhausted",
+					errorMsg:  fmt.Sprintf("budget pool pre-check failed: %v", err),
+					err:       contracts.ErrBudgetPoolExhausted,
+				})
+				continue
+			}
+			if errors.Is(err, contracts.ErrCurrencyMismatch) {
+				// A converter could not price the estimate in the budget currency
+				denied = append(denied, deniedResult{
+					taskID:    tid,
+					errorCode: "currency_mismatch",
+					errorMsg:  fmt.Sprintf("budget pre-check failed: %v", err),
+					err:       err,
+				})
+				continue
+			}
+			logRunEvent(run, "budget_precheck_failed", audit.Fields{
+				"task_id":        tid,
+				"estimated_cost": cost.Amount,
+				"currency":       cost.Currency,
+				"reason":         "budget_exceeded",
+			})
 			denied = append(denied, deniedResult{
 				taskID:    tid,
 				errorCode: "budget_exceeded",
@@ -311,21 +594,95 @@ func (o *orchestrator) preCheckBudget(
 			continue
 		}
 
+		// Per-model budget check, independent of the overall budget
+		modelEstimate, _ := cost.Add(reservedByModel[task.Model]) // currency checked above
+		if hasModelEnforcer {
+			if err := modelEnforcer.AllowModel(run, task.Model, modelEstimate); err != nil {
+				logRunEvent(run, "budget_precheck_failed", audit.Fields{
+					"task_id":        tid,
+					"model":          task.Model,
+					"estimated_cost": cost.Amount,
+					"currency":       cost.Currency,
+					"reason":         "model_budget_exceeded",
+				})
+				denied = append(denied, deniedResult{
+					taskID:    tid,
+					errorCode: "model_budget_exceeded",
+					errorMsg:  fmt.Sprintf("model budget pre-check failed: %v", err),
+					err:       contracts.ErrModelBudgetExceeded,
+				})
+				continue
+			}
+		}
+
 		// Budget precheck passed
-		audit.Log("event=budget_precheck_ok run_id=%s task_id=%s estimated_tokens=%d estimated_cost=%.4f%s",
-			run.ID, tid, tokens, cost.Amount, cost.Currency)
+		logRunEvent(run, "budget_precheck_ok", audit.Fields{
+			"task_id":          tid,
+			"estimated_tokens": tokens,
+			"estimated_cost":   cost.Amount,
+			"currency":         cost.Currency,
+		})
 
 		// Reserve this cost for subsequent checks in this batch
-		reservedCost.Amount += cost.Amount
-		if reservedCost.Currency == "" {
-			reservedCost.Currency = cost.Currency
-		}
+		reservedCost = totalEstimate
+		reservedByModel[task.Model] = modelEstimate
 
 		allowed = append(allowed, tid)
 	}
 	return allowed, denied
 }
 
+// releaseReservations drops the run's shared pool reservations, if the
+// budget enforcer holds any.
+func (o *orchestrator) releaseReservations(run *contracts.Run) {
+	if reserving, ok := o.budgetEnforcer.(contracts.ReservingBudgetEnforcer); ok {
+		reserving.Release(run)
+	}
+}
+
+// selectAffordable returns the longest prefix of ready whose estimates fit in
+// the remaining run budget. Tasks are estimated in order, stopping at the first
+// one that doesn't fit, so the rest are never estimated this batch. The first
+// task is always admitted: if even it doesn't fit, preCheckBudget denies it
+// and the run fails as it would without selection. Estimates in a currency
+// other than the budget's turn selection off for the batch.
+func (o *orchestrator) selectAffordable(run *contracts.Run, ready []contracts.TaskID, batchNum int) []contracts.TaskID {
+	if run.Policy.BudgetDisabled || run.Policy.BudgetLimit.Amount <= 0 || len(ready) <= 1 {
+		return ready
+	}
+
+	budget := run.Policy.BudgetLimit
+	remaining := budget.Amount - run.Usage.Cost.Amount
+	var reserved float64
+	for i, tid := range ready {
+		task, exists := run.Tasks[tid]
+		if !exists {
+			continue // denied by preCheckBudget
+		}
+		_, cost, dr := o.estimateCost(run, tid, task)
+		if dr != nil {
+			continue // denied by preCheckBudget
+		}
+		if cost.Currency != "" && budget.Currency != "" && cost.Currency != budget.Currency {
+			// Only the enforcer can price the estimate in the budget
+			// currency; leave the whole batch to preCheckBudget
+			return ready
+		}
+		reserved += cost.Amount
+		if reserved > remaining && i > 0 {
+			logRunEvent(run, "ready_deferred", audit.Fields{
+				"batch":     batchNum,
+				"count":     len(ready) - i,
+				"reason":    "budget_remaining",
+				"remaining": remaining,
+				"currency":  run.Policy.BudgetLimit.Currency,
+			})
+			return ready[:i]
+		}
+	}
+	return ready
+}
+
 // executeBatch executes tasks in parallel (executor I/O only).
 // Each goroutine sets task.State = TaskRunning (safe: each touches different task).
 // Returns results slice with same indices as input taskIDs.
@@ -348,21 +705,31 @@ func (o *orchestrator) executeBatch(
 				results[idx] = batchResult{
 					taskID:    tid,
 					err:       fmt.Errorf("task %s not found", tid),
-					startTime: time.Now(),
+					startTime: o.clock.Now(),
 				}
 				return
 			}
 
 			// Log task started (after existence check to avoid panic)
-			taskStart := time.Now()
-			audit.Log("event=task_started run_id=%s task_id=%s model=%s",
-				run.ID, tid, task.Model)
+			taskStart := o.clock.Now()
+			logRunEvent(run, "task_started", audit.Fields{"task_id": tid, "model": task.Model})
 
-			// Mark as running (safe: each goroutine touches different task)
+			// Mark as running and record the input hash for executor caches
+			// (safe: each goroutine touches different task). Memory inputs are
+			// resolved here too, as runs without budget checks skip Build
 			task.State = contracts.TaskRunning
+			setInputHash(run, task)
+
+			// Per-task context so a cancel request can stop this task alone
+			taskCtx := ctx
+			if o.canceller != nil {
+				var done func()
+				taskCtx, done = o.canceller.start(ctx, tid)
+				defer done()
+			}
 
 			// Execute via ParallelExecutor (respects ctx, semaphore)
-			result, err := o.executor.Execute(ctx, run, tid)
+			result, err := o.executor.Execute(taskCtx, run, tid)
 			results[idx] = batchResult{taskID: tid, result: result, err: err, startTime: taskStart}
 		}(i, taskID)
 	}
@@ -374,7 +741,9 @@ func (o *orchestrator) executeBatch(
 // mergeBatchResults applies batch results SEQUENTIALLY with fail-fast.
 // Results are sorted by TaskID for determinism before applying side-effects.
 // Returns error on first failure.
-func (o *orchestrator) mergeBatchResults(run *contracts.Run, results []batchResult) error {
+// If graceAbort is set, cancellations caused by the abort are not failures:
+// tasks that never started return to pending, the rest of the batch is recorded.
+func (o *orchestrator) mergeBatchResults(run *contracts.Run, results []batchResult, graceAbort bool) error {
 	// 1. Sort by TaskID for determinism
 	sort.Slice(results, func(i, j int) bool {
 		return string(results[i].taskID) < string(results[j].taskID)
@@ -387,20 +756,63 @@ func (o *orchestrator) mergeBatchResults(run *contracts.Run, results []batchResu
 			return fmt.Errorf("task %s not found during merge", r.taskID)
 		}
 
+		if r.err != nil && graceAbort && errors.Is(r.err, contracts.ErrTaskCancelled) {
+			if errors.Is(r.err, errNotDispatched) {
+				task.State = contracts.TaskPending
+				continue
+			}
+			task.State = contracts.TaskFailed
+			task.Error = &contracts.TaskError{
+				Code:    "cancelled",
+				Message: r.err.Error(),
+			}
+			logRunEvent(run, "task_cancelled", audit.Fields{
+				"task_id":     r.taskID,
+				"duration_ms": o.since(r.startTime).Milliseconds(),
+				"reason":      "abort_grace_expired",
+			})
+			continue
+		}
+
+		// A cancelled task that did not finish is handled per its cancel mode;
+		// one that completed anyway keeps its result
+		if mode, cancelled := o.takeCancel(r.taskID); cancelled && r.err != nil {
+			if err := o.cancelTask(run, r.taskID, mode); err != nil {
+				return err
+			}
+			continue
+		}
+
 		if r.err != nil {
 			// Mark task failed with error
+			code := "execution_failed"
+			if errors.Is(r.err, contracts.ErrTaskTimeout) {
+				code = "task_timeout"
+			}
 			task.State = contracts.TaskFailed
 			task.Error = &contracts.TaskError{
-				Code:    "execution_failed",
+				Code:    code,
 				Message: r.err.Error(),
 			}
-			durationMs := time.Since(r.startTime).Milliseconds()
-			audit.Log("event=task_failed run_id=%s task_id=%s duration_ms=%d error_code=execution_failed error_msg=%s",
-				run.ID, r.taskID, durationMs, r.err.Error())
+			durationMs := o.since(r.startTime).Milliseconds()
+			logRunEvent(run, "task_failed", audit.Fields{
+				"task_id":     r.taskID,
+				"duration_ms": durationMs,
+				"error_code":  code,
+				"error_msg":   r.err.Error(),
+			})
+			if o.continueAfterFailure(run, task) {
+				continue
+			}
 			// FAIL-FAST: return immediately
 			return fmt.Errorf("task %s execution failed: %w", r.taskID, r.err)
 		}
 
+		// Executors may report only the input/output split; Tokens is their sum
+		if r.result != nil && r.result.Usage.Tokens == 0 {
+			r.result.Usage.Tokens = r.result.Usage.InputTokens + r.result.Usage.OutputTokens
+		}
+
 		// Validate result
 		if r.result == nil || r.result.Usage.Tokens == 0 {
 			task.State = contracts.TaskFailed
@@ -408,31 +820,59 @@ func (o *orchestrator) mergeBatchResults(run *contracts.Run, results []batchResu
 				Code:    "invalid_result",
 				Message: "executor returned nil or zero usage",
 			}
-			durationMs := time.Since(r.startTime).Milliseconds()
-			audit.Log("event=task_failed run_id=%s task_id=%s duration_ms=%d error_code=invalid_result error_msg=executor returned nil or zero usage",
-				run.ID, r.taskID, durationMs)
+			durationMs := o.since(r.startTime).Milliseconds()
+			logRunEvent(run, "task_failed", audit.Fields{
+				"task_id":     r.taskID,
+				"duration_ms": durationMs,
+				"error_code":  "invalid_result",
+				"error_msg":   "executor returned nil or zero usage",
+			})
+			if o.continueAfterFailure(run, task) {
+				continue
+			}
 			return fmt.Errorf("task %s: invalid result", r.taskID)
 		}
 
-		// Record budget (may fail if over budget post-execution)
-		if err := o.budgetEnforcer.Record(run, r.result.Usage.Cost); err != nil {
-			task.State = contracts.TaskFailed
-			task.Error = &contracts.TaskError{
-				Code:    "budget_exceeded",
-				Message: err.Error(),
-			}
-			audit.Log("event=budget_record_failed run_id=%s task_id=%s actual_cost=%.4f%s reason=exceeded",
-				run.ID, r.taskID, r.result.Usage.Cost.Amount, r.result.Usage.Cost.Currency)
-			return fmt.Errorf("task %s budget exceeded: %w", r.taskID, err)
+		// Results are accounted in the run's currency
+		if err := o.checkResultCurrency(run, task, r); err != nil {
+			return err
 		}
 
-		// Budget record succeeded
-		audit.Log("event=budget_record_ok run_id=%s task_id=%s actual_cost=%.4f%s",
-			run.ID, r.taskID, r.result.Usage.Cost.Amount, r.result.Usage.Cost.Currency)
+		if run.Policy.BudgetDisabled {
+			// Trusted mode: track cost for reporting without enforcing limits
+			total, err := run.Usage.Cost.Add(r.result.Usage.Cost)
+			if err != nil {
+				task.State = contracts.TaskFailed
+				task.Error = &contracts.TaskError{
+					Code:    "currency_mismatch",
+					Message: err.Error(),
+				}
+				return fmt.Errorf("task %s: %w", r.taskID, err)
+			}
+			run.Usage.Cost = total
+		} else if err := o.recordBudget(run, task, r); err != nil {
+			return err
+		}
+		checkSoftLimit(run, r.taskID)
 
 		// Track usage
 		o.usageTracker.Add(run, r.result.Usage)
 
+		// Enforce non-empty output (after usage is tracked: the cost was incurred)
+		if task.RequireNonEmptyOutput && r.result.Output == "" {
+			task.State = contracts.TaskFailed
+			task.Error = &contracts.TaskError{
+				Code:    "empty_output",
+				Message: "executor returned empty output",
+			}
+			durationMs := o.since(r.startTime).Milliseconds()
+			logRunEvent(run, "task_failed", audit.Fields{"task_id": r.taskID, "duration_ms": durationMs, "error_code": "empty_output"})
+			if o.continueAfterFailure(run, task) {
+				continue
+			}
+			return fmt.Errorf("task %s: empty output: %w", r.taskID, contracts.ErrTaskFailed)
+		}
+
 		// Scheduler.MarkComplete: sets task.State = Completed, task.Outputs = result
 		// This is the ONLY place where task state becomes Completed
 		if err := o.scheduler.MarkComplete(run, r.taskID, r.result); err != nil {
@@ -441,17 +881,25 @@ func (o *orchestrator) mergeBatchResults(run *contracts.Run, results []batchResu
 				Code:    "scheduler_error",
 				Message: err.Error(),
 			}
-			durationMs := time.Since(r.startTime).Milliseconds()
-			audit.Log("event=task_failed run_id=%s task_id=%s duration_ms=%d error_code=scheduler_error error_msg=%s",
-				run.ID, r.taskID, durationMs, err.Error())
+			durationMs := o.since(r.startTime).Milliseconds()
+			logRunEvent(run, "task_failed", audit.Fields{
+				"task_id":     r.taskID,
+				"duration_ms": durationMs,
+				"error_code":  "scheduler_error",
+				"error_msg":   err.Error(),
+			})
 			return fmt.Errorf("task %s scheduler error: %w", r.taskID, err)
 		}
 
 		// Task completed successfully - log after all finalization steps
-		durationMs := time.Since(r.startTime).Milliseconds()
-		audit.Log("event=task_completed run_id=%s task_id=%s duration_ms=%d tokens=%d cost=%.4f%s",
-			run.ID, r.taskID, durationMs, r.result.Usage.Tokens,
-			r.result.Usage.Cost.Amount, r.result.Usage.Cost.Currency)
+		durationMs := o.since(r.startTime).Milliseconds()
+		logRunEvent(run, "task_completed", audit.Fields{
+			"task_id":     r.taskID,
+			"duration_ms": durationMs,
+			"tokens":      r.result.Usage.Tokens,
+			"cost":        r.result.Usage.Cost.Amount,
+			"currency":    r.result.Usage.Cost.Currency,
+		})
 
 		// Route to dependents: iterate DAG.Nodes[taskID].Next
 		// Routing errors are FATAL — inconsistent context state
@@ -483,6 +931,595 @@ func (o *orchestrator) mergeBatchResults(run *contracts.Run, results []batchResu
 	return nil
 }
 
+// batchEstimate is the pre-check estimate of one task in a ready batch.
+type batchEstimate struct {
+	tokens contracts.TokenCount
+	cost   contracts.Cost
+	denied *deniedResult
+}
+
+// estimateBatch estimates the cost of each existing task in taskIDs.
+// Up to Policy.MaxContextBuilds estimations run concurrently; each holds its
+// slot from building the context bundle until tokens are counted, so at most
+// that many bundles are alive at once. Results are indexed like taskIDs;
+// entries for missing tasks are left zero (the caller denies them).
+func (o *orchestrator) estimateBatch(run *contracts.Run, taskIDs []contracts.TaskID) []batchEstimate {
+	estimates := make([]batchEstimate, len(taskIDs))
+	estimate := func(i int, tid contracts.TaskID, task *contracts.Task) {
+		// Cached across iterations when an EstimateCache is set
+		e := &estimates[i]
+		e.tokens, e.cost, e.denied = o.estimateCost(run, tid, task)
+	}
+
+	limit := run.Policy.MaxContextBuilds
+	if limit <= 1 || len(taskIDs) <= 1 {
+		for i, tid := range taskIDs {
+			if task, exists := run.Tasks[tid]; exists {
+				estimate(i, tid, task)
+			}
+		}
+		return estimates
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, tid := range taskIDs {
+		task, exists := run.Tasks[tid]
+		if !exists {
+			continue
+		}
+		// Acquire before spawning so waiting tasks don't hold goroutines either
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			estimate(i, tid, task)
+		}()
+	}
+	wg.Wait()
+	return estimates
+}
+
+// estimateCost builds and compacts the task's context and estimates its cost.
+// Returns a deniedResult if any step fails. Successful estimates are cached
+// when an EstimateCache is configured; failures are never cached.
+func (o *orchestrator) estimateCost(run *contracts.Run, tid contracts.TaskID, task *contracts.Task) (contracts.TokenCount, contracts.Cost, *deniedResult) {
+	var key uint64
+	if o.estimateCache != nil {
+		key = o.estimateCache.Key(run, task)
+		if cached, ok := o.estimateCache.Get(run.ID, tid, key); ok {
+			return cached.Tokens, cached.Cost, nil
+		}
+	}
+
+	// Build and compact context for estimation
+	compacted, dr := o.compactedContext(run, tid, task)
+	if dr == nil {
+		dr = o.checkContextCap(tid, task, compacted)
+	}
+	if dr != nil {
+		return 0, contracts.Cost{}, dr
+	}
+
+	// Estimate tokens, split into input and expected output when supported
+	var in, out contracts.TokenCount
+	var err error
+	if split, ok := o.tokenEstimator.(contracts.SplitTokenEstimator); ok {
+		in, out, err = split.EstimateSplit(task.Inputs, compacted)
+	} else {
+		in, err = o.tokenEstimator.Estimate(task.Inputs, compacted)
+	}
+	if err != nil {
+		return 0, contracts.Cost{}, &deniedResult{
+			taskID:    tid,
+			errorCode: "token_estimation_failed",
+			errorMsg:  fmt.Sprintf("failed to estimate tokens: %v", err),
+			err:       err,
+		}
+	}
+	tokens := in + out
+
+	// Estimate the combined cost, pricing input and output separately when
+	// both are known and the calculator supports it
+	var cost contracts.Cost
+	if split, ok := o.costCalc.(contracts.SplitCostCalculator); ok && out > 0 {
+		cost, err = split.EstimateSplit(in, out, task.Model)
+	} else {
+		cost, err = o.costCalc.Estimate(tokens, task.Model)
+	}
+	if err != nil {
+		return 0, contracts.Cost{}, &deniedResult{
+			taskID:    tid,
+			errorCode: "model_unknown",
+			errorMsg:  fmt.Sprintf("failed to estimate cost for model %s: %v", task.Model, err),
+			err:       err,
+		}
+	}
+
+	if o.estimateCache != nil {
+		o.estimateCache.Put(run.ID, tid, key, Estimate{Tokens: tokens, Cost: cost})
+	}
+	return tokens, cost, nil
+}
+
+// compactedContext builds the task's context bundle and compacts it, forcing
+// compaction if it nears the model's window.
+func (o *orchestrator) compactedContext(run *contracts.Run, tid contracts.TaskID, task *contracts.Task) (*contracts.ContextBundle, *deniedResult) {
+	bundle, err := o.contextBuilder.Build(run, tid)
+	if err != nil {
+		return nil, &deniedResult{
+			taskID:    tid,
+			errorCode: "context_build_failed",
+			errorMsg:  fmt.Sprintf("failed to build context: %v", err),
+			err:       err,
+		}
+	}
+
+	compacted, err := o.compactWithTimeout(run, tid, task, bundle)
+	if errors.Is(err, contracts.ErrContextTimeout) {
+		return nil, &deniedResult{
+			taskID:    tid,
+			errorCode: "context_timeout",
+			errorMsg:  err.Error(),
+			err:       err,
+		}
+	}
+	if err != nil {
+		return nil, &deniedResult{
+			taskID:    tid,
+			errorCode: "context_compact_failed",
+			errorMsg:  fmt.Sprintf("failed to compact context: %v", err),
+			err:       err,
+		}
+	}
+	return compacted, nil
+}
+
+// checkContextCap returns a context_too_large deniedResult if the compacted
+// context bundle exceeds the task's MaxContextTokens. Only the bundle is
+// counted, not the prompt and inputs.
+func (o *orchestrator) checkContextCap(tid contracts.TaskID, task *contracts.Task, compacted *contracts.ContextBundle) *deniedResult {
+	if task.MaxContextTokens <= 0 {
+		return nil
+	}
+	tokens, err := o.tokenEstimator.Estimate(&contracts.TaskInput{}, compacted)
+	if err != nil {
+		return &deniedResult{
+			taskID:    tid,
+			errorCode: "token_estimation_failed",
+			errorMsg:  fmt.Sprintf("failed to estimate context tokens: %v", err),
+			err:       err,
+		}
+	}
+	if tokens <= task.MaxContextTokens {
+		return nil
+	}
+	err = fmt.Errorf("task %s context has %d tokens after compaction, exceeds max_context_tokens %d: %w",
+		tid, tokens, task.MaxContextTokens, contracts.ErrContextTooLarge)
+	return &deniedResult{
+		taskID:    tid,
+		errorCode: "context_too_large",
+		errorMsg:  err.Error(),
+		err:       err,
+	}
+}
+
+// takeCancel removes and returns the pending cancel request for a task.
+func (o *orchestrator) takeCancel(taskID contracts.TaskID) (CancelMode, bool) {
+	if o.canceller == nil {
+		return "", false
+	}
+	return o.canceller.take(taskID)
+}
+
+// applyBudgetOverride applies a pending budget limit change at a batch boundary.
+func (o *orchestrator) applyBudgetOverride(run *contracts.Run) {
+	if o.budgetOverride == nil {
+		return
+	}
+	limit, ok := o.budgetOverride.take()
+	if !ok {
+		return
+	}
+	logRunEvent(run, "budget_updated", audit.Fields{
+		"old_limit": run.Policy.BudgetLimit.Amount,
+		"new_limit": limit.Amount,
+		"currency":  limit.Currency,
+	})
+	run.Policy.BudgetLimit = limit
+}
+
+// applyCancels applies cancel requests at a batch boundary, when no task is
+// running. Requests for tasks that already finished are dropped.
+func (o *orchestrator) applyCancels(run *contracts.Run) error {
+	if o.canceller == nil {
+		return nil
+	}
+	requests := o.canceller.takeAll()
+	ids := make([]contracts.TaskID, 0, len(requests))
+	for id := range requests {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, tid := range ids {
+		task, exists := run.Tasks[tid]
+		if !exists || isTerminal(task.State) {
+			continue
+		}
+		if err := o.cancelTask(run, tid, requests[tid]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// continueAfterFailure reports whether the run goes on after task failed.
+// For a task with ContinueOnFail it does: the failure is kept, and every
+// task depending on it (directly or transitively) is skipped with code
+// dependency_failed instead of failing the run.
+func (o *orchestrator) continueAfterFailure(run *contracts.Run, task *contracts.Task) bool {
+	if !task.ContinueOnFail {
+		return false
+	}
+	logRunEvent(run, "task_failure_tolerated", audit.Fields{"task_id": task.ID, "error_code": task.Error.Code})
+
+	queue := []contracts.TaskID{task.ID}
+	for len(queue) > 0 {
+		failed := queue[0]
+		queue = queue[1:]
+		node, exists := run.DAG.Nodes[failed]
+		if !exists {
+			continue
+		}
+		for _, nextID := range node.Next {
+			next, ok := run.Tasks[nextID]
+			if !ok || isTerminal(next.State) {
+				continue
+			}
+			next.State = contracts.TaskSkipped
+			next.Error = &contracts.TaskError{
+				Code:    "dependency_failed",
+				Message: fmt.Sprintf("dependency %s failed", failed),
+			}
+			logRunEvent(run, "task_skipped", audit.Fields{"task_id": nextID, "reason": "dependency_failed", "dependency": failed})
+			queue = append(queue, nextID)
+		}
+	}
+	return true
+}
+
+// cancelTask applies a cancel request to a task that did not complete.
+// Hard: the task fails with code cancelled and an error is returned (fail-fast).
+// Soft: the task is skipped with code soft_cancelled and an empty output is
+// routed to its dependents, so they run without it.
+func (o *orchestrator) cancelTask(run *contracts.Run, tid contracts.TaskID, mode CancelMode) error {
+	task := run.Tasks[tid]
+	logRunEvent(run, "task_cancelled", audit.Fields{"task_id": tid, "mode": mode})
+
+	if mode != CancelSoft {
+		task.State = contracts.TaskFailed
+		task.Error = &contracts.TaskError{
+			Code:    "cancelled",
+			Message: "task cancelled",
+		}
+		return fmt.Errorf("task %s: %w", tid, contracts.ErrTaskCancelled)
+	}
+
+	task.State = contracts.TaskSkipped
+	task.Error = &contracts.TaskError{
+		Code:    "soft_cancelled",
+		Message: "task cancelled; dependents run without its output",
+	}
+	return o.routeSkipped(run, tid)
+}
+
+// routeSkipped routes an empty output from a skipped task to its dependents
+// and releases their dependency on it, so they run without its output.
+func (o *orchestrator) routeSkipped(run *contracts.Run, tid contracts.TaskID) error {
+	node, exists := run.DAG.Nodes[tid]
+	if !exists {
+		return nil
+	}
+	for _, nextID := range node.Next {
+		if err := o.router.Route(run, tid, nextID, &contracts.TaskResult{}); err != nil {
+			return fmt.Errorf("routing from %s to %s failed: %w", tid, nextID, err)
+		}
+		if next, ok := run.DAG.Nodes[nextID]; ok && next.Pending > 0 {
+			next.Pending--
+		}
+	}
+	return nil
+}
+
+// compact applies the run's context policy to a task's context bundle.
+// When the assembled context exceeds ContextPolicy.ForceCompactRatio of the
+// model's window, compaction is forced with the configured strategy (truncate
+// if none), capped to the window share left after the prompt and inputs.
+// If forced compaction fails, the run's own policy is applied instead.
+func (o *orchestrator) compact(run *contracts.Run, tid contracts.TaskID, task *contracts.Task, bundle *contracts.ContextBundle) (*contracts.ContextBundle, error) {
+	policy := run.Policy.ContextPolicy
+	limit := o.forceCompactLimit(policy, task.Model)
+	if limit <= 0 {
+		return o.compactor.Compact(bundle, policy)
+	}
+
+	assembled, err := o.tokenEstimator.Estimate(task.Inputs, bundle)
+	if err != nil || assembled <= limit {
+		return o.compactor.Compact(bundle, policy)
+	}
+	inputs, err := o.tokenEstimator.Estimate(task.Inputs, nil)
+	if err != nil {
+		return o.compactor.Compact(bundle, policy)
+	}
+
+	forced := policy
+	if forced.Strategy == "" || forced.Strategy == ctxpkg.StrategyNone {
+		forced.Strategy = ctxpkg.StrategyTruncate
+	}
+	if room := max(limit-inputs, 1); forced.MaxTokens <= 0 || forced.MaxTokens > room {
+		forced.MaxTokens = room
+	}
+
+	compacted, err := o.compactor.Compact(bundle, forced)
+	if err != nil {
+		logRunEvent(run, "context_compaction_forced", audit.Fields{
+			"task_id":   tid,
+			"tokens":    assembled,
+			"limit":     limit,
+			"strategy":  forced.Strategy,
+			"result":    "failed",
+			"error_msg": err.Error(),
+		})
+		return o.compactor.Compact(bundle, policy)
+	}
+	logRunEvent(run, "context_compaction_forced", audit.Fields{
+		"task_id":  tid,
+		"tokens":   assembled,
+		"limit":    limit,
+		"strategy": forced.Strategy,
+		"result":   "ok",
+	})
+	return compacted, nil
+}
+
+// compactWithTimeout runs compact, giving up after run.Policy.ContextTimeoutMs
+// (0 = no limit). Compaction only works on the task's bundle, so one that
+// times out is left to finish in the background and its result is dropped.
+func (o *orchestrator) compactWithTimeout(run *contracts.Run, tid contracts.TaskID, task *contracts.Task, bundle *contracts.ContextBundle) (*contracts.ContextBundle, error) {
+	timeout := time.Duration(run.Policy.ContextTimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		return o.compact(run, tid, task, bundle)
+	}
+
+	type compacted struct {
+		bundle *contracts.ContextBundle
+		err    error
+	}
+	done := make(chan compacted, 1)
+	go func() {
+		bundle, err := o.compact(run, tid, task, bundle)
+		done <- compacted{bundle, err}
+	}()
+
+	select {
+	case c := <-done:
+		return c.bundle, c.err
+	case <-o.clock.After(timeout):
+		return nil, fmt.Errorf("compacting context of task %s took longer than %s: %w", tid, timeout, contracts.ErrContextTimeout)
+	}
+}
+
+// forceCompactLimit returns the assembled context size above which compaction
+// is forced for model, or 0 if the guard is disabled or the model is unknown.
+func (o *orchestrator) forceCompactLimit(policy contracts.ContextPolicy, model contracts.ModelID) contracts.TokenCount {
+	if policy.ForceCompactRatio <= 0 || o.modelCatalog == nil {
+		return 0
+	}
+	info, ok := o.modelCatalog.Get(model)
+	if !ok || info.MaxContext <= 0 {
+		return 0
+	}
+	return contracts.TokenCount(float64(info.MaxContext) * policy.ForceCompactRatio)
+}
+
+// recordBudget records actual cost against the run (and per-model) budgets.
+// On failure marks the task failed and returns the fail-fast error.
+func (o *orchestrator) recordBudget(run *contracts.Run, task *contracts.Task, r batchResult) error {
+	// Record budget (may fail if over budget post-execution)
+	if err := o.budgetEnforcer.Record(run, r.result.Usage.Cost); err != nil {
+		code := "budget_exceeded"
+		switch {
+		case errors.Is(err, contracts.ErrBudgetPoolExhausted):
+			code = "budget_pool_exhausted"
+		case errors.Is(err, contracts.ErrCurrencyMismatch):
+			code = "currency_mismatch"
+		}
+		task.State = contracts.TaskFailed
+		task.Error = &contracts.TaskError{
+			Code:    code,
+			Message: err.Error(),
+		}
+		logRunEvent(run, "budget_record_failed", audit.Fields{
+			"task_id":     r.taskID,
+			"actual_cost": r.result.Usage.Cost.Amount,
+			"currency":    r.result.Usage.Cost.Currency,
+			"reason":      "exceeded",
+		})
+		return fmt.Errorf("task %s budget exceeded: %w", r.taskID, err)
+	}
+
+	// Record per-model usage (may fail if over model budget post-execution)
+	if modelEnforcer, ok := o.budgetEnforcer.(contracts.ModelBudgetEnforcer); ok {
+		if err := modelEnforcer.RecordModel(run, task.Model, r.result.Usage); err != nil {
+			code := "model_budget_exceeded"
+			if errors.Is(err, contracts.ErrCurrencyMismatch) {
+				code = "currency_mismatch"
+			}
+			task.State = contracts.TaskFailed
+			task.Error = &contracts.TaskError{
+				Code:    code,
+				Message: err.Error(),
+			}
+			logRunEvent(run, "budget_record_failed", audit.Fields{
+				"task_id":     r.taskID,
+				"model":       task.Model,
+				"actual_cost": r.result.Usage.Cost.Amount,
+				"currency":    r.result.Usage.Cost.Currency,
+				"reason":      "model_exceeded",
+			})
+			return fmt.Errorf("task %s model budget exceeded: %w", r.taskID, err)
+		}
+	}
+
+	// Budget record succeeded
+	logRunEvent(run, "budget_record_ok", audit.Fields{
+		"task_id":     r.taskID,
+		"actual_cost": r.result.Usage.Cost.Amount,
+		"currency":    r.result.Usage.Cost.Currency,
+	})
+	return nil
+}
+
+// checkResultCurrency gives a result cost without a currency the run's
+// currency: that of its usage so far, else of its budget. A cost in another
+// currency fails the task with currency_mismatch unless the budget enforcer
+// converts it; otherwise the first result would set the currency the run's
+// usage is kept in, and later ones would be compared against the wrong budget.
+func (o *orchestrator) checkResultCurrency(run *contracts.Run, task *contracts.Task, r batchResult) error {
+	currency := run.Usage.Cost.Currency
+	if currency == "" {
+		currency = run.Policy.BudgetLimit.Currency
+	}
+	cost := &r.result.Usage.Cost
+	if cost.Currency == "" {
+		cost.Currency = currency
+		return nil
+	}
+	if currency == "" || cost.Currency == currency {
+		return nil
+	}
+	if conv, ok := o.budgetEnforcer.(contracts.CurrencyConvertingEnforcer); ok && conv.ConvertsCurrency() && !run.Policy.BudgetDisabled {
+		return nil
+	}
+
+	msg := fmt.Sprintf("executor reported cost in %s, run currency is %s", cost.Currency, currency)
+	task.State = contracts.TaskFailed
+	task.Error = &contracts.TaskError{
+		Code:    "currency_mismatch",
+		Message: msg,
+	}
+	logRunEvent(run, "task_failed", audit.Fields{
+		"task_id":     r.taskID,
+		"duration_ms": o.since(r.startTime).Milliseconds(),
+		"error_code":  "currency_mismatch",
+		"error_msg":   msg,
+	})
+	return fmt.Errorf("task %s: %s: %w", r.taskID, msg, contracts.ErrCurrencyMismatch)
+}
+
+// withAbortGrace returns a context for executor calls that is cancelled grace
+// after ctx is done, as timed by clock. It carries ctx as the dispatch context, so tasks still
+// waiting for an executor slot when ctx is done are not started.
+func withAbortGrace(ctx context.Context, clock Clock, grace time.Duration) (context.Context, context.CancelFunc) {
+	graceCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-graceCtx.Done():
+			return
+		}
+		select {
+		case <-clock.After(grace):
+			cancel()
+		case <-graceCtx.Done():
+		}
+	}()
+	return withDispatchContext(graceCtx, ctx), cancel
+}
+
+// runSummary adds task outcome counts and total usage for a run's final
+// audit event to fields, so one line tells how the run ended.
+func runSummary(run *contracts.Run, fields audit.Fields) audit.Fields {
+	counts := contracts.CountTasks(run.Tasks)
+	fields["tasks_completed"] = counts.Completed
+	fields["tasks_failed"] = counts.Failed
+	fields["tasks_skipped"] = counts.Skipped
+	fields["tasks_unfinished"] = counts.Unfinished
+	fields["total_tokens"] = run.Usage.Tokens
+	fields["total_cost"] = run.Usage.Cost.Amount
+	fields["total_currency"] = run.Usage.Cost.Currency
+	return fields
+}
+
+// logRunEvent logs a structured audit event for run, adding its run and
+// trace IDs to fields.
+func logRunEvent(run *contracts.Run, event string, fields audit.Fields) {
+	fields["run_id"] = run.ID
+	fields["trace_id"] = run.TraceID
+	audit.LogEvent(event, fields)
+}
+
+// since returns the time elapsed on the orchestrator's clock since t.
+func (o *orchestrator) since(t time.Time) time.Duration {
+	return o.clock.Now().Sub(t)
+}
+
+// logRunEnd logs a run's final audit event with its duration and runSummary.
+func (o *orchestrator) logRunEnd(run *contracts.Run, event string, fields audit.Fields) {
+	run.FinishedAt = contracts.Timestamp(o.clock.Now().UnixMilli())
+	fields["duration_ms"] = o.since(o.runStart).Milliseconds()
+	logRunEvent(run, event, runSummary(run, fields))
+}
+
+// dropNotReady returns ids without tasks that are not pending or ready,
+// logging each dropped task as a scheduler inconsistency. Unknown task IDs
+// are kept so they fail as before. Returns ids unchanged if nothing is dropped.
+func (o *orchestrator) dropNotReady(run *contracts.Run, ids []contracts.TaskID, batchNum int) []contracts.TaskID {
+	var out []contracts.TaskID
+	for i, id := range ids {
+		task, ok := run.Tasks[id]
+		if ok && task.State != contracts.TaskPending && task.State != contracts.TaskReady {
+			if out == nil {
+				out = append(make([]contracts.TaskID, 0, len(ids)), ids[:i]...)
+			}
+			logRunEvent(run, "scheduler_inconsistency", audit.Fields{"batch": batchNum, "task_id": id, "state": task.State.String()})
+			continue
+		}
+		if out != nil {
+			out = append(out, id)
+		}
+	}
+	if out == nil {
+		return ids
+	}
+	return out
+}
+
+// dedupTaskIDs returns ids without repeats, keeping the first occurrence and order.
+// Returns ids unchanged if there are no duplicates.
+func dedupTaskIDs(ids []contracts.TaskID) []contracts.TaskID {
+	seen := make(map[contracts.TaskID]struct{}, len(ids))
+	var out []contracts.TaskID
+	for i, id := range ids {
+		if _, dup := seen[id]; dup {
+			if out == nil {
+				out = append(make([]contracts.TaskID, 0, len(ids)), ids[:i]...)
+			}
+			continue
+		}
+		seen[id] = struct{}{}
+		if out != nil {
+			out = append(out, id)
+		}
+	}
+	if out == nil {
+		return ids
+	}
+	return out
+}
+
 // isTerminal checks if a task state is terminal (no further processing needed).
 func isTerminal(state contracts.TaskState) bool {
 	return state == contracts.TaskCompleted ||
@@ -500,10 +1537,47 @@ func (o *orchestrator) allTerminal(run *contracts.Run) bool {
 	return true
 }
 
-// hasFailures checks if any task has failed.
-func (o *orchestrator) hasFailures(run *contracts.Run) bool {
+// failureThresholdExceeded reports whether more tasks have failed than
+// run.Policy.MaxFailures allows, with the failure count and the limit as a
+// task count. A fractional MaxFailures is applied to the number of tasks.
+func failureThresholdExceeded(run *contracts.Run) (int, float64, bool) {
+	limit := run.Policy.MaxFailures
+	if limit <= 0 {
+		return 0, 0, false
+	}
+	if limit < 1 {
+		limit *= float64(len(run.Tasks))
+	}
+	failed := 0
 	for _, task := range run.Tasks {
 		if task.State == contracts.TaskFailed {
+			failed++
+		}
+	}
+	return failed, limit, float64(failed) > limit
+}
+
+// checkSoftLimit sets run.BudgetWarning the first time the run's cost reaches
+// run.Policy.SoftLimit. It only warns; BudgetLimit is enforced as before.
+func checkSoftLimit(run *contracts.Run, taskID contracts.TaskID) {
+	soft := run.Policy.SoftLimit
+	if run.BudgetWarning || soft.Amount <= 0 || run.Usage.Cost.Amount < soft.Amount {
+		return
+	}
+	run.BudgetWarning = true
+	logRunEvent(run, "budget_warning", audit.Fields{
+		"task_id":      taskID,
+		"usage":        run.Usage.Cost.Amount,
+		"soft_limit":   soft.Amount,
+		"budget_limit": run.Policy.BudgetLimit.Amount,
+		"currency":     run.Policy.BudgetLimit.Currency,
+	})
+}
+
+// hasFailures checks if any task has failed, ignoring ContinueOnFail tasks.
+func (o *orchestrator) hasFailures(run *contracts.Run) bool {
+	for _, task := range run.Tasks {
+		if task.State == contracts.TaskFailed && !task.ContinueOnFail {
 			return true
 		}
 	}
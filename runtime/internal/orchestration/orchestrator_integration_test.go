@@ -1,15 +1,21 @@
 package orchestration
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"os"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/anthropics/claude-workflow/runtime/contracts"
+	"github.com/anthropics/claude-workflow/runtime/internal/audit"
 	ctxpkg "github.com/anthropics/claude-workflow/runtime/internal/context"
 	"github.com/anthropics/claude-workflow/runtime/internal/cost"
 )
@@ -154,6 +160,38 @@ func defaultPolicy() contracts.RunPolicy {
 	}
 }
 
+// buildRun builds the DAG of specs (IDs and deps) through the real resolver
+// and returns a run of haiku tasks with 40-character prompts.
+func buildRun(t *testing.T, id contracts.RunID, policy contracts.RunPolicy, specs ...contracts.Task) *contracts.Run {
+	t.Helper()
+	dag, err := NewDependencyResolver().BuildDAG(specs)
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	return createRun(id, dag, createTasksFromDAG(dag, 40), policy)
+}
+
+// fixedExecutor returns output and 100 tokens costing c for every task.
+func fixedExecutor(output string, c contracts.Cost) TaskExecutorFunc {
+	return func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		return &contracts.TaskResult{
+			Output: output,
+			Usage:  contracts.Usage{Tokens: 100, Cost: c},
+		}, nil
+	}
+}
+
+// fixedRates converts at fixed rates keyed "FROM->TO".
+type fixedRates map[string]float64
+
+func (f fixedRates) Convert(amount float64, from, to string) (float64, error) {
+	rate, ok := f[from+"->"+to]
+	if !ok {
+		return 0, fmt.Errorf("no rate for %s to %s", from, to)
+	}
+	return amount * rate, nil
+}
+
 // =============================================================================
 // Assertions
 // =============================================================================
@@ -394,6 +432,9 @@ func TestIntegration_SingleTask(t *testing.T) {
 	assertRunCompleted(t, run)
 	assertAllTasksCompleted(t, run)
 	assertTotalTokens(t, run, 100)
+	if run.StartedAt == 0 || run.FinishedAt < run.StartedAt {
+		t.Errorf("StartedAt = %d, FinishedAt = %d, want 0 < StartedAt <= FinishedAt", run.StartedAt, run.FinishedAt)
+	}
 }
 
 // TestIntegration_EmptyDAG tests empty DAG (no tasks)
@@ -512,6 +553,62 @@ func TestIntegration_BudgetExceeded(t *testing.T) {
 	}
 }
 
+// TestIntegration_ModelBudgetExceeded tests that a per-model cap stops an
+// expensive model while the overall run budget still has room.
+func TestIntegration_ModelBudgetExceeded(t *testing.T) {
+	dag, err := buildLinearDAG([]contracts.TaskID{"A", "B"})
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+
+	tasks := createTasksFromDAG(dag, 400) // 400 chars = 100 base tokens
+	for _, task := range tasks {
+		task.Model = "claude-opus-4-20250514" // avg 45.0 per 1M
+	}
+
+	// Cost calculation (opus, avg 45.0 per 1M):
+	// A: 100 tokens -> estimate 0.0045, actual (stub) 0.000075
+	// B: 102 tokens -> estimate 0.00459, projected 0.000075 + 0.00459 = 0.004665
+	//
+	// Model cap 0.0046 allows A but denies B; run budget 1.0 is never reached.
+	policy := contracts.RunPolicy{
+		MaxParallelism: 1,
+		BudgetLimit:    contracts.Cost{Amount: 1.0, Currency: "USD"},
+		ModelBudgets: map[contracts.ModelID]contracts.Cost{
+			"claude-opus-4-20250514": {Amount: 0.0046, Currency: "USD"},
+		},
+	}
+
+	run := createRun("run-model-budget", dag, tasks, policy)
+
+	stub := newStubExecutor()
+	deps := createRealDeps(policy, stub.Execute)
+
+	orch := NewOrchestrator(deps)
+	err = orch.Run(context.Background(), run)
+
+	if !errors.Is(err, contracts.ErrModelBudgetExceeded) {
+		t.Fatalf("expected ErrModelBudgetExceeded, got %v", err)
+	}
+
+	assertRunFailed(t, run)
+	assertTaskCompleted(t, run, "A")
+
+	taskB := run.Tasks["B"]
+	if taskB.Error == nil || taskB.Error.Code != "model_budget_exceeded" {
+		t.Errorf("expected task B error with code model_budget_exceeded, got %+v", taskB.Error)
+	}
+
+	// Per-model usage tracked on the run
+	opusUsage := run.ModelUsage["claude-opus-4-20250514"]
+	if opusUsage.Tokens != 100 {
+		t.Errorf("expected 100 opus tokens, got %d", opusUsage.Tokens)
+	}
+	if run.Usage.Cost.Amount >= policy.BudgetLimit.Amount {
+		t.Errorf("run budget should not be exhausted, usage %v", run.Usage.Cost.Amount)
+	}
+}
+
 // TestIntegration_TaskFailure tests run failure when a task fails
 func TestIntegration_TaskFailure(t *testing.T) {
 	dag, err := buildLinearDAG([]contracts.TaskID{"A", "B", "C"})
@@ -544,6 +641,331 @@ func TestIntegration_TaskFailure(t *testing.T) {
 	}
 }
 
+// TestIntegration_BudgetPoolSharedAcrossRuns tests that two runs labeled with the
+// same budget pool collectively exhaust it, even though each run budget has room.
+func TestIntegration_BudgetPoolSharedAcrossRuns(t *testing.T) {
+	// Cost calculation (opus, avg 45.0 per 1M):
+	// run-1 A: estimate 0.0045 (pool usage 0) -> allowed, actual (stub) 0.000075
+	// run-2 A: estimate 0.0045 + pool usage 0.000075 = 0.004575 > 0.00455 -> denied
+	pool := cost.NewBudgetPool(map[string]contracts.Cost{
+		"team-a": {Amount: 0.00455, Currency: "USD"},
+	})
+	policy := contracts.RunPolicy{
+		MaxParallelism: 1,
+		BudgetLimit:    contracts.Cost{Amount: 1.0, Currency: "USD"},
+		BudgetPool:     "team-a",
+	}
+
+	runWithPool := func(id contracts.RunID) (*contracts.Run, error) {
+		dag, err := buildLinearDAG([]contracts.TaskID{"A"})
+		if err != nil {
+			t.Fatalf("BuildDAG failed: %v", err)
+		}
+		tasks := createTasksFromDAG(dag, 400)
+		for _, task := range tasks {
+			task.Model = "claude-opus-4-20250514"
+		}
+		run := createRun(id, dag, tasks, policy)
+
+		deps := createRealDeps(policy, newStubExecutor().Execute)
+		deps.BudgetEnforcer = cost.NewBudgetEnforcerWithPool(pool)
+		return run, NewOrchestrator(deps).Run(context.Background(), run)
+	}
+
+	run1, err := runWithPool("run-pool-1")
+	if err != nil {
+		t.Fatalf("run-pool-1: expected no error, got %v", err)
+	}
+	assertTaskCompleted(t, run1, "A")
+
+	// run-1 no longer holds the unspent part of its estimate
+	if err := pool.Allow("team-a", contracts.Cost{Amount: 0.004, Currency: "USD"}); err != nil {
+		t.Errorf("run-1 reservation not released: %v", err)
+	}
+
+	run2, err := runWithPool("run-pool-2")
+	if !errors.Is(err, contracts.ErrBudgetPoolExhausted) {
+		t.Fatalf("run-pool-2: expected ErrBudgetPoolExhausted, got %v", err)
+	}
+	assertRunFailed(t, run2)
+	if task := run2.Tasks["A"]; task.Error == nil || task.Error.Code != "budget_pool_exhausted" {
+		t.Errorf("expected task error with code budget_pool_exhausted, got %+v", task.Error)
+	}
+}
+
+// TestIntegration_EmptyOutput tests RequireNonEmptyOutput: an empty Output fails
+// the task with empty_output only when the flag is set.
+func TestIntegration_EmptyOutput(t *testing.T) {
+	emptyOutput := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		return &contracts.TaskResult{
+			Output: "",
+			Usage: contracts.Usage{
+				Tokens: 100,
+				Cost:   contracts.Cost{Amount: 0.000075, Currency: "USD"},
+			},
+		}, nil
+	}
+
+	for _, require := range []bool{false, true} {
+		t.Run(fmt.Sprintf("require=%v", require), func(t *testing.T) {
+			dag, err := buildLinearDAG([]contracts.TaskID{"A"})
+			if err != nil {
+				t.Fatalf("BuildDAG failed: %v", err)
+			}
+			tasks := createTasksFromDAG(dag, 400)
+			tasks["A"].RequireNonEmptyOutput = require
+			policy := defaultPolicy()
+			run := createRun("run-empty-output", dag, tasks, policy)
+
+			orch := NewOrchestrator(createRealDeps(policy, emptyOutput))
+			err = orch.Run(context.Background(), run)
+
+			if !require {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				assertTaskCompleted(t, run, "A")
+				return
+			}
+
+			if !errors.Is(err, contracts.ErrTaskFailed) {
+				t.Fatalf("expected ErrTaskFailed, got %v", err)
+			}
+			assertRunFailed(t, run)
+			if task := run.Tasks["A"]; task.Error == nil || task.Error.Code != "empty_output" {
+				t.Errorf("expected task error with code empty_output, got %+v", task.Error)
+			}
+			// Cost was incurred and is still accounted
+			if run.Usage.Cost.Amount == 0 {
+				t.Error("expected usage to be recorded for the failed task")
+			}
+		})
+	}
+}
+
+// TestIntegration_BudgetDisabled tests that a run with BudgetDisabled executes
+// past its budget while usage is still tracked.
+func TestIntegration_BudgetDisabled(t *testing.T) {
+	dag, err := buildLinearDAG([]contracts.TaskID{"A", "B", "C"})
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	tasks := createTasksFromDAG(dag, 400)
+	policy := contracts.RunPolicy{
+		MaxParallelism: 1,
+		BudgetLimit:    contracts.Cost{Amount: 0.00001, Currency: "USD"},
+		BudgetDisabled: true,
+	}
+	run := createRun("run-budget-disabled", dag, tasks, policy)
+
+	orch := NewOrchestrator(createRealDeps(policy, nil))
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("expected no error with budget disabled, got %v", err)
+	}
+
+	assertRunCompleted(t, run)
+	if run.Usage.Tokens == 0 {
+		t.Error("expected tokens to be tracked")
+	}
+	if run.Usage.Cost.Amount <= policy.BudgetLimit.Amount {
+		t.Errorf("expected tracked cost to exceed the bypassed limit, got %.6f", run.Usage.Cost.Amount)
+	}
+}
+
+// TestIntegration_AbortGrace tests that with AbortGraceMs an in-flight task
+// finishes and is recorded after abort, while queued tasks never start.
+func TestIntegration_AbortGrace(t *testing.T) {
+	tests := []struct {
+		name          string
+		graceMs       int64
+		wantCompleted bool
+	}{
+		{"nearly done task completes", 2000, true},
+		{"grace expires", 20, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A and C are both ready; B depends on A. One slot, so one of A/C waits.
+			dag, err := NewDependencyResolver().BuildDAG([]contracts.Task{
+				{ID: "A"},
+				{ID: "B", Deps: []contracts.TaskID{"A"}},
+				{ID: "C"},
+			})
+			if err != nil {
+				t.Fatalf("BuildDAG failed: %v", err)
+			}
+			tasks := createTasksFromDAG(dag, 400)
+			policy := defaultPolicy()
+			policy.MaxParallelism = 1
+			policy.AbortGraceMs = tt.graceMs
+			run := createRun("run-abort-grace", dag, tasks, policy)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var mu sync.Mutex
+			var started []contracts.TaskID
+			executor := func(execCtx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+				mu.Lock()
+				started = append(started, task.ID)
+				mu.Unlock()
+				cancel() // abort while the first task is in flight
+
+				select {
+				case <-time.After(100 * time.Millisecond):
+				case <-execCtx.Done():
+					return nil, execCtx.Err()
+				}
+				return &contracts.TaskResult{
+					Output: "done",
+					Usage:  contracts.Usage{Tokens: 100, Cost: contracts.Cost{Amount: 0.000075, Currency: "USD"}},
+				}, nil
+			}
+
+			orch := NewOrchestrator(createRealDeps(policy, executor))
+			err = orch.Run(ctx, run)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected context.Canceled, got %v", err)
+			}
+			assertRunAborted(t, run)
+
+			if len(started) != 1 {
+				t.Fatalf("expected exactly one task to start, got %v", started)
+			}
+			first := started[0]
+			queued := contracts.TaskID("C")
+			if first == "C" {
+				queued = "A"
+			}
+			if state := run.Tasks[queued].State; state != contracts.TaskPending {
+				t.Errorf("expected queued task %s to stay pending, got %v", queued, state)
+			}
+			if state := run.Tasks["B"].State; state != contracts.TaskPending {
+				t.Errorf("expected B not to be dispatched, got %v", state)
+			}
+
+			if tt.wantCompleted {
+				assertTaskCompleted(t, run, first)
+				if run.Usage.Tokens != 100 || run.Usage.Cost.Amount == 0 {
+					t.Errorf("expected completed work to be recorded, got %+v", run.Usage)
+				}
+				return
+			}
+			assertTaskFailed(t, run, first)
+			if code := run.Tasks[first].Error.Code; code != "cancelled" {
+				t.Errorf("expected error code cancelled, got %s", code)
+			}
+		})
+	}
+}
+
+// TestIntegration_AbortAfterLastTaskCompletes tests that a cancel arriving after
+// the last task succeeded (but before the run loop observes it) yields RunCompleted.
+func TestIntegration_AbortAfterLastTaskCompletes(t *testing.T) {
+	dag, err := buildLinearDAG([]contracts.TaskID{"A", "B"})
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+
+	tasks := createTasksFromDAG(dag, 400)
+	policy := defaultPolicy()
+	run := createRun("run-abort-race", dag, tasks, policy)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	execute := func(_ context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		return &contracts.TaskResult{
+			Output: fmt.Sprintf("ok:%s", task.ID),
+			Usage: contracts.Usage{
+				Tokens: 100,
+				Cost:   contracts.Cost{Amount: 0.000075, Currency: "USD"},
+			},
+		}, nil
+	}
+
+	// Abort lands after the last batch is merged, before the loop re-checks ctx
+	onProgress := func(r *contracts.Run) {
+		if r.Tasks["B"].State == contracts.TaskCompleted {
+			cancel()
+		}
+	}
+
+	orch := NewOrchestratorWithCallback(createRealDeps(policy, execute), onProgress)
+	if err := orch.Run(ctx, run); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if run.State != contracts.RunCompleted {
+		t.Errorf("expected RunCompleted, got %v", run.State)
+	}
+}
+
+// TestIntegration_ProgressCallbackPanic tests that a panicking progress callback
+// does not crash the run and that the missed update is resynced at the next batch
+// boundary, before later tasks execute.
+func TestIntegration_ProgressCallbackPanic(t *testing.T) {
+	dag, err := buildLinearDAG([]contracts.TaskID{"A", "B", "C"})
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+
+	tasks := createTasksFromDAG(dag, 400)
+	policy := defaultPolicy()
+	run := createRun("run-progress-panic", dag, tasks, policy)
+
+	// shadow mimics the API's shadow state: a copy of task states made by the callback
+	var mu sync.Mutex
+	shadow := make(map[contracts.TaskID]contracts.TaskState)
+	calls := 0
+	onProgress := func(r *contracts.Run) {
+		calls++
+		if calls == 1 {
+			panic("callback failed")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for id, task := range r.Tasks {
+			shadow[id] = task.State
+		}
+	}
+
+	var shadowAtB contracts.TaskState
+	execute := func(_ context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		if task.ID == "B" {
+			mu.Lock()
+			shadowAtB = shadow["A"]
+			mu.Unlock()
+		}
+		return &contracts.TaskResult{
+			Output: fmt.Sprintf("ok:%s", task.ID),
+			Usage: contracts.Usage{
+				Tokens: 100,
+				Cost:   contracts.Cost{Amount: 0.000075, Currency: "USD"},
+			},
+		}, nil
+	}
+
+	orch := NewOrchestratorWithCallback(createRealDeps(policy, execute), onProgress)
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if run.State != contracts.RunCompleted {
+		t.Errorf("expected RunCompleted, got %v", run.State)
+	}
+	if shadowAtB != contracts.TaskCompleted {
+		t.Errorf("shadow state of A when B ran = %v, want completed", shadowAtB)
+	}
+	for id, state := range shadow {
+		if state != contracts.TaskCompleted {
+			t.Errorf("final shadow state of %s = %v, want completed", id, state)
+		}
+	}
+	if len(shadow) != 3 {
+		t.Errorf("expected 3 tasks in shadow, got %d", len(shadow))
+	}
+}
+
 // TestIntegration_ContextCancellation tests run behavior on context cancellation.
 // Depending on timing, cancellation may surface as ErrTaskCancelled (ctx.Done path)
 // or ErrTaskFailed (executor returns ctx.Err and is wrapped). Run should be Failed.
@@ -605,3 +1027,1011 @@ func TestIntegration_ContextCancellation(t *testing.T) {
 	// Run state is Failed (not Aborted) because cancellation happened during task
 	assertRunFailed(t, run)
 }
+
+// peakBuilder wraps a ContextBuilder and records the peak number of
+// concurrent Build calls.
+type peakBuilder struct {
+	contracts.ContextBuilder
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (b *peakBuilder) Build(run *contracts.Run, taskID contracts.TaskID) (*contracts.ContextBundle, error) {
+	b.mu.Lock()
+	b.inFlight++
+	b.peak = max(b.peak, b.inFlight)
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.inFlight--
+		b.mu.Unlock()
+	}()
+
+	// Hold the slot long enough for builds to overlap
+	time.Sleep(time.Millisecond)
+	return b.ContextBuilder.Build(run, taskID)
+}
+
+// TestIntegration_MaxContextBuilds verifies that a wide fan-in ready set is
+// estimated with at most MaxContextBuilds concurrent context builds and that
+// pre-check results match sequential estimation.
+func TestIntegration_MaxContextBuilds(t *testing.T) {
+	const roots, fanIn, limit = 8, 48, 4
+
+	specs := make([]contracts.Task, 0, roots+fanIn)
+	rootIDs := make([]contracts.TaskID, roots)
+	for i := range rootIDs {
+		rootIDs[i] = contracts.TaskID(fmt.Sprintf("root-%d", i))
+		specs = append(specs, contracts.Task{ID: rootIDs[i]})
+	}
+	ready := make([]contracts.TaskID, fanIn)
+	for i := range ready {
+		ready[i] = contracts.TaskID(fmt.Sprintf("fan-%02d", i))
+		specs = append(specs, contracts.Task{ID: ready[i], Deps: rootIDs})
+	}
+	dag, err := NewDependencyResolver().BuildDAG(specs)
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+
+	newRun := func(maxBuilds int) *contracts.Run {
+		tasks := createTasksFromDAG(dag, 100)
+		for _, id := range rootIDs {
+			tasks[id].State = contracts.TaskCompleted
+			tasks[id].Outputs = &contracts.TaskResult{Output: strings.Repeat(string(id), 500)}
+		}
+		// Vary prompt sizes so the budget cut-off depends on reservation order
+		for i, id := range ready {
+			tasks[id].Inputs.Prompt = strings.Repeat("x", 100*(i%5+1))
+		}
+		policy := defaultPolicy()
+		policy.BudgetLimit = contracts.Cost{Amount: 0.005, Currency: "USD"}
+		policy.MaxContextBuilds = maxBuilds
+		run := createRun("run-context-builds", dag, tasks, policy)
+		run.State = contracts.RunRunning
+		return run
+	}
+
+	precheck := func(maxBuilds int) ([]contracts.TaskID, []deniedResult, int) {
+		run := newRun(maxBuilds)
+		deps := createRealDeps(run.Policy, nil)
+		builder := &peakBuilder{ContextBuilder: deps.ContextBuilder}
+		deps.ContextBuilder = builder
+		orch := NewOrchestrator(deps).(*orchestrator)
+		allowed, denied := orch.preCheckBudget(run, ready)
+		return allowed, denied, builder.peak
+	}
+
+	wantAllowed, wantDenied, seqPeak := precheck(0)
+	if seqPeak != 1 {
+		t.Fatalf("expected sequential estimation, got peak %d", seqPeak)
+	}
+	if len(wantAllowed) == 0 || len(wantDenied) == 0 {
+		t.Fatalf("expected budget to split the batch, got %d allowed and %d denied",
+			len(wantAllowed), len(wantDenied))
+	}
+
+	gotAllowed, gotDenied, peak := precheck(limit)
+	if peak > limit {
+		t.Errorf("expected at most %d concurrent builds, got %d", limit, peak)
+	}
+	if peak < 2 {
+		t.Errorf("expected concurrent builds, got peak %d", peak)
+	}
+	if fmt.Sprint(gotAllowed) != fmt.Sprint(wantAllowed) {
+		t.Errorf("allowed = %v, want %v", gotAllowed, wantAllowed)
+	}
+	if len(gotDenied) != len(wantDenied) {
+		t.Fatalf("denied %d tasks, want %d", len(gotDenied), len(wantDenied))
+	}
+	for i := range gotDenied {
+		if gotDenied[i].taskID != wantDenied[i].taskID || gotDenied[i].errorCode != wantDenied[i].errorCode {
+			t.Errorf("denied[%d] = %s/%s, want %s/%s", i,
+				gotDenied[i].taskID, gotDenied[i].errorCode, wantDenied[i].taskID, wantDenied[i].errorCode)
+		}
+	}
+}
+
+// TestIntegration_TraceID verifies that a run gets a trace ID that appears in
+// every audit line for the run and in each executor's context.
+func TestIntegration_TraceID(t *testing.T) {
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	defer log.SetOutput(os.Stderr)
+
+	dag, err := buildFanInDAG()
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	run := createRun("run-trace", dag, createTasksFromDAG(dag, 100), defaultPolicy())
+
+	var mu sync.Mutex
+	seen := make(map[contracts.TaskID]string)
+	execute := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		mu.Lock()
+		seen[task.ID] = contracts.TraceIDFromContext(ctx)
+		mu.Unlock()
+		return &contracts.TaskResult{
+			Output: "ok",
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+
+	if err := NewOrchestrator(createRealDeps(run.Policy, execute)).Run(context.Background(), run); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(run.TraceID) != 32 {
+		t.Fatalf("expected a generated 32-char trace ID, got %q", run.TraceID)
+	}
+
+	for id, traceID := range seen {
+		if traceID != run.TraceID {
+			t.Errorf("task %s: executor context trace ID = %q, want %q", id, traceID, run.TraceID)
+		}
+	}
+	if len(seen) != len(run.Tasks) {
+		t.Errorf("expected %d executor calls, got %d", len(run.Tasks), len(seen))
+	}
+
+	var auditLines int
+	for _, event := range auditEvents(t, logBuf.String()) {
+		if event["run_id"] != "run-trace" {
+			continue
+		}
+		auditLines++
+		if event["trace_id"] != run.TraceID {
+			t.Errorf("audit event missing trace ID: %v", event)
+		}
+	}
+	if auditLines == 0 {
+		t.Fatal("expected audit lines for the run")
+	}
+}
+
+// policyRecorder wraps a ContextCompactor and records the policy of each call.
+type policyRecorder struct {
+	contracts.ContextCompactor
+	mu       sync.Mutex
+	policies []contracts.ContextPolicy
+}
+
+func (r *policyRecorder) Compact(bundle *contracts.ContextBundle, policy contracts.ContextPolicy) (*contracts.ContextBundle, error) {
+	r.mu.Lock()
+	r.policies = append(r.policies, policy)
+	r.mu.Unlock()
+	return r.ContextCompactor.Compact(bundle, policy)
+}
+
+// TestIntegration_ForceCompactRatio verifies that context accumulated along a
+// chain forces compaction once it exceeds the configured share of the model's
+// window, and not before.
+func TestIntegration_ForceCompactRatio(t *testing.T) {
+	ids := []contracts.TaskID{"A", "B", "C", "D"}
+	dag, err := buildLinearDAG(ids)
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+
+	tasks := createTasksFromDAG(dag, 100)
+	for _, task := range tasks {
+		task.Model = "tiny"
+	}
+	policy := defaultPolicy()
+	policy.MaxParallelism = 1
+	policy.ContextPolicy.ForceCompactRatio = 0.5 // 500 of 1000 tokens
+	run := createRun("run-force-compact", dag, tasks, policy)
+
+	// Each output doubles the prompt plus routed inputs, so context grows along the chain:
+	// assembled context is 25 (A), 125 (B), 325 (C) and 725 (D) tokens.
+	execute := func(_ context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		all := task.Inputs.Prompt
+		for _, v := range task.Inputs.Inputs {
+			all += v
+		}
+		return &contracts.TaskResult{
+			Output: strings.Repeat(all, 2),
+			Usage:  contracts.Usage{Tokens: 100, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+
+	catalog := cost.NewModelCatalogWithModels([]contracts.ModelInfo{
+		{ID: "tiny", MaxContext: 1000, InputCostPer1M: 1, OutputCostPer1M: 1},
+	}, nil)
+	recorder := &policyRecorder{ContextCompactor: ctxpkg.NewContextCompactor()}
+	deps := createRealDeps(policy, execute)
+	deps.Compactor = recorder
+	deps.CostCalc = cost.NewCostCalculatorWithCatalog(catalog, "USD")
+	deps.ModelCatalog = catalog
+
+	if err := NewOrchestrator(deps).Run(context.Background(), run); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assertRunCompleted(t, run)
+
+	if len(recorder.policies) != len(ids) {
+		t.Fatalf("expected %d compactions, got %d", len(ids), len(recorder.policies))
+	}
+	for i, p := range recorder.policies[:3] {
+		if p.Strategy != "" || p.MaxTokens != 0 {
+			t.Errorf("task %s: expected run policy, got forced %+v", ids[i], p)
+		}
+	}
+	// D: 500 token limit minus 375 tokens of prompt and routed input
+	forced := recorder.policies[3]
+	if forced.Strategy != ctxpkg.StrategyTruncate || forced.MaxTokens != 125 {
+		t.Errorf("task D: expected truncate to 125 tokens, got %+v", forced)
+	}
+}
+
+// TestIntegration_CancelTask verifies per-task cancel modes: a soft cancel skips
+// the task and its dependents still run with an empty routed output, a hard
+// cancel fails the run.
+func TestIntegration_CancelTask(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         CancelMode
+		target       contracts.TaskID
+		running      bool // cancel while the target executes (else before the run)
+		wantState    contracts.RunState
+		wantExecuted []contracts.TaskID
+	}{
+		{"soft while running", CancelSoft, "A", true, contracts.RunCompleted, []contracts.TaskID{"A", "B", "C"}},
+		{"soft before start", CancelSoft, "B", false, contracts.RunCompleted, []contracts.TaskID{"A", "C"}},
+		{"hard while running", CancelHard, "A", true, contracts.RunFailed, []contracts.TaskID{"A"}},
+		{"hard before start", CancelHard, "B", false, contracts.RunFailed, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dag, err := buildLinearDAG([]contracts.TaskID{"A", "B", "C"})
+			if err != nil {
+				t.Fatalf("BuildDAG failed: %v", err)
+			}
+			policy := defaultPolicy()
+			run := createRun("run-cancel-task", dag, createTasksFromDAG(dag, 100), policy)
+			canceller := NewTaskCanceller()
+			if !tt.running {
+				canceller.Cancel(tt.target, tt.mode)
+			}
+
+			stub := &stubExecutor{failFor: make(map[contracts.TaskID]error)}
+			execute := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+				stub.mu.Lock()
+				stub.executed = append(stub.executed, task.ID)
+				stub.mu.Unlock()
+				if tt.running && task.ID == tt.target {
+					canceller.Cancel(task.ID, tt.mode)
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+				return &contracts.TaskResult{
+					Output: fmt.Sprintf("ok:%s", task.ID),
+					Usage:  contracts.Usage{Tokens: 100, Cost: contracts.Cost{Amount: 0.000075, Currency: "USD"}},
+				}, nil
+			}
+
+			deps := createRealDeps(policy, execute)
+			deps.Canceller = canceller
+			err = NewOrchestrator(deps).Run(context.Background(), run)
+
+			if run.State != tt.wantState {
+				t.Errorf("run state = %v, want %v (err: %v)", run.State, tt.wantState, err)
+			}
+			// Tasks in one batch execute concurrently; compare as sets
+			got := stub.ExecutedTasks()
+			slices.Sort(got)
+			if !slices.Equal(got, tt.wantExecuted) {
+				t.Errorf("executed %v, want %v", got, tt.wantExecuted)
+			}
+
+			target := run.Tasks[tt.target]
+			switch tt.mode {
+			case CancelSoft:
+				if target.State != contracts.TaskSkipped || target.Error == nil || target.Error.Code != "soft_cancelled" {
+					t.Errorf("target = %v %+v, want skipped with soft_cancelled", target.State, target.Error)
+				}
+				next := run.DAG.Nodes[tt.target].Next[0]
+				if v, ok := run.Tasks[next].Inputs.Inputs[string(tt.target)]; !ok || v != "" {
+					t.Errorf("dependent %s input %s = %q (present %v), want empty", next, tt.target, v, ok)
+				}
+			case CancelHard:
+				if !errors.Is(err, contracts.ErrTaskCancelled) {
+					t.Errorf("expected ErrTaskCancelled, got %v", err)
+				}
+				if target.State != contracts.TaskFailed || target.Error == nil || target.Error.Code != "cancelled" {
+					t.Errorf("target = %v %+v, want failed with cancelled", target.State, target.Error)
+				}
+			}
+		})
+	}
+}
+
+// TestIntegration_RunSummaryAudit verifies that the final audit event counts
+// task outcomes and totals usage for a mixed-outcome run.
+func TestIntegration_RunSummaryAudit(t *testing.T) {
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	defer log.SetOutput(os.Stderr)
+
+	// A -> B, A -> C, B -> D, C -> D: A completes, B is skipped, C fails, D never runs
+	dag, err := buildDiamondDAG()
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	policy := defaultPolicy()
+	run := createRun("run-summary", dag, createTasksFromDAG(dag, 100), policy)
+
+	canceller := NewTaskCanceller()
+	canceller.Cancel("B", CancelSoft)
+	execute := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		if task.ID == "C" {
+			return nil, errors.New("boom")
+		}
+		return &contracts.TaskResult{
+			Output: "ok",
+			Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+		}, nil
+	}
+
+	deps := createRealDeps(policy, execute)
+	deps.Canceller = canceller
+	if err := NewOrchestrator(deps).Run(context.Background(), run); err == nil {
+		t.Fatal("expected run to fail")
+	}
+
+	var final map[string]any
+	for _, event := range auditEvents(t, logBuf.String()) {
+		if event["event"] == "run_failed" {
+			final = event
+		}
+	}
+	// Numeric fields are JSON numbers
+	for key, want := range map[string]any{
+		"tasks_completed": 1.0, "tasks_failed": 1.0, "tasks_skipped": 1.0, "tasks_unfinished": 1.0,
+		"total_tokens": 10.0, "total_cost": 0.0001, "total_currency": "USD",
+	} {
+		if final[key] != want {
+			t.Errorf("final audit event %s = %v, want %v: %v", key, final[key], want, final)
+		}
+	}
+	if _, ok := final["duration_ms"].(float64); !ok {
+		t.Errorf("final audit event duration_ms = %v, want a number", final["duration_ms"])
+	}
+}
+
+// TestIntegration_AuditEventSequence captures the audit events of a
+// single-task run through audit.SetOutput and checks their lifecycle order.
+func TestIntegration_AuditEventSequence(t *testing.T) {
+	var logBuf bytes.Buffer
+	audit.SetOutput(&logBuf)
+	defer audit.SetOutput(nil)
+
+	dag, err := buildLinearDAG([]contracts.TaskID{"A"})
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	policy := defaultPolicy()
+	run := createRun("run-audit-sequence", dag, createTasksFromDAG(dag, 100), policy)
+	stub := newStubExecutor()
+	if err := NewOrchestrator(createRealDeps(policy, stub.Execute)).Run(context.Background(), run); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	// Budget events come in between; only the lifecycle is checked
+	var got []string
+	for _, event := range auditEvents(t, logBuf.String()) {
+		name, _ := event["event"].(string)
+		if strings.HasPrefix(name, "run_") || strings.HasPrefix(name, "batch_") || strings.HasPrefix(name, "task_") {
+			got = append(got, name)
+		}
+	}
+	want := []string{"run_started", "batch_started", "task_started", "task_completed", "batch_completed", "run_completed"}
+	if !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+// auditEvents decodes the JSON audit events in logs, skipping other lines.
+func auditEvents(t *testing.T, logs string) []map[string]any {
+	t.Helper()
+	var events []map[string]any
+	for _, line := range strings.Split(logs, "\n") {
+		_, data, ok := strings.Cut(line, "[AUDIT] {")
+		if !ok {
+			continue
+		}
+		var event map[string]any
+		if err := json.Unmarshal([]byte("{"+data), &event); err != nil {
+			t.Fatalf("invalid audit event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestIntegration_ContinueOnFail(t *testing.T) {
+	// A -> B, A -> C, B -> D, C -> D
+	tests := []struct {
+		name      string
+		failing   contracts.TaskID
+		wantState map[contracts.TaskID]contracts.TaskState
+	}{
+		{
+			name:    "leaf",
+			failing: "D",
+			wantState: map[contracts.TaskID]contracts.TaskState{
+				"A": contracts.TaskCompleted, "B": contracts.TaskCompleted,
+				"C": contracts.TaskCompleted, "D": contracts.TaskFailed,
+			},
+		},
+		{
+			name:    "dependents skipped",
+			failing: "C",
+			wantState: map[contracts.TaskID]contracts.TaskState{
+				"A": contracts.TaskCompleted, "B": contracts.TaskCompleted,
+				"C": contracts.TaskFailed, "D": contracts.TaskSkipped,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dag, err := buildDiamondDAG()
+			if err != nil {
+				t.Fatalf("BuildDAG failed: %v", err)
+			}
+			policy := defaultPolicy()
+			run := createRun("continue-on-fail", dag, createTasksFromDAG(dag, 100), policy)
+			run.Tasks[tt.failing].ContinueOnFail = true
+
+			execute := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+				if task.ID == tt.failing {
+					return nil, errors.New("notification service down")
+				}
+				return &contracts.TaskResult{
+					Output: "ok",
+					Usage:  contracts.Usage{Tokens: 10, Cost: contracts.Cost{Amount: 0.0001, Currency: "USD"}},
+				}, nil
+			}
+
+			if err := NewOrchestrator(createRealDeps(policy, execute)).Run(context.Background(), run); err != nil {
+				t.Fatalf("expected run to complete, got %v", err)
+			}
+			if run.State != contracts.RunCompleted {
+				t.Errorf("run state = %v, want completed", run.State)
+			}
+			for id, want := range tt.wantState {
+				if got := run.Tasks[id].State; got != want {
+					t.Errorf("task %s state = %v, want %v", id, got, want)
+				}
+			}
+			if got := run.Tasks[tt.failing].Error; got == nil || got.Code != "execution_failed" {
+				t.Errorf("failing task error = %+v, want execution_failed", got)
+			}
+			if d := run.Tasks["D"]; tt.failing == "C" && (d.Error == nil || d.Error.Code != "dependency_failed") {
+				t.Errorf("task D error = %+v, want dependency_failed", d.Error)
+			}
+		})
+	}
+}
+
+// countingEstimator counts token estimations.
+type countingEstimator struct {
+	contracts.TokenEstimator
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *countingEstimator) Estimate(input *contracts.TaskInput, ctx *contracts.ContextBundle) (contracts.TokenCount, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	return c.TokenEstimator.Estimate(input, ctx)
+}
+
+func TestIntegration_BudgetAwareSelection(t *testing.T) {
+	ids := []contracts.TaskID{"A", "B", "C", "D", "E", "F"}
+	independent := make([]contracts.Task, len(ids))
+	for i, id := range ids {
+		independent[i] = contracts.Task{ID: id}
+	}
+
+	// Each task costs exactly its estimate; the budget covers two and a half
+	run := func(t *testing.T, selection bool) (*contracts.Run, []contracts.TaskID, int, error) {
+		t.Helper()
+		dag, err := NewDependencyResolver().BuildDAG(independent)
+		if err != nil {
+			t.Fatalf("BuildDAG failed: %v", err)
+		}
+		policy := defaultPolicy()
+		r := createRun("budget-aware", dag, createTasksFromDAG(dag, 400), policy)
+		r.State = contracts.RunRunning
+		_, estimate, dr := NewOrchestrator(createRealDeps(policy, nil)).(*orchestrator).estimateCost(r, "A", r.Tasks["A"])
+		if dr != nil {
+			t.Fatalf("estimate denied: %s", dr.errorMsg)
+		}
+		r.State = contracts.RunPending
+		r.Policy.BudgetLimit.Amount = estimate.Amount * 2.5
+
+		var mu sync.Mutex
+		var executed []contracts.TaskID
+		execute := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+			mu.Lock()
+			executed = append(executed, task.ID)
+			mu.Unlock()
+			return &contracts.TaskResult{Output: "ok", Usage: contracts.Usage{Tokens: 100, Cost: estimate}}, nil
+		}
+
+		estimator := &countingEstimator{TokenEstimator: cost.NewTokenEstimator()}
+		deps := createRealDeps(r.Policy, execute)
+		deps.TokenEstimator = estimator
+		deps.BudgetAwareSelection = selection
+		err = NewOrchestrator(deps).Run(context.Background(), r)
+		slices.Sort(executed)
+		return r, executed, estimator.calls, err
+	}
+
+	naiveRun, naiveExecuted, naiveCalls, err := run(t, false)
+	if !errors.Is(err, contracts.ErrBudgetExceeded) {
+		t.Fatalf("naive: expected ErrBudgetExceeded, got %v", err)
+	}
+	assertRunFailed(t, naiveRun)
+	if naiveCalls != len(ids) || len(naiveExecuted) != 0 {
+		t.Errorf("naive: expected %d estimations and no executions, got %d and %v", len(ids), naiveCalls, naiveExecuted)
+	}
+
+	selectRun, selectExecuted, selectCalls, err := run(t, true)
+	if !errors.Is(err, contracts.ErrBudgetExceeded) {
+		t.Fatalf("selection: expected ErrBudgetExceeded, got %v", err)
+	}
+	assertRunFailed(t, selectRun)
+	// Batch 1 estimates A, B and C and runs A and B; batch 2 reuses C's
+	// estimate, estimates D, and the pre-check denies C
+	if selectCalls != 4 {
+		t.Errorf("selection: expected 4 estimations (naive %d), got %d", naiveCalls, selectCalls)
+	}
+	if !slices.Equal(selectExecuted, []contracts.TaskID{"A", "B"}) {
+		t.Errorf("selection: expected A and B to run, got %v", selectExecuted)
+	}
+	if c := selectRun.Tasks["C"]; c.Error == nil || c.Error.Code != "budget_exceeded" {
+		t.Errorf("selection: expected C denied by the pre-check, got %+v", c.Error)
+	}
+	if selectRun.Usage.Cost.Amount > selectRun.Policy.BudgetLimit.Amount {
+		t.Errorf("selection: usage %.6f exceeds budget %.6f", selectRun.Usage.Cost.Amount, selectRun.Policy.BudgetLimit.Amount)
+	}
+}
+
+func TestSelectAffordable_OtherCurrency(t *testing.T) {
+	ids := []contracts.TaskID{"A", "B", "C"}
+	independent := make([]contracts.Task, len(ids))
+	for i, id := range ids {
+		independent[i] = contracts.Task{ID: id}
+	}
+	dag, err := NewDependencyResolver().BuildDAG(independent)
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	policy := defaultPolicy()
+	policy.BudgetLimit.Currency = "EUR"
+	run := createRun("budget-aware-eur", dag, createTasksFromDAG(dag, 400), policy)
+	run.State = contracts.RunRunning
+
+	deps := createRealDeps(policy, nil)
+	deps.BudgetEnforcer = cost.NewBudgetEnforcerWithConverter(fixedRates{"USD->EUR": 0.5})
+	deps.BudgetAwareSelection = true
+	o := NewOrchestrator(deps).(*orchestrator)
+	_, estimate, dr := o.estimateCost(run, "A", run.Tasks["A"])
+	if dr != nil {
+		t.Fatalf("estimate denied: %s", dr.errorMsg)
+	}
+	if estimate.Currency != "USD" {
+		t.Fatalf("estimate currency = %s, want USD", estimate.Currency)
+	}
+
+	// Two tasks fit in EUR, but only one if USD amounts were compared with it
+	run.Policy.BudgetLimit.Amount = estimate.Amount * 1.1
+	if got := o.selectAffordable(run, ids, 1); !slices.Equal(got, ids) {
+		t.Errorf("selected %v, want all of %v for the pre-check to convert", got, ids)
+	}
+}
+
+func TestIntegration_SplitTokenPreCheck(t *testing.T) {
+	// 4000 chars = 1000 input tokens of haiku (0.25/1M input, 1.25/1M output).
+	// Default ratio: 400 output tokens = 0.00075; ratio 4: 4000 = 0.00525.
+	tests := []struct {
+		name      string
+		role      string
+		wantState contracts.RunState
+	}{
+		{name: "default ratio fits", role: "reviewer", wantState: contracts.RunCompleted},
+		{name: "output-heavy role exceeds budget", role: "writer", wantState: contracts.RunFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dag, err := NewDependencyResolver().BuildDAG([]contracts.Task{{ID: "A"}})
+			if err != nil {
+				t.Fatalf("BuildDAG failed: %v", err)
+			}
+			policy := defaultPolicy()
+			policy.BudgetLimit.Amount = 0.002
+			run := createRun("split-estimate", dag, createTasksFromDAG(dag, 4000), policy)
+			run.Tasks["A"].Inputs.Metadata = map[string]string{"role": tt.role}
+
+			// The executor reports only the split; Tokens is filled in as the sum
+			execute := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+				return &contracts.TaskResult{Output: "ok", Usage: contracts.Usage{
+					InputTokens:  1000,
+					OutputTokens: 300,
+					Cost:         contracts.Cost{Amount: 0.000625, Currency: "USD"},
+				}}, nil
+			}
+
+			deps := createRealDeps(policy, execute)
+			deps.TokenEstimator = cost.NewTokenEstimatorWithOptions(cost.TokenEstimatorOptions{
+				RoleOutputRatios: map[string]float64{"writer": 4},
+			})
+			_ = NewOrchestrator(deps).Run(context.Background(), run)
+
+			if run.State != tt.wantState {
+				t.Fatalf("run state = %v, want %v", run.State, tt.wantState)
+			}
+			if tt.wantState == contracts.RunFailed {
+				if e := run.Tasks["A"].Error; e == nil || e.Code != "budget_exceeded" {
+					t.Errorf("task error = %+v, want budget_exceeded", e)
+				}
+				return
+			}
+			want := contracts.Usage{Tokens: 1300, InputTokens: 1000, OutputTokens: 300}
+			got := run.Usage
+			got.Cost = contracts.Cost{}
+			if got != want {
+				t.Errorf("run usage = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestIntegration_MaxFailures(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxFailures float64
+		wantStop    bool
+	}{
+		{name: "count", maxFailures: 1, wantStop: true},
+		{name: "fraction", maxFailures: 0.2, wantStop: true}, // 1 of 5 tasks
+		{name: "unlimited", maxFailures: 0},
+		{name: "count not exceeded", maxFailures: 2},
+		{name: "fraction not exceeded", maxFailures: 0.4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A chain A -> B -> C next to F1 and F2, which fail in the first batch
+			policy := defaultPolicy()
+			policy.MaxParallelism = 3
+			policy.MaxFailures = tt.maxFailures
+			run := buildRun(t, "run-failure-threshold", policy,
+				contracts.Task{ID: "A"},
+				contracts.Task{ID: "B", Deps: []contracts.TaskID{"A"}},
+				contracts.Task{ID: "C", Deps: []contracts.TaskID{"B"}},
+				contracts.Task{ID: "F1"},
+				contracts.Task{ID: "F2"},
+			)
+			for _, task := range run.Tasks {
+				task.ContinueOnFail = true
+			}
+			stub := newStubExecutor()
+			stub.failFor["F1"] = errors.New("upstream down")
+			stub.failFor["F2"] = errors.New("upstream down")
+
+			err := NewOrchestrator(createRealDeps(policy, stub.Execute)).Run(context.Background(), run)
+			if !tt.wantStop {
+				if err != nil {
+					t.Fatalf("run failed: %v", err)
+				}
+				assertRunCompleted(t, run)
+				assertTaskCompleted(t, run, "C")
+				return
+			}
+
+			if !errors.Is(err, contracts.ErrFailureThresholdExceeded) {
+				t.Fatalf("err = %v, want ErrFailureThresholdExceeded", err)
+			}
+			assertRunFailed(t, run)
+
+			// Only the first batch was dispatched
+			executed := stub.ExecutedTasks()
+			slices.Sort(executed)
+			if want := []contracts.TaskID{"A", "F1", "F2"}; !slices.Equal(executed, want) {
+				t.Fatalf("executed = %v, want %v", executed, want)
+			}
+			for _, id := range []contracts.TaskID{"B", "C"} {
+				if state := run.Tasks[id].State; state != contracts.TaskPending {
+					t.Errorf("%s state = %v, want pending", id, state)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegration_SoftLimit(t *testing.T) {
+	// A chain A -> B -> C whose tasks each cost 0.1
+	tests := []struct {
+		name        string
+		softLimit   float64
+		budgetLimit float64
+		wantWarning bool
+		wantErr     error
+	}{
+		{name: "crossed", softLimit: 0.15, budgetLimit: 1, wantWarning: true},
+		{name: "reached", softLimit: 0.3, budgetLimit: 1, wantWarning: true},
+		{name: "not reached", softLimit: 0.5, budgetLimit: 1},
+		{name: "disabled", softLimit: 0, budgetLimit: 1},
+		// A and B reach the soft limit; C's cost exceeds the budget when recorded
+		{name: "then hard limit", softLimit: 0.15, budgetLimit: 0.25, wantWarning: true, wantErr: contracts.ErrBudgetExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := defaultPolicy()
+			policy.BudgetLimit.Amount = tt.budgetLimit
+			policy.SoftLimit = contracts.Cost{Amount: tt.softLimit, Currency: "USD"}
+			run := buildRun(t, "run-soft-limit", policy,
+				contracts.Task{ID: "A"},
+				contracts.Task{ID: "B", Deps: []contracts.TaskID{"A"}},
+				contracts.Task{ID: "C", Deps: []contracts.TaskID{"B"}},
+			)
+			execute := fixedExecutor("ok", contracts.Cost{Amount: 0.1, Currency: "USD"})
+
+			err := NewOrchestrator(createRealDeps(policy, execute)).Run(context.Background(), run)
+			if run.BudgetWarning != tt.wantWarning {
+				t.Errorf("BudgetWarning = %v, want %v", run.BudgetWarning, tt.wantWarning)
+			}
+			if tt.wantErr == nil {
+				// The soft limit never stops the run
+				if err != nil {
+					t.Fatalf("run failed: %v", err)
+				}
+				assertRunCompleted(t, run)
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			assertRunFailed(t, run)
+			assertTaskCompleted(t, run, "B")
+			if c := run.Tasks["C"]; c.State != contracts.TaskFailed || c.Error == nil || c.Error.Code != "budget_exceeded" {
+				t.Errorf("C = %v %+v, want failed with budget_exceeded", c.State, c.Error)
+			}
+		})
+	}
+}
+
+func TestIntegration_ResultCurrency(t *testing.T) {
+	// A single task whose executor reports a cost of 0.1 in currency
+	tests := []struct {
+		name           string
+		currency       contracts.Currency
+		converter      contracts.CurrencyConverter
+		budgetDisabled bool
+		wantUsage      contracts.Cost
+	}{
+		{name: "mismatch", currency: "EUR"},
+		{name: "mismatch with budget disabled", currency: "EUR", budgetDisabled: true},
+		{name: "inherited", currency: "", wantUsage: contracts.Cost{Amount: 0.1, Currency: "USD"}},
+		{name: "converted", currency: "EUR", converter: fixedRates{"EUR->USD": 2}, wantUsage: contracts.Cost{Amount: 0.2, Currency: "USD"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := defaultPolicy()
+			policy.BudgetDisabled = tt.budgetDisabled
+			run := buildRun(t, "run-currency", policy, contracts.Task{ID: "A"})
+			deps := createRealDeps(policy, fixedExecutor("ok", contracts.Cost{Amount: 0.1, Currency: tt.currency}))
+			if tt.converter != nil {
+				deps.BudgetEnforcer = cost.NewBudgetEnforcerWithConverter(tt.converter)
+			}
+
+			err := NewOrchestrator(deps).Run(context.Background(), run)
+			if tt.wantUsage.Amount == 0 {
+				if !errors.Is(err, contracts.ErrCurrencyMismatch) {
+					t.Fatalf("err = %v, want ErrCurrencyMismatch", err)
+				}
+				assertRunFailed(t, run)
+				if a := run.Tasks["A"]; a.State != contracts.TaskFailed || a.Error == nil || a.Error.Code != "currency_mismatch" {
+					t.Errorf("A = %v %+v, want failed with currency_mismatch", a.State, a.Error)
+				}
+				if run.Usage.Cost.Amount != 0 {
+					t.Errorf("usage = %+v, want nothing recorded", run.Usage.Cost)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("run failed: %v", err)
+			}
+			assertRunCompleted(t, run)
+			if run.Usage.Cost != tt.wantUsage {
+				t.Errorf("run usage = %+v, want %+v", run.Usage.Cost, tt.wantUsage)
+			}
+			if tt.converter == nil {
+				if got := run.Tasks["A"].Outputs.Usage.Cost; got != tt.wantUsage {
+					t.Errorf("task cost = %+v, want %+v", got, tt.wantUsage)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegration_TaskCountsMixedOutcomes(t *testing.T) {
+	// A -> B (fails, continue_on_fail) -> D (skipped: dependency_failed)
+	// A -> C (skipped: condition_not_met)
+	// A -> E (completed)
+	run := buildRun(t, "run-task-counts", defaultPolicy(),
+		contracts.Task{ID: "A"},
+		contracts.Task{ID: "B", Deps: []contracts.TaskID{"A"}},
+		contracts.Task{ID: "C", Deps: []contracts.TaskID{"A"}},
+		contracts.Task{ID: "D", Deps: []contracts.TaskID{"B"}},
+		contracts.Task{ID: "E", Deps: []contracts.TaskID{"A"}},
+	)
+	run.Tasks["B"].ContinueOnFail = true
+	run.Tasks["C"].RunIf = &contracts.Condition{Key: "A", Equals: "needs_review"}
+
+	stub := newStubExecutor()
+	stub.failFor["B"] = errors.New("notification service down")
+
+	orch := NewOrchestrator(createRealDeps(run.Policy, stub.Execute)).(*orchestrator)
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	assertRunCompleted(t, run)
+
+	// Skipped tasks are terminal, and a tolerated failure is not a run failure
+	if !orch.allTerminal(run) {
+		t.Error("expected all tasks terminal")
+	}
+	if orch.hasFailures(run) {
+		t.Error("expected no run-failing task failures")
+	}
+
+	want := contracts.TaskCounts{Completed: 2, Failed: 1, Skipped: 2}
+	if counts := contracts.CountTasks(run.Tasks); counts != want {
+		t.Errorf("counts = %+v, want %+v", counts, want)
+	}
+
+	// Only the completed tasks added usage (the stub reports 100 tokens each)
+	if run.Usage.Tokens != 200 {
+		t.Errorf("usage tokens = %d, want 200", run.Usage.Tokens)
+	}
+}
+
+func TestIntegration_ContextTimeout(t *testing.T) {
+	// A fan-in (A, B -> C) whose dependency outputs exceed C's context limit,
+	// so C's context is summarized before C runs
+	tests := []struct {
+		name             string
+		contextTimeoutMs int64
+		timeoutMs        int64
+		summarizeDelay   time.Duration // blocks until the test ends if negative
+		executeDelay     time.Duration // of C
+		wantErr          error
+		wantCode         string
+	}{
+		{name: "compaction times out", contextTimeoutMs: 50, timeoutMs: 5000, summarizeDelay: -1,
+			wantErr: contracts.ErrContextTimeout, wantCode: "context_timeout"},
+		// Compaction takes longer than the task timeout but within the context timeout
+		{name: "separate from task timeout", contextTimeoutMs: 5000, timeoutMs: 50, summarizeDelay: 100 * time.Millisecond},
+		{name: "task times out", contextTimeoutMs: 5000, timeoutMs: 50, executeDelay: 5 * time.Second,
+			wantErr: contracts.ErrTaskTimeout, wantCode: "task_timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			defer close(release)
+
+			policy := defaultPolicy()
+			policy.ContextTimeoutMs = tt.contextTimeoutMs
+			policy.TimeoutMs = tt.timeoutMs
+			policy.ContextPolicy = contracts.ContextPolicy{Strategy: ctxpkg.StrategySummarize, MaxTokens: 150}
+			run := buildRun(t, "run-context-timeout", policy,
+				contracts.Task{ID: "A"},
+				contracts.Task{ID: "B"},
+				contracts.Task{ID: "C", Deps: []contracts.TaskID{"A", "B"}},
+			)
+
+			output := fixedExecutor(strings.Repeat("x", 400), contracts.Cost{Amount: 0.000075, Currency: "USD"})
+			execute := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+				if task.ID == "C" {
+					select {
+					case <-time.After(tt.executeDelay):
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					}
+				}
+				return output(ctx, task)
+			}
+			deps := createRealDeps(policy, execute)
+			deps.Compactor = ctxpkg.NewContextCompactorWithSummarizer(func(messages []string) string {
+				if tt.summarizeDelay < 0 {
+					<-release
+				}
+				time.Sleep(tt.summarizeDelay)
+				return "summary"
+			})
+
+			err := NewOrchestrator(deps).Run(context.Background(), run)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("run failed: %v", err)
+				}
+				assertRunCompleted(t, run)
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			assertRunFailed(t, run)
+			if c := run.Tasks["C"]; c.State != contracts.TaskFailed || c.Error == nil || c.Error.Code != tt.wantCode {
+				t.Errorf("C = %v %+v, want failed with %s", c.State, c.Error, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestIntegration_MaxContextTokens(t *testing.T) {
+	// A chain A -> B whose tasks output 400 characters, so B's context holds
+	// about 100 tokens. The run's context policy allows 1000.
+	tests := []struct {
+		name           string
+		limit          contracts.TokenCount
+		budgetDisabled bool
+		wantExceeded   bool
+	}{
+		{name: "exceeded", limit: 50, wantExceeded: true},
+		{name: "exceeded with budget disabled", limit: 50, budgetDisabled: true, wantExceeded: true},
+		{name: "no cap", limit: 0},
+		{name: "within cap", limit: 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := defaultPolicy()
+			policy.BudgetDisabled = tt.budgetDisabled
+			policy.ContextPolicy = contracts.ContextPolicy{Strategy: ctxpkg.StrategyTruncate, MaxTokens: 1000}
+			run := buildRun(t, "run-context-cap", policy,
+				contracts.Task{ID: "A"},
+				contracts.Task{ID: "B", Deps: []contracts.TaskID{"A"}},
+			)
+			run.Tasks["B"].MaxContextTokens = tt.limit
+			stub := newStubExecutor()
+			output := fixedExecutor(strings.Repeat("x", 400), contracts.Cost{Amount: 0.000075, Currency: "USD"})
+			execute := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+				stub.Execute(ctx, task)
+				return output(ctx, task)
+			}
+
+			err := NewOrchestrator(createRealDeps(policy, execute)).Run(context.Background(), run)
+			executedB := slices.Contains(stub.ExecutedTasks(), "B")
+			if !tt.wantExceeded {
+				if err != nil {
+					t.Fatalf("run failed: %v", err)
+				}
+				assertRunCompleted(t, run)
+				if !executedB {
+					t.Error("B not executed")
+				}
+				return
+			}
+
+			if !errors.Is(err, contracts.ErrContextTooLarge) {
+				t.Fatalf("err = %v, want ErrContextTooLarge", err)
+			}
+			assertRunFailed(t, run)
+			if b := run.Tasks["B"]; b.State != contracts.TaskFailed || b.Error == nil || b.Error.Code != "context_too_large" {
+				t.Errorf("B = %v %+v, want failed with context_too_large", b.State, b.Error)
+			}
+			if executedB {
+				t.Error("B executed despite its context cap")
+			}
+		})
+	}
+}
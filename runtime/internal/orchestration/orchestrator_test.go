@@ -41,6 +41,14 @@ func (m *mockDependencyResolver) BuildDAG(tasks []contracts.Task) (*contracts.DA
 	return nil, nil
 }
 
+func (m *mockDependencyResolver) Plan(dag *contracts.DAG) ([][]contracts.TaskID, error) {
+	return nil, nil
+}
+
+func (m *mockDependencyResolver) CriticalPath(dag *contracts.DAG) ([]contracts.TaskID, int) {
+	return nil, 0
+}
+
 func (m *mockDependencyResolver) Validate(dag *contracts.DAG) error {
 	if m.validateFn != nil {
 		return m.validateFn(dag)
@@ -276,6 +284,68 @@ func TestOrchestrator_SingleTask(t *testing.T) {
 	}
 }
 
+func TestOrchestrator_BatchLimitExceeded(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxBatches int
+		wantExecs  int
+	}{
+		{"explicit limit", 5, 5},
+		{"default limit", 0, defaultBatchesPerTask * 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := defaultDeps()
+			deps.MaxBatches = tt.maxBatches
+			// Scheduler keeps returning task-1 but never marks it complete
+			deps.Scheduler = &mockScheduler{
+				nextReadyFn: func(run *contracts.Run) ([]contracts.TaskID, error) {
+					return []contracts.TaskID{"task-1"}, nil
+				},
+				markCompleteFn: func(run *contracts.Run, taskID contracts.TaskID, result *contracts.TaskResult) error {
+					run.Tasks[taskID].State = contracts.TaskPending
+					return nil
+				},
+			}
+			execs := 0
+			deps.Executor = &mockParallelExecutor{
+				executeFn: func(ctx context.Context, run *contracts.Run, taskID contracts.TaskID) (*contracts.TaskResult, error) {
+					execs++
+					return &contracts.TaskResult{
+						Output: "done",
+						Usage:  contracts.Usage{Tokens: 100, Cost: contracts.Cost{Amount: 0.01, Currency: "USD"}},
+					}, nil
+				},
+			}
+
+			orch := NewOrchestrator(deps)
+			run := &contracts.Run{
+				ID: "run-1",
+				DAG: &contracts.DAG{Nodes: map[contracts.TaskID]*contracts.DAGNode{
+					"task-1": {ID: "task-1"},
+					"task-2": {ID: "task-2"},
+				}},
+				Tasks: map[contracts.TaskID]*contracts.Task{
+					"task-1": {ID: "task-1", State: contracts.TaskPending},
+					"task-2": {ID: "task-2", State: contracts.TaskPending},
+				},
+			}
+
+			err := orch.Run(context.Background(), run)
+			if !errors.Is(err, contracts.ErrBatchLimitExceeded) {
+				t.Fatalf("expected ErrBatchLimitExceeded, got %v", err)
+			}
+			if run.State != contracts.RunFailed {
+				t.Errorf("expected RunFailed, got %v", run.State)
+			}
+			if execs != tt.wantExecs {
+				t.Errorf("executed %d batches, want %d", execs, tt.wantExecs)
+			}
+		})
+	}
+}
+
 func TestOrchestrator_BudgetExceeded(t *testing.T) {
 	deps := defaultDeps()
 	deps.Scheduler = &mockScheduler{
@@ -681,6 +751,130 @@ func TestOrchestrator_NoDuplicateQueueing(t *testing.T) {
 	}
 }
 
+func TestOrchestrator_DuplicateReadyIDs(t *testing.T) {
+	deps := defaultDeps()
+
+	deps.Scheduler = &mockScheduler{
+		nextReadyFn: func(run *contracts.Run) ([]contracts.TaskID, error) {
+			// Faulty scheduler: repeats IDs within one batch
+			if run.Tasks["task-1"].State == contracts.TaskPending {
+				return []contracts.TaskID{"task-1", "task-2", "task-1", "task-2"}, nil
+			}
+			return nil, nil
+		},
+	}
+
+	var mu sync.Mutex
+	executions := make(map[contracts.TaskID]int)
+	deps.Executor = &mockParallelExecutor{
+		executeFn: func(ctx context.Context, run *contracts.Run, taskID contracts.TaskID) (*contracts.TaskResult, error) {
+			mu.Lock()
+			executions[taskID]++
+			mu.Unlock()
+			return &contracts.TaskResult{
+				Output: "done",
+				Usage:  contracts.Usage{Tokens: 100, Cost: contracts.Cost{Amount: 0.01, Currency: "USD"}},
+			}, nil
+		},
+	}
+
+	recorded := 0
+	deps.BudgetEnforcer = &mockBudgetEnforcer{
+		recordFn: func(run *contracts.Run, actual contracts.Cost) error {
+			recorded++
+			return nil
+		},
+	}
+
+	orch := NewOrchestrator(deps)
+	run := &contracts.Run{
+		ID: "run-1",
+		DAG: &contracts.DAG{Nodes: map[contracts.TaskID]*contracts.DAGNode{
+			"task-1": {ID: "task-1"},
+			"task-2": {ID: "task-2"},
+		}},
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"task-1": {ID: "task-1", State: contracts.TaskPending},
+			"task-2": {ID: "task-2", State: contracts.TaskPending},
+		},
+	}
+
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, id := range []contracts.TaskID{"task-1", "task-2"} {
+		if executions[id] != 1 {
+			t.Errorf("expected %s to execute once, got %d", id, executions[id])
+		}
+	}
+	if recorded != 2 {
+		t.Errorf("expected cost recorded once per task, got %d records", recorded)
+	}
+}
+
+func TestOrchestrator_ReadyTaskAlreadyRunning(t *testing.T) {
+	deps := defaultDeps()
+
+	calls := 0
+	deps.Scheduler = &mockScheduler{
+		nextReadyFn: func(run *contracts.Run) ([]contracts.TaskID, error) {
+			calls++
+			if calls == 1 {
+				// Faulty scheduler: task-2 is still running from an earlier dispatch
+				return []contracts.TaskID{"task-1", "task-2"}, nil
+			}
+			// The earlier dispatch of task-2 finishes on its own
+			run.Tasks["task-2"].State = contracts.TaskCompleted
+			return nil, nil
+		},
+	}
+
+	var mu sync.Mutex
+	executions := make(map[contracts.TaskID]int)
+	deps.Executor = &mockParallelExecutor{
+		executeFn: func(ctx context.Context, run *contracts.Run, taskID contracts.TaskID) (*contracts.TaskResult, error) {
+			mu.Lock()
+			executions[taskID]++
+			mu.Unlock()
+			return &contracts.TaskResult{
+				Output: "done",
+				Usage:  contracts.Usage{Tokens: 100, Cost: contracts.Cost{Amount: 0.01, Currency: "USD"}},
+			}, nil
+		},
+	}
+
+	orch := NewOrchestrator(deps)
+	run := &contracts.Run{
+		ID: "run-1",
+		DAG: &contracts.DAG{Nodes: map[contracts.TaskID]*contracts.DAGNode{
+			"task-1": {ID: "task-1"},
+			"task-2": {ID: "task-2"},
+		}},
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"task-1": {ID: "task-1", State: contracts.TaskPending},
+			"task-2": {ID: "task-2", State: contracts.TaskRunning},
+		},
+	}
+
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if executions["task-1"] != 1 {
+		t.Errorf("expected task-1 to execute once, got %d", executions["task-1"])
+	}
+	if executions["task-2"] != 0 {
+		t.Errorf("expected running task-2 to be filtered, executed %d times", executions["task-2"])
+	}
+	if run.Tasks["task-2"].Error != nil {
+		t.Errorf("expected task-2 not to fail, got %+v", run.Tasks["task-2"].Error)
+	}
+	if run.State != contracts.RunCompleted {
+		t.Errorf("expected RunCompleted, got %v", run.State)
+	}
+}
+
 func TestOrchestrator_ContextBuildError(t *testing.T) {
 	deps := defaultDeps()
 	deps.Scheduler = &mockScheduler{
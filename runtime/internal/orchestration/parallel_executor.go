@@ -2,17 +2,31 @@ package orchestration
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/anthropics/claude-workflow/runtime/contracts"
+	"github.com/anthropics/claude-workflow/runtime/internal/audit"
 )
 
 // TaskExecutorFunc is the function type for actual task execution.
 // In production, this would call an LLM API.
 type TaskExecutorFunc func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error)
 
+// StreamingTaskExecutorFunc is a TaskExecutorFunc variant that can emit partial
+// output chunks via emit while the task is running.
+// The returned TaskResult still carries the final output.
+type StreamingTaskExecutorFunc func(ctx context.Context, task *contracts.Task, emit func(chunk string)) (*contracts.TaskResult, error)
+
+// OutputChunkFunc receives a partial output chunk emitted by a streaming executor.
+// Must be safe for concurrent use: chunks from parallel tasks may arrive concurrently.
+type OutputChunkFunc func(taskID contracts.TaskID, chunk string)
+
 // parallelExecutor implements contracts.ParallelExecutor with bounded concurrency.
 // CRITICAL: This component handles concurrent task execution.
 // Race conditions here cause incorrect results or deadlocks.
@@ -23,12 +37,20 @@ type parallelExecutor struct {
 	sem      chan struct{}            // semaphore for bounded concurrency
 	executor TaskExecutorFunc         // actual task execution function
 	running  map[contracts.TaskID]bool // tracks currently running tasks
+	keySems  map[string]chan struct{}  // per-ConcurrencyKey semaphores, created on first use
+	clock    Clock                     // times task timeouts and retry delays
 }
 
 // NewParallelExecutor creates a new ParallelExecutor with specified max parallelism.
 // If maxParallelism <= 0, defaults to 1.
 // If executor is nil, uses a no-op executor that returns empty result.
 func NewParallelExecutor(maxParallelism int, executor TaskExecutorFunc) contracts.ParallelExecutor {
+	return NewParallelExecutorWithClock(maxParallelism, executor, nil)
+}
+
+// NewParallelExecutorWithClock creates a ParallelExecutor whose task timeouts
+// and retry delays run on clock. If clock is nil, uses SystemClock.
+func NewParallelExecutorWithClock(maxParallelism int, executor TaskExecutorFunc, clock Clock) contracts.ParallelExecutor {
 	if maxParallelism <= 0 {
 		maxParallelism = 1
 	}
@@ -39,6 +61,8 @@ func NewParallelExecutor(maxParallelism int, executor TaskExecutorFunc) contract
 		sem:      make(chan struct{}, maxParallelism),
 		executor: executor,
 		running:  make(map[contracts.TaskID]bool),
+		keySems:  make(map[string]chan struct{}),
+		clock:    clockOrSystem(clock),
 	}
 }
 
@@ -47,6 +71,23 @@ func NewParallelExecutorFromPolicy(policy contracts.RunPolicy, executor TaskExec
 	return NewParallelExecutor(policy.MaxParallelism, executor)
 }
 
+// NewStreamingParallelExecutor creates a ParallelExecutor for a streaming executor.
+// Partial output chunks are forwarded to onChunk (may be nil) tagged with the task ID.
+// If executor is nil, uses a no-op executor that returns empty result.
+func NewStreamingParallelExecutor(maxParallelism int, executor StreamingTaskExecutorFunc, onChunk OutputChunkFunc) contracts.ParallelExecutor {
+	if executor == nil {
+		return NewParallelExecutor(maxParallelism, nil)
+	}
+	return NewParallelExecutor(maxParallelism, func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		emit := func(chunk string) {
+			if onChunk != nil {
+				onChunk(task.ID, chunk)
+			}
+		}
+		return executor(ctx, task, emit)
+	})
+}
+
 // defaultExecutor is a no-op executor for testing.
 func defaultExecutor(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
 	return &contracts.TaskResult{
@@ -60,7 +101,8 @@ func defaultExecutor(ctx context.Context, task *contracts.Task) (*contracts.Task
 
 // Execute runs a task and returns its result.
 // Blocks until a concurrency slot is available.
-// ctx is used for cancellation; if run.Policy.TimeoutMs > 0, a timeout is also applied.
+// ctx is used for cancellation; if the task timeout (task.TimeoutMs, falling back
+// to run.Policy.TimeoutMs) is > 0, a timeout is also applied.
 //
 // IMPORTANT: This executor is "pure" - it does NOT mutate task.State or task.Outputs.
 // State management is the responsibility of Orchestrator and Scheduler.
@@ -83,19 +125,43 @@ func (p *parallelExecutor) Execute(ctx context.Context, run *contracts.Run, task
 	}
 	defer p.untrack(taskID)
 
+	// Acquire the task's concurrency key slot before a global slot, so tasks
+	// waiting on a busy resource don't hold parallelism from other tasks
+	dispatch := dispatchDone(ctx)
+	if task.ConcurrencyKey != "" {
+		keySem := p.keySemaphore(run, task.ConcurrencyKey)
+		select {
+		case keySem <- struct{}{}:
+			defer func() { <-keySem }()
+		case <-ctx.Done():
+			return nil, fmt.Errorf("task %s: concurrency key %s acquire cancelled: %w", taskID, task.ConcurrencyKey, contracts.ErrTaskCancelled)
+		case <-dispatch:
+			return nil, fmt.Errorf("task %s: dispatch stopped: %w: %w", taskID, errNotDispatched, contracts.ErrTaskCancelled)
+		}
+	}
+
 	// Acquire semaphore slot with ctx check (blocks if at capacity)
 	select {
 	case p.sem <- struct{}{}:
 		defer func() { <-p.sem }()
 	case <-ctx.Done():
 		return nil, fmt.Errorf("task %s: semaphore acquire cancelled: %w", taskID, contracts.ErrTaskCancelled)
+	case <-dispatch:
+		return nil, fmt.Errorf("task %s: dispatch stopped: %w: %w", taskID, errNotDispatched, contracts.ErrTaskCancelled)
 	}
 
-	// Apply timeout from policy if specified
-	execCtx := ctx
-	if run.Policy.TimeoutMs > 0 {
+	// Don't start new tasks once dispatch has stopped (abort grace window)
+	select {
+	case <-dispatch:
+		return nil, fmt.Errorf("task %s: dispatch stopped: %w: %w", taskID, errNotDispatched, contracts.ErrTaskCancelled)
+	default:
+	}
+
+	// Apply the task or policy timeout if specified
+	execCtx := contracts.WithTraceID(ctx, run.TraceID)
+	if timeout := taskTimeout(run, task); timeout > 0 {
 		var cancel context.CancelFunc
-		execCtx, cancel = context.WithTimeout(ctx, time.Duration(run.Policy.TimeoutMs)*time.Millisecond)
+		execCtx, cancel = withClockTimeout(execCtx, p.clock, timeout)
 		defer cancel()
 	}
 
@@ -104,7 +170,7 @@ func (p *parallelExecutor) Execute(ctx context.Context, run *contracts.Run, task
 	errCh := make(chan error, 1)
 
 	go func() {
-		result, err := p.executor(execCtx, task)
+		result, err := p.executeWithRetry(execCtx, run, task)
 		if err != nil {
 			errCh <- err
 		} else {
@@ -121,13 +187,175 @@ func (p *parallelExecutor) Execute(ctx context.Context, run *contracts.Run, task
 		return nil, fmt.Errorf("task %s failed: %w: %v", taskID, contracts.ErrTaskFailed, err)
 
 	case <-execCtx.Done():
-		if execCtx.Err() == context.DeadlineExceeded {
+		// A result delivered together with the cancel wins
+		select {
+		case result := <-resultCh:
+			return result, nil
+		default:
+		}
+		if context.Cause(execCtx) == context.DeadlineExceeded {
 			return nil, fmt.Errorf("task %s timed out: %w", taskID, contracts.ErrTaskTimeout)
 		}
 		return nil, fmt.Errorf("task %s cancelled: %w", taskID, contracts.ErrTaskCancelled)
 	}
 }
 
+// taskTimeout returns the task's own timeout, or the run-level one when
+// the task sets none. 0 = no timeout.
+func taskTimeout(run *contracts.Run, task *contracts.Task) time.Duration {
+	if task.TimeoutMs > 0 {
+		return time.Duration(task.TimeoutMs) * time.Millisecond
+	}
+	return time.Duration(run.Policy.TimeoutMs) * time.Millisecond
+}
+
+// keySemaphore returns the semaphore for a concurrency key. Its capacity is
+// run.Policy.ConcurrencyLimits[key] (default 1) when the key is first used.
+func (p *parallelExecutor) keySemaphore(run *contracts.Run, key string) chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sem, ok := p.keySems[key]
+	if !ok {
+		limit := run.Policy.ConcurrencyLimits[key]
+		if limit <= 0 {
+			limit = 1
+		}
+		sem = make(chan struct{}, limit)
+		p.keySems[key] = sem
+	}
+	return sem
+}
+
+// errNotDispatched marks a task cancelled before it started executing.
+var errNotDispatched = errors.New("task not dispatched")
+
+type dispatchCtxKey struct{}
+
+// withDispatchContext attaches dispatch to ctx; once dispatch is done, Execute
+// rejects tasks that have not started yet while ctx keeps running ones alive.
+func withDispatchContext(ctx, dispatch context.Context) context.Context {
+	return context.WithValue(ctx, dispatchCtxKey{}, dispatch)
+}
+
+// dispatchDone returns the Done channel of the dispatch context, or nil if none.
+func dispatchDone(ctx context.Context) <-chan struct{} {
+	if dispatch, ok := ctx.Value(dispatchCtxKey{}).(context.Context); ok {
+		return dispatch.Done()
+	}
+	return nil
+}
+
+// MaxTaskAttempts caps executor calls per task when its RetryOn matches the
+// failure and the task has no Retry policy.
+const MaxTaskAttempts = 3
+
+// retryBackoff is the delay before the first retry; it grows linearly per attempt.
+// Tasks with a Retry policy use its BackoffMs, doubled per attempt.
+var retryBackoff = 100 * time.Millisecond
+
+// executeWithRetry calls the executor, retrying failures that shouldRetry
+// accepts up to the task's attempt cap.
+// Retries share ctx, so the policy timeout bounds all attempts together.
+func (p *parallelExecutor) executeWithRetry(ctx context.Context, run *contracts.Run, task *contracts.Task) (*contracts.TaskResult, error) {
+	var jitter *rand.Rand
+	if run.Policy.RetryJitter {
+		jitter = jitterRand(run, task.ID)
+	}
+
+	maxAttempts := MaxTaskAttempts
+	if task.Retry != nil {
+		maxAttempts = task.Retry.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := p.executor(ctx, task)
+		if err == nil {
+			return result, nil
+		}
+
+		category := contracts.ClassifyError(err)
+		if attempt >= maxAttempts || !shouldRetry(task, category, err) {
+			return nil, err
+		}
+		if category == "" {
+			category = "other"
+		}
+
+		logRunEvent(run, "task_retry", audit.Fields{
+			"task_id":   task.ID,
+			"attempt":   attempt,
+			"category":  category,
+			"error_msg": err.Error(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-p.clock.After(taskRetryDelay(task, attempt, jitter)):
+		}
+	}
+}
+
+// shouldRetry reports whether a failure of the given category is retried.
+// Categories listed in task.RetryOn always are; with a Retry policy and no
+// RetryOn, every failure except cancellation is.
+func shouldRetry(task *contracts.Task, category string, err error) bool {
+	if len(task.RetryOn) > 0 {
+		return category != "" && slices.Contains(task.RetryOn, category)
+	}
+	return task.Retry != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, contracts.ErrTaskCancelled)
+}
+
+// maxRetryBackoff caps the exponential backoff of a task's Retry policy.
+const maxRetryBackoff = 5 * time.Minute
+
+// taskRetryDelay returns the backoff after a failed attempt of task:
+// exponential from task.Retry.BackoffMs (capped at maxRetryBackoff) if set,
+// retryDelay otherwise.
+func taskRetryDelay(task *contracts.Task, attempt int, jitter *rand.Rand) time.Duration {
+	if task.Retry == nil {
+		return retryDelay(attempt, jitter)
+	}
+	d := time.Duration(task.Retry.BackoffMs) * time.Millisecond
+	for i := 1; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return withJitter(min(d, maxRetryBackoff), jitter)
+}
+
+// retryDelay returns the default backoff after a failed attempt, linear in
+// the attempt. See withJitter for the jitter applied.
+func retryDelay(attempt int, jitter *rand.Rand) time.Duration {
+	return withJitter(time.Duration(attempt)*retryBackoff, jitter)
+}
+
+// withJitter applies equal jitter to a backoff d when a jitter source is set:
+// half of d plus a random share of the other half.
+func withJitter(d time.Duration, jitter *rand.Rand) time.Duration {
+	if jitter == nil {
+		return d
+	}
+	half := d / 2
+	return half + time.Duration(jitter.Int64N(int64(d-half)+1))
+}
+
+// jitterRand returns a task's backoff jitter source, seeded from the policy's
+// JitterSeed (or the run ID if unset) and the task ID.
+func jitterRand(run *contracts.Run, taskID contracts.TaskID) *rand.Rand {
+	seed := uint64(run.Policy.JitterSeed)
+	if seed == 0 {
+		seed = hashString(string(run.ID))
+	}
+	return rand.New(rand.NewPCG(seed, hashString(string(taskID))))
+}
+
+// hashString returns the 64-bit FNV-1a hash of s.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
 // validateAndTrack validates task exists and tracks it as being executed.
 // Does NOT mutate task state - that's Orchestrator's responsibility.
 func (p *parallelExecutor) validateAndTrack(run *contracts.Run, taskID contracts.TaskID) (*contracts.Task, error) {
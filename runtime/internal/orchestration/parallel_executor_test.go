@@ -3,6 +3,8 @@ package orchestration
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -229,6 +231,241 @@ func TestParallelExecutor_Timeout(t *testing.T) {
 	// Orchestrator is responsible for setting TaskFailed on timeout
 }
 
+func TestParallelExecutor_TaskTimeout(t *testing.T) {
+	slowExecutor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+			return &contracts.TaskResult{Output: "done"}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	tests := []struct {
+		name          string
+		policyTimeout int64
+		taskTimeout   int64
+		wantErr       error
+	}{
+		{name: "override shorter than policy", policyTimeout: 5000, taskTimeout: 20, wantErr: contracts.ErrTaskTimeout},
+		{name: "override longer than policy", policyTimeout: 20, taskTimeout: 5000},
+		{name: "no override uses policy", policyTimeout: 20, wantErr: contracts.ErrTaskTimeout},
+		{name: "override without policy", taskTimeout: 20, wantErr: contracts.ErrTaskTimeout},
+		{name: "no timeouts", policyTimeout: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewParallelExecutor(1, slowExecutor)
+			run := &contracts.Run{
+				ID:     "run-1",
+				State:  contracts.RunRunning,
+				Policy: contracts.RunPolicy{TimeoutMs: tt.policyTimeout},
+				Tasks: map[contracts.TaskID]*contracts.Task{
+					"task-1": {ID: "task-1", State: contracts.TaskPending, TimeoutMs: tt.taskTimeout},
+				},
+			}
+
+			_, err := executor.Execute(context.Background(), run, "task-1")
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("expected success, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParallelExecutor_TaskTimeoutMixed(t *testing.T) {
+	slowExecutor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+			return &contracts.TaskResult{Output: "done"}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	executor := NewParallelExecutor(3, slowExecutor)
+	run := &contracts.Run{
+		ID:     "run-1",
+		State:  contracts.RunRunning,
+		Policy: contracts.RunPolicy{TimeoutMs: 50},
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"short":   {ID: "short", State: contracts.TaskPending, TimeoutMs: 20},
+			"long":    {ID: "long", State: contracts.TaskPending, TimeoutMs: 5000},
+			"inherit": {ID: "inherit", State: contracts.TaskPending},
+		},
+	}
+
+	errs := make(map[contracts.TaskID]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for id := range run.Tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := executor.Execute(context.Background(), run, id)
+			mu.Lock()
+			errs[id] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if !errors.Is(errs["short"], contracts.ErrTaskTimeout) {
+		t.Errorf("short: expected ErrTaskTimeout, got %v", errs["short"])
+	}
+	if errs["long"] != nil {
+		t.Errorf("long: expected success, got %v", errs["long"])
+	}
+	if !errors.Is(errs["inherit"], contracts.ErrTaskTimeout) {
+		t.Errorf("inherit: expected ErrTaskTimeout, got %v", errs["inherit"])
+	}
+}
+
+func TestParallelExecutor_TaskTimeoutKeepsCancellation(t *testing.T) {
+	slowExecutor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	executor := NewParallelExecutor(1, slowExecutor)
+	run := &contracts.Run{
+		ID:    "run-1",
+		State: contracts.RunRunning,
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"task-1": {ID: "task-1", State: contracts.TaskPending, TimeoutMs: 5000},
+		},
+	}
+
+	// Cancelling the run context ends the task well before its own timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	_, err := executor.Execute(ctx, run, "task-1")
+	if !errors.Is(err, contracts.ErrTaskCancelled) {
+		t.Errorf("expected ErrTaskCancelled, got %v", err)
+	}
+}
+
+func TestParallelExecutor_RetryOn(t *testing.T) {
+	defer func(prev time.Duration) { retryBackoff = prev }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	errSchema := errors.New("output does not match schema")
+
+	tests := []struct {
+		name      string
+		retryOn   []string
+		retry     *contracts.RetryPolicy
+		failures  []error // returned by successive attempts before succeeding
+		wantCalls int32
+		wantErr   error
+	}{
+		{
+			name:      "timeout retried",
+			retryOn:   []string{contracts.RetryOnTimeout},
+			failures:  []error{context.DeadlineExceeded},
+			wantCalls: 2,
+		},
+		{
+			name:      "schema failure not retried",
+			retryOn:   []string{contracts.RetryOnTimeout},
+			failures:  []error{errSchema},
+			wantCalls: 1,
+			wantErr:   errSchema,
+		},
+		{
+			name:      "unlisted category not retried",
+			retryOn:   []string{contracts.RetryOnTimeout},
+			failures:  []error{contracts.ErrRateLimited},
+			wantCalls: 1,
+			wantErr:   contracts.ErrRateLimited,
+		},
+		{
+			name:      "no retry_on",
+			failures:  []error{contracts.ErrTransient},
+			wantCalls: 1,
+			wantErr:   contracts.ErrTransient,
+		},
+		{
+			name:      "attempts capped",
+			retryOn:   []string{contracts.RetryOnRateLimit},
+			failures:  []error{contracts.ErrRateLimited, contracts.ErrRateLimited, contracts.ErrRateLimited},
+			wantCalls: MaxTaskAttempts,
+			wantErr:   contracts.ErrRateLimited,
+		},
+		{
+			name:      "retry policy succeeds on second attempt",
+			retry:     &contracts.RetryPolicy{MaxAttempts: 3, BackoffMs: 1},
+			failures:  []error{errSchema},
+			wantCalls: 2,
+		},
+		{
+			name:      "retry policy exhausted",
+			retry:     &contracts.RetryPolicy{MaxAttempts: 4, BackoffMs: 1},
+			failures:  []error{errSchema, errSchema, errSchema, errSchema},
+			wantCalls: 4,
+			wantErr:   errSchema,
+		},
+		{
+			name:      "retry policy limited to retry_on",
+			retryOn:   []string{contracts.RetryOnRateLimit},
+			retry:     &contracts.RetryPolicy{MaxAttempts: 3, BackoffMs: 1},
+			failures:  []error{errSchema},
+			wantCalls: 1,
+			wantErr:   errSchema,
+		},
+		{
+			name:      "retry policy skips cancellation",
+			retry:     &contracts.RetryPolicy{MaxAttempts: 3, BackoffMs: 1},
+			failures:  []error{contracts.ErrTaskCancelled},
+			wantCalls: 1,
+			wantErr:   contracts.ErrTaskCancelled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			executor := NewParallelExecutor(1, func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+				n := atomic.AddInt32(&calls, 1)
+				if int(n) <= len(tt.failures) {
+					return nil, tt.failures[n-1]
+				}
+				return &contracts.TaskResult{Output: "done"}, nil
+			})
+
+			run := &contracts.Run{
+				ID:    "run-1",
+				State: contracts.RunRunning,
+				Tasks: map[contracts.TaskID]*contracts.Task{
+					"task-1": {ID: "task-1", State: contracts.TaskPending, RetryOn: tt.retryOn, Retry: tt.retry},
+				},
+			}
+
+			result, err := executor.Execute(context.Background(), run, "task-1")
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("expected %d executor calls, got %d", tt.wantCalls, got)
+			}
+			if tt.wantErr == nil {
+				if err != nil || result == nil || result.Output != "done" {
+					t.Fatalf("expected success after retry, got result=%v err=%v", result, err)
+				}
+				return
+			}
+			if !errors.Is(err, contracts.ErrTaskFailed) {
+				t.Errorf("expected ErrTaskFailed, got %v", err)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr.Error()) {
+				t.Errorf("expected error to mention %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestParallelExecutor_BoundedConcurrency(t *testing.T) {
 	maxParallelism := 2
 	var concurrent int32
@@ -278,6 +515,68 @@ func TestParallelExecutor_BoundedConcurrency(t *testing.T) {
 	}
 }
 
+func TestParallelExecutor_ConcurrencyKey(t *testing.T) {
+	var mu sync.Mutex
+	concurrent := make(map[string]int)
+	maxConcurrent := make(map[string]int)
+	track := func(key string, delta int) {
+		mu.Lock()
+		defer mu.Unlock()
+		concurrent[key] += delta
+		maxConcurrent[key] = max(maxConcurrent[key], concurrent[key])
+	}
+
+	slowExecutor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
+		track(task.ConcurrencyKey, 1)
+		track("*", 1)
+		defer track(task.ConcurrencyKey, -1)
+		defer track("*", -1)
+		time.Sleep(50 * time.Millisecond)
+		return &contracts.TaskResult{Output: string(task.ID)}, nil
+	}
+
+	executor := NewParallelExecutor(8, slowExecutor)
+
+	run := &contracts.Run{
+		ID:     "run-1",
+		State:  contracts.RunRunning,
+		Policy: contracts.RunPolicy{ConcurrencyLimits: map[string]int{"api": 2}},
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"db-1":   {ID: "db-1", State: contracts.TaskPending, ConcurrencyKey: "db"},
+			"db-2":   {ID: "db-2", State: contracts.TaskPending, ConcurrencyKey: "db"},
+			"api-1":  {ID: "api-1", State: contracts.TaskPending, ConcurrencyKey: "api"},
+			"api-2":  {ID: "api-2", State: contracts.TaskPending, ConcurrencyKey: "api"},
+			"free-1": {ID: "free-1", State: contracts.TaskPending},
+			"free-2": {ID: "free-2", State: contracts.TaskPending},
+		},
+	}
+
+	var wg sync.WaitGroup
+	for id := range run.Tasks {
+		wg.Add(1)
+		go func(id contracts.TaskID) {
+			defer wg.Done()
+			if _, err := executor.Execute(context.Background(), run, id); err != nil {
+				t.Errorf("task %s: %v", id, err)
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	if maxConcurrent["db"] != 1 {
+		t.Errorf("db tasks overlapped: max concurrent = %d, want 1", maxConcurrent["db"])
+	}
+	if maxConcurrent["api"] != 2 {
+		t.Errorf("api tasks: max concurrent = %d, want limit 2", maxConcurrent["api"])
+	}
+	if maxConcurrent[""] != 2 {
+		t.Errorf("unkeyed tasks: max concurrent = %d, want 2", maxConcurrent[""])
+	}
+	if maxConcurrent["*"] < 3 {
+		t.Errorf("tasks with different keys did not overlap: max concurrent = %d", maxConcurrent["*"])
+	}
+}
+
 func TestParallelExecutor_PreventsDuplicateExecution(t *testing.T) {
 	blockingExecutor := func(ctx context.Context, task *contracts.Task) (*contracts.TaskResult, error) {
 		time.Sleep(100 * time.Millisecond)
@@ -401,3 +700,96 @@ func TestParallelExecutor_ResultReturned(t *testing.T) {
 	// Note: ParallelExecutor is now "pure" - it does NOT set task.Outputs
 	// Scheduler.MarkComplete is responsible for that
 }
+
+func TestStreamingParallelExecutor_ForwardsChunks(t *testing.T) {
+	var mu sync.Mutex
+	var chunks []string
+
+	onChunk := func(taskID contracts.TaskID, chunk string) {
+		mu.Lock()
+		defer mu.Unlock()
+		chunks = append(chunks, string(taskID)+":"+chunk)
+	}
+
+	executor := NewStreamingParallelExecutor(1, func(ctx context.Context, task *contracts.Task, emit func(string)) (*contracts.TaskResult, error) {
+		emit("Hel")
+		emit("lo")
+		return &contracts.TaskResult{Output: "Hello", Usage: contracts.Usage{Tokens: 100}}, nil
+	}, onChunk)
+
+	run := &contracts.Run{
+		ID:    "run-1",
+		State: contracts.RunRunning,
+		Tasks: map[contracts.TaskID]*contracts.Task{
+			"task-1": {ID: "task-1", State: contracts.TaskPending},
+		},
+	}
+
+	result, err := executor.Execute(context.Background(), run, "task-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Output != "Hello" {
+		t.Errorf("result.Output = %q, want %q", result.Output, "Hello")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(chunks) != 2 || chunks[0] != "task-1:Hel" || chunks[1] != "task-1:lo" {
+		t.Errorf("unexpected chunks: %v", chunks)
+	}
+}
+
+func TestRetryDelay_Jitter(t *testing.T) {
+	run := &contracts.Run{
+		ID:     "run-1",
+		Policy: contracts.RunPolicy{RetryJitter: true, JitterSeed: 42},
+	}
+	delays := func(run *contracts.Run, taskID contracts.TaskID) []time.Duration {
+		jitter := jitterRand(run, taskID)
+		out := make([]time.Duration, MaxTaskAttempts-1)
+		for i := range out {
+			out[i] = retryDelay(i+1, jitter)
+		}
+		return out
+	}
+
+	a := delays(run, "task-a")
+	for i, d := range a {
+		base := time.Duration(i+1) * retryBackoff
+		if d < base/2 || d > base {
+			t.Errorf("attempt %d: delay %v outside [%v, %v]", i+1, d, base/2, base)
+		}
+	}
+	if again := delays(run, "task-a"); !slices.Equal(a, again) {
+		t.Errorf("same seed and task: delays %v, then %v", a, again)
+	}
+	if b := delays(run, "task-b"); slices.Equal(a, b) {
+		t.Errorf("tasks a and b got identical delays %v", a)
+	}
+
+	reseeded := &contracts.Run{ID: "run-1", Policy: contracts.RunPolicy{RetryJitter: true, JitterSeed: 7}}
+	if c := delays(reseeded, "task-a"); slices.Equal(a, c) {
+		t.Errorf("seeds 42 and 7 got identical delays %v", a)
+	}
+
+	if d := retryDelay(2, nil); d != 2*retryBackoff {
+		t.Errorf("without jitter: delay %v, want %v", d, 2*retryBackoff)
+	}
+}
+
+func TestTaskRetryDelay_Exponential(t *testing.T) {
+	task := &contracts.Task{Retry: &contracts.RetryPolicy{MaxAttempts: 4, BackoffMs: 10}}
+	for attempt, want := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond} {
+		if d := taskRetryDelay(task, attempt+1, nil); d != want {
+			t.Errorf("attempt %d: delay %v, want %v", attempt+1, d, want)
+		}
+	}
+
+	if d := taskRetryDelay(task, 40, nil); d != maxRetryBackoff {
+		t.Errorf("attempt 40: delay %v, want cap %v", d, maxRetryBackoff)
+	}
+	if d := taskRetryDelay(&contracts.Task{}, 2, nil); d != 2*retryBackoff {
+		t.Errorf("without retry policy: delay %v, want %v", d, 2*retryBackoff)
+	}
+}
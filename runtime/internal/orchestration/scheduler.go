@@ -9,7 +9,7 @@ import (
 
 // scheduler implements contracts.Scheduler using DAG-based task scheduling.
 // It determines which tasks are ready to execute based on dependency completion
-// and uses topological order with TaskID as tie-breaker for determinism.
+// and orders ready tasks by priority with TaskID as tie-breaker for determinism.
 //
 // Thread-safety: The scheduler assumes the caller holds appropriate locks.
 // All operations on Run and DAG must be externally synchronized.
@@ -20,7 +20,8 @@ func NewScheduler() contracts.Scheduler {
 	return &scheduler{}
 }
 
-// NextReady returns task IDs that are ready to execute (all deps satisfied).
+// NextReady returns task IDs that are ready to execute (all deps satisfied),
+// by Task.Priority (highest first) and then by TaskID.
 // Returns empty slice if no tasks are ready.
 // Returns error if run is in invalid state.
 func (s *scheduler) NextReady(run *contracts.Run) ([]contracts.TaskID, error) {
@@ -69,8 +70,12 @@ func (s *scheduler) NextReady(run *contracts.Run) ([]contracts.TaskID, error) {
 		}
 	}
 
-	// Sort by TaskID for deterministic ordering
+	// Sort by priority, then TaskID for deterministic ordering
 	sort.Slice(ready, func(i, j int) bool {
+		pi, pj := run.Tasks[ready[i]].Priority, run.Tasks[ready[j]].Priority
+		if pi != pj {
+			return pi > pj
+		}
 		return string(ready[i]) < string(ready[j])
 	})
 
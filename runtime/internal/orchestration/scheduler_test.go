@@ -513,3 +513,41 @@ func TestScheduler_ParallelTasks(t *testing.T) {
 		t.Errorf("ready = %v, want [task-3]", ready)
 	}
 }
+
+func TestScheduler_NextReadyPriority(t *testing.T) {
+	scheduler := NewScheduler()
+
+	priorities := map[contracts.TaskID]int{
+		"a": 0,
+		"b": 5,
+		"c": -1,
+		"d": 5,
+		"e": 0,
+		"f": 10,
+	}
+	run := &contracts.Run{
+		ID:    "run-1",
+		State: contracts.RunRunning,
+		DAG:   &contracts.DAG{Nodes: map[contracts.TaskID]*contracts.DAGNode{}},
+		Tasks: map[contracts.TaskID]*contracts.Task{},
+	}
+	for id, priority := range priorities {
+		run.DAG.Nodes[id] = &contracts.DAGNode{ID: id}
+		run.Tasks[id] = &contracts.Task{ID: id, State: contracts.TaskPending, Priority: priority}
+	}
+
+	ready, err := scheduler.NextReady(run)
+	if err != nil {
+		t.Fatalf("NextReady() error = %v", err)
+	}
+	// Highest priority first; ties (b/d, a/e) broken by ID
+	want := []contracts.TaskID{"f", "b", "d", "a", "e", "c"}
+	if len(ready) != len(want) {
+		t.Fatalf("NextReady() = %v, want %v", ready, want)
+	}
+	for i := range want {
+		if ready[i] != want[i] {
+			t.Fatalf("NextReady() = %v, want %v", ready, want)
+		}
+	}
+}
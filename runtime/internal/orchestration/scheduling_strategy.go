@@ -0,0 +1,35 @@
+package orchestration
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+// orderByCost returns ready sorted by descending estimated cost
+// (Policy.SchedulingStrategy = SchedulingCost), so the most expensive tasks
+// are pre-checked and handed to the executor first. Estimates are cached, so
+// the pre-check reuses them. Ties keep the scheduler's order; tasks that
+// can't be estimated go last and are denied by the pre-check.
+func (o *orchestrator) orderByCost(run *contracts.Run, ready []contracts.TaskID) []contracts.TaskID {
+	if len(ready) <= 1 {
+		return ready
+	}
+
+	estimates := o.estimateBatch(run, ready)
+	costs := make(map[contracts.TaskID]float64, len(ready))
+	for i, tid := range ready {
+		if _, exists := run.Tasks[tid]; !exists || estimates[i].denied != nil {
+			costs[tid] = -1
+			continue
+		}
+		costs[tid] = estimates[i].cost.Amount
+	}
+
+	ordered := slices.Clone(ready)
+	slices.SortStableFunc(ordered, func(a, b contracts.TaskID) int {
+		return cmp.Compare(costs[b], costs[a])
+	})
+	return ordered
+}
@@ -0,0 +1,107 @@
+package orchestration
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+// newCostSchedulingRun returns a run of three independent tasks whose prompt
+// sizes make B the most expensive and A the cheapest.
+func newCostSchedulingRun(t *testing.T, policy contracts.RunPolicy) *contracts.Run {
+	t.Helper()
+	dag, err := NewDependencyResolver().BuildDAG([]contracts.Task{{ID: "A"}, {ID: "B"}, {ID: "C"}})
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	tasks := createTasksFromDAG(dag, 40)
+	tasks["B"].Inputs.Prompt = strings.Repeat("x", 4000)
+	tasks["C"].Inputs.Prompt = strings.Repeat("x", 400)
+	return createRun("run-cost-scheduling", dag, tasks, policy)
+}
+
+func TestOrderByCost_ExpensiveFirst(t *testing.T) {
+	policy := defaultPolicy()
+	policy.MaxParallelism = 1
+	policy.SchedulingStrategy = contracts.SchedulingCost
+	run := newCostSchedulingRun(t, policy)
+
+	o := NewOrchestrator(createRealDeps(policy, nil)).(*orchestrator)
+	if err := o.init(run); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	ready, err := o.scheduler.NextReady(run)
+	if err != nil {
+		t.Fatalf("NextReady failed: %v", err)
+	}
+
+	ordered := o.orderByCost(run, ready)
+	if want := []contracts.TaskID{"B", "C", "A"}; !slices.Equal(ordered, want) {
+		t.Fatalf("dispatch order = %v, want %v", ordered, want)
+	}
+	if want := []contracts.TaskID{"A", "B", "C"}; !slices.Equal(ready, want) {
+		t.Errorf("scheduler order modified: %v, want %v", ready, want)
+	}
+
+	// The pre-check reuses the estimates made for ordering
+	allowed, denied := o.preCheckBudget(run, ordered)
+	if len(denied) != 0 || !slices.Equal(allowed, ordered) {
+		t.Fatalf("preCheckBudget = %v (denied %v), want %v", allowed, denied, ordered)
+	}
+	if hits, misses := o.estimateCache.Stats(); hits != 3 || misses != 3 {
+		t.Errorf("estimate cache hits=%d misses=%d, want 3 and 3", hits, misses)
+	}
+}
+
+func TestOrderByCost_TiesKeepSchedulerOrder(t *testing.T) {
+	policy := defaultPolicy()
+	policy.SchedulingStrategy = contracts.SchedulingCost
+	run := newCostSchedulingRun(t, policy)
+	run.Tasks["C"].Inputs.Prompt = run.Tasks["B"].Inputs.Prompt
+	run.Tasks["A"].Model = "unknown-model" // can't be estimated
+
+	o := NewOrchestrator(createRealDeps(policy, nil)).(*orchestrator)
+	if err := o.init(run); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	ordered := o.orderByCost(run, []contracts.TaskID{"C", "A", "B"})
+	if want := []contracts.TaskID{"C", "B", "A"}; !slices.Equal(ordered, want) {
+		t.Errorf("dispatch order = %v, want %v", ordered, want)
+	}
+}
+
+func TestIntegration_CostSchedulingDryRunPlan(t *testing.T) {
+	policy := defaultPolicy()
+	policy.DryRun = true
+	policy.SchedulingStrategy = contracts.SchedulingCost
+	run := newCostSchedulingRun(t, policy)
+
+	if err := NewOrchestrator(createRealDeps(policy, nil)).Run(context.Background(), run); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if run.Plan == nil || len(run.Plan.Batches) != 1 {
+		t.Fatalf("plan = %+v, want one batch", run.Plan)
+	}
+	if want := []contracts.TaskID{"B", "C", "A"}; !slices.Equal(run.Plan.Batches[0], want) {
+		t.Errorf("batch = %v, want %v", run.Plan.Batches[0], want)
+	}
+}
+
+func TestIntegration_CostScheduling(t *testing.T) {
+	policy := defaultPolicy()
+	policy.MaxParallelism = 1
+	policy.SchedulingStrategy = contracts.SchedulingCost
+	run := newCostSchedulingRun(t, policy)
+
+	executor := newStubExecutor()
+	if err := NewOrchestrator(createRealDeps(policy, executor.Execute)).Run(context.Background(), run); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	assertRunCompleted(t, run)
+	if executed := executor.ExecutedTasks(); len(executed) != 3 {
+		t.Errorf("executed %v, want all three tasks", executed)
+	}
+}
@@ -0,0 +1,86 @@
+package orchestration
+
+import (
+	"context"
+	"sync"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+// CancelMode selects how a cancelled task affects the rest of the run.
+type CancelMode string
+
+const (
+	// CancelHard fails the task with code "cancelled"; fail-fast ends the run.
+	CancelHard CancelMode = "hard"
+	// CancelSoft skips the task with code "soft_cancelled" and routes an empty
+	// output to its dependents, which still run.
+	CancelSoft CancelMode = "soft"
+)
+
+// TaskCanceller collects per-task cancel requests for a run.
+// A running task's context is cancelled immediately; the orchestrator applies
+// the request when it merges the task's result, or at the next batch boundary
+// for tasks that have not started. Requests for finished tasks are ignored.
+//
+// Thread-safety: safe for concurrent use by API handlers and the orchestrator.
+type TaskCanceller struct {
+	mu       sync.Mutex
+	requests map[contracts.TaskID]CancelMode
+	running  map[contracts.TaskID]context.CancelFunc
+}
+
+// NewTaskCanceller creates an empty TaskCanceller.
+func NewTaskCanceller() *TaskCanceller {
+	return &TaskCanceller{
+		requests: make(map[contracts.TaskID]CancelMode),
+		running:  make(map[contracts.TaskID]context.CancelFunc),
+	}
+}
+
+// Cancel requests cancellation of a task. A later request replaces the mode
+// of an earlier one that has not been applied yet.
+func (c *TaskCanceller) Cancel(taskID contracts.TaskID, mode CancelMode) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests[taskID] = mode
+	if cancel, ok := c.running[taskID]; ok {
+		cancel()
+	}
+}
+
+// start returns the execution context for a task, cancelled when the task is.
+// The returned func must be called once the task's executor returns.
+func (c *TaskCanceller) start(ctx context.Context, taskID contracts.TaskID) (context.Context, func()) {
+	taskCtx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, requested := c.requests[taskID]; requested {
+		cancel()
+	}
+	c.running[taskID] = cancel
+	return taskCtx, func() {
+		c.mu.Lock()
+		delete(c.running, taskID)
+		c.mu.Unlock()
+		cancel()
+	}
+}
+
+// take removes and returns the pending request for a task.
+func (c *TaskCanceller) take(taskID contracts.TaskID) (CancelMode, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	mode, ok := c.requests[taskID]
+	delete(c.requests, taskID)
+	return mode, ok
+}
+
+// takeAll removes and returns all pending requests.
+func (c *TaskCanceller) takeAll() map[contracts.TaskID]CancelMode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	requests := c.requests
+	c.requests = make(map[contracts.TaskID]CancelMode)
+	return requests
+}
@@ -0,0 +1,58 @@
+package orchestration
+
+import (
+	"fmt"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+	"github.com/anthropics/claude-workflow/runtime/internal/audit"
+)
+
+// skipUnmetConditions skips every ready task whose RunIf condition does not
+// hold and returns the remaining tasks and the number skipped. A skipped task
+// routes an empty output to its dependents (see routeSkipped), so they run as
+// if it completed with no output. Routing errors are fatal.
+func (o *orchestrator) skipUnmetConditions(run *contracts.Run, ready []contracts.TaskID) ([]contracts.TaskID, int, error) {
+	var out []contracts.TaskID
+	skipped := 0
+	for i, tid := range ready {
+		task, exists := run.Tasks[tid]
+		if !exists || task.RunIf == nil || conditionHolds(run, task) {
+			if out != nil {
+				out = append(out, tid)
+			}
+			continue
+		}
+		if out == nil {
+			out = append(make([]contracts.TaskID, 0, len(ready)), ready[:i]...)
+		}
+		skipped++
+
+		cond := task.RunIf
+		task.State = contracts.TaskSkipped
+		task.Error = &contracts.TaskError{
+			Code:    "condition_not_met",
+			Message: fmt.Sprintf("run_if %s == %q does not hold", cond.Key, cond.Equals),
+		}
+		logRunEvent(run, "task_skipped", audit.Fields{"task_id": tid, "reason": "condition_not_met", "key": cond.Key})
+		if err := o.routeSkipped(run, tid); err != nil {
+			return nil, skipped, err
+		}
+	}
+	if out == nil {
+		return ready, 0, nil
+	}
+	return out, skipped, nil
+}
+
+// conditionHolds evaluates task.RunIf against the task's routed inputs,
+// falling back to run memory.
+func conditionHolds(run *contracts.Run, task *contracts.Task) bool {
+	cond := task.RunIf
+	if task.Inputs != nil {
+		if value, ok := task.Inputs.Inputs[cond.Key]; ok {
+			return value == cond.Equals
+		}
+	}
+	value, ok := run.Memory[cond.Key]
+	return ok && value == cond.Equals
+}
@@ -0,0 +1,112 @@
+package orchestration
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+)
+
+// newConditionRun returns a diamond run (A -> B, C -> D) with the given RunIf
+// conditions set.
+func newConditionRun(t *testing.T, conds map[contracts.TaskID]*contracts.Condition) *contracts.Run {
+	t.Helper()
+	dag, err := buildDiamondDAG()
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	tasks := createTasksFromDAG(dag, 40)
+	for id, cond := range conds {
+		tasks[id].RunIf = cond
+	}
+	return createRun("run-condition", dag, tasks, defaultPolicy())
+}
+
+func TestIntegration_RunIfSkipsDiamondBranch(t *testing.T) {
+	run := newConditionRun(t, map[contracts.TaskID]*contracts.Condition{
+		"B": {Key: "A", Equals: "needs_review"},
+		"C": {Key: "A", Equals: "ok:A"},
+	})
+	stub := newStubExecutor()
+
+	orch := NewOrchestrator(createRealDeps(run.Policy, stub.Execute))
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	assertRunCompleted(t, run)
+
+	executed := stub.ExecutedTasks()
+	slices.Sort(executed)
+	if want := []contracts.TaskID{"A", "C", "D"}; !slices.Equal(executed, want) {
+		t.Fatalf("executed = %v, want %v", executed, want)
+	}
+
+	b := run.Tasks["B"]
+	if b.State != contracts.TaskSkipped {
+		t.Fatalf("B state = %v, want skipped", b.State)
+	}
+	if b.Error == nil || b.Error.Code != "condition_not_met" {
+		t.Errorf("B error = %+v, want condition_not_met", b.Error)
+	}
+
+	// D runs with an empty output from the skipped branch
+	inputs := run.Tasks["D"].Inputs.Inputs
+	if got, ok := inputs["B"]; !ok || got != "" {
+		t.Errorf("D input B = %q (present=%v), want empty", got, ok)
+	}
+	if got := inputs["C"]; got != "ok:C" {
+		t.Errorf("D input C = %q, want ok:C", got)
+	}
+}
+
+func TestIntegration_RunIfSkipsWholeBatch(t *testing.T) {
+	run := newConditionRun(t, map[contracts.TaskID]*contracts.Condition{
+		"B": {Key: "A", Equals: "no"},
+		"C": {Key: "A", Equals: "no"},
+	})
+	stub := newStubExecutor()
+
+	orch := NewOrchestrator(createRealDeps(run.Policy, stub.Execute))
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	assertRunCompleted(t, run)
+
+	executed := stub.ExecutedTasks()
+	slices.Sort(executed)
+	if want := []contracts.TaskID{"A", "D"}; !slices.Equal(executed, want) {
+		t.Fatalf("executed = %v, want %v", executed, want)
+	}
+	for _, id := range []contracts.TaskID{"B", "C"} {
+		if state := run.Tasks[id].State; state != contracts.TaskSkipped {
+			t.Errorf("%s state = %v, want skipped", id, state)
+		}
+	}
+	if pending := run.DAG.Nodes["D"].Pending; pending != 0 {
+		t.Errorf("D pending = %d, want 0", pending)
+	}
+}
+
+func TestIntegration_RunIfMemory(t *testing.T) {
+	run := newConditionRun(t, map[contracts.TaskID]*contracts.Condition{
+		"B": {Key: "needs_review", Equals: "true"},
+		"C": {Key: "missing", Equals: ""},
+	})
+	run.Memory["needs_review"] = "true"
+	stub := newStubExecutor()
+
+	orch := NewOrchestrator(createRealDeps(run.Policy, stub.Execute))
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	assertRunCompleted(t, run)
+
+	if state := run.Tasks["B"].State; state != contracts.TaskCompleted {
+		t.Errorf("B state = %v, want completed (memory condition holds)", state)
+	}
+	// A key found in neither inputs nor memory never matches, even ""
+	if state := run.Tasks["C"].State; state != contracts.TaskSkipped {
+		t.Errorf("C state = %v, want skipped", state)
+	}
+}
@@ -0,0 +1,119 @@
+package orchestration
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+	"github.com/anthropics/claude-workflow/runtime/internal/audit"
+)
+
+// MetadataDedupedFrom is the result metadata key set on a task completed with
+// a copy of an identical task's result. Its value is the executed task's ID.
+const MetadataDedupedFrom = "deduped_from"
+
+// dedupIdentical splits ready into tasks to execute and duplicates, mapped to
+// the task whose result they will share. A ready task is a duplicate when its
+// InputHash, recorded by setInputHash as at execution, matches a completed
+// task or a ready task with a smaller ID (ready is sorted, so the first of
+// each group executes).
+func (o *orchestrator) dedupIdentical(run *contracts.Run, ready []contracts.TaskID) ([]contracts.TaskID, map[contracts.TaskID]contracts.TaskID) {
+	primaries := make(map[string]contracts.TaskID)
+	for _, tid := range slices.Sorted(maps.Keys(run.Tasks)) {
+		task := run.Tasks[tid]
+		if task.State != contracts.TaskCompleted || task.Outputs == nil || task.Inputs == nil {
+			continue
+		}
+		if _, deduped := task.Outputs.Metadata[MetadataDedupedFrom]; deduped {
+			continue
+		}
+		if sum := task.Inputs.Metadata[MetadataInputHash]; sum != "" {
+			if _, seen := primaries[sum]; !seen {
+				primaries[sum] = tid
+			}
+		}
+	}
+
+	var dups map[contracts.TaskID]contracts.TaskID
+	execute := make([]contracts.TaskID, 0, len(ready))
+	for _, tid := range ready {
+		task, exists := run.Tasks[tid]
+		if !exists {
+			execute = append(execute, tid)
+			continue
+		}
+		setInputHash(run, task)
+		sum := task.Inputs.Metadata[MetadataInputHash]
+		if primary, seen := primaries[sum]; seen {
+			if dups == nil {
+				dups = make(map[contracts.TaskID]contracts.TaskID)
+			}
+			dups[tid] = primary
+			continue
+		}
+		primaries[sum] = tid
+		execute = append(execute, tid)
+	}
+	return execute, dups
+}
+
+// completeDuplicates completes each duplicate whose primary task completed,
+// in TaskID order, with a copy of the primary's result and zero usage, and
+// routes it to the duplicate's dependents. Duplicates of a primary that did
+// not complete are left as they are and considered again next batch.
+// Routing errors are fatal, as in mergeBatchResults.
+func (o *orchestrator) completeDuplicates(run *contracts.Run, dups map[contracts.TaskID]contracts.TaskID) error {
+	for _, tid := range slices.Sorted(maps.Keys(dups)) {
+		primaryID := dups[tid]
+		primary := run.Tasks[primaryID]
+		if primary.State != contracts.TaskCompleted || primary.Outputs == nil {
+			continue
+		}
+
+		task := run.Tasks[tid]
+		setInputHash(run, task)
+		result := &contracts.TaskResult{
+			Output:   primary.Outputs.Output,
+			Outputs:  maps.Clone(primary.Outputs.Outputs),
+			Metadata: maps.Clone(primary.Outputs.Metadata),
+		}
+		if result.Metadata == nil {
+			result.Metadata = make(map[string]string, 1)
+		}
+		result.Metadata[MetadataDedupedFrom] = string(primaryID)
+
+		if err := o.scheduler.MarkComplete(run, tid, result); err != nil {
+			task.State = contracts.TaskFailed
+			task.Error = &contracts.TaskError{
+				Code:    "scheduler_error",
+				Message: err.Error(),
+			}
+			return fmt.Errorf("task %s scheduler error: %w", tid, err)
+		}
+		logRunEvent(run, "task_deduped", audit.Fields{"task_id": tid, "deduped_from": primaryID})
+
+		node, nodeExists := run.DAG.Nodes[tid]
+		if !nodeExists {
+			task.State = contracts.TaskFailed
+			task.Error = &contracts.TaskError{
+				Code:    "dag_inconsistent",
+				Message: fmt.Sprintf("DAG node for task %s not found", tid),
+			}
+			return fmt.Errorf("task %s: DAG node not found", tid)
+		}
+		for _, depID := range node.Next {
+			if err := o.router.Route(run, tid, depID, result); err != nil {
+				if depTask, depExists := run.Tasks[depID]; depExists {
+					depTask.State = contracts.TaskFailed
+					depTask.Error = &contracts.TaskError{
+						Code:    "routing_failed",
+						Message: fmt.Sprintf("failed to route from %s: %v", tid, err),
+					}
+				}
+				return fmt.Errorf("routing from %s to %s failed: %w", tid, depID, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package orchestration
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/anthropics/claude-workflow/runtime/contracts"
+	ctxpkg "github.com/anthropics/claude-workflow/runtime/internal/context"
+)
+
+// newDedupRun returns a fan-in run (A, B -> C) where A and B are identical.
+func newDedupRun(t *testing.T, dedup bool) *contracts.Run {
+	t.Helper()
+	dag, err := buildFanInDAG()
+	if err != nil {
+		t.Fatalf("BuildDAG failed: %v", err)
+	}
+	policy := defaultPolicy()
+	policy.DedupIdenticalTasks = dedup
+	return createRun("run-dedup", dag, createTasksFromDAG(dag, 40), policy)
+}
+
+func TestIntegration_DedupIdenticalTasks(t *testing.T) {
+	run := newDedupRun(t, true)
+	stub := newStubExecutor()
+
+	orch := NewOrchestrator(createRealDeps(run.Policy, stub.Execute))
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	assertRunCompleted(t, run)
+
+	executed := stub.ExecutedTasks()
+	slices.Sort(executed)
+	if want := []contracts.TaskID{"A", "C"}; !slices.Equal(executed, want) {
+		t.Fatalf("executed = %v, want %v", executed, want)
+	}
+
+	b := run.Tasks["B"]
+	if b.State != contracts.TaskCompleted {
+		t.Fatalf("B state = %v, want completed", b.State)
+	}
+	if b.Outputs.Output != "ok:A" {
+		t.Errorf("B output = %q, want A's output", b.Outputs.Output)
+	}
+	if got := b.Outputs.Metadata[MetadataDedupedFrom]; got != "A" {
+		t.Errorf("B %s = %q, want A", MetadataDedupedFrom, got)
+	}
+	if b.Inputs.Metadata[MetadataInputHash] != run.Tasks["A"].Inputs.Metadata[MetadataInputHash] {
+		t.Error("B input hash differs from A")
+	}
+
+	// The dependent receives the shared output from both tasks
+	for _, from := range []string{"A", "B"} {
+		if got := run.Tasks["C"].Inputs.Inputs[from]; got != "ok:A" {
+			t.Errorf("C input %s = %q, want ok:A", from, got)
+		}
+	}
+	if run.Usage.Tokens != 200 {
+		t.Errorf("run tokens = %d, want 200 (A and C only)", run.Usage.Tokens)
+	}
+}
+
+func TestIntegration_DedupDisabled(t *testing.T) {
+	run := newDedupRun(t, false)
+	stub := newStubExecutor()
+
+	orch := NewOrchestrator(createRealDeps(run.Policy, stub.Execute))
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	assertRunCompleted(t, run)
+
+	if executed := stub.ExecutedTasks(); len(executed) != 3 {
+		t.Errorf("executed = %v, want all 3 tasks", executed)
+	}
+	if _, deduped := run.Tasks["B"].Outputs.Metadata[MetadataDedupedFrom]; deduped {
+		t.Error("B marked deduped with dedup disabled")
+	}
+}
+
+func TestIntegration_DedupDifferentInputs(t *testing.T) {
+	run := newDedupRun(t, true)
+	run.Tasks["B"].Inputs.Prompt = "different"
+	stub := newStubExecutor()
+
+	orch := NewOrchestrator(createRealDeps(run.Policy, stub.Execute))
+	if err := orch.Run(context.Background(), run); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if executed := stub.ExecutedTasks(); len(executed) != 3 {
+		t.Errorf("executed = %v, want all 3 tasks", executed)
+	}
+}
+
+func TestDedupIdentical_CompletedPrimary(t *testing.T) {
+	run := newDedupRun(t, true)
+	run.State = contracts.RunRunning
+	a := run.Tasks["A"]
+	a.State = contracts.TaskCompleted
+	a.Outputs = &contracts.TaskResult{Output: "done"}
+	setInputHash(run, a)
+
+	o := NewOrchestrator(createRealDeps(run.Policy, nil)).(*orchestrator)
+	execute, dups := o.dedupIdentical(run, []contracts.TaskID{"B"})
+	if len(execute) != 0 {
+		t.Errorf("execute = %v, want none", execute)
+	}
+	if dups["B"] != "A" {
+		t.Fatalf("dups = %v, want B -> A", dups)
+	}
+
+	if err := o.completeDuplicates(run, dups); err != nil {
+		t.Fatalf("completeDuplicates: %v", err)
+	}
+	if b := run.Tasks["B"]; b.State != contracts.TaskCompleted || b.Outputs.Output != "done" {
+		t.Errorf("B = %v %+v, want completed with A's output", b.State, b.Outputs)
+	}
+	if node := run.DAG.Nodes["C"]; node.Pending != 1 {
+		t.Errorf("C pending = %d, want 1 (only A's completion was not scheduled)", node.Pending)
+	}
+}
+
+func TestDedupIdentical_FromMemory(t *testing.T) {
+	run := newDedupRun(t, true)
+	run.State = contracts.RunRunning
+	run.Memory = map[string]string{"spec": "v1"}
+	for _, id := range []contracts.TaskID{"A", "B"} {
+		run.Tasks[id].Inputs.FromMemory = []string{"spec"}
+	}
+
+	// A executed in an earlier batch, with its memory inputs resolved
+	a := run.Tasks["A"]
+	a.State = contracts.TaskCompleted
+	a.Outputs = &contracts.TaskResult{Output: "done"}
+	ctxpkg.ResolveMemoryInputs(run, a)
+	a.Inputs.Metadata = map[string]string{MetadataInputHash: InputHash(run, a)}
+
+	o := NewOrchestrator(createRealDeps(run.Policy, nil)).(*orchestrator)
+	execute, dups := o.dedupIdentical(run, []contracts.TaskID{"B"})
+	if len(execute) != 0 || dups["B"] != "A" {
+		t.Fatalf("execute = %v, dups = %v, want B -> A", execute, dups)
+	}
+	if got := run.Tasks["B"].Inputs.Inputs["memory:spec"]; got != "v1" {
+		t.Errorf("B memory input = %q, want v1", got)
+	}
+}